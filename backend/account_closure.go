@@ -0,0 +1,582 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== ĐÓNG TÀI KHOẢN: ẨN DANH HOÁ, GIỮ LỊCH SỬ TÀI CHÍNH; XOÁ CỨNG CHỈ KHI QUÁ HẠN LƯU TRỮ ===== */
+
+// xử lý tuyến dưới của tài khoản bị đóng
+const (
+	DOWNLINE_KEEP     = "keep"     // giữ nguyên, upline vẫn là tài khoản đã đóng (không nhận hoa hồng)
+	DOWNLINE_REPARENT = "reparent" // chuyển F1 lên upline của tài khoản bị đóng
+)
+
+const (
+	CLOSURE_BY_ADMIN = "ADMIN"
+	CLOSURE_BY_USER  = "USER"
+)
+
+// Hồ sơ đóng tài khoản (giữ cả sau khi xoá cứng để đối chiếu). Không chứa thông tin cá nhân.
+type AccountClosure struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null;uniqueIndex" json:"userId"`
+	Initiator      string     `gorm:"size:10;not null" json:"initiator"` // ADMIN | USER
+	ClosedBy       uint       `gorm:"not null" json:"closedBy"`
+	Reason         string     `gorm:"size:255" json:"reason"`
+	DownlinePolicy string     `gorm:"size:10;not null" json:"downlinePolicy"`
+	ReparentedTo   *uint      `json:"reparentedTo,omitempty"`
+	Downlines      int64      `gorm:"not null;default:0" json:"downlines"`  // số F1 lúc đóng
+	Coins          int64      `gorm:"not null;default:0" json:"coins"`      // số dư còn lại trên tài khoản đã đóng
+	BonusCoins     int64      `gorm:"not null;default:0" json:"bonusCoins"` // (vẫn nằm trong users, sổ cái không đổi)
+	ClosedAt       time.Time  `gorm:"index" json:"closedAt"`
+	PurgedAt       *time.Time `json:"purgedAt,omitempty"`
+	PurgedBy       *uint      `json:"purgedBy,omitempty"`
+}
+
+type CloseAccountRequest struct {
+	Reason         string `json:"reason" binding:"required,max=255"`
+	DownlinePolicy string `json:"downlinePolicy" binding:"omitempty,oneof=keep reparent"` // mặc định theo closure.downline_policy
+}
+
+// File cần xoá sau khi transaction đóng tài khoản commit.
+type closedAccountFiles []string
+
+func (f closedAccountFiles) remove() {
+	for _, p := range f {
+		_ = os.Remove(p)
+	}
+}
+
+// Đóng tài khoản trong tx: trả lại tiền giữ lệnh mua & vật phẩm đang bán, ẩn danh hoá hồ sơ,
+// xử lý tuyến dưới. Dòng users được giữ làm "bia mộ" để mọi giao dịch cũ vẫn trỏ tới được.
+func closeAccount(tx *gorm.DB, uid uint, initiator string, by uint, reason, policy string) (AccountClosure, closedAccountFiles, error) {
+	var cl AccountClosure
+	var u User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, uid).Error; err != nil {
+		return cl, nil, apiError(ERR_USER_NOT_FOUND)
+	}
+	if u.Role == ROLE_SYSTEM {
+		return cl, nil, apiError(ERR_FORBIDDEN)
+	}
+	if u.Status == ACCOUNT_CLOSED {
+		return cl, nil, apiError(ERR_ACCOUNT_ALREADY_CLOSED)
+	}
+	if policy == "" {
+		policy = settingString("closure.downline_policy")
+	}
+	if policy != DOWNLINE_REPARENT {
+		policy = DOWNLINE_KEEP
+	}
+
+	if err := releaseMarketHoldings(tx, uid); err != nil {
+		return cl, nil, err
+	}
+	// số dư sau khi hoàn tiền giữ lệnh mua
+	if err := tx.Select("id, coins, bonus_coins").First(&u, uid).Error; err != nil {
+		return cl, nil, err
+	}
+
+	now := time.Now()
+	cl = AccountClosure{
+		UserID: uid, Initiator: initiator, ClosedBy: by, Reason: strings.TrimSpace(reason),
+		DownlinePolicy: policy, Coins: u.Coins, BonusCoins: u.BonusCoins, ClosedAt: now,
+	}
+	if err := tx.Model(&User{}).Where("referred_by = ?", uid).Count(&cl.Downlines).Error; err != nil {
+		return cl, nil, err
+	}
+	if policy == DOWNLINE_REPARENT && cl.Downlines > 0 {
+		cl.ReparentedTo = u.ReferredBy
+		if err := tx.Model(&User{}).Where("referred_by = ?", uid).Update("referred_by", u.ReferredBy).Error; err != nil {
+			return cl, nil, fmt.Errorf("reparent downlines: %w", err)
+		}
+	}
+
+	// file KYC & ảnh đại diện tải lên (xoá sau commit)
+	var files closedAccountFiles
+	for _, p := range []string{u.KYCFrontPath, u.KYCBackPath} {
+		if p != "" {
+			files = append(files, filepath.Join(kycAbs, p))
+		}
+	}
+	if name, ok := strings.CutPrefix(u.AvatarURL, "/uploads/"); ok && name != "" {
+		files = append(files, filepath.Join(uploadsAbs, filepath.Base(name)))
+	}
+
+	// ẩn danh hoá: không đăng nhập được nữa (không còn mật khẩu, username đổi)
+	if err := tx.Model(&User{}).Where("id = ?", uid).Updates(map[string]any{
+		"username": fmt.Sprintf("closed_%d", uid), "name": "", "phone": "", "avatar_url": "",
+		"password_hash": "", "second_password_hash": "", "txn_pin_hash": "",
+		"kyc_status": "NONE", "kyc_full_name": "", "kyc_number": "", "kyc_dob": "",
+		"kyc_front_path": "", "kyc_back_path": "", "referral_code": nil,
+		"status": ACCOUNT_CLOSED, "status_reason": cl.Reason, "closed_at": now,
+	}).Error; err != nil {
+		return cl, nil, fmt.Errorf("anonymize user: %w", err)
+	}
+	if err := tx.Where("user_id = ?", uid).Delete(&NotificationPref{}).Error; err != nil {
+		return cl, nil, err
+	}
+	// file xuất dữ liệu chứa ảnh KYC & thông tin cá nhân: huỷ link tải, xoá file (cả file đang tạo dở)
+	var exports []DataExport
+	if err := tx.Where("user_id = ? AND status IN ?", uid,
+		[]string{EXPORT_PENDING, EXPORT_RUNNING, EXPORT_READY}).Find(&exports).Error; err != nil {
+		return cl, nil, err
+	}
+	if len(exports) > 0 {
+		dir, err := ensureDirAbs(EXPORT_DIR)
+		if err != nil {
+			return cl, nil, err
+		}
+		ids := make([]uint, 0, len(exports))
+		for _, e := range exports {
+			ids = append(ids, e.ID)
+			name := dataExportFileName(e)
+			files = append(files, filepath.Join(dir, name), filepath.Join(dir, name+".tmp"))
+		}
+		if err := tx.Model(&DataExport{}).Where("id IN ?", ids).
+			Updates(map[string]any{"status": EXPORT_EXPIRED, "expires_at": now}).Error; err != nil {
+			return cl, nil, fmt.Errorf("expire data exports: %w", err)
+		}
+	}
+	if err := tx.Create(&cl).Error; err != nil {
+		return cl, nil, err
+	}
+	forgetAccountStatus(uid)
+	return cl, files, nil
+}
+
+// huỷ lệnh mua đang mở (hoàn tiền giữ) và đóng listing đang bán (trả vật phẩm về túi)
+func releaseMarketHoldings(tx *gorm.DB, uid uint) error {
+	var bids []MarketBid
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("buyer_id = ? AND status = ?", uid, BID_OPEN).Find(&bids).Error; err != nil {
+		return fmt.Errorf("lock market_bids: %w", err)
+	}
+	for _, b := range bids {
+		if b.Escrow > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", uid).
+				Update("coins", gorm.Expr("coins + ?", b.Escrow)).Error; err != nil {
+				return fmt.Errorf("refund market_bid %d: %w", b.ID, err)
+			}
+			if err := addLedger(tx, uid, CUR_COIN, LEDGER_BID_REFUND, b.ID, b.Escrow); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&b).Updates(map[string]any{"status": BID_CANCELLED, "escrow": 0}).Error; err != nil {
+			return err
+		}
+	}
+	var listings []MarketListing
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("seller_id = ? AND is_active = 1 AND qty > 0", uid).Find(&listings).Error; err != nil {
+		return fmt.Errorf("lock market_listings: %w", err)
+	}
+	for i := range listings {
+		if _, err := closeListing(tx, &listings[i]); err != nil {
+			return fmt.Errorf("close market_listing %d: %w", listings[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func parseIDParam(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// POST /admin/users/:id/close { reason, downlinePolicy }
+func adminCloseAccountHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	uid, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var req CloseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if uid == adminID {
+		respondError(c, apiError(ERR_FORBIDDEN))
+		return
+	}
+	var cl AccountClosure
+	var files closedAccountFiles
+	if err := withEvents(func(tx *gorm.DB) error {
+		var err error
+		cl, files, err = closeAccount(tx, uid, CLOSURE_BY_ADMIN, adminID, req.Reason, req.DownlinePolicy)
+		return err
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	files.remove()
+	c.JSON(200, gin.H{"message": "Đã đóng tài khoản", "closure": cl})
+}
+
+// GET /admin/account-closures?purgeable=1&cursor=&limit=
+func adminListAccountClosuresHandler(c *gin.Context) {
+	f, aerr := parseHistoryFilter(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	q := DB.Model(&AccountClosure{})
+	if c.Query("purgeable") == "1" {
+		q = q.Where("purged_at IS NULL AND closed_at <= ?", purgeCutoff())
+	}
+	if f.Cursor > 0 {
+		q = q.Where("id < ?", f.Cursor)
+	}
+	rows := []AccountClosure{}
+	if err := q.Order("id DESC").Limit(f.Limit + 1).Find(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	var next *uint
+	if len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		next = &rows[f.Limit-1].ID
+	}
+	c.JSON(200, gin.H{"rows": rows, "nextCursor": next, "retentionDays": settingInt("closure.purge_retention_days")})
+}
+
+/* ----- xoá cứng (superadmin) ----- */
+
+// tài khoản đóng trước mốc này mới được xoá cứng
+func purgeCutoff() time.Time {
+	return time.Now().AddDate(0, 0, -int(settingInt("closure.purge_retention_days")))
+}
+
+func superAdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+		var u User
+		if err := DB.Select("id, is_super_admin").First(&u, uid).Error; err != nil || !u.IsSuperAdmin {
+			respondError(c, apiError(ERR_SUPERADMIN_ONLY))
+			return
+		}
+		c.Next()
+	}
+}
+
+// chưa có superadmin nào ⇒ nâng admin cũ nhất
+func ensureSuperAdmin() {
+	var n int64
+	DB.Model(&User{}).Where("is_super_admin = ?", true).Count(&n)
+	if n > 0 {
+		return
+	}
+	var admin User
+	if err := DB.Where("role = ? AND status <> ?", "admin", ACCOUNT_CLOSED).
+		Order("id ASC").First(&admin).Error; err != nil {
+		return
+	}
+	if err := DB.Model(&admin).Update("is_super_admin", true).Error; err == nil {
+		fmt.Printf("✅ %s là superadmin\n", admin.Username)
+	}
+}
+
+// kiểm tra tài khoản được phép xoá cứng: đã đóng và quá hạn lưu trữ
+func checkPurgeable(u User) error {
+	if u.Status != ACCOUNT_CLOSED || u.ClosedAt == nil {
+		return apiError(ERR_ACCOUNT_NOT_CLOSED)
+	}
+	days := settingInt("closure.purge_retention_days")
+	if until := u.ClosedAt.AddDate(0, 0, int(days)); time.Now().Before(until) {
+		return apiError(ERR_PURGE_TOO_EARLY, "until", until.Format("2006-01-02"), "days", days)
+	}
+	return nil
+}
+
+/* ----- user tự đóng tài khoản ----- */
+
+const (
+	CLOSE_REQ_PENDING  = "PENDING"
+	CLOSE_REQ_APPROVED = "APPROVED"
+	CLOSE_REQ_REJECTED = "REJECTED"
+)
+
+const closeRequestFreezeReason = "Đang chờ chi trả để đóng tài khoản"
+
+// Yêu cầu đóng tài khoản còn số dư: tài khoản bị khoá (chỉ xem) tới khi admin chi trả & đóng hoặc từ chối.
+type AccountCloseRequest struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"userId"`
+	Reason     string     `gorm:"size:255" json:"reason"`
+	PayoutInfo string     `gorm:"size:255;not null" json:"payoutInfo"` // thông tin nhận tiền user cung cấp
+	Coins      int64      `gorm:"not null" json:"coins"`               // số dư lúc yêu cầu
+	BonusCoins int64      `gorm:"not null;default:0" json:"bonusCoins"`
+	Status     string     `gorm:"size:10;not null;index" json:"status"`
+	AdminID    *uint      `json:"adminId,omitempty"`
+	AdminNote  string     `gorm:"size:255" json:"adminNote,omitempty"`
+	WithdrawID *uint      `json:"withdrawId,omitempty"` // withdraw_txns.id của lần chi trả
+	CreatedAt  time.Time  `json:"createdAt"`
+	DecidedAt  *time.Time `json:"decidedAt,omitempty"`
+}
+
+type SelfCloseAccountRequest struct {
+	TxnPin         string `json:"txnPin" binding:"required,len=6"`
+	SecondPassword string `json:"secondPassword" binding:"required"`
+	Reason         string `json:"reason" binding:"max=255"`
+	Payout         bool   `json:"payout"`                       // còn coin: yêu cầu admin chi trả rồi đóng
+	PayoutInfo     string `json:"payoutInfo" binding:"max=255"` // bắt buộc khi payout
+}
+
+// POST /private/account/close { txnPin, secondPassword, reason, payout, payoutInfo }
+// Số dư coin = 0 ⇒ đóng ngay; còn coin ⇒ phải payout, tạo yêu cầu chờ admin chi trả.
+func selfCloseAccountHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req SelfCloseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	var u User
+	if err := DB.First(&u, uid).Error; err != nil {
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
+		return
+	}
+	if u.SecondPasswordHash == "" {
+		respondError(c, apiError(ERR_SECOND_PASSWORD_NOT_SET))
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.SecondPasswordHash), []byte(req.SecondPassword)); err != nil {
+		respondError(c, apiError(ERR_SECOND_PASSWORD_INVALID))
+		return
+	}
+	if strings.TrimSpace(u.TxnPinHash) == "" {
+		respondError(c, apiError(ERR_PIN_NOT_SET))
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.TxnPinHash), []byte(req.TxnPin)); err != nil {
+		respondError(c, apiError(ERR_PIN_INVALID))
+		return
+	}
+	req.PayoutInfo = strings.TrimSpace(req.PayoutInfo)
+
+	var files closedAccountFiles
+	var cr *AccountCloseRequest
+	if err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, uid).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		var n int64
+		if err := tx.Model(&AccountCloseRequest{}).Where("user_id = ? AND status = ?", uid, CLOSE_REQ_PENDING).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return apiError(ERR_CLOSE_REQUEST_PENDING)
+		}
+		// tiền giữ lệnh mua được hoàn trước khi xét số dư
+		if err := releaseMarketHoldings(tx, uid); err != nil {
+			return err
+		}
+		if err := tx.Select("id, coins, bonus_coins").First(&u, uid).Error; err != nil {
+			return err
+		}
+		if u.Coins == 0 {
+			var err error
+			_, files, err = closeAccount(tx, uid, CLOSURE_BY_USER, uid, req.Reason, "")
+			return err
+		}
+		if !req.Payout {
+			return apiError(ERR_CLOSE_BALANCE_NOT_ZERO, "coins", u.Coins)
+		}
+		if req.PayoutInfo == "" {
+			return apiError(ERR_INVALID_INPUT, "field", "payoutInfo", "detail", "bắt buộc khi payout")
+		}
+		cr = &AccountCloseRequest{
+			UserID: uid, Reason: strings.TrimSpace(req.Reason), PayoutInfo: req.PayoutInfo,
+			Coins: u.Coins, BonusCoins: u.BonusCoins, Status: CLOSE_REQ_PENDING,
+		}
+		if err := tx.Create(cr).Error; err != nil {
+			return err
+		}
+		if err := setAccountStatus(tx, uid, ACCOUNT_FROZEN, closeRequestFreezeReason, nil); err != nil {
+			return err
+		}
+		var admins []uint
+		if err := tx.Model(&User{}).Where("role = 'admin'").Pluck("id", &admins).Error; err != nil {
+			return err
+		}
+		for _, aid := range admins {
+			if err := notify(tx, aid, NT_CLOSE_REQUEST, map[string]any{"id": cr.ID, "userId": uid, "coins": u.Coins}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	if cr != nil {
+		c.JSON(200, gin.H{"message": "Đã gửi yêu cầu đóng tài khoản, tài khoản tạm khoá tới khi chi trả xong", "closed": false, "request": cr})
+		return
+	}
+	files.remove()
+	c.JSON(200, gin.H{"message": "Đã đóng tài khoản", "closed": true, "request": cr})
+}
+
+/* ----- admin duyệt yêu cầu đóng tài khoản ----- */
+
+// GET /admin/account-close-requests?status=PENDING|APPROVED|REJECTED|all&cursor=&limit=
+func adminListCloseRequestsHandler(c *gin.Context) {
+	f, aerr := parseHistoryFilter(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	q := DB.Model(&AccountCloseRequest{})
+	switch st := strings.ToUpper(strings.TrimSpace(c.DefaultQuery("status", CLOSE_REQ_PENDING))); st {
+	case "ALL":
+	case CLOSE_REQ_PENDING, CLOSE_REQ_APPROVED, CLOSE_REQ_REJECTED:
+		q = q.Where("status = ?", st)
+	default:
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "status", "detail", "chỉ nhận PENDING|APPROVED|REJECTED|all"))
+		return
+	}
+	if f.Cursor > 0 {
+		q = q.Where("id < ?", f.Cursor)
+	}
+	rows := []AccountCloseRequest{}
+	if err := q.Order("id DESC").Limit(f.Limit + 1).Find(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	var next *uint
+	if len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		next = &rows[f.Limit-1].ID
+	}
+	c.JSON(200, gin.H{"rows": rows, "nextCursor": next})
+}
+
+type CloseRequestDecision struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+// khoá & kiểm tra yêu cầu còn PENDING
+func lockCloseRequest(tx *gorm.DB, id uint) (AccountCloseRequest, error) {
+	var cr AccountCloseRequest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cr, id).Error; err != nil {
+		return cr, apiError(ERR_CLOSE_REQUEST_NOT_FOUND)
+	}
+	if cr.Status != CLOSE_REQ_PENDING {
+		return cr, apiError(ERR_CLOSE_REQUEST_DECIDED)
+	}
+	return cr, nil
+}
+
+// POST /admin/account-close-requests/:id/approve { note } — rút toàn bộ coin (đã chi trả ngoài hệ thống) rồi đóng tài khoản
+func adminApproveCloseRequestHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var req CloseRequestDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	var cr AccountCloseRequest
+	var cl AccountClosure
+	var files closedAccountFiles
+	if err := withEvents(func(tx *gorm.DB) error {
+		var err error
+		if cr, err = lockCloseRequest(tx, id); err != nil {
+			return err
+		}
+		var u User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, cr.UserID).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		now := time.Now()
+		upd := map[string]any{"status": CLOSE_REQ_APPROVED, "admin_id": adminID, "admin_note": strings.TrimSpace(req.Note), "decided_at": now}
+		if u.Coins > 0 {
+			if err := checkRestriction(tx, u.ID, RESTRICT_WITHDRAW); err != nil {
+				return err
+			}
+			if err := tx.Model(&u).Update("coins", gorm.Expr("coins - ?", u.Coins)).Error; err != nil {
+				return err
+			}
+			emitBalanceChanged(tx, u.ID, "withdraw")
+			txn := WithdrawTxn{UserID: u.ID, AdminID: adminID, Amount: u.Coins, Note: fmt.Sprintf("Chi trả đóng tài khoản (yêu cầu #%d)", cr.ID)}
+			if err := tx.Create(&txn).Error; err != nil {
+				return err
+			}
+			if err := addLedger(tx, u.ID, CUR_COIN, LEDGER_WITHDRAW, txn.ID, -u.Coins); err != nil {
+				return err
+			}
+			upd["withdraw_id"] = txn.ID
+		}
+		if err := tx.Model(&cr).Updates(upd).Error; err != nil {
+			return err
+		}
+		cl, files, err = closeAccount(tx, u.ID, CLOSURE_BY_USER, adminID, cr.Reason, "")
+		return err
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	files.remove()
+	c.JSON(200, gin.H{"message": "Đã chi trả và đóng tài khoản", "request": cr, "closure": cl})
+}
+
+// POST /admin/account-close-requests/:id/reject { note } — mở khoá tài khoản, báo user
+func adminRejectCloseRequestHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var req CloseRequestDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	var cr AccountCloseRequest
+	if err := withEvents(func(tx *gorm.DB) error {
+		var err error
+		if cr, err = lockCloseRequest(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Model(&cr).Updates(map[string]any{
+			"status": CLOSE_REQ_REJECTED, "admin_id": adminID, "admin_note": req.Note, "decided_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		// chỉ mở khoá nếu tài khoản đang bị khoá vì yêu cầu này (không phải do đối soát...)
+		var u User
+		if err := tx.Select("id, status, status_reason").First(&u, cr.UserID).Error; err != nil {
+			return err
+		}
+		if u.Status == ACCOUNT_FROZEN && u.StatusReason == closeRequestFreezeReason {
+			if err := setAccountStatus(tx, u.ID, ACCOUNT_ACTIVE, "", nil); err != nil {
+				return err
+			}
+		}
+		return notify(tx, u.ID, NT_CLOSE_REJECTED, map[string]any{"note": req.Note})
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã từ chối yêu cầu đóng tài khoản", "request": cr})
+}
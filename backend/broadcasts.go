@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== ADMIN: GỬI THÔNG BÁO HÀNG LOẠT (BROADCAST) ===== */
+
+const (
+	BROADCAST_SCHEDULED = "SCHEDULED"
+	BROADCAST_RUNNING   = "RUNNING"
+	BROADCAST_DONE      = "DONE"
+	BROADCAST_CANCELLED = "CANCELLED"
+	BROADCAST_FAILED    = "FAILED"
+)
+
+const (
+	broadcastBatchSize = 500
+	broadcastLease     = 2 * time.Minute // instance đang gửi phải gia hạn trước khi hết lease
+)
+
+// Nhóm người nhận. Để trống toàn bộ => gửi mọi người dùng.
+type BroadcastSegment struct {
+	VipLevels      []int      `json:"vipLevels,omitempty"`
+	KycStatus      string     `json:"kycStatus,omitempty"` // NONE | VERIFIED
+	RegisteredFrom *time.Time `json:"registeredFrom,omitempty"`
+	RegisteredTo   *time.Time `json:"registeredTo,omitempty"`
+	DownlineOf     *uint      `json:"downlineOf,omitempty"`
+	DownlineDepth  int        `json:"downlineDepth,omitempty"` // 1..9, 0 => 9
+}
+
+type Broadcast struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Title       string     `gorm:"size:120;not null" json:"title"`
+	Body        string     `gorm:"size:500;not null" json:"body"`
+	Segment     string     `gorm:"type:text" json:"-"` // BroadcastSegment dạng JSON
+	Status      string     `gorm:"size:12;not null;index:idx_broadcast_due,priority:1" json:"status"`
+	ScheduledAt time.Time  `gorm:"not null;index:idx_broadcast_due,priority:2" json:"scheduledAt"`
+	Total       int64      `gorm:"not null;default:0" json:"total"`
+	Sent        int64      `gorm:"not null;default:0" json:"sent"`
+	LastUserID  uint       `gorm:"not null;default:0" json:"-"` // con trỏ để gửi tiếp sau khi khởi động lại
+	LeaseUntil  *time.Time `json:"-"`
+	Error       string     `gorm:"size:255" json:"error,omitempty"`
+	CreatedBy   uint       `gorm:"not null" json:"createdBy"`
+	StartedAt   *time.Time `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func (b *Broadcast) segment() BroadcastSegment {
+	var s BroadcastSegment
+	_ = json.Unmarshal([]byte(b.Segment), &s)
+	return s
+}
+
+func (s *BroadcastSegment) validate() error {
+	for _, lv := range s.VipLevels {
+		if lv < 0 {
+			return apiError(ERR_SEGMENT_INVALID, "field", "vipLevels")
+		}
+	}
+	s.KycStatus = strings.ToUpper(strings.TrimSpace(s.KycStatus))
+	if s.KycStatus != "" && s.KycStatus != "NONE" && s.KycStatus != "VERIFIED" {
+		return apiError(ERR_SEGMENT_INVALID, "field", "kycStatus")
+	}
+	if s.RegisteredFrom != nil && s.RegisteredTo != nil && s.RegisteredTo.Before(*s.RegisteredFrom) {
+		return apiError(ERR_SEGMENT_INVALID, "field", "registeredTo")
+	}
+	if s.DownlineOf != nil {
+		var n int64
+		DB.Model(&User{}).Where("id = ?", *s.DownlineOf).Count(&n)
+		if n == 0 {
+			return apiError(ERR_SEGMENT_INVALID, "field", "downlineOf")
+		}
+	}
+	if s.DownlineDepth < 0 || s.DownlineDepth > 9 {
+		return apiError(ERR_SEGMENT_INVALID, "field", "downlineDepth")
+	}
+	return nil
+}
+
+// lọc user theo segment; downline lọc riêng bằng danh sách ID.
+// Luôn bỏ tài khoản hệ thống, tài khoản đã đóng (bia mộ ẩn danh) và đang bị cấm (không đăng nhập được để đọc);
+// FROZEN vẫn nhận vì còn xem được.
+func (s BroadcastSegment) scope(db *gorm.DB) *gorm.DB {
+	db = db.Where("role <> ? AND status <> ?", ROLE_SYSTEM, ACCOUNT_CLOSED).
+		Where("NOT (status = ? AND (status_until IS NULL OR status_until > ?))", ACCOUNT_BANNED, time.Now())
+	if len(s.VipLevels) > 0 {
+		db = db.Where("v_ip_level IN ?", s.VipLevels)
+	}
+	if s.KycStatus != "" {
+		db = db.Where("kyc_status = ?", s.KycStatus)
+	}
+	if s.RegisteredFrom != nil {
+		db = db.Where("created_at >= ?", *s.RegisteredFrom)
+	}
+	if s.RegisteredTo != nil {
+		db = db.Where("created_at < ?", *s.RegisteredTo)
+	}
+	return db
+}
+
+// danh sách ID downline đã sắp xếp (nil nếu segment không lọc theo downline)
+func (s BroadcastSegment) downlineIDs() ([]uint, error) {
+	if s.DownlineOf == nil {
+		return nil, nil
+	}
+	depth := s.DownlineDepth
+	if depth == 0 {
+		depth = 9
+	}
+	_, ids, err := downlineIDsByDepth(DB, *s.DownlineOf, depth)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func countSegment(s BroadcastSegment) (int64, error) {
+	down, err := s.downlineIDs()
+	if err != nil {
+		return 0, err
+	}
+	if s.DownlineOf == nil {
+		var n int64
+		err := DB.Model(&User{}).Scopes(s.scope).Count(&n).Error
+		return n, err
+	}
+	var total int64
+	for chunk := range slices.Chunk(down, 1000) {
+		var n int64
+		if err := DB.Model(&User{}).Scopes(s.scope).Where("id IN ?", chunk).Count(&n).Error; err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// lô người nhận tiếp theo sau con trỏ afterID; trả về con trỏ mới, done=true khi hết
+func nextRecipients(s BroadcastSegment, down []uint, afterID uint) (ids []uint, cursor uint, done bool, err error) {
+	if s.DownlineOf == nil {
+		err = DB.Model(&User{}).Scopes(s.scope).Where("id > ?", afterID).
+			Order("id ASC").Limit(broadcastBatchSize).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return nil, afterID, err == nil, err
+		}
+		return ids, ids[len(ids)-1], len(ids) < broadcastBatchSize, nil
+	}
+	i, _ := slices.BinarySearch(down, afterID+1)
+	if i >= len(down) {
+		return nil, afterID, true, nil
+	}
+	chunk := down[i:min(i+broadcastBatchSize, len(down))]
+	err = DB.Model(&User{}).Scopes(s.scope).Where("id IN ?", chunk).Order("id ASC").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, afterID, false, err
+	}
+	return ids, chunk[len(chunk)-1], i+len(chunk) >= len(down), nil
+}
+
+/* ----- worker ----- */
+
+var broadcastKick = make(chan struct{}, 1)
+
+// đánh thức worker (gửi ngay thay vì chờ tick)
+func kickBroadcastWorker() {
+	select {
+	case broadcastKick <- struct{}{}:
+	default:
+	}
+}
+
+func startBroadcastWorker() {
+	go func() {
+		t := time.NewTicker(10 * time.Second)
+		defer t.Stop()
+		for {
+			runDueBroadcasts()
+			select {
+			case <-t.C:
+			case <-broadcastKick:
+			}
+		}
+	}()
+}
+
+func runDueBroadcasts() {
+	now := time.Now()
+	var ids []uint
+	if err := DB.Model(&Broadcast{}).
+		Where("(status = ? AND scheduled_at <= ?) OR (status = ? AND lease_until < ?)",
+			BROADCAST_SCHEDULED, now, BROADCAST_RUNNING, now).
+		Order("scheduled_at ASC").Limit(20).Pluck("id", &ids).Error; err != nil {
+		log.Println("broadcast query error:", err)
+		return
+	}
+	for _, id := range ids {
+		if claimBroadcast(id) {
+			processBroadcast(id)
+		}
+	}
+}
+
+// nhận quyền gửi (chống 2 instance cùng gửi 1 broadcast)
+func claimBroadcast(id uint) bool {
+	now := time.Now()
+	res := DB.Model(&Broadcast{}).
+		Where("id = ? AND ((status = ? AND scheduled_at <= ?) OR (status = ? AND lease_until < ?))",
+			id, BROADCAST_SCHEDULED, now, BROADCAST_RUNNING, now).
+		Updates(map[string]any{
+			"status":      BROADCAST_RUNNING,
+			"lease_until": now.Add(broadcastLease),
+			"started_at":  gorm.Expr("COALESCE(started_at, ?)", now),
+		})
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+func processBroadcast(id uint) {
+	var b Broadcast
+	if err := DB.First(&b, id).Error; err != nil {
+		return
+	}
+	seg := b.segment()
+	fail := func(err error) {
+		log.Println("broadcast", id, "error:", err)
+		msg := err.Error()
+		if r := []rune(msg); len(r) > 255 {
+			msg = string(r[:255])
+		}
+		DB.Model(&Broadcast{}).Where("id = ? AND status = ?", id, BROADCAST_RUNNING).
+			Updates(map[string]any{"status": BROADCAST_FAILED, "error": msg, "finished_at": time.Now()})
+	}
+
+	down, err := seg.downlineIDs()
+	if err != nil {
+		fail(err)
+		return
+	}
+	// lần chạy đầu: chốt số người nhận để hiển thị tiến độ
+	if b.Sent == 0 && b.LastUserID == 0 {
+		if b.Total, err = countSegment(seg); err != nil {
+			fail(err)
+			return
+		}
+		DB.Model(&b).Update("total", b.Total)
+	}
+
+	cursor := b.LastUserID
+	for {
+		ids, next, done, err := nextRecipients(seg, down, cursor)
+		if err != nil {
+			fail(err)
+			return
+		}
+		stopped := false
+		err = withEvents(func(tx *gorm.DB) error {
+			up := map[string]any{
+				"last_user_id": next,
+				"sent":         gorm.Expr("sent + ?", len(ids)),
+				"lease_until":  time.Now().Add(broadcastLease),
+			}
+			if done {
+				up["status"] = BROADCAST_DONE
+				up["finished_at"] = time.Now()
+			}
+			// chỉ ghi tiếp khi vẫn RUNNING (admin có thể đã huỷ)
+			res := tx.Model(&Broadcast{}).Where("id = ? AND status = ?", id, BROADCAST_RUNNING).Updates(up)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				stopped = true
+				return nil
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			notes := make([]Notification, 0, len(ids))
+			for _, uid := range ids {
+				notes = append(notes, Notification{
+					UserID: uid, Type: NT_ADMIN_BROADCAST, Category: NOTI_SYSTEM, Title: b.Title, Body: b.Body,
+				})
+			}
+			return tx.CreateInBatches(&notes, 100).Error
+		})
+		if err != nil {
+			fail(err)
+			return
+		}
+		if stopped || done {
+			return
+		}
+		cursor = next
+	}
+}
+
+/* ----- handlers ----- */
+
+type BroadcastRequest struct {
+	Title       string           `json:"title"`
+	Body        string           `json:"body"`
+	Segment     BroadcastSegment `json:"segment"`
+	ScheduledAt *time.Time       `json:"scheduledAt"`
+	DryRun      bool             `json:"dryRun"` // chỉ đếm số người nhận
+}
+
+// POST /admin/notifications/broadcast
+// { title, body, segment?: { vipLevels, kycStatus, registeredFrom, registeredTo, downlineOf, downlineDepth }, scheduledAt?, dryRun? }
+func adminBroadcastHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req BroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apiError(ERR_INVALID_INPUT))
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Title == "" || req.Body == "" {
+		respondError(c, apiError(ERR_CONTENT_REQUIRED))
+		return
+	}
+	if len([]rune(req.Title)) > 120 || len([]rune(req.Body)) > 500 {
+		respondError(c, apiError(ERR_CONTENT_TOO_LONG))
+		return
+	}
+	if err := req.Segment.validate(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	recipients, err := countSegment(req.Segment)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if req.DryRun {
+		c.JSON(200, gin.H{"recipients": recipients})
+		return
+	}
+	if recipients == 0 {
+		respondError(c, apiError(ERR_NO_RECIPIENTS))
+		return
+	}
+
+	when := time.Now()
+	if req.ScheduledAt != nil && req.ScheduledAt.After(when) {
+		when = *req.ScheduledAt
+	}
+	seg, _ := json.Marshal(req.Segment)
+	b := Broadcast{
+		Title: req.Title, Body: req.Body, Segment: string(seg),
+		Status: BROADCAST_SCHEDULED, ScheduledAt: when, Total: recipients, CreatedBy: adminID,
+	}
+	if err := DB.Create(&b).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	if !when.After(time.Now()) {
+		kickBroadcastWorker()
+	}
+	c.JSON(200, gin.H{"message": "Đã xếp lịch gửi thông báo", "broadcast": b, "segment": req.Segment})
+}
+
+type broadcastView struct {
+	Broadcast
+	Segment  BroadcastSegment `json:"segment"`
+	Progress float64          `json:"progress"` // 0..100
+}
+
+func viewBroadcast(b Broadcast) broadcastView {
+	v := broadcastView{Broadcast: b, Segment: b.segment()}
+	switch {
+	case b.Status == BROADCAST_DONE:
+		v.Progress = 100
+	case b.Total > 0:
+		v.Progress = min(float64(b.Sent)*100/float64(b.Total), 100)
+	}
+	return v
+}
+
+// GET /admin/notifications/broadcasts?status=
+func adminListBroadcastsHandler(c *gin.Context) {
+	q := DB.Model(&Broadcast{})
+	if st := strings.ToUpper(strings.TrimSpace(c.Query("status"))); st != "" {
+		q = q.Where("status = ?", st)
+	}
+	var rows []Broadcast
+	q.Order("id DESC").Limit(200).Find(&rows)
+	out := make([]broadcastView, 0, len(rows))
+	for _, b := range rows {
+		out = append(out, viewBroadcast(b))
+	}
+	c.JSON(200, gin.H{"rows": out})
+}
+
+// GET /admin/notifications/broadcasts/:id
+func adminGetBroadcastHandler(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return
+	}
+	var b Broadcast
+	if err := DB.First(&b, uint(id64)).Error; err != nil {
+		respondError(c, apiError(ERR_BROADCAST_NOT_FOUND))
+		return
+	}
+	c.JSON(200, viewBroadcast(b))
+}
+
+// POST /admin/notifications/broadcasts/:id/cancel (người đã nhận vẫn giữ thông báo)
+func adminCancelBroadcastHandler(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return
+	}
+	res := DB.Model(&Broadcast{}).
+		Where("id = ? AND status IN ?", uint(id64), []string{BROADCAST_SCHEDULED, BROADCAST_RUNNING}).
+		Updates(map[string]any{"status": BROADCAST_CANCELLED, "finished_at": time.Now()})
+	if res.Error != nil {
+		respondError(c, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		respondError(c, apiError(ERR_BROADCAST_FINISHED))
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã huỷ broadcast"})
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== ĐIỂM DANH HẰNG NGÀY & CHUỖI NGÀY ===== */
+
+const (
+	checkinMaxDays       = 31                 // độ dài tối đa của lịch thưởng
+	checkinTZChangeEvery = 7 * 24 * time.Hour // đổi múi giờ tối đa 1 lần / 7 ngày (chống điểm danh 2 lần/ngày)
+)
+
+// lịch thưởng: ngày thứ Day của chuỗi (hết lịch thì quay lại ngày 1)
+type CheckinDay struct {
+	Day int `gorm:"primaryKey;autoIncrement:false" json:"day"`
+	RewardBundle
+	UpdatedAt time.Time `json:"-"`
+}
+
+// trạng thái chuỗi của user; LastDay theo múi giờ của user
+type CheckinState struct {
+	UserID            uint       `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Streak            int        `gorm:"not null;default:0" json:"streak"`
+	BestStreak        int        `gorm:"not null;default:0" json:"bestStreak"`
+	LastDay           string     `gorm:"size:10" json:"lastDay"`  // YYYY-MM-DD
+	Timezone          string     `gorm:"size:64" json:"timezone"` // IANA, trống => giờ server
+	TimezoneChangedAt *time.Time `json:"-"`
+	UpdatedAt         time.Time  `json:"-"`
+}
+
+// nhật ký điểm danh, mỗi user tối đa 1 dòng / ngày
+type CheckinLog struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	UserID        uint   `gorm:"not null;uniqueIndex:uniq_checkin_user_day,priority:1" json:"-"`
+	Day           string `gorm:"size:10;not null;uniqueIndex:uniq_checkin_user_day,priority:2" json:"day"`
+	Streak        int    `gorm:"not null" json:"streak"`
+	CalendarDay   int    `gorm:"not null" json:"calendarDay"`
+	MultiplierPct int64  `gorm:"not null" json:"multiplierPct"` // 100 = x1
+	RewardBundle
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// lịch mặc định khi admin chưa cấu hình
+var defaultCheckinCalendar = []RewardBundle{
+	{FreeSpins: 1},
+	{BonusCoins: 20},
+	{FreeSpins: 1},
+	{BonusCoins: 50},
+	{ItemCode: "EV", ItemQty: 3},
+	{FreeSpins: 2},
+	{BonusCoins: 100, FreeSpins: 3},
+}
+
+func seedCheckinCalendar() {
+	var n int64
+	DB.Model(&CheckinDay{}).Count(&n)
+	if n > 0 {
+		return
+	}
+	days := make([]CheckinDay, 0, len(defaultCheckinCalendar))
+	for i, r := range defaultCheckinCalendar {
+		days = append(days, CheckinDay{Day: i + 1, RewardBundle: r})
+	}
+	DB.Create(&days)
+}
+
+func loadCheckinCalendar(tx *gorm.DB) ([]CheckinDay, error) {
+	days := []CheckinDay{}
+	err := tx.Order("day ASC").Find(&days).Error
+	return days, err
+}
+
+func (s CheckinState) location() *time.Location {
+	if s.Timezone != "" {
+		if loc, err := time.LoadLocation(s.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// số ngày từ a đến b (YYYY-MM-DD)
+func daysBetween(a, b string) int {
+	ta, err1 := time.Parse("2006-01-02", a)
+	tb, err2 := time.Parse("2006-01-02", b)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return int(tb.Sub(ta).Hours() / 24)
+}
+
+// chuỗi còn giữ được nếu hôm nay điểm danh: bỏ lỡ tối đa checkin.grace_days ngày liên tiếp
+func (s CheckinState) streakAlive(today string) bool {
+	return s.streakAliveWithin(today, settingInt("checkin.grace_days"))
+}
+
+func (s CheckinState) streakAliveWithin(today string, graceDays int64) bool {
+	if s.LastDay == "" {
+		return false
+	}
+	return daysBetween(s.LastDay, today) <= 1+int(graceDays)
+}
+
+// hệ số thưởng theo VIP: 100% + vipLevel * checkin.vip_bonus_pct
+func checkinMultiplierPct(vipLevel int) int64 {
+	return 100 + int64(vipLevel)*settingInt("checkin.vip_bonus_pct")
+}
+
+type CheckinStatus struct {
+	Today          string       `json:"today"`
+	Timezone       string       `json:"timezone"`
+	CheckedInToday bool         `json:"checkedInToday"`
+	Streak         int          `json:"streak"` // 0 nếu chuỗi đã đứt
+	BestStreak     int          `json:"bestStreak"`
+	NextDay        int          `json:"nextDay"` // ngày trong lịch của lần điểm danh tiếp theo
+	GraceDays      int64        `json:"graceDays"`
+	MultiplierPct  int64        `json:"multiplierPct"`
+	Calendar       []CheckinDay `json:"calendar"`
+}
+
+func buildCheckinStatus(st CheckinState, vipLevel int, calendar []CheckinDay) CheckinStatus {
+	loc := st.location()
+	today := time.Now().In(loc).Format("2006-01-02")
+	out := CheckinStatus{
+		Today: today, Timezone: loc.String(), BestStreak: st.BestStreak,
+		GraceDays: settingInt("checkin.grace_days"), MultiplierPct: checkinMultiplierPct(vipLevel),
+		Calendar: calendar,
+	}
+	next := 1
+	switch {
+	case st.LastDay != "" && today <= st.LastDay:
+		out.CheckedInToday = true
+		out.Streak = st.Streak
+		next = st.Streak + 1
+	case st.streakAlive(today):
+		out.Streak = st.Streak
+		next = st.Streak + 1
+	}
+	if len(calendar) > 0 {
+		out.NextDay = (next-1)%len(calendar) + 1
+	}
+	return out
+}
+
+// GET /private/checkin
+func checkinStatusHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var u User
+	if err := DB.Select("id, v_ip_level").First(&u, uid).Error; err != nil {
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
+		return
+	}
+	st := CheckinState{UserID: uid}
+	_ = DB.Where("user_id = ?", uid).Limit(1).Find(&st).Error
+	calendar, err := loadCheckinCalendar(DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, buildCheckinStatus(st, u.VIPLevel, calendar))
+}
+
+// POST /private/checkin — idempotent theo ngày (múi giờ của user): gọi lại trong ngày trả về lượt đã điểm danh
+func checkinHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+
+	var entry CheckinLog
+	already := false
+	if err := withEvents(func(tx *gorm.DB) error {
+		var u User
+		if err := tx.Select("id, v_ip_level").First(&u, uid).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		// 🔒 khoá trạng thái (tạo nếu chưa có) để 2 request song song không cùng điểm danh
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&CheckinState{UserID: uid}).Error; err != nil {
+			return err
+		}
+		var st CheckinState
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&st, "user_id = ?", uid).Error; err != nil {
+			return err
+		}
+
+		today := time.Now().In(st.location()).Format("2006-01-02")
+		if st.LastDay != "" && today <= st.LastDay {
+			already = true
+			return tx.Where("user_id = ? AND day = ?", uid, st.LastDay).First(&entry).Error
+		}
+
+		calendar, err := loadCheckinCalendar(tx)
+		if err != nil {
+			return err
+		}
+		if len(calendar) == 0 {
+			return apiError(ERR_CHECKIN_CALENDAR_EMPTY)
+		}
+		streak := 1
+		if st.streakAlive(today) {
+			streak = st.Streak + 1
+		}
+		day := calendar[(streak-1)%len(calendar)]
+		pct := checkinMultiplierPct(u.VIPLevel)
+		entry = CheckinLog{
+			UserID: uid, Day: today, Streak: streak, CalendarDay: day.Day,
+			MultiplierPct: pct, RewardBundle: day.RewardBundle.scaled(pct),
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		if err := grantReward(tx, uid, entry.RewardBundle, LEDGER_CHECKIN, entry.ID); err != nil {
+			return err
+		}
+		if err := domainEvent(tx, uid, DE_CHECKIN, 1); err != nil {
+			return err
+		}
+		return tx.Model(&st).Updates(map[string]any{
+			"streak": streak, "best_streak": max(st.BestStreak, streak), "last_day": today,
+		}).Error
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	msg := "Điểm danh thành công"
+	if already {
+		msg = "Hôm nay bạn đã điểm danh"
+	}
+	c.JSON(200, gin.H{"message": msg, "alreadyCheckedIn": already, "checkin": entry})
+}
+
+type CheckinTimezoneRequest struct {
+	Timezone string `json:"timezone" binding:"required,max=64"` // IANA, vd Asia/Ho_Chi_Minh
+}
+
+// PUT /private/checkin/timezone { timezone }
+func checkinTimezoneHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req CheckinTimezoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "Local" {
+		respondError(c, apiError(ERR_CHECKIN_TIMEZONE_INVALID))
+		return
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&CheckinState{UserID: uid}).Error; err != nil {
+			return err
+		}
+		var st CheckinState
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&st, "user_id = ?", uid).Error; err != nil {
+			return err
+		}
+		if st.Timezone == req.Timezone {
+			return nil
+		}
+		if st.TimezoneChangedAt != nil && time.Since(*st.TimezoneChangedAt) < checkinTZChangeEvery {
+			next := st.TimezoneChangedAt.Add(checkinTZChangeEvery)
+			return apiError(ERR_CHECKIN_TIMEZONE_LOCKED, "until", next.Format(time.RFC3339))
+		}
+		return tx.Model(&st).Updates(map[string]any{"timezone": req.Timezone, "timezone_changed_at": time.Now()}).Error
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã đổi múi giờ điểm danh", "timezone": req.Timezone})
+}
+
+/* ----- admin ----- */
+
+// GET /admin/checkin/calendar
+func adminCheckinCalendarHandler(c *gin.Context) {
+	days, err := loadCheckinCalendar(DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"days": days})
+}
+
+type CheckinCalendarRequest struct {
+	Days []RewardBundle `json:"days" binding:"required"` // phần tử i = thưởng ngày i+1
+}
+
+// PUT /admin/checkin/calendar { days: [{ coins, bonusCoins, freeSpins, itemCode, itemQty }, ...] } — thay toàn bộ lịch
+func adminUpdateCheckinCalendarHandler(c *gin.Context) {
+	var req CheckinCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if len(req.Days) == 0 || len(req.Days) > checkinMaxDays {
+		respondError(c, apiError(ERR_CHECKIN_CALENDAR_INVALID, "field", "days"))
+		return
+	}
+	days := make([]CheckinDay, 0, len(req.Days))
+	for i := range req.Days {
+		if f := req.Days[i].invalidField(); f != "" {
+			respondError(c, apiError(ERR_CHECKIN_CALENDAR_INVALID, "field", "days["+strconv.Itoa(i)+"]."+f))
+			return
+		}
+		days = append(days, CheckinDay{Day: i + 1, RewardBundle: req.Days[i]})
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&CheckinDay{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&days).Error
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã cập nhật lịch điểm danh", "days": days})
+}
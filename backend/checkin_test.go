@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestStreakAliveWithin(t *testing.T) {
+	tests := []struct {
+		lastDay, today string
+		grace          int64
+		want           bool
+	}{
+		{"", "2026-01-10", 1, false},
+		{"2026-01-10", "2026-01-10", 0, true},
+		{"2026-01-09", "2026-01-10", 0, true},
+		{"2026-01-08", "2026-01-10", 0, false},
+		{"2026-01-08", "2026-01-10", 1, true},
+		{"2026-01-07", "2026-01-10", 1, false},
+		{"2026-01-07", "2026-01-10", 2, true},
+		{"2026-02-28", "2026-03-01", 0, true}, // qua tháng
+		{"2025-12-31", "2026-01-01", 0, true}, // qua năm
+		{"2026-03-28", "2026-03-30", 0, false},
+	}
+	for _, tt := range tests {
+		st := CheckinState{LastDay: tt.lastDay}
+		if got := st.streakAliveWithin(tt.today, tt.grace); got != tt.want {
+			t.Errorf("streakAliveWithin(last=%q, today=%q, grace=%d) = %v, want %v",
+				tt.lastDay, tt.today, tt.grace, got, tt.want)
+		}
+	}
+}
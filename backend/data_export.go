@@ -0,0 +1,363 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== TỰ XUẤT DỮ LIỆU CÁ NHÂN: FILE ZIP TẠO NỀN, TẢI QUA LINK CÓ HẠN ===== */
+
+const (
+	EXPORT_PENDING = "PENDING"
+	EXPORT_RUNNING = "RUNNING"
+	EXPORT_READY   = "READY"
+	EXPORT_FAILED  = "FAILED"
+	EXPORT_EXPIRED = "EXPIRED"
+)
+
+const dataExportCooldown = 24 * time.Hour // mỗi user tối đa 1 lần xuất / 24 giờ
+
+const dataExportJobTimeout = 10 * time.Minute // lease của job data_exports.build
+
+type DataExport struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Status     string     `gorm:"size:10;not null;index" json:"status"`
+	Token      string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // bí mật của link tải
+	FileName   string     `gorm:"size:100" json:"-"`                     // trong EXPORT_DIR
+	Size       int64      `gorm:"not null;default:0" json:"size"`
+	Error      string     `gorm:"size:255" json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"` // lúc job nhận việc (RUNNING)
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+type DataExportView struct {
+	DataExport
+	URL string `json:"url,omitempty"` // chỉ có khi READY
+}
+
+func (e DataExport) view() DataExportView {
+	v := DataExportView{DataExport: e}
+	if e.Status == EXPORT_READY {
+		v.URL = "/public/data-export/" + e.Token
+	}
+	return v
+}
+
+func newExportToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// POST /private/data-export — xếp hàng tạo file, xong sẽ có thông báo kèm link
+func requestDataExportHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+
+	var last DataExport
+	if DB.Where("user_id = ?", uid).Order("id DESC").Limit(1).Find(&last).RowsAffected > 0 {
+		if last.Status == EXPORT_PENDING || last.Status == EXPORT_RUNNING {
+			respondError(c, apiError(ERR_DATA_EXPORT_PENDING))
+			return
+		}
+		if until := last.CreatedAt.Add(dataExportCooldown); last.Status != EXPORT_FAILED && time.Now().Before(until) {
+			respondError(c, apiError(ERR_DATA_EXPORT_TOO_SOON, "until", until.Format(exportTimeLayout)))
+			return
+		}
+	}
+	e := DataExport{UserID: uid, Status: EXPORT_PENDING, Token: newExportToken()}
+	if err := DB.Create(&e).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	_ = requestJobRun("data_exports.build", nil)
+	c.JSON(202, gin.H{"message": "Đang tạo file dữ liệu, bạn sẽ nhận thông báo khi xong", "export": e.view()})
+}
+
+// GET /private/data-export — các lần xuất gần đây
+func listDataExportsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var rows []DataExport
+	if err := DB.Where("user_id = ?", uid).Order("id DESC").Limit(10).Find(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	out := make([]DataExportView, len(rows))
+	for i, e := range rows {
+		out[i] = e.view()
+	}
+	c.JSON(200, gin.H{"rows": out})
+}
+
+// GET /public/data-export/:token — link tải (không cần đăng nhập, hết hạn sau export.link_ttl_hours)
+func downloadDataExportHandler(c *gin.Context) {
+	var e DataExport
+	if err := DB.Where("token = ?", c.Param("token")).First(&e).Error; err != nil {
+		respondError(c, apiError(ERR_DATA_EXPORT_NOT_FOUND))
+		return
+	}
+	if e.Status != EXPORT_READY || e.ExpiresAt == nil || time.Now().After(*e.ExpiresAt) {
+		respondError(c, apiError(ERR_DATA_EXPORT_NOT_FOUND))
+		return
+	}
+	dir, err := ensureDirAbs(EXPORT_DIR)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.FileAttachment(filepath.Join(dir, e.FileName), fmt.Sprintf("data-export-%s.zip", e.CreatedAt.Format("20060102")))
+}
+
+/* ----- job data_exports.build ----- */
+
+func dataExportFileName(e DataExport) string {
+	return fmt.Sprintf("%d_%d.zip", e.UserID, e.ID)
+}
+
+// tạo file cho các yêu cầu PENDING, rồi xoá file đã hết hạn
+func buildDataExports() error {
+	dir, err := ensureDirAbs(EXPORT_DIR)
+	if err != nil {
+		return err
+	}
+	if err := failStaleDataExports(dir); err != nil {
+		return err
+	}
+	var pending []DataExport
+	if err := DB.Where("status = ?", EXPORT_PENDING).Order("id ASC").Limit(20).Find(&pending).Error; err != nil {
+		return err
+	}
+	ttl := time.Duration(settingInt("export.link_ttl_hours")) * time.Hour
+	for _, e := range pending {
+		// nhận việc (nhiều instance không làm trùng)
+		if res := DB.Model(&DataExport{}).Where("id = ? AND status = ?", e.ID, EXPORT_PENDING).
+			Updates(map[string]any{"status": EXPORT_RUNNING, "started_at": time.Now()}); res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		e.FileName = dataExportFileName(e)
+		size, err := writeDataExport(filepath.Join(dir, e.FileName), e.UserID)
+		now := time.Now()
+		upd := map[string]any{"finished_at": now}
+		if err != nil {
+			upd["status"], upd["error"] = EXPORT_FAILED, truncate(err.Error(), 255)
+		} else {
+			exp := now.Add(ttl)
+			upd["status"], upd["file_name"], upd["size"], upd["expires_at"] = EXPORT_READY, e.FileName, size, exp
+			e.Status, e.ExpiresAt = EXPORT_READY, &exp
+		}
+		if err := withEvents(func(tx *gorm.DB) error {
+			res := tx.Model(&DataExport{}).Where("id = ? AND status = ?", e.ID, EXPORT_RUNNING).Updates(upd)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				// bị huỷ trong lúc tạo (tài khoản vừa đóng, hoặc đã bị đánh FAILED): bỏ file
+				_ = os.Remove(filepath.Join(dir, e.FileName))
+				return nil
+			}
+			if e.Status != EXPORT_READY {
+				return nil
+			}
+			return notify(tx, e.UserID, NT_DATA_EXPORT_READY, map[string]any{
+				"url": e.view().URL, "expiresAt": e.ExpiresAt.Format(exportTimeLayout),
+			})
+		}); err != nil {
+			return err
+		}
+	}
+
+	var expired []DataExport
+	if err := DB.Where("status = ? AND expires_at < ?", EXPORT_READY, time.Now()).Find(&expired).Error; err != nil {
+		return err
+	}
+	for _, e := range expired {
+		_ = os.Remove(filepath.Join(dir, e.FileName))
+		if err := DB.Model(&e).Update("status", EXPORT_EXPIRED).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RUNNING quá thời hạn job: instance tạo file đã chết giữa chừng. Chuyển FAILED để user yêu cầu lại được
+// (FAILED không tính thời gian chờ 24 giờ), xoá file tạm còn sót.
+func failStaleDataExports(dir string) error {
+	var stale []DataExport
+	if err := DB.Where("status = ? AND (started_at IS NULL OR started_at < ?)", EXPORT_RUNNING, time.Now().Add(-dataExportJobTimeout)).
+		Find(&stale).Error; err != nil {
+		return err
+	}
+	for _, e := range stale {
+		_ = os.Remove(filepath.Join(dir, dataExportFileName(e)+".tmp"))
+		if err := DB.Model(&DataExport{}).Where("id = ? AND status = ?", e.ID, EXPORT_RUNNING).
+			Updates(map[string]any{"status": EXPORT_FAILED, "error": "Bị gián đoạn, vui lòng yêu cầu lại", "finished_at": time.Now()}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+type exportReferral struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	VIPLevel  int       `json:"vipLevel"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ghi file zip: JSON hồ sơ/túi đồ/thông báo/giới thiệu, CSV giao dịch, ảnh đại diện & ảnh KYC
+func writeDataExport(path string, uid uint) (int64, error) {
+	var u User
+	if err := DB.First(&u, uid).Error; err != nil {
+		return 0, err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	zw := zip.NewWriter(f)
+	err = func() error {
+		// JSON
+		var inv []InventoryItem
+		var notis []Notification
+		var f1 []exportReferral
+		var upline *exportReferral
+		if err := DB.Where("user_id = ? AND qty > 0", uid).Order("code").Find(&inv).Error; err != nil {
+			return err
+		}
+		if err := DB.Where("user_id = ?", uid).Order("id ASC").Find(&notis).Error; err != nil {
+			return err
+		}
+		if err := DB.Model(&User{}).Select("id, username, v_ip_level, created_at").
+			Where("referred_by = ?", uid).Order("id ASC").Scan(&f1).Error; err != nil {
+			return err
+		}
+		if u.ReferredBy != nil {
+			var up exportReferral
+			if DB.Model(&User{}).Select("id, username, v_ip_level, created_at").
+				Where("id = ?", *u.ReferredBy).Limit(1).Scan(&up).RowsAffected > 0 {
+				upline = &up
+			}
+		}
+		for _, j := range []struct {
+			name string
+			v    any
+		}{
+			{"profile.json", u},
+			{"inventory.json", inv},
+			{"notifications.json", notis},
+			{"referrals.json", gin.H{"upline": upline, "f1": f1}},
+		} {
+			w, err := zw.Create(j.name)
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(j.v); err != nil {
+				return err
+			}
+		}
+
+		// CSV giao dịch (cùng cột với /private/history/*/export)
+		for _, t := range []struct {
+			name  string
+			write func(uid uint, w io.Writer) error
+		}{
+			{"transactions/ledger.csv", historyCSV(ledgerHistory)},
+			{"transactions/topups.csv", historyCSV(topupHistory)},
+			{"transactions/withdraws.csv", historyCSV(withdrawHistory)},
+			{"transactions/transfers.csv", historyCSV(transferHistory)},
+			{"transactions/vip.csv", historyCSV(vipHistory)},
+			{"transactions/commissions.csv", historyCSV(commissionHistory)},
+		} {
+			w, err := zw.Create(t.name)
+			if err != nil {
+				return err
+			}
+			if err := t.write(uid, w); err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+		}
+
+		// ảnh
+		var files [][2]string // tên trong zip, đường dẫn
+		if name, ok := strings.CutPrefix(u.AvatarURL, "/uploads/"); ok && name != "" {
+			if dir, err := ensureUploadsDirAbs(); err == nil {
+				files = append(files, [2]string{"files/avatar" + filepath.Ext(name), filepath.Join(dir, filepath.Base(name))})
+			}
+		}
+		if dir, err := ensureKycDirAbs(); err == nil {
+			for side, p := range map[string]string{"front": u.KYCFrontPath, "back": u.KYCBackPath} {
+				if p != "" {
+					files = append(files, [2]string{"files/kyc_" + side + filepath.Ext(p), filepath.Join(dir, p)})
+				}
+			}
+		}
+		for _, fl := range files {
+			src, err := os.Open(fl[1])
+			if err != nil {
+				continue // file đã mất: bỏ qua
+			}
+			w, err := zw.Create(fl[0])
+			if err == nil {
+				_, err = io.Copy(w, src)
+			}
+			src.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	st, err := os.Stat(tmp)
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), os.Rename(tmp, path)
+}
+
+// sổ cái của user (chỉ dùng khi xuất dữ liệu)
+var ledgerHistory = historySource[LedgerEntry]{
+	Name: "ledger", IDCol: "id", TimeCol: "created_at", AmtCol: "amount",
+	Query: func(uid uint, _ historyFilter) *gorm.DB {
+		return DB.Model(&LedgerEntry{}).Where("user_id = ?", uid)
+	},
+	RowID:   func(r LedgerEntry) uint { return r.ID },
+	Columns: []string{"id", "createdAt", "currency", "type", "refId", "amount"},
+	Record: func(r LedgerEntry) []any {
+		return []any{r.ID, r.CreatedAt, r.Currency, r.Type, r.RefID, r.Amount}
+	},
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/* ===== LỖI NGHIỆP VỤ: MÃ LỖI CỐ ĐỊNH, HTTP STATUS & THÔNG BÁO THEO NGÔN NGỮ ===== */
+
+// ngôn ngữ hỗ trợ, phần tử đầu là mặc định
+var supportedLocales = []string{"vi", "en"}
+
+// mã lỗi trả về FE (giữ nguyên giá trị, FE dựa vào đây để xử lý)
+const (
+	ERR_INVALID_INPUT = "INVALID_INPUT"
+	ERR_INVALID_ID    = "INVALID_ID"
+	ERR_UNAUTHORIZED  = "UNAUTHORIZED"
+	ERR_TOKEN_INVALID = "TOKEN_INVALID"
+	ERR_FORBIDDEN     = "FORBIDDEN"
+	ERR_ADMIN_ONLY    = "ADMIN_ONLY"
+	ERR_CONFLICT      = "CONFLICT"
+	ERR_INTERNAL      = "INTERNAL"
+	ERR_NOT_FOUND     = "NOT_FOUND"
+	ERR_FILE_REQUIRED = "FILE_REQUIRED"
+
+	// tài khoản & bảo mật
+	ERR_USER_NOT_FOUND            = "USER_NOT_FOUND"
+	ERR_USERNAME_REQUIRED         = "USERNAME_REQUIRED"
+	ERR_PASSWORD_REQUIRED         = "PASSWORD_REQUIRED"
+	ERR_USERNAME_TAKEN            = "USERNAME_TAKEN"
+	ERR_LOGIN_FAILED              = "LOGIN_FAILED"
+	ERR_PASSWORD_INCORRECT        = "PASSWORD_INCORRECT"
+	ERR_SECOND_PASSWORD_TOO_SHORT = "SECOND_PASSWORD_TOO_SHORT"
+	ERR_SECOND_PASSWORD_INVALID   = "SECOND_PASSWORD_INVALID"
+	ERR_SECOND_PASSWORD_NOT_SET   = "SECOND_PASSWORD_NOT_SET"
+	ERR_PIN_FORMAT                = "PIN_FORMAT"
+	ERR_PIN_NOT_SET               = "PIN_NOT_SET"
+	ERR_PIN_INVALID               = "PIN_INVALID"
+	ERR_KYC_FIELDS_REQUIRED       = "KYC_FIELDS_REQUIRED"
+	ERR_KYC_FILES_REQUIRED        = "KYC_FILES_REQUIRED"
+	ERR_KYC_IMAGE_NOT_FOUND       = "KYC_IMAGE_NOT_FOUND"
+
+	// số dư, vật phẩm, chuyển coin, VIP
+	ERR_INSUFFICIENT_BALANCE  = "INSUFFICIENT_BALANCE"
+	ERR_ITEM_CODE_INVALID     = "ITEM_CODE_INVALID"
+	ERR_INSUFFICIENT_ITEMS    = "INSUFFICIENT_ITEMS"
+	ERR_DRAGON_BALL_MISSING   = "DRAGON_BALL_MISSING"
+	ERR_TRANSFER_SELF         = "TRANSFER_SELF"
+	ERR_RECIPIENT_NOT_FOUND   = "RECIPIENT_NOT_FOUND"
+	ERR_RECIPIENT_UNAVAILABLE = "RECIPIENT_UNAVAILABLE"
+	ERR_VIP_ALREADY           = "VIP_ALREADY"
+
+	// gift code
+	ERR_PROMO_CODE_REQUIRED      = "PROMO_CODE_REQUIRED"
+	ERR_PROMO_NOT_FOUND          = "PROMO_NOT_FOUND"
+	ERR_PROMO_EXPIRED            = "PROMO_EXPIRED"
+	ERR_PROMO_EXHAUSTED          = "PROMO_EXHAUSTED"
+	ERR_PROMO_ALREADY_USED       = "PROMO_ALREADY_USED"
+	ERR_PROMO_MAX_USES_INVALID   = "PROMO_MAX_USES_INVALID"
+	ERR_PROMO_GENERATION_FAILED  = "PROMO_GENERATION_FAILED"
+	ERR_PROMO_NOT_STARTED        = "PROMO_NOT_STARTED"
+	ERR_PROMO_USER_LIMIT         = "PROMO_USER_LIMIT"
+	ERR_PROMO_VIP_REQUIRED       = "PROMO_VIP_REQUIRED"
+	ERR_PROMO_KYC_REQUIRED       = "PROMO_KYC_REQUIRED"
+	ERR_PROMO_ACCOUNT_TOO_NEW    = "PROMO_ACCOUNT_TOO_NEW"
+	ERR_PROMO_NEW_USERS_ONLY     = "PROMO_NEW_USERS_ONLY"
+	ERR_PROMO_INVALID            = "PROMO_INVALID"
+	ERR_PROMO_CODE_TAKEN         = "PROMO_CODE_TAKEN"
+	ERR_PROMO_CAMPAIGN_NOT_FOUND = "PROMO_CAMPAIGN_NOT_FOUND"
+
+	// chợ
+	ERR_LISTING_NOT_FOUND        = "LISTING_NOT_FOUND"
+	ERR_LISTING_NOT_OWNER        = "LISTING_NOT_OWNER"
+	ERR_LISTING_INACTIVE         = "LISTING_INACTIVE"
+	ERR_LISTING_EXPIRED          = "LISTING_EXPIRED"
+	ERR_LISTING_QTY_INSUFFICIENT = "LISTING_QTY_INSUFFICIENT"
+	ERR_SELF_TRADE               = "SELF_TRADE"
+	ERR_BID_NOT_FOUND            = "BID_NOT_FOUND"
+	ERR_BID_NOT_OWNER            = "BID_NOT_OWNER"
+	ERR_BID_CLOSED               = "BID_CLOSED"
+	ERR_ORDER_MODE_INVALID       = "ORDER_MODE_INVALID"
+	ERR_ORDER_NO_LIQUIDITY       = "ORDER_NO_LIQUIDITY"
+	ERR_ORDER_NOT_FILLED         = "ORDER_NOT_FILLED"
+	ERR_INTERVAL_INVALID         = "INTERVAL_INVALID"
+
+	// shop EV
+	ERR_SHOP_ITEM_NOT_FOUND     = "SHOP_ITEM_NOT_FOUND"
+	ERR_SHOP_ITEM_INACTIVE      = "SHOP_ITEM_INACTIVE"
+	ERR_SHOP_NOT_STARTED        = "SHOP_NOT_STARTED"
+	ERR_SHOP_ENDED              = "SHOP_ENDED"
+	ERR_SHOP_OUT_OF_STOCK       = "SHOP_OUT_OF_STOCK"
+	ERR_SHOP_LIMIT_REACHED      = "SHOP_LIMIT_REACHED"
+	ERR_SHOP_COST_CODE_INVALID  = "SHOP_COST_CODE_INVALID"
+	ERR_SHOP_REWARD_INVALID     = "SHOP_REWARD_INVALID"
+	ERR_SHOP_DISCOUNT_TOO_HIGH  = "SHOP_DISCOUNT_TOO_HIGH"
+	ERR_SHOP_STOCK_INVALID      = "SHOP_STOCK_INVALID"
+	ERR_SHOP_STOCK_BELOW_SOLD   = "SHOP_STOCK_BELOW_SOLD"
+	ERR_SHOP_LIMIT_INVALID      = "SHOP_LIMIT_INVALID"
+	ERR_SHOP_TIME_RANGE_INVALID = "SHOP_TIME_RANGE_INVALID"
+
+	// thông báo, broadcast, cấu hình
+	ERR_NOTIFICATION_NOT_FOUND  = "NOTIFICATION_NOT_FOUND"
+	ERR_LOCALE_UNSUPPORTED      = "LOCALE_UNSUPPORTED"
+	ERR_CATEGORY_INVALID        = "CATEGORY_INVALID"
+	ERR_CATEGORY_NOT_MUTABLE    = "CATEGORY_NOT_MUTABLE"
+	ERR_TEMPLATE_TYPE_UNKNOWN   = "TEMPLATE_TYPE_UNKNOWN"
+	ERR_TEMPLATE_NOT_OVERRIDDEN = "TEMPLATE_NOT_OVERRIDDEN"
+	ERR_CONTENT_REQUIRED        = "CONTENT_REQUIRED"
+	ERR_CONTENT_TOO_LONG        = "CONTENT_TOO_LONG"
+	ERR_SEGMENT_INVALID         = "SEGMENT_INVALID"
+	ERR_NO_RECIPIENTS           = "NO_RECIPIENTS"
+	ERR_BROADCAST_NOT_FOUND     = "BROADCAST_NOT_FOUND"
+	ERR_BROADCAST_FINISHED      = "BROADCAST_FINISHED"
+	ERR_SETTING_KEY_UNKNOWN     = "SETTING_KEY_UNKNOWN"
+	ERR_SETTING_VALUE_INVALID   = "SETTING_VALUE_INVALID"
+
+	// mùa giải
+	ERR_SEASON_NOT_FOUND = "SEASON_NOT_FOUND"
+	ERR_SEASON_INVALID   = "SEASON_INVALID"
+	ERR_SEASON_STARTED   = "SEASON_STARTED"
+	ERR_SEASON_CLOSED    = "SEASON_CLOSED"
+
+	// điểm danh
+	ERR_CHECKIN_CALENDAR_EMPTY   = "CHECKIN_CALENDAR_EMPTY"
+	ERR_CHECKIN_CALENDAR_INVALID = "CHECKIN_CALENDAR_INVALID"
+	ERR_CHECKIN_TIMEZONE_INVALID = "CHECKIN_TIMEZONE_INVALID"
+	ERR_CHECKIN_TIMEZONE_LOCKED  = "CHECKIN_TIMEZONE_LOCKED"
+
+	// nhiệm vụ
+	ERR_MISSION_NOT_FOUND     = "MISSION_NOT_FOUND"
+	ERR_MISSION_INVALID       = "MISSION_INVALID"
+	ERR_MISSION_NOT_COMPLETED = "MISSION_NOT_COMPLETED"
+	ERR_MISSION_CLAIMED       = "MISSION_CLAIMED"
+
+	// job nền
+	ERR_JOB_NOT_FOUND        = "JOB_NOT_FOUND"
+	ERR_JOB_RUNNING          = "JOB_RUNNING"
+	ERR_JOB_SCHEDULE_INVALID = "JOB_SCHEDULE_INVALID"
+
+	// đối soát số dư
+	ERR_ACCOUNT_FROZEN  = "ACCOUNT_FROZEN"
+	ERR_DRIFT_NOT_FOUND = "DRIFT_NOT_FOUND"
+	ERR_DRIFT_RESOLVED  = "DRIFT_RESOLVED"
+
+	// đóng / xoá tài khoản
+	ERR_ACCOUNT_CLOSED          = "ACCOUNT_CLOSED"
+	ERR_ACCOUNT_ALREADY_CLOSED  = "ACCOUNT_ALREADY_CLOSED"
+	ERR_ACCOUNT_NOT_CLOSED      = "ACCOUNT_NOT_CLOSED"
+	ERR_PURGE_TOO_EARLY         = "PURGE_TOO_EARLY"
+	ERR_SUPERADMIN_ONLY         = "SUPERADMIN_ONLY"
+	ERR_CLOSE_BALANCE_NOT_ZERO  = "CLOSE_BALANCE_NOT_ZERO"
+	ERR_CLOSE_REQUEST_PENDING   = "CLOSE_REQUEST_PENDING"
+	ERR_CLOSE_REQUEST_NOT_FOUND = "CLOSE_REQUEST_NOT_FOUND"
+	ERR_CLOSE_REQUEST_DECIDED   = "CLOSE_REQUEST_DECIDED"
+
+	// khoá / cấm / hạn chế tài khoản
+	ERR_ACCOUNT_BANNED        = "ACCOUNT_BANNED"
+	ERR_ACCOUNT_RESTRICTED    = "ACCOUNT_RESTRICTED"
+	ERR_RESTRICTION_NOT_FOUND = "RESTRICTION_NOT_FOUND"
+
+	// hạn mức chuyển coin
+	ERR_TRANSFER_ACCOUNT_TOO_NEW = "TRANSFER_ACCOUNT_TOO_NEW"
+	ERR_TRANSFER_OVER_TX_LIMIT   = "TRANSFER_OVER_TX_LIMIT"
+	ERR_TRANSFER_DAILY_LIMIT     = "TRANSFER_DAILY_LIMIT"
+	ERR_TRANSFER_MONTHLY_LIMIT   = "TRANSFER_MONTHLY_LIMIT"
+	ERR_TRANSFER_NEW_RECIPIENTS  = "TRANSFER_NEW_RECIPIENTS"
+	ERR_TRANSFER_TIER_MISSING    = "TRANSFER_TIER_MISSING"
+	ERR_TRANSFER_TIER_NOT_FOUND  = "TRANSFER_TIER_NOT_FOUND"
+	ERR_TRANSFER_TIER_BASE       = "TRANSFER_TIER_BASE"
+
+	// xuất dữ liệu cá nhân
+	ERR_DATA_EXPORT_PENDING   = "DATA_EXPORT_PENDING"
+	ERR_DATA_EXPORT_TOO_SOON  = "DATA_EXPORT_TOO_SOON"
+	ERR_DATA_EXPORT_NOT_FOUND = "DATA_EXPORT_NOT_FOUND"
+)
+
+type errorDef struct {
+	Status int
+	Vi, En string // biến dạng {ten_bien} lấy từ Params
+}
+
+var errorDefs = map[string]errorDef{
+	ERR_INVALID_INPUT: {400, "Dữ liệu không hợp lệ", "Invalid request data"},
+	ERR_INVALID_ID:    {400, "ID không hợp lệ", "Invalid ID"},
+	ERR_UNAUTHORIZED:  {401, "Bạn chưa đăng nhập", "Authentication required"},
+	ERR_TOKEN_INVALID: {401, "Token không hợp lệ", "Invalid or expired token"},
+	ERR_FORBIDDEN:     {403, "Bạn không có quyền thực hiện thao tác này", "You are not allowed to do this"},
+	ERR_ADMIN_ONLY:    {403, "Chỉ quản trị viên mới được phép", "Admin only"},
+	ERR_CONFLICT:      {409, "Dữ liệu vừa thay đổi, vui lòng thử lại", "The data changed, please try again"},
+	ERR_INTERNAL:      {500, "Lỗi hệ thống, vui lòng thử lại sau", "Internal error, please try again later"},
+	ERR_NOT_FOUND:     {404, "Không tìm thấy dữ liệu", "Not found"},
+	ERR_FILE_REQUIRED: {400, "Không có file", "No file uploaded"},
+
+	ERR_USER_NOT_FOUND:            {404, "User không tồn tại", "User not found"},
+	ERR_USERNAME_REQUIRED:         {400, "Thiếu username", "Username is required"},
+	ERR_PASSWORD_REQUIRED:         {400, "Thiếu mật khẩu", "Password is required"},
+	ERR_USERNAME_TAKEN:            {409, "Username đã tồn tại", "Username is already taken"},
+	ERR_LOGIN_FAILED:              {401, "Sai username hoặc mật khẩu", "Wrong username or password"},
+	ERR_PASSWORD_INCORRECT:        {400, "Mật khẩu hiện tại không đúng", "Current password is incorrect"},
+	ERR_SECOND_PASSWORD_TOO_SHORT: {400, "Mật khẩu cấp 2 tối thiểu 6 ký tự", "Second password must be at least 6 characters"},
+	ERR_SECOND_PASSWORD_INVALID:   {400, "Mật khẩu cấp 2 không đúng", "Second password is incorrect"},
+	ERR_SECOND_PASSWORD_NOT_SET:   {400, "Bạn chưa thiết lập mật khẩu cấp 2. Vui lòng liên hệ hỗ trợ", "You have not set a second password. Please contact support"},
+	ERR_PIN_FORMAT:                {400, "Mã bảo mật (PIN) phải gồm đúng 6 chữ số", "PIN must be exactly 6 digits"},
+	ERR_PIN_NOT_SET:               {400, "Bạn chưa thiết lập mã bảo mật (PIN). Hãy vào Hồ sơ > Bảo mật để đặt PIN 6 số.", "You have not set a PIN. Go to Profile > Security to set a 6-digit PIN."},
+	ERR_PIN_INVALID:               {400, "Mã PIN không đúng", "Incorrect PIN"},
+	ERR_KYC_FIELDS_REQUIRED:       {400, "Vui lòng nhập đủ thông tin KYC bắt buộc (họ tên, số CCCD...)", "Please fill in all required KYC fields (full name, ID number...)"},
+	ERR_KYC_FILES_REQUIRED:        {400, "Thiếu ảnh mặt trước / mặt sau CCCD", "Front / back ID images are required"},
+	ERR_KYC_IMAGE_NOT_FOUND:       {404, "Chưa có ảnh KYC {side}", "KYC image {side} not found"},
+
+	ERR_INSUFFICIENT_BALANCE:  {400, "Số dư không đủ", "Insufficient balance"},
+	ERR_ITEM_CODE_INVALID:     {400, "Mã vật phẩm không hợp lệ (chỉ DB1..DB7 hoặc EV)", "Invalid item code (DB1..DB7 or EV only)"},
+	ERR_INSUFFICIENT_ITEMS:    {400, "Vật phẩm {code} không đủ", "Not enough {code}"},
+	ERR_DRAGON_BALL_MISSING:   {400, "Thiếu {code}", "Missing {code}"},
+	ERR_TRANSFER_SELF:         {400, "Không thể tự chuyển cho chính mình", "You cannot transfer to yourself"},
+	ERR_RECIPIENT_NOT_FOUND:   {404, "Người nhận không tồn tại", "Recipient not found"},
+	ERR_RECIPIENT_UNAVAILABLE: {409, "Tài khoản người nhận hiện không nhận được coin", "The recipient account cannot receive coins right now"},
+	ERR_VIP_ALREADY:           {400, "Bạn đã là VIP", "You are already VIP"},
+
+	ERR_PROMO_CODE_REQUIRED:      {400, "Thiếu mã code", "Code is required"},
+	ERR_PROMO_NOT_FOUND:          {404, "Code không tồn tại hoặc đã bị vô hiệu", "Code does not exist or was disabled"},
+	ERR_PROMO_EXPIRED:            {400, "Code đã hết hạn", "Code has expired"},
+	ERR_PROMO_EXHAUSTED:          {400, "Code đã dùng hết", "Code has been fully used"},
+	ERR_PROMO_ALREADY_USED:       {409, "Bạn đã nhập code này rồi", "You have already redeemed this code"},
+	ERR_PROMO_MAX_USES_INVALID:   {400, "MaxUses phải >= 1 hoặc bỏ trống để vô hạn", "maxUses must be >= 1 or empty for unlimited"},
+	ERR_PROMO_GENERATION_FAILED:  {500, "Không tạo được code, thử lại sau", "Could not generate codes, please retry"},
+	ERR_PROMO_NOT_STARTED:        {400, "Code chưa đến thời gian sử dụng (từ {startsAt})", "Code is not valid yet (from {startsAt})"},
+	ERR_PROMO_USER_LIMIT:         {409, "Bạn đã nhập tối đa {limit} code của chương trình này", "You have already redeemed {limit} codes from this campaign"},
+	ERR_PROMO_VIP_REQUIRED:       {403, "Code chỉ dành cho VIP {level} trở lên", "This code requires VIP {level} or higher"},
+	ERR_PROMO_KYC_REQUIRED:       {403, "Cần xác minh KYC để nhập code này", "KYC verification is required for this code"},
+	ERR_PROMO_ACCOUNT_TOO_NEW:    {403, "Tài khoản cần tạo ít nhất {hours} giờ để nhập code này", "Your account must be at least {hours} hours old to use this code"},
+	ERR_PROMO_NEW_USERS_ONLY:     {403, "Code chỉ dành cho người dùng mới", "This code is for new users only"},
+	ERR_PROMO_INVALID:            {400, "Thông tin chiến dịch không hợp lệ: {field}", "Invalid campaign data: {field}"},
+	ERR_PROMO_CODE_TAKEN:         {409, "Code {code} đã tồn tại", "Code {code} already exists"},
+	ERR_PROMO_CAMPAIGN_NOT_FOUND: {404, "Chiến dịch không tồn tại", "Campaign not found"},
+
+	ERR_LISTING_NOT_FOUND:        {404, "Listing không tồn tại", "Listing not found"},
+	ERR_LISTING_NOT_OWNER:        {403, "Bạn không phải chủ bài đăng này", "You do not own this listing"},
+	ERR_LISTING_INACTIVE:         {409, "Listing đã hết hoặc không hoạt động", "Listing is sold out or inactive"},
+	ERR_LISTING_EXPIRED:          {409, "Listing đã hết hạn", "Listing has expired"},
+	ERR_LISTING_QTY_INSUFFICIENT: {409, "Số lượng không đủ", "Not enough quantity left"},
+	ERR_SELF_TRADE:               {400, "Bạn không thể mua sản phẩm của chính mình. Hãy rút lại nếu muốn.", "You cannot buy your own listing. Withdraw it instead."},
+	ERR_BID_NOT_FOUND:            {404, "Lệnh mua không tồn tại", "Bid not found"},
+	ERR_BID_NOT_OWNER:            {403, "Bạn không phải chủ lệnh mua này", "You do not own this bid"},
+	ERR_BID_CLOSED:               {409, "Lệnh mua đã khớp hết hoặc đã huỷ", "Bid is already filled or cancelled"},
+	ERR_ORDER_MODE_INVALID:       {400, "mode phải là FOK hoặc IOC", "mode must be FOK or IOC"},
+	ERR_ORDER_NO_LIQUIDITY:       {409, "Không có listing phù hợp (hết hàng hoặc vượt ngân sách)", "No matching listings (sold out or over budget)"},
+	ERR_ORDER_NOT_FILLED:         {409, "Không đủ hàng trong ngân sách (khớp được {filled}/{qty})", "Not enough supply within budget (filled {filled}/{qty})"},
+	ERR_INTERVAL_INVALID:         {400, "interval phải là 5m, 15m, 1h, 4h hoặc 1d", "interval must be 5m, 15m, 1h, 4h or 1d"},
+
+	ERR_SHOP_ITEM_NOT_FOUND:     {404, "Món quà không tồn tại", "Shop item not found"},
+	ERR_SHOP_ITEM_INACTIVE:      {409, "Món quà đã ngừng đổi", "Shop item is no longer available"},
+	ERR_SHOP_NOT_STARTED:        {409, "Chưa đến thời gian đổi quà", "Redemption has not started yet"},
+	ERR_SHOP_ENDED:              {409, "Đã hết thời gian đổi quà", "Redemption period has ended"},
+	ERR_SHOP_OUT_OF_STOCK:       {409, "Quà đã hết (còn {left})", "Out of stock ({left} left)"},
+	ERR_SHOP_LIMIT_REACHED:      {409, "Bạn chỉ được đổi tối đa {limit} lần (đã đổi {used})", "You can redeem at most {limit} times (already {used})"},
+	ERR_SHOP_COST_CODE_INVALID:  {400, "Mã vật phẩm đổi không hợp lệ (chỉ DB1..DB7 hoặc EV)", "Invalid cost item code (DB1..DB7 or EV only)"},
+	ERR_SHOP_REWARD_INVALID:     {400, "Phần thưởng không hợp lệ (rewardKind / rewardCode)", "Invalid reward (rewardKind / rewardCode)"},
+	ERR_SHOP_DISCOUNT_TOO_HIGH:  {400, "Giảm giá VIP tối đa 100%", "VIP discount cannot exceed 100%"},
+	ERR_SHOP_STOCK_INVALID:      {400, "Tồn kho không hợp lệ", "Invalid stock"},
+	ERR_SHOP_STOCK_BELOW_SOLD:   {400, "Tồn kho không được nhỏ hơn số đã bán ({sold})", "Stock cannot be lower than the sold count ({sold})"},
+	ERR_SHOP_LIMIT_INVALID:      {400, "Giới hạn mỗi người phải > 0 hoặc bỏ trống", "Per-user limit must be > 0 or empty"},
+	ERR_SHOP_TIME_RANGE_INVALID: {400, "Thời gian kết thúc phải sau thời gian bắt đầu", "End time must be after start time"},
+
+	ERR_NOTIFICATION_NOT_FOUND:  {404, "Thông báo không tồn tại", "Notification not found"},
+	ERR_LOCALE_UNSUPPORTED:      {400, "Ngôn ngữ không hỗ trợ (vi, en)", "Unsupported language (vi, en)"},
+	ERR_CATEGORY_INVALID:        {400, "Danh mục không hợp lệ: {category}", "Invalid category: {category}"},
+	ERR_CATEGORY_NOT_MUTABLE:    {400, "Không thể tắt thông báo hệ thống", "System notifications cannot be muted"},
+	ERR_TEMPLATE_TYPE_UNKNOWN:   {400, "Loại thông báo không tồn tại", "Unknown notification type"},
+	ERR_TEMPLATE_NOT_OVERRIDDEN: {404, "Mẫu đang là mặc định", "Template is already the default"},
+	ERR_CONTENT_REQUIRED:        {400, "Thiếu tiêu đề hoặc nội dung", "Title and body are required"},
+	ERR_CONTENT_TOO_LONG:        {400, "Tiêu đề tối đa 120 ký tự, nội dung tối đa 500 ký tự", "Title max 120 characters, body max 500 characters"},
+	ERR_SEGMENT_INVALID:         {400, "Điều kiện lọc người nhận không hợp lệ: {field}", "Invalid recipient filter: {field}"},
+	ERR_NO_RECIPIENTS:           {400, "Không có người nhận nào phù hợp", "No matching recipients"},
+	ERR_BROADCAST_NOT_FOUND:     {404, "Broadcast không tồn tại", "Broadcast not found"},
+	ERR_BROADCAST_FINISHED:      {409, "Broadcast đã kết thúc", "Broadcast has already finished"},
+	ERR_SETTING_KEY_UNKNOWN:     {400, "Key cấu hình không tồn tại", "Unknown setting key"},
+	ERR_SETTING_VALUE_INVALID:   {400, "Giá trị phải là số nguyên >= 0", "Value must be an integer >= 0"},
+
+	ERR_SEASON_NOT_FOUND: {404, "Mùa giải không tồn tại", "Season not found"},
+	ERR_SEASON_INVALID:   {400, "Thông tin mùa giải không hợp lệ: {field}", "Invalid season data: {field}"},
+	ERR_SEASON_STARTED:   {409, "Mùa giải đã bắt đầu, không sửa được", "Season has already started and cannot be edited"},
+	ERR_SEASON_CLOSED:    {409, "Mùa giải đã kết thúc hoặc bị huỷ", "Season is already closed or cancelled"},
+
+	ERR_CHECKIN_CALENDAR_EMPTY:   {409, "Chưa cấu hình lịch điểm danh", "Check-in calendar is not configured"},
+	ERR_CHECKIN_CALENDAR_INVALID: {400, "Lịch điểm danh không hợp lệ: {field}", "Invalid check-in calendar: {field}"},
+	ERR_CHECKIN_TIMEZONE_INVALID: {400, "Múi giờ không hợp lệ (dạng IANA, vd Asia/Ho_Chi_Minh)", "Invalid time zone (IANA name, e.g. Asia/Ho_Chi_Minh)"},
+	ERR_CHECKIN_TIMEZONE_LOCKED:  {409, "Chỉ được đổi múi giờ 7 ngày/lần (đổi lại sau {until})", "Time zone can only be changed once every 7 days (next change after {until})"},
+
+	ERR_MISSION_NOT_FOUND:     {404, "Nhiệm vụ không tồn tại", "Mission not found"},
+	ERR_MISSION_INVALID:       {400, "Thông tin nhiệm vụ không hợp lệ: {field}", "Invalid mission data: {field}"},
+	ERR_MISSION_NOT_COMPLETED: {409, "Nhiệm vụ chưa hoàn thành ({progress}/{target})", "Mission not completed yet ({progress}/{target})"},
+	ERR_MISSION_CLAIMED:       {409, "Bạn đã nhận thưởng nhiệm vụ này", "Mission reward already claimed"},
+
+	ERR_JOB_NOT_FOUND:        {404, "Job {name} không tồn tại", "Job {name} not found"},
+	ERR_JOB_RUNNING:          {409, "Job {name} đang chạy", "Job {name} is already running"},
+	ERR_JOB_SCHEDULE_INVALID: {400, "Lịch cron không hợp lệ: {detail}", "Invalid cron schedule: {detail}"},
+
+	ERR_ACCOUNT_FROZEN:  {403, "Tài khoản đang bị tạm khoá ({reason}) tới {until}, vui lòng liên hệ hỗ trợ", "Your account is temporarily frozen ({reason}) until {until}, please contact support"},
+	ERR_DRIFT_NOT_FOUND: {404, "Không tìm thấy bản ghi lệch số dư", "Balance drift not found"},
+	ERR_DRIFT_RESOLVED:  {409, "Bản ghi lệch số dư đã được xử lý", "Balance drift already resolved"},
+
+	ERR_ACCOUNT_CLOSED:          {401, "Tài khoản đã đóng", "This account has been closed"},
+	ERR_ACCOUNT_ALREADY_CLOSED:  {409, "Tài khoản đã được đóng trước đó", "Account is already closed"},
+	ERR_ACCOUNT_NOT_CLOSED:      {409, "Chỉ xoá cứng được tài khoản đã đóng", "Only closed accounts can be purged"},
+	ERR_PURGE_TOO_EARLY:         {409, "Chưa hết thời hạn lưu trữ {days} ngày, xoá được từ {until}", "Retention period of {days} days not over yet, purge allowed from {until}"},
+	ERR_SUPERADMIN_ONLY:         {403, "Chỉ superadmin mới được phép", "Superadmin only"},
+	ERR_CLOSE_BALANCE_NOT_ZERO:  {409, "Tài khoản còn {coins} coin: hãy dùng hết hoặc yêu cầu chi trả (payout) trước khi đóng", "Your account still has {coins} coins: spend them or request a payout before closing"},
+	ERR_CLOSE_REQUEST_PENDING:   {409, "Bạn đã có yêu cầu đóng tài khoản đang chờ xử lý", "You already have a pending account closure request"},
+	ERR_CLOSE_REQUEST_NOT_FOUND: {404, "Không tìm thấy yêu cầu đóng tài khoản", "Account closure request not found"},
+	ERR_CLOSE_REQUEST_DECIDED:   {409, "Yêu cầu đóng tài khoản đã được xử lý", "Account closure request already decided"},
+
+	ERR_ACCOUNT_BANNED:        {403, "Tài khoản bị cấm ({reason}) tới {until}", "Your account is banned ({reason}) until {until}"},
+	ERR_ACCOUNT_RESTRICTED:    {403, "Tài khoản đang bị hạn chế {kind} ({reason}) tới {until}", "Your account is restricted from {kind} ({reason}) until {until}"},
+	ERR_RESTRICTION_NOT_FOUND: {404, "Không tìm thấy hạn chế đang áp dụng", "Active restriction not found"},
+
+	ERR_TRANSFER_ACCOUNT_TOO_NEW: {403, "Tài khoản mới: hạn mức {tier} cho phép chuyển coin sau {days} ngày kể từ khi tạo (từ {until})", "New account: tier {tier} allows transfers {days} days after sign-up (from {until})"},
+	ERR_TRANSFER_OVER_TX_LIMIT:   {400, "Hạn mức {tier}: mỗi lần chuyển tối đa {limit} coin", "Tier {tier}: at most {limit} coins per transfer"},
+	ERR_TRANSFER_DAILY_LIMIT:     {409, "Hạn mức {tier}: hôm nay đã chuyển {used}/{limit} coin, còn được {remaining} coin (đặt lại lúc {resetAt})", "Tier {tier}: {used}/{limit} coins transferred today, {remaining} left (resets at {resetAt})"},
+	ERR_TRANSFER_MONTHLY_LIMIT:   {409, "Hạn mức {tier}: tháng này đã chuyển {used}/{limit} coin, còn được {remaining} coin (đặt lại lúc {resetAt})", "Tier {tier}: {used}/{limit} coins transferred this month, {remaining} left (resets at {resetAt})"},
+	ERR_TRANSFER_NEW_RECIPIENTS:  {409, "Hạn mức {tier}: mỗi ngày chỉ chuyển cho tối đa {limit} người nhận mới (đặt lại lúc {resetAt}); người đã từng nhận vẫn chuyển được", "Tier {tier}: at most {limit} new recipients per day (resets at {resetAt}); previous recipients are still allowed"},
+	ERR_TRANSFER_TIER_MISSING:    {503, "Chưa cấu hình hạn mức chuyển coin", "Transfer limits are not configured"},
+	ERR_TRANSFER_TIER_NOT_FOUND:  {404, "Không tìm thấy hạn mức chuyển coin", "Transfer tier not found"},
+	ERR_TRANSFER_TIER_BASE:       {409, "Không xoá được hạn mức gốc (VIP 0, chưa KYC)", "The base tier (VIP 0, no KYC) cannot be deleted"},
+
+	ERR_DATA_EXPORT_PENDING:   {409, "Yêu cầu xuất dữ liệu trước đang được xử lý", "Your previous data export is still being prepared"},
+	ERR_DATA_EXPORT_TOO_SOON:  {429, "Mỗi 24 giờ chỉ xuất dữ liệu 1 lần, thử lại sau {until}", "Data can be exported once every 24 hours, try again after {until}"},
+	ERR_DATA_EXPORT_NOT_FOUND: {404, "Link tải không tồn tại hoặc đã hết hạn", "Download link not found or expired"},
+}
+
+// Lỗi nghiệp vụ. Error() trả về thông điệp tiếng Việt (dùng cho log).
+type AppError struct {
+	Code   string
+	Params map[string]any
+	Cause  error // lỗi gốc (chỉ ghi log, không trả về FE)
+}
+
+// apiError(ERR_INSUFFICIENT_ITEMS, "code", "DB1", "have", 0)
+func apiError(code string, kv ...any) *AppError {
+	e := &AppError{Code: code}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if e.Params == nil {
+			e.Params = map[string]any{}
+		}
+		e.Params[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	return e
+}
+
+func (e *AppError) Error() string { return e.Message(supportedLocales[0]) }
+
+func (e *AppError) Unwrap() error { return e.Cause }
+
+func (e *AppError) wrap(cause error) *AppError {
+	e.Cause = cause
+	return e
+}
+
+func (e *AppError) Status() int {
+	if d, ok := errorDefs[e.Code]; ok {
+		return d.Status
+	}
+	return 500
+}
+
+func (e *AppError) Message(locale string) string {
+	d, ok := errorDefs[e.Code]
+	if !ok {
+		d = errorDefs[ERR_INTERNAL]
+	}
+	msg := d.Vi
+	if locale == "en" {
+		msg = d.En
+	}
+	return fillPlaceholders(msg, e.Params)
+}
+
+// thay {ten_bien} bằng giá trị; biến không truyền thì bỏ trống
+func fillPlaceholders(s string, vars map[string]any) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	args := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		args = append(args, "{"+k+"}", fmt.Sprint(v))
+	}
+	s = strings.NewReplacer(args...).Replace(s)
+	for {
+		i := strings.Index(s, "{")
+		j := strings.Index(s, "}")
+		if i < 0 || j < i {
+			return s
+		}
+		s = s[:i] + s[j+1:]
+	}
+}
+
+// ngôn ngữ theo ?lang= hoặc Accept-Language (chọn q cao nhất), mặc định vi
+func requestLocale(c *gin.Context) string {
+	if l := strings.ToLower(strings.TrimSpace(c.Query("lang"))); slices.Contains(supportedLocales, l) {
+		return l
+	}
+	best, bestQ := supportedLocales[0], -1.0
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, q := strings.TrimSpace(part), 1.0
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(tag[i+1:]), "q="), 64); err == nil {
+				q = v
+			}
+			tag = tag[:i]
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if slices.Contains(supportedLocales, base) && q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
+
+// Trả lỗi theo định dạng thống nhất: { error, code, params? } và dừng chain.
+// Lỗi không phải AppError => ghi log, trả INTERNAL (không lộ chi tiết DB).
+func respondError(c *gin.Context, err error) {
+	var ae *AppError
+	if !errors.As(err, &ae) {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ae = apiError(ERR_NOT_FOUND)
+		} else {
+			log.Printf("%s %s error: %v", c.Request.Method, c.FullPath(), err)
+			ae = apiError(ERR_INTERNAL)
+		}
+	} else if ae.Cause != nil {
+		log.Printf("%s %s %s: %v", c.Request.Method, c.FullPath(), ae.Code, ae.Cause)
+	}
+	body := gin.H{"error": ae.Message(requestLocale(c)), "code": ae.Code}
+	if len(ae.Params) > 0 {
+		body["params"] = ae.Params
+	}
+	c.AbortWithStatusJSON(ae.Status(), body)
+}
+
+// lỗi bind JSON/query: giữ chi tiết validator trong params.detail
+func invalidInput(err error) *AppError {
+	if err == nil {
+		return apiError(ERR_INVALID_INPUT)
+	}
+	return apiError(ERR_INVALID_INPUT, "detail", err.Error())
+}
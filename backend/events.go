@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== REALTIME: PUB/SUB HUB & SSE ===== */
+
+// loại event đẩy về FE
+const (
+	EV_NOTIFICATION_CREATED = "notification.created"
+	EV_BALANCE_CHANGED      = "balance.changed"
+	EV_LISTING_SOLD         = "listing.sold"
+	EV_KYC_DECIDED          = "kyc.decided"
+	EV_MISSION_COMPLETED    = "mission.completed"
+	EV_ACCOUNT_STATUS       = "account.status"   // kèm xoá cache trạng thái ở mọi instance
+	EV_SETTINGS_CHANGED     = "settings.changed" // gửi admin vừa sửa; mọi instance xoá cache cấu hình
+)
+
+type Event struct {
+	ID     uint64    `json:"id"`
+	UserID uint      `json:"-"`
+	Type   string    `json:"type"`
+	Data   any       `json:"data"`
+	At     time.Time `json:"at"`
+}
+
+// Broker chuyển event giữa các instance. Publish gửi tới MỌI instance (kể cả chính nó),
+// Run gọi deliver cho từng event nhận được (theo thứ tự ID tăng dần) và trả về
+// ID lớn nhất đã tồn tại trước khi instance này bắt đầu nhận.
+type EventBroker interface {
+	Publish(ev Event) error
+	Run(deliver func(Event)) uint64
+}
+
+/* ----- broker trong tiến trình (1 instance) ----- */
+type localBroker struct {
+	seq     atomic.Uint64
+	deliver func(Event)
+	mu      sync.Mutex
+}
+
+func (b *localBroker) Publish(ev Event) error {
+	// cấp ID trong lúc giữ khoá: ID tăng dần đúng thứ tự giao, client không bỏ sót event (bỏ qua ID <= lastID)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ev.ID = b.seq.Add(1)
+	if b.deliver != nil {
+		b.deliver(ev)
+	}
+	return nil
+}
+
+func (b *localBroker) Run(deliver func(Event)) uint64 {
+	b.mu.Lock()
+	b.deliver = deliver
+	b.mu.Unlock()
+	// ID tăng dần qua các lần khởi động lại (micro giây)
+	floor := uint64(time.Now().UnixMicro())
+	b.seq.Store(floor)
+	return floor
+}
+
+/* ----- broker qua MySQL (nhiều instance dùng chung DB) ----- */
+type StreamEvent struct {
+	ID        uint64    `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index;not null"`
+	Type      string    `gorm:"size:40;not null"`
+	Data      string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+type dbBroker struct {
+	poll time.Duration
+}
+
+// Transaction song song có thể commit ID nhỏ sau ID lớn: gặp khoảng trống thì chờ tối đa
+// eventGapWait cho ID còn thiếu rồi mới bỏ qua (khoảng trống do rollback không bao giờ được lấp).
+const eventGapWait = 5 * time.Second
+
+func (b *dbBroker) Publish(ev Event) error {
+	raw, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	return DB.Create(&StreamEvent{UserID: ev.UserID, Type: ev.Type, Data: string(raw)}).Error
+}
+
+func (b *dbBroker) Run(deliver func(Event)) uint64 {
+	var last uint64
+	DB.Model(&StreamEvent{}).Select("COALESCE(MAX(id),0)").Scan(&last)
+	floor := last
+	step := uint64(1) // auto_increment_increment (cụm nhiều master có thể > 1)
+	DB.Raw("SELECT @@auto_increment_increment").Scan(&step)
+	step = max(step, 1)
+	go func() {
+		t := time.NewTicker(b.poll)
+		defer t.Stop()
+		lastCleanup := time.Now()
+		var gapSince time.Time // lúc thấy khoảng trống ngay sau last
+		for range t.C {
+			var rows []StreamEvent
+			if err := DB.Where("id > ?", last).Order("id ASC").Limit(500).Find(&rows).Error; err != nil {
+				log.Println("event broker poll error:", err)
+				continue
+			}
+			for _, r := range rows {
+				if last > 0 && r.ID != last+step {
+					if gapSince.IsZero() {
+						gapSince = time.Now()
+					}
+					if time.Since(gapSince) < eventGapWait {
+						break // đọc lại từ last ở lượt sau
+					}
+				}
+				gapSince = time.Time{}
+				var data any
+				_ = json.Unmarshal([]byte(r.Data), &data)
+				deliver(Event{ID: r.ID, UserID: r.UserID, Type: r.Type, Data: data, At: r.CreatedAt})
+				last = r.ID
+			}
+			// dọn event cũ (đủ cho reconnect)
+			if time.Since(lastCleanup) > 10*time.Minute {
+				DB.Where("created_at < ?", time.Now().Add(-24*time.Hour)).Delete(&StreamEvent{})
+				lastCleanup = time.Now()
+			}
+		}
+	}()
+	return floor
+}
+
+/* ----- hub: giữ subscriber & bộ đệm replay theo user ----- */
+const (
+	eventReplaySize = 200
+	eventReplayTTL  = time.Hour // bỏ bộ đệm của user không còn kết nối quá thời gian này
+)
+
+type eventSub struct {
+	ch   chan Event
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *eventSub) close() { s.once.Do(func() { close(s.done) }) }
+
+// bộ đệm replay của 1 user
+type userEvents struct {
+	events  []Event
+	trimmed uint64    // ID lớn nhất đã bị đẩy khỏi bộ đệm
+	touched time.Time // event cuối hoặc lần ngắt kết nối cuối
+}
+
+type EventHub struct {
+	broker  EventBroker
+	mu      sync.RWMutex
+	subs    map[uint]map[*eventSub]struct{}
+	recent  map[uint]*userEvents
+	evicted uint64 // ID lớn nhất trong các bộ đệm đã bị bỏ (user vắng lâu)
+	floor   uint64 // event <= floor không có trong bộ đệm của instance này
+}
+
+var Hub *EventHub
+
+func newEventHub(b EventBroker) *EventHub {
+	h := &EventHub{
+		broker: b,
+		subs:   map[uint]map[*eventSub]struct{}{},
+		recent: map[uint]*userEvents{},
+	}
+	h.floor = b.Run(h.dispatch)
+	go func() {
+		for range time.Tick(eventReplayTTL / 4) {
+			h.evictIdle(time.Now())
+		}
+	}()
+	return h
+}
+
+// bỏ bộ đệm của user không còn subscriber và đã quá eventReplayTTL;
+// client quay lại với Last-Event-ID cũ hơn phần đã bỏ sẽ nhận "resync"
+func (h *EventHub) evictIdle(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for uid, ue := range h.recent {
+		if len(h.subs[uid]) > 0 || now.Sub(ue.touched) < eventReplayTTL {
+			continue
+		}
+		h.evicted = max(h.evicted, ue.trimmed)
+		if n := len(ue.events); n > 0 {
+			h.evicted = max(h.evicted, ue.events[n-1].ID)
+		}
+		delete(h.recent, uid)
+	}
+}
+
+func startEventHub() {
+	var b EventBroker = &localBroker{}
+	if EVENT_BROKER == "db" {
+		b = &dbBroker{poll: 500 * time.Millisecond}
+	}
+	Hub = newEventHub(b)
+}
+
+func (h *EventHub) Publish(uid uint, typ string, data any) {
+	if h == nil || uid == 0 {
+		return
+	}
+	if err := h.broker.Publish(Event{UserID: uid, Type: typ, Data: data, At: time.Now()}); err != nil {
+		log.Println("event publish error:", err)
+	}
+}
+
+// nhận event từ broker: lưu bộ đệm + đẩy cho subscriber
+func (h *EventHub) dispatch(ev Event) {
+	switch ev.Type {
+	case EV_ACCOUNT_STATUS:
+		forgetAccountStatus(ev.UserID)
+	case EV_SETTINGS_CHANGED:
+		forgetSettings()
+	}
+	h.mu.Lock()
+	ue := h.recent[ev.UserID]
+	if ue == nil {
+		// bộ đệm mới không biết event nào của user đã bị bỏ trước đó
+		ue = &userEvents{trimmed: h.evicted}
+		h.recent[ev.UserID] = ue
+	}
+	ue.events = append(ue.events, ev)
+	if n := len(ue.events) - eventReplaySize; n > 0 {
+		ue.trimmed = ue.events[n-1].ID
+		ue.events = slices.Delete(ue.events, 0, n)
+	}
+	ue.touched = time.Now()
+	subs := make([]*eventSub, 0, len(h.subs[ev.UserID]))
+	for s := range h.subs[ev.UserID] {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subs {
+		select {
+		case s.ch <- ev:
+		default:
+			// client quá chậm: ngắt để client reconnect & replay bằng Last-Event-ID
+			s.close()
+		}
+	}
+}
+
+func (h *EventHub) subscribe(uid uint) *eventSub {
+	s := &eventSub{ch: make(chan Event, 64), done: make(chan struct{})}
+	h.mu.Lock()
+	if h.subs[uid] == nil {
+		h.subs[uid] = map[*eventSub]struct{}{}
+	}
+	h.subs[uid][s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+func (h *EventHub) unsubscribe(uid uint, s *eventSub) {
+	h.mu.Lock()
+	delete(h.subs[uid], s)
+	if len(h.subs[uid]) == 0 {
+		delete(h.subs, uid)
+		if ue := h.recent[uid]; ue != nil {
+			ue.touched = time.Now()
+		}
+	}
+	h.mu.Unlock()
+	s.close()
+}
+
+// các event sau lastID; ok=false nếu lastID đã trôi khỏi bộ đệm (client cần tải lại)
+func (h *EventHub) since(uid uint, lastID uint64) ([]Event, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ue := h.recent[uid]
+	if ue == nil {
+		ue = &userEvents{trimmed: h.evicted}
+	}
+	if lastID < h.floor || lastID < ue.trimmed {
+		return nil, false
+	}
+	out := []Event{}
+	for _, ev := range ue.events {
+		if ev.ID > lastID {
+			out = append(out, ev)
+		}
+	}
+	return out, true
+}
+
+/* ----- phát event sau khi transaction commit ----- */
+
+type pendingEventsKey struct{}
+
+type pendingEvents struct {
+	events   []Event
+	balances map[uint]string // uid -> lý do
+}
+
+// Chạy transaction và chỉ phát event (emitEvent/emitBalanceChanged) khi đã commit thành công.
+func withEvents(fn func(tx *gorm.DB) error) error {
+	p := &pendingEvents{balances: map[uint]string{}}
+	ctx := context.WithValue(context.Background(), pendingEventsKey{}, p)
+	if err := DB.WithContext(ctx).Transaction(fn); err != nil {
+		return err
+	}
+	for _, ev := range p.events {
+		Hub.Publish(ev.UserID, ev.Type, ev.Data)
+	}
+	for uid, reason := range p.balances {
+		publishBalance(uid, reason)
+	}
+	return nil
+}
+
+func pendingFrom(tx *gorm.DB) *pendingEvents {
+	if tx == nil || tx.Statement == nil || tx.Statement.Context == nil {
+		return nil
+	}
+	p, _ := tx.Statement.Context.Value(pendingEventsKey{}).(*pendingEvents)
+	return p
+}
+
+// ghi nhận event; ngoài withEvents thì phát ngay
+func emitEvent(tx *gorm.DB, uid uint, typ string, data any) {
+	if p := pendingFrom(tx); p != nil {
+		p.events = append(p.events, Event{UserID: uid, Type: typ, Data: data})
+		return
+	}
+	Hub.Publish(uid, typ, data)
+}
+
+// số dư thay đổi: gộp theo user, đọc số dư mới sau commit
+func emitBalanceChanged(tx *gorm.DB, uid uint, reason string) {
+	if p := pendingFrom(tx); p != nil {
+		p.balances[uid] = reason
+		return
+	}
+	publishBalance(uid, reason)
+}
+
+func publishBalance(uid uint, reason string) {
+	var u User
+	if err := DB.Select("id, coins, bonus_coins, free_spins").First(&u, uid).Error; err != nil {
+		return
+	}
+	Hub.Publish(uid, EV_BALANCE_CHANGED, gin.H{
+		"reason":     reason,
+		"coins":      u.Coins,
+		"bonusCoins": u.BonusCoins,
+		"totalCoins": u.Coins + u.BonusCoins,
+		"freeSpins":  u.FreeSpins,
+	})
+}
+
+// mọi Notification tạo ra đều đẩy realtime
+func (n *Notification) AfterCreate(tx *gorm.DB) error {
+	emitEvent(tx, n.UserID, EV_NOTIFICATION_CREATED, gin.H{
+		"id": n.ID, "type": n.Type, "category": n.Category,
+		"title": n.Title, "body": n.Body, "createdAt": n.CreatedAt,
+	})
+	return nil
+}
+
+/* ----- vé mở luồng SSE (EventSource không gửi được header Authorization) ----- */
+
+const streamTicketTTL = time.Minute
+
+// vé dùng 1 lần, lưu DB để instance nào nhận kết nối cũng đổi được
+type StreamTicket struct {
+	Token     string    `gorm:"size:64;primaryKey"`
+	UserID    uint      `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// POST /private/events/ticket ⇒ { ticket, expiresAt } — mở GET /private/events?ticket=... trong 1 phút
+func issueStreamTicketHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	t := StreamTicket{Token: newExportToken(), UserID: uid, ExpiresAt: time.Now().Add(streamTicketTTL)}
+	DB.Where("expires_at < ?", time.Now()).Delete(&StreamTicket{})
+	if err := DB.Create(&t).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"ticket": t.Token, "expiresAt": t.ExpiresAt})
+}
+
+// đổi vé lấy user (xoá vé ngay: link lọt vào access log cũng không dùng lại được)
+func redeemStreamTicket(token string) (uint, bool) {
+	var t StreamTicket
+	if err := DB.Where("token = ? AND expires_at > ?", token, time.Now()).First(&t).Error; err != nil {
+		return 0, false
+	}
+	if res := DB.Where("token = ?", token).Delete(&StreamTicket{}); res.Error != nil || res.RowsAffected == 0 {
+		return 0, false
+	}
+	return t.UserID, true
+}
+
+// GET /private/events (SSE). Header Bearer hoặc ?ticket= (vé 1 lần từ POST /private/events/ticket)
+// Reconnect: trình duyệt tự gửi Last-Event-ID; hoặc ?lastEventId= (cần vé mới cho mỗi lần kết nối)
+func eventsStreamHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+
+	lastRaw := c.GetHeader("Last-Event-ID")
+	if lastRaw == "" {
+		lastRaw = c.Query("lastEventId")
+	}
+	lastID, _ := strconv.ParseUint(lastRaw, 10, 64)
+
+	sub := Hub.subscribe(uid)
+	defer Hub.unsubscribe(uid, sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	write := func(w io.Writer, ev Event) {
+		raw, _ := json.Marshal(ev)
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, raw)
+		lastID = ev.ID
+	}
+
+	if lastRaw != "" {
+		replay, ok := Hub.since(uid, lastID)
+		if !ok {
+			// quá cũ: báo FE tải lại toàn bộ (notifications, wallet)
+			fmt.Fprint(c.Writer, "event: resync\ndata: {}\n\n")
+		}
+		for _, ev := range replay {
+			write(c.Writer, ev)
+		}
+	}
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	ping := time.NewTicker(25 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-sub.done:
+			return
+		case ev := <-sub.ch:
+			if ev.ID <= lastID {
+				continue // đã gửi trong phần replay
+			}
+			write(c.Writer, ev)
+			c.Writer.Flush()
+		case <-ping.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
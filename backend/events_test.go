@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventHubEvictIdle(t *testing.T) {
+	h := newEventHub(&localBroker{})
+	ev := func(uid uint, id uint64) Event { return Event{ID: h.floor + id, UserID: uid, Type: EV_BALANCE_CHANGED} }
+	h.dispatch(ev(1, 1))
+	h.dispatch(ev(2, 2))
+	s := h.subscribe(2)
+	defer h.unsubscribe(2, s)
+
+	if got, ok := h.since(1, h.floor); !ok || len(got) != 1 {
+		t.Fatalf("trước khi dọn: since(1) = %v, %v", got, ok)
+	}
+	h.evictIdle(time.Now().Add(eventReplayTTL + time.Minute))
+
+	tests := []struct {
+		name   string
+		uid    uint
+		lastID uint64
+		wantOK bool
+		want   int
+	}{
+		{"user vắng: Last-Event-ID cũ phải resync", 1, h.floor, false, 0},
+		{"user vắng: đã nhận hết thì không cần resync", 1, h.floor + 2, true, 0},
+		{"user đang kết nối không bị dọn", 2, h.floor, true, 1},
+	}
+	for _, tt := range tests {
+		got, ok := h.since(tt.uid, tt.lastID)
+		if ok != tt.wantOK || len(got) != tt.want {
+			t.Errorf("%s: since = %d event, ok=%v; want %d, ok=%v", tt.name, len(got), ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// bộ đệm tạo lại sau khi dọn vẫn nhớ phần đã bỏ
+	h.dispatch(ev(1, 3))
+	if _, ok := h.since(1, h.floor); ok {
+		t.Errorf("bộ đệm mới của user 1 phải yêu cầu resync với Last-Event-ID cũ")
+	}
+	if got, ok := h.since(1, h.floor+2); !ok || len(got) != 1 {
+		t.Errorf("since(1, floor+2) = %v, %v; want 1 event", got, ok)
+	}
+}
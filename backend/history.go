@@ -0,0 +1,444 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== LỊCH SỬ GIAO DỊCH: PHÂN TRANG CURSOR, BỘ LỌC, XUẤT CSV/XLSX ===== */
+
+const (
+	historyDefaultLimit = 50
+	historyMaxLimit     = 200
+	historyExportBatch  = 500    // số dòng mỗi lần đọc DB khi xuất file
+	historyExportMax    = 100000 // chặn trên số dòng 1 file
+)
+
+// bộ lọc chung, đọc từ query string
+type historyFilter struct {
+	Cursor    uint
+	Limit     int
+	From, To  *time.Time // [from, to)
+	MinAmount *int64
+	MaxAmount *int64
+	Direction string // transfers: in | out
+	Kind      string // commissions: UPLINE | ADMIN
+	Depth     int    // commissions: 1..9
+}
+
+// from/to nhận YYYY-MM-DD (giờ địa phương) hoặc RFC3339; to dạng ngày được tính hết ngày đó
+func parseHistoryTime(field, raw string, endOfDay bool) (*time.Time, *AppError) {
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, apiError(ERR_INVALID_INPUT, "field", field, "detail", "YYYY-MM-DD hoặc RFC3339")
+	}
+	return &t, nil
+}
+
+func parseHistoryFilter(c *gin.Context) (historyFilter, *AppError) {
+	f := historyFilter{Limit: historyDefaultLimit}
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		f.Limit = min(n, historyMaxLimit)
+	}
+	if cur, err := strconv.ParseUint(c.Query("cursor"), 10, 64); err == nil && cur > 0 {
+		f.Cursor = uint(cur)
+	}
+	var aerr *AppError
+	if f.From, aerr = parseHistoryTime("from", c.Query("from"), false); aerr != nil {
+		return f, aerr
+	}
+	if f.To, aerr = parseHistoryTime("to", c.Query("to"), true); aerr != nil {
+		return f, aerr
+	}
+	for _, p := range []struct {
+		name string
+		dst  **int64
+	}{{"minAmount", &f.MinAmount}, {"maxAmount", &f.MaxAmount}} {
+		if raw := c.Query(p.name); raw != "" {
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return f, apiError(ERR_INVALID_INPUT, "field", p.name, "detail", "phải là số nguyên")
+			}
+			*p.dst = &n
+		}
+	}
+	f.Direction = strings.TrimSpace(c.Query("direction"))
+	if f.Direction != "" && f.Direction != "in" && f.Direction != "out" {
+		return f, apiError(ERR_INVALID_INPUT, "field", "direction", "detail", "chỉ nhận in|out")
+	}
+	f.Kind = strings.ToUpper(strings.TrimSpace(c.Query("kind")))
+	f.Depth, _ = strconv.Atoi(c.Query("depth"))
+	return f, nil
+}
+
+// nguồn dữ liệu của 1 loại lịch sử
+type historySource[T any] struct {
+	Name    string // dùng đặt tên file xuất
+	IDCol   string // cột khoá cursor (giảm dần)
+	TimeCol string
+	AmtCol  string
+	// câu truy vấn gốc: chỉ dữ liệu của uid + bộ lọc riêng (direction/kind)
+	Query   func(uid uint, f historyFilter) *gorm.DB
+	RowID   func(r T) uint
+	Columns []string
+	Record  func(r T) []any
+}
+
+func (s historySource[T]) page(uid uint, f historyFilter, limit int) ([]T, *uint, error) {
+	q := s.Query(uid, f)
+	if f.Cursor > 0 {
+		q = q.Where(s.IDCol+" < ?", f.Cursor)
+	}
+	if f.From != nil {
+		q = q.Where(s.TimeCol+" >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where(s.TimeCol+" < ?", *f.To)
+	}
+	if f.MinAmount != nil {
+		q = q.Where(s.AmtCol+" >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		q = q.Where(s.AmtCol+" <= ?", *f.MaxAmount)
+	}
+	rows := []T{}
+	if err := q.Order(s.IDCol + " DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	var next *uint
+	if len(rows) > limit {
+		rows = rows[:limit]
+		id := s.RowID(rows[limit-1])
+		next = &id
+	}
+	return rows, next, nil
+}
+
+// GET /private/history/<loại>?cursor=&limit=&from=&to=&minAmount=&maxAmount=[&direction=|&kind=&depth=]
+func historyListHandler[T any](s historySource[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+		f, aerr := parseHistoryFilter(c)
+		if aerr != nil {
+			respondError(c, aerr)
+			return
+		}
+		rows, next, err := s.page(uid, f, f.Limit)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(200, gin.H{"rows": rows, "nextCursor": next})
+	}
+}
+
+// GET /private/history/<loại>/export?format=csv|xlsx + cùng bộ lọc như trên (bỏ qua cursor/limit)
+func historyExportHandler[T any](s historySource[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+		f, aerr := parseHistoryFilter(c)
+		if aerr != nil {
+			respondError(c, aerr)
+			return
+		}
+		format := c.DefaultQuery("format", "csv")
+		var tw tableWriter
+		switch format {
+		case "csv":
+			tw = newCSVTable(c.Writer)
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+		case "xlsx":
+			tw = newXLSXTable(c.Writer)
+			c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		default:
+			respondError(c, apiError(ERR_INVALID_INPUT, "field", "format", "detail", "chỉ nhận csv|xlsx"))
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`,
+			s.Name, time.Now().Format("20060102"), format))
+		c.Status(200)
+
+		if err := s.writeAll(uid, f, tw, c.Writer.Flush); err != nil {
+			_ = c.Error(err) // header đã gửi, không đổi được status
+		}
+		_ = tw.Close()
+	}
+}
+
+// đọc từng lô theo cursor (bỏ qua f.Cursor), ghi thẳng ra tw; flush sau mỗi lô
+func (s historySource[T]) writeAll(uid uint, f historyFilter, tw tableWriter, flush func()) error {
+	if err := tw.Header(s.Columns); err != nil {
+		return err
+	}
+	f.Cursor = 0
+	for written := 0; written < historyExportMax; {
+		rows, next, err := s.page(uid, f, historyExportBatch)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err := tw.Row(s.Record(r)); err != nil {
+				return err // client đóng kết nối
+			}
+		}
+		written += len(rows)
+		if flush != nil {
+			flush()
+		}
+		if next == nil {
+			return nil
+		}
+		f.Cursor = *next
+	}
+	return nil
+}
+
+// toàn bộ lịch sử (không lọc) dạng CSV — dùng cho file xuất dữ liệu cá nhân
+func historyCSV[T any](s historySource[T]) func(uid uint, w io.Writer) error {
+	return func(uid uint, w io.Writer) error {
+		tw := newCSVTable(w)
+		if err := s.writeAll(uid, historyFilter{}, tw, nil); err != nil {
+			return err
+		}
+		return tw.Close()
+	}
+}
+
+/* ----- các nguồn lịch sử ----- */
+
+var topupHistory = historySource[TopupRow]{
+	Name: "topups", IDCol: "t.id", TimeCol: "t.created_at", AmtCol: "t.amount",
+	Query: func(uid uint, _ historyFilter) *gorm.DB {
+		return DB.Table("coin_txns t").
+			Select("t.id, t.amount, t.note, t.created_at, a.username AS admin_username").
+			Joins("LEFT JOIN users a ON a.id = t.admin_id").
+			Where("t.user_id = ?", uid)
+	},
+	RowID:   func(r TopupRow) uint { return r.ID },
+	Columns: []string{"id", "createdAt", "amount", "adminUsername", "note"},
+	Record: func(r TopupRow) []any {
+		return []any{r.ID, r.CreatedAt, r.Amount, r.AdminUsername, r.Note}
+	},
+}
+
+var withdrawHistory = historySource[WithdrawRow]{
+	Name: "withdraws", IDCol: "w.id", TimeCol: "w.created_at", AmtCol: "w.amount",
+	Query: func(uid uint, _ historyFilter) *gorm.DB {
+		return DB.Table("withdraw_txns w").
+			Select("w.id, w.amount, w.note, w.created_at, a.username AS admin_username").
+			Joins("LEFT JOIN users a ON a.id = w.admin_id").
+			Where("w.user_id = ?", uid)
+	},
+	RowID:   func(r WithdrawRow) uint { return r.ID },
+	Columns: []string{"id", "createdAt", "amount", "adminUsername", "note"},
+	Record: func(r WithdrawRow) []any {
+		return []any{r.ID, r.CreatedAt, r.Amount, r.AdminUsername, r.Note}
+	},
+}
+
+var transferHistory = historySource[TransferRow]{
+	Name: "transfers", IDCol: "t.id", TimeCol: "t.created_at", AmtCol: "t.amount",
+	Query: func(uid uint, f historyFilter) *gorm.DB {
+		q := DB.Table("transfer_txns t").
+			Select(`
+				t.id,
+				IF(t.from_id = ?, 'out', 'in') AS direction,
+				t.amount, t.fee, t.created_at,
+				COALESCE(t.note, '') AS note,
+				CASE WHEN t.from_id = ? THEN to_u.username ELSE from_u.username END AS counterpart`,
+				uid, uid).
+			Joins("LEFT JOIN users from_u ON from_u.id = t.from_id").
+			Joins("LEFT JOIN users to_u   ON to_u.id   = t.to_id")
+		switch f.Direction {
+		case "in":
+			return q.Where("t.to_id = ?", uid)
+		case "out":
+			return q.Where("t.from_id = ?", uid)
+		}
+		return q.Where("t.from_id = ? OR t.to_id = ?", uid, uid)
+	},
+	RowID:   func(r TransferRow) uint { return r.ID },
+	Columns: []string{"id", "createdAt", "direction", "counterpart", "amount", "fee", "note"},
+	Record: func(r TransferRow) []any {
+		return []any{r.ID, r.CreatedAt, r.Direction, r.Counterpart, r.Amount, r.Fee, r.Note}
+	},
+}
+
+var vipHistory = historySource[VipBuyRow]{
+	Name: "vip", IDCol: "id", TimeCol: "created_at", AmtCol: "price",
+	Query: func(uid uint, _ historyFilter) *gorm.DB {
+		return DB.Model(&VipPurchaseTxn{}).Where("user_id = ?", uid)
+	},
+	RowID:   func(r VipBuyRow) uint { return r.ID },
+	Columns: []string{"id", "createdAt", "level", "oldLevel", "price"},
+	Record: func(r VipBuyRow) []any {
+		return []any{r.ID, r.CreatedAt, r.Level, r.OldLevel, r.Price}
+	},
+}
+
+var commissionHistory = historySource[CommissionRow]{
+	Name: "commissions", IDCol: "ct.id", TimeCol: "ct.created_at", AmtCol: "ct.amount",
+	Query: func(uid uint, f historyFilter) *gorm.DB {
+		q := DB.Table("commission_txns ct").
+			Select(`ct.id, b.username AS buyer_username, ct.depth, ct.percent, ct.amount, ct.kind, ct.vip_level_bought AS vip_level, ct.created_at`).
+			Joins("LEFT JOIN users b ON b.id = ct.buyer_id").
+			Where("ct.beneficiary_id = ?", uid)
+		if f.Kind != "" {
+			q = q.Where("ct.kind = ?", f.Kind)
+		}
+		if f.Depth > 0 {
+			q = q.Where("ct.depth = ?", f.Depth)
+		}
+		return q
+	},
+	RowID:   func(r CommissionRow) uint { return r.ID },
+	Columns: []string{"id", "createdAt", "kind", "depth", "percent", "buyerUsername", "vipLevel", "amount"},
+	Record: func(r CommissionRow) []any {
+		return []any{r.ID, r.CreatedAt, r.Kind, r.Depth, r.Percent, r.BuyerUsername, r.VipLevel, r.Amount}
+	},
+}
+
+/* ----- ghi bảng ra CSV / XLSX (stream, không giữ cả file trong RAM) ----- */
+
+type tableWriter interface {
+	Header(cols []string) error
+	Row(vals []any) error
+	Close() error
+}
+
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+func cellText(v any) string {
+	switch x := v.(type) {
+	case time.Time:
+		return x.Local().Format(exportTimeLayout)
+	case string:
+		return x
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+type csvTable struct{ w *csv.Writer }
+
+func newCSVTable(w io.Writer) *csvTable {
+	_, _ = io.WriteString(w, "\ufeff") // BOM để Excel đọc đúng UTF-8 (tiếng Việt)
+	return &csvTable{csv.NewWriter(w)}
+}
+
+func (t *csvTable) Header(cols []string) error { return t.w.Write(cols) }
+
+func (t *csvTable) Row(vals []any) error {
+	rec := make([]string, len(vals))
+	for i, v := range vals {
+		s := cellText(v)
+		// chặn CSV injection: chuỗi do người dùng nhập bắt đầu bằng = + - @ sẽ bị Excel hiểu là công thức
+		if _, isText := v.(string); isText && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+			s = "'" + s
+		}
+		rec[i] = s
+	}
+	if err := t.w.Write(rec); err != nil {
+		return err
+	}
+	t.w.Flush()
+	return t.w.Error()
+}
+
+func (t *csvTable) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// XLSX tối giản: 1 sheet, chuỗi inline (không cần sharedStrings)
+type xlsxTable struct {
+	zw    *zip.Writer
+	sheet io.Writer
+}
+
+var xlsxStaticParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func newXLSXTable(w io.Writer) *xlsxTable {
+	return &xlsxTable{zw: zip.NewWriter(w)}
+}
+
+func (t *xlsxTable) Header(cols []string) error {
+	for _, p := range xlsxStaticParts {
+		fw, err := t.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, p.body); err != nil {
+			return err
+		}
+	}
+	sheet, err := t.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	t.sheet = sheet
+	if _, err := io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return err
+	}
+	vals := make([]any, len(cols))
+	for i, c := range cols {
+		vals[i] = c
+	}
+	return t.Row(vals)
+}
+
+func (t *xlsxTable) Row(vals []any) error {
+	var b strings.Builder
+	b.WriteString("<row>")
+	for _, v := range vals {
+		switch v.(type) {
+		case int, int64, uint, float64:
+			fmt.Fprintf(&b, `<c><v>%v</v></c>`, v)
+		default:
+			b.WriteString(`<c t="inlineStr"><is><t>`)
+			_ = xml.EscapeText(&b, []byte(cellText(v)))
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	b.WriteString("</row>")
+	_, err := io.WriteString(t.sheet, b.String())
+	return err
+}
+
+func (t *xlsxTable) Close() error {
+	if t.sheet != nil {
+		if _, err := io.WriteString(t.sheet, `</sheetData></worksheet>`); err != nil {
+			return err
+		}
+	}
+	return t.zw.Close()
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== LỊCH CHẠY NỀN: CRON, LỊCH SỬ CHẠY, CHỈ 1 INSTANCE CHẠY MỖI JOB ===== */
+
+const (
+	JOB_TRIGGER_SCHEDULE = "SCHEDULE"
+	JOB_TRIGGER_MANUAL   = "MANUAL"
+
+	JOB_RUNNING = "RUNNING"
+	JOB_OK      = "OK"
+	JOB_FAILED  = "FAILED"
+)
+
+const jobPollEvery = 15 * time.Second
+
+// job khai báo trong code; lịch (cron) lưu DB để admin đổi được
+type jobDef struct {
+	Name        string
+	Schedule    string // cron mặc định khi tạo dòng jobs lần đầu
+	Description string
+	Timeout     time.Duration // thời hạn lease: quá hạn coi như instance đã chết, instance khác được chạy lại
+	Run         func() error
+}
+
+var jobDefs = []jobDef{
+	{"market.expire_listings", "* * * * *", "Đóng bài đăng chợ quá hạn, trả vật phẩm", 5 * time.Minute, expireMarketListings},
+	{"seasons.close", "* * * * *", "Đóng mùa giải đến hạn & trả thưởng", 10 * time.Minute, runDueSeasons},
+	{"promo.archive", "30 3 * * *", "Lưu trữ chiến dịch gift code hết hạn quá promo.archive_after_days ngày", 5 * time.Minute, archiveEndedPromoCampaigns},
+	{"stats.rollup", "*/10 * * * *", "Tổng hợp số liệu kinh tế theo ngày cho /admin/stats (tính lại hôm qua & hôm nay)", 10 * time.Minute, rollupStats},
+	{"recon.balances", "0 * * * *", "Đối soát số dư với sổ cái, ghi lệch & báo admin (khoá tài khoản khi vượt recon.freeze_threshold)", 30 * time.Minute, reconcileJob},
+	{"data_exports.build", "* * * * *", "Tạo file xuất dữ liệu cá nhân đang chờ, xoá file đã hết hạn", dataExportJobTimeout, buildDataExports},
+	{"jobs.prune_runs", "0 4 * * *", "Xoá lịch sử chạy job cũ hơn jobs.run_retention_days ngày", 5 * time.Minute, pruneJobRuns},
+}
+
+func findJobDef(name string) (jobDef, bool) {
+	for _, d := range jobDefs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return jobDef{}, false
+}
+
+type Job struct {
+	Name           string     `gorm:"primaryKey;size:64" json:"name"`
+	Schedule       string     `gorm:"size:64;not null" json:"schedule"`
+	IsPaused       bool       `gorm:"not null;default:false" json:"isPaused"`
+	NextRunAt      *time.Time `gorm:"index" json:"nextRunAt"`
+	RunRequested   bool       `gorm:"not null;default:false" json:"runRequested"` // admin bấm chạy ngay, chờ instance nhận
+	RunRequestedBy *uint      `json:"-"`
+	LockedBy       string     `gorm:"size:100;not null;default:''" json:"lockedBy"` // instance đang giữ lease
+	LockedUntil    *time.Time `json:"lockedUntil"`
+	LastRunAt      *time.Time `json:"lastRunAt"`
+	LastStatus     string     `gorm:"size:10;not null;default:''" json:"lastStatus"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+type JobRun struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Job         string     `gorm:"size:64;not null;index:idx_job_runs_job,priority:1" json:"job"`
+	Trigger     string     `gorm:"size:10;not null" json:"trigger"` // SCHEDULE | MANUAL
+	TriggeredBy *uint      `json:"triggeredBy"`                     // admin (chạy tay)
+	Instance    string     `gorm:"size:100;not null" json:"instance"`
+	Status      string     `gorm:"size:10;not null;index" json:"status"` // RUNNING | OK | FAILED
+	Error       string     `gorm:"type:text" json:"error"`
+	StartedAt   time.Time  `gorm:"index:idx_job_runs_job,priority:2" json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt"`
+	DurationMs  int64      `gorm:"not null;default:0" json:"durationMs"`
+}
+
+// định danh instance: host-pid
+var jobInstance = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+var jobKick = make(chan struct{}, 1)
+
+func kickJobs() {
+	select {
+	case jobKick <- struct{}{}:
+	default:
+	}
+}
+
+// tạo dòng jobs còn thiếu rồi chạy vòng quét nền
+func startJobScheduler() {
+	now := time.Now()
+	for _, d := range jobDefs {
+		sched, err := parseCron(d.Schedule)
+		if err != nil {
+			log.Fatalf("❌ job %s: cron %q không hợp lệ: %v", d.Name, d.Schedule, err)
+		}
+		next := sched.next(now)
+		if err := DB.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Job{Name: d.Name, Schedule: d.Schedule, NextRunAt: &next}).Error; err != nil {
+			log.Fatal("❌ seed jobs error:", err)
+		}
+	}
+
+	go func() {
+		t := time.NewTicker(jobPollEvery)
+		defer t.Stop()
+		for {
+			runDueJobs()
+			select {
+			case <-t.C:
+			case <-jobKick:
+			}
+		}
+	}()
+}
+
+// Nhận lease các job đến hạn (hoặc được yêu cầu chạy tay) bằng 1 câu UPDATE có điều kiện:
+// chỉ 1 instance update được dòng đó, instance đó chạy job.
+func runDueJobs() {
+	for _, d := range jobDefs {
+		now := time.Now()
+		res := DB.Model(&Job{}).
+			Where("name = ? AND (locked_until IS NULL OR locked_until < ?)", d.Name, now).
+			Where("run_requested = 1 OR (is_paused = 0 AND next_run_at <= ?)", now).
+			Updates(map[string]any{"locked_by": jobInstance, "locked_until": now.Add(d.Timeout)})
+		if res.Error != nil {
+			log.Println("claim job", d.Name, "error:", res.Error)
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		var job Job
+		if err := DB.First(&job, "name = ?", d.Name).Error; err != nil {
+			log.Println("load job", d.Name, "error:", err)
+			continue
+		}
+		go runJob(d, job)
+	}
+}
+
+func runJob(d jobDef, job Job) {
+	start := time.Now()
+	run := JobRun{Job: d.Name, Trigger: JOB_TRIGGER_SCHEDULE, Instance: jobInstance, Status: JOB_RUNNING, StartedAt: start}
+	if job.RunRequested {
+		run.Trigger, run.TriggeredBy = JOB_TRIGGER_MANUAL, job.RunRequestedBy
+		// xoá yêu cầu ngay khi nhận: yêu cầu mới đến trong lúc đang chạy sẽ được chạy tiếp ở lượt sau
+		if err := DB.Model(&Job{}).Where("name = ? AND locked_by = ?", d.Name, jobInstance).
+			Updates(map[string]any{"run_requested": false, "run_requested_by": nil}).Error; err != nil {
+			log.Println("clear job request", d.Name, "error:", err)
+		}
+	}
+	// lần chạy trước còn RUNNING nghĩa là instance đó chết giữa chừng (lease đã hết)
+	_ = DB.Model(&JobRun{}).Where("job = ? AND status = ?", d.Name, JOB_RUNNING).
+		Updates(map[string]any{"status": JOB_FAILED, "error": "mất lease (instance dừng giữa chừng?)"}).Error
+	if err := DB.Create(&run).Error; err != nil {
+		log.Println("create job run", d.Name, "error:", err)
+	}
+
+	// gia hạn lease trong lúc chạy: job chạy lâu hơn timeout không bị instance khác nhận chạy song song
+	done := make(chan struct{})
+	go renewJobLease(d, done)
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return d.Run()
+	}()
+	close(done)
+
+	end := time.Now()
+	run.Status, run.FinishedAt, run.DurationMs = JOB_OK, &end, end.Sub(start).Milliseconds()
+	if err != nil {
+		run.Status, run.Error = JOB_FAILED, err.Error()
+		log.Println("job", d.Name, "error:", err)
+	}
+	if run.ID > 0 {
+		_ = DB.Model(&run).Select("status", "error", "finished_at", "duration_ms").Updates(&run).Error
+	}
+
+	// chạy tay không làm lệch lịch: chỉ tính lại khi mốc cũ đã qua
+	upd := map[string]any{
+		"locked_by": "", "locked_until": nil,
+		"last_run_at": start, "last_status": run.Status,
+	}
+	if job.NextRunAt == nil || !job.NextRunAt.After(end) {
+		if sched, err := parseCron(job.Schedule); err == nil {
+			upd["next_run_at"] = sched.next(end)
+		}
+	}
+	if err := DB.Model(&Job{}).Where("name = ? AND locked_by = ?", d.Name, jobInstance).Updates(upd).Error; err != nil {
+		log.Println("release job", d.Name, "error:", err)
+	}
+}
+
+// mỗi 1/3 timeout đẩy locked_until thêm 1 timeout; instance chết thì lease hết hạn như cũ
+func renewJobLease(d jobDef, done <-chan struct{}) {
+	t := time.NewTicker(d.Timeout / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-t.C:
+			if err := DB.Model(&Job{}).Where("name = ? AND locked_by = ?", d.Name, jobInstance).
+				Update("locked_until", now.Add(d.Timeout)).Error; err != nil {
+				log.Println("renew job lease", d.Name, "error:", err)
+			}
+		}
+	}
+}
+
+func pruneJobRuns() error {
+	days := settingInt("jobs.run_retention_days")
+	if days <= 0 {
+		return nil
+	}
+	return DB.Where("started_at < ? AND status <> ?", time.Now().AddDate(0, 0, -int(days)), JOB_RUNNING).
+		Delete(&JobRun{}).Error
+}
+
+/* ----- cron 5 trường: phút giờ ngày tháng thứ ----- */
+
+// hỗ trợ * , - / và @hourly @daily @weekly @monthly; thứ 0 hoặc 7 = Chủ nhật; theo giờ địa phương
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit i bật = giá trị i khớp
+	domAny, dowAny                bool
+}
+
+var cronAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if a, ok := cronAliases[expr]; ok {
+		expr = a
+	}
+	f := strings.Fields(expr)
+	if len(f) != 5 {
+		return nil, errors.New("cần 5 trường: phút giờ ngày tháng thứ")
+	}
+	var s cronSchedule
+	var err error
+	for i, p := range []struct {
+		dst      *uint64
+		lo, hi   int
+		name     string
+		fieldAny *bool
+	}{
+		{&s.minute, 0, 59, "phút", nil},
+		{&s.hour, 0, 23, "giờ", nil},
+		{&s.dom, 1, 31, "ngày", &s.domAny},
+		{&s.month, 1, 12, "tháng", nil},
+		{&s.dow, 0, 7, "thứ", &s.dowAny},
+	} {
+		if *p.dst, err = parseCronField(f[i], p.lo, p.hi); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.name, err)
+		}
+		if p.fieldAny != nil {
+			*p.fieldAny = f[i] == "*"
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return &s, nil
+}
+
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bước %q không hợp lệ", part)
+			}
+			rng, step = part[:i], n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("giá trị %q không hợp lệ", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("giá trị %q không hợp lệ", part)
+				}
+			} else if step > 1 {
+				to = hi // "5/15" = từ 5, mỗi 15
+			}
+			if from < lo || to > hi || from > to {
+				return 0, fmt.Errorf("%q ngoài khoảng %d-%d", part, lo, hi)
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	// như cron chuẩn: giới hạn cả ngày lẫn thứ thì chỉ cần khớp 1 trong 2
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// mốc chạy kế tiếp sau t (tính theo phút); không có trong 5 năm tới thì trả t + 5 năm
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return limit
+}
+
+/* ----- admin ----- */
+
+type JobView struct {
+	Job
+	Description string `json:"description"`
+	Running     bool   `json:"running"`
+}
+
+func loadJob(c *gin.Context) (jobDef, Job, bool) {
+	d, ok := findJobDef(c.Param("name"))
+	var job Job
+	if ok {
+		ok = DB.First(&job, "name = ?", d.Name).Error == nil
+	}
+	if !ok {
+		respondError(c, apiError(ERR_JOB_NOT_FOUND, "name", c.Param("name")))
+	}
+	return d, job, ok
+}
+
+func (j Job) running(now time.Time) bool {
+	return j.LockedUntil != nil && j.LockedUntil.After(now)
+}
+
+// GET /admin/jobs
+func adminListJobsHandler(c *gin.Context) {
+	var jobs []Job
+	DB.Find(&jobs)
+	byName := map[string]Job{}
+	for _, j := range jobs {
+		byName[j.Name] = j
+	}
+	now := time.Now()
+	rows := []JobView{}
+	for _, d := range jobDefs {
+		if j, ok := byName[d.Name]; ok {
+			rows = append(rows, JobView{Job: j, Description: d.Description, Running: j.running(now)})
+		}
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// GET /admin/jobs/:name/runs?status=&from=&to=&cursor=&limit=
+func adminJobRunsHandler(c *gin.Context) {
+	d, _, ok := loadJob(c)
+	if !ok {
+		return
+	}
+	f, aerr := parseHistoryFilter(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	q := DB.Model(&JobRun{}).Where("job = ?", d.Name)
+	if st := strings.ToUpper(strings.TrimSpace(c.Query("status"))); st != "" {
+		q = q.Where("status = ?", st)
+	}
+	if f.Cursor > 0 {
+		q = q.Where("id < ?", f.Cursor)
+	}
+	if f.From != nil {
+		q = q.Where("started_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("started_at < ?", *f.To)
+	}
+	rows := []JobRun{}
+	if err := q.Order("id DESC").Limit(f.Limit + 1).Find(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	var next *uint
+	if len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		next = &rows[f.Limit-1].ID
+	}
+	c.JSON(200, gin.H{"rows": rows, "nextCursor": next})
+}
+
+// POST /admin/jobs/:name/run — chạy ngay (kể cả khi đang tạm dừng); instance nào nhận lease trước sẽ chạy
+func adminRunJobHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	d, job, ok := loadJob(c)
+	if !ok {
+		return
+	}
+	if job.running(time.Now()) {
+		respondError(c, apiError(ERR_JOB_RUNNING, "name", d.Name))
+		return
+	}
+	if err := requestJobRun(d.Name, &adminID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã xếp lịch chạy ngay"})
+}
+
+// yêu cầu chạy job ở lượt quét kế tiếp (by = nil: do hệ thống, vd user vừa tạo việc cho job)
+func requestJobRun(name string, by *uint) error {
+	if err := DB.Model(&Job{}).Where("name = ?", name).
+		Updates(map[string]any{"run_requested": true, "run_requested_by": by}).Error; err != nil {
+		return err
+	}
+	kickJobs()
+	return nil
+}
+
+// POST /admin/jobs/:name/pause | resume
+func adminPauseJobHandler(paused bool, msg string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, job, ok := loadJob(c)
+		if !ok {
+			return
+		}
+		upd := map[string]any{"is_paused": paused}
+		if !paused {
+			// bỏ qua các mốc đã lỡ trong lúc tạm dừng
+			if sched, err := parseCron(job.Schedule); err == nil {
+				upd["next_run_at"] = sched.next(time.Now())
+			}
+		}
+		if err := DB.Model(&job).Updates(upd).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(200, gin.H{"message": msg, "job": job})
+	}
+}
+
+type JobScheduleRequest struct {
+	Schedule string `json:"schedule" binding:"required,max=64"` // cron 5 trường hoặc @hourly|@daily|@weekly|@monthly
+}
+
+// PUT /admin/jobs/:name { schedule }
+func adminUpdateJobHandler(c *gin.Context) {
+	_, job, ok := loadJob(c)
+	if !ok {
+		return
+	}
+	var req JobScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	sched, err := parseCron(req.Schedule)
+	if err != nil {
+		respondError(c, apiError(ERR_JOB_SCHEDULE_INVALID, "detail", err.Error()))
+		return
+	}
+	next := sched.next(time.Now())
+	if err := DB.Model(&job).Updates(map[string]any{"schedule": strings.TrimSpace(req.Schedule), "next_run_at": next}).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã cập nhật lịch chạy", "job": job})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"*/15 * * * *", false},
+		{"5/15 * * * *", false},
+		{"0 9 * * 1-5", false},
+		{"0 0 1,15 * *", false},
+		{"0 0 * * 7", false},
+		{" @daily ", false},
+		{"@monthly", false},
+		{"", true},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"*/0 * * * *", true},
+		{"5-1 * * * *", true},
+		{"a * * * *", true},
+		{"@yearly", true},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCron(%q) err = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// 2026-01-01 là thứ Năm
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{"mỗi 15 phút", "*/15 * * * *", "2026-01-01 10:07:30", "2026-01-01 10:15:00"},
+		{"đúng mốc thì lấy mốc sau", "*/15 * * * *", "2026-01-01 10:15:00", "2026-01-01 10:30:00"},
+		{"bắt đầu từ 5 mỗi 15", "5/15 * * * *", "2026-01-01 10:06:00", "2026-01-01 10:20:00"},
+		{"qua giờ", "0 * * * *", "2026-01-01 10:59:59", "2026-01-01 11:00:00"},
+		{"daily qua ngày", "@daily", "2026-01-01 10:00:00", "2026-01-02 00:00:00"},
+		{"ngày thường bỏ cuối tuần", "0 9 * * 1-5", "2026-01-02 10:00:00", "2026-01-05 09:00:00"},
+		{"thứ 7 = Chủ nhật", "0 0 * * 7", "2026-01-01 00:00:00", "2026-01-04 00:00:00"},
+		{"bỏ qua tháng không có ngày 31", "0 0 31 * *", "2026-02-01 00:00:00", "2026-03-31 00:00:00"},
+		{"ngày hoặc thứ", "0 0 1 * 1", "2026-01-01 00:00:00", "2026-01-05 00:00:00"},
+		{"qua năm", "@monthly", "2026-12-15 08:00:00", "2027-01-01 00:00:00"},
+		{"không bao giờ khớp", "0 0 30 2 *", "2026-01-01 00:00:00", "2031-01-01 00:01:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q): %v", tt.expr, err)
+			}
+			if got := s.next(at(tt.from)); !got.Equal(at(tt.want)) {
+				t.Errorf("next(%s) = %s, want %s", tt.from, got.Format(time.DateTime), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== BẢNG XẾP HẠNG: THEO KHUNG THỜI GIAN, TÍNH SẴN TRONG BỘ NHỚ ===== */
+
+const leaderboardRefreshEvery = time.Minute
+
+// khung thời gian (giờ địa phương)
+const (
+	LB_DAY   = "day"   // từ 00:00 hôm nay
+	LB_WEEK  = "week"  // từ 00:00 thứ Hai tuần này
+	LB_MONTH = "month" // từ ngày 1 tháng này
+	LB_ALL   = "all"
+)
+
+var leaderboardWindows = []string{LB_DAY, LB_WEEK, LB_MONTH, LB_ALL}
+
+// loại bảng xếp hạng (cũng là metric của mùa giải) -> truy vấn điểm (user_id, score) trong [since, until) (nil = không giới hạn)
+var leaderboardBoards = map[string]func(since, until *time.Time) *gorm.DB{
+	// tổng hoa hồng nhận từ F1
+	"f1": func(since, until *time.Time) *gorm.DB {
+		return sinceCol(DB.Table("commission_txns").
+			Select("beneficiary_id AS user_id, SUM(amount) AS score").
+			Where("depth = 1").Group("beneficiary_id"), "created_at", since, until)
+	},
+	// tổng hoa hồng nhận từ cả hệ thống (tầng 1..9)
+	"system": func(since, until *time.Time) *gorm.DB {
+		return sinceCol(DB.Table("commission_txns").
+			Select("beneficiary_id AS user_id, SUM(amount) AS score").
+			Where("depth BETWEEN 1 AND 9").Group("beneficiary_id"), "created_at", since, until)
+	},
+	// số lần mở rương
+	"chest": func(since, until *time.Time) *gorm.DB {
+		return sinceCol(DB.Table("chest_txns").
+			Select("user_id, COUNT(*) AS score").Group("user_id"), "created_at", since, until)
+	},
+	// giá trị giao dịch chợ (cả mua lẫn bán)
+	"market": func(since, until *time.Time) *gorm.DB {
+		sell := sinceCol(DB.Table("market_trades").Select("seller_id AS user_id, total"), "created_at", since, until)
+		buy := sinceCol(DB.Table("market_trades").Select("buyer_id AS user_id, total"), "created_at", since, until)
+		return DB.Table("(? UNION ALL ?) AS t", sell, buy).
+			Select("user_id, SUM(total) AS score").Group("user_id")
+	},
+	// số F1 mua VIP
+	"vip_referrals": func(since, until *time.Time) *gorm.DB {
+		return sinceCol(DB.Table("vip_purchase_txns AS v").
+			Joins("JOIN users b ON b.id = v.user_id").
+			Select("b.referred_by AS user_id, COUNT(DISTINCT v.user_id) AS score").
+			Where("b.referred_by IS NOT NULL").Group("b.referred_by"), "v.created_at", since, until)
+	},
+}
+
+func sinceCol(q *gorm.DB, col string, since, until *time.Time) *gorm.DB {
+	if since != nil {
+		q = q.Where(col+" >= ?", *since)
+	}
+	if until != nil {
+		q = q.Where(col+" < ?", *until)
+	}
+	return q
+}
+
+func leaderboardSince(window string, now time.Time) *time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var t time.Time
+	switch window {
+	case LB_DAY:
+		t = day
+	case LB_WEEK:
+		t = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case LB_MONTH:
+		t = day.AddDate(0, 0, 1-day.Day())
+	default:
+		return nil
+	}
+	return &t
+}
+
+type leaderboardEntry struct {
+	UserID   uint
+	Username string
+	Score    int64
+}
+
+// 1 bảng đã tính: entries giảm dần theo điểm, chỉ gồm user thường có điểm > 0
+type leaderboardSnapshot struct {
+	entries []leaderboardEntry
+	byUser  map[uint]int // user_id -> vị trí trong entries
+	builtAt time.Time
+}
+
+// hạng = 1 + số người có điểm cao hơn (đồng điểm đồng hạng); tìm nhị phân O(log n)
+func (s *leaderboardSnapshot) rankOf(score int64) int {
+	return sort.Search(len(s.entries), func(i int) bool { return s.entries[i].Score <= score }) + 1
+}
+
+func (s *leaderboardSnapshot) scoreOf(uid uint) int64 {
+	if i, ok := s.byUser[uid]; ok {
+		return s.entries[i].Score
+	}
+	return 0
+}
+
+var leaderboardCache = struct {
+	mu    sync.RWMutex
+	snaps map[string]*leaderboardSnapshot // "<kind>:<window>"
+}{snaps: map[string]*leaderboardSnapshot{}}
+
+// điểm của mọi user thường có điểm > 0, giảm dần
+func leaderboardScores(kind string, since, until *time.Time) ([]leaderboardEntry, error) {
+	rows := []leaderboardEntry{}
+	err := DB.Table("(?) AS s", leaderboardBoards[kind](since, until)).
+		Joins("JOIN users u ON u.id = s.user_id").
+		Where("u.role = ? AND s.score > 0", "user").
+		Select("s.user_id, u.username, s.score").
+		Order("s.score DESC, s.user_id ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func buildLeaderboard(kind, window string) (*leaderboardSnapshot, error) {
+	now := time.Now()
+	entries, err := leaderboardScores(kind, leaderboardSince(window, now), nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &leaderboardSnapshot{entries: entries, byUser: map[uint]int{}, builtAt: now}
+	for i, e := range s.entries {
+		s.byUser[e.UserID] = i
+	}
+	return s, nil
+}
+
+func refreshLeaderboard(kind, window string) (*leaderboardSnapshot, error) {
+	s, err := buildLeaderboard(kind, window)
+	if err != nil {
+		return nil, err
+	}
+	leaderboardCache.mu.Lock()
+	leaderboardCache.snaps[kind+":"+window] = s
+	leaderboardCache.mu.Unlock()
+	return s, nil
+}
+
+func refreshAllLeaderboards() {
+	for kind := range leaderboardBoards {
+		for _, w := range leaderboardWindows {
+			if _, err := refreshLeaderboard(kind, w); err != nil {
+				// giữ bảng cũ, thử lại ở lượt sau
+				log.Println("leaderboard", kind, w, "error:", err)
+			}
+		}
+	}
+}
+
+// chạy nền mỗi phút
+func startLeaderboardWorker() {
+	go func() {
+		refreshAllLeaderboards()
+		t := time.NewTicker(leaderboardRefreshEvery)
+		defer t.Stop()
+		for range t.C {
+			refreshAllLeaderboards()
+		}
+	}()
+}
+
+// bảng đã tính; worker chưa chạy xong lượt đầu thì tính ngay
+func getLeaderboard(kind, window string) (*leaderboardSnapshot, error) {
+	leaderboardCache.mu.RLock()
+	s := leaderboardCache.snaps[kind+":"+window]
+	leaderboardCache.mu.RUnlock()
+	if s != nil {
+		return s, nil
+	}
+	return refreshLeaderboard(kind, window)
+}
+
+// đọc kind (mặc định f1) & window (mặc định all)
+func leaderboardParams(c *gin.Context) (string, string, *AppError) {
+	kind := strings.ToLower(c.DefaultQuery("kind", "f1"))
+	if _, ok := leaderboardBoards[kind]; !ok {
+		return "", "", apiError(ERR_INVALID_INPUT, "field", "kind", "detail", "f1|system|chest|market|vip_referrals")
+	}
+	window := strings.ToLower(c.DefaultQuery("window", LB_ALL))
+	if !slices.Contains(leaderboardWindows, window) {
+		return "", "", apiError(ERR_INVALID_INPUT, "field", "window", "detail", "day|week|month|all")
+	}
+	return kind, window, nil
+}
+
+// GET /public/leaderboard?kind=f1|system|chest|market|vip_referrals&window=day|week|month|all&limit=100
+func publicLeaderboardHandler(c *gin.Context) {
+	kind, window, aerr := leaderboardParams(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	s, err := getLeaderboard(kind, window)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	top := s.entries[:min(limit, len(s.entries))]
+	rows := make([]LeaderboardRow, 0, len(top))
+	for _, e := range top {
+		rows = append(rows, LeaderboardRow{Rank: s.rankOf(e.Score), Username: e.Username, Score: e.Score})
+	}
+	c.JSON(200, gin.H{"rows": rows, "kind": kind, "window": window, "updatedAt": s.builtAt})
+}
+
+// GET /private/leaderboard/me?kind=&window=
+func privateMyLeaderboardHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	kind, window, aerr := leaderboardParams(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+
+	// lấy username (không lỗi cũng tiếp tục)
+	var uname string
+	_ = DB.Table("users").Select("username").Where("id = ?", uid).Scan(&uname).Error
+
+	s, err := getLeaderboard(kind, window)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	score := s.scoreOf(uid)
+	c.JSON(200, MyRankResp{
+		Rank:     s.rankOf(score), // FE hiển thị # nếu > 100
+		Username: uname,
+		Score:    score,
+	})
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== SỔ CÁI SỐ DƯ & SAO KÊ ===== */
+
+// loại tiền
+const (
+	CUR_COIN  = "COIN"  // users.coins
+	CUR_BONUS = "BONUS" // users.bonus_coins
+)
+
+// loại biến động; ref_id trỏ tới bản ghi nguồn tương ứng
+const (
+	LEDGER_TOPUP           = "TOPUP"           // coin_txns.id
+	LEDGER_WITHDRAW        = "WITHDRAW"        // withdraw_txns.id
+	LEDGER_TRANSFER_IN     = "TRANSFER_IN"     // transfer_txns.id
+	LEDGER_TRANSFER_OUT    = "TRANSFER_OUT"    // transfer_txns.id
+	LEDGER_TRANSFER_FEE    = "TRANSFER_FEE"    // transfer_txns.id
+	LEDGER_TRANSFER_FEE_IN = "TRANSFER_FEE_IN" // transfer_txns.id (ví phí hệ thống)
+	LEDGER_VIP_PURCHASE    = "VIP_PURCHASE"    // vip_purchase_txns.id
+	LEDGER_COMMISSION      = "COMMISSION"      // commission_txns.id
+	LEDGER_REFERRAL_BONUS  = "REFERRAL_BONUS"  // vip_purchase_txns.id (lượt mua làm đạt mốc)
+	LEDGER_CHEST_OPEN      = "CHEST_OPEN"      // chest_txns.id
+	LEDGER_CHEST_MILESTONE = "CHEST_MILESTONE" // chest_txns.id
+	LEDGER_MERGE_REWARD    = "MERGE_REWARD"    // 0
+	LEDGER_MARKET_BUY      = "MARKET_BUY"      // market_trades.id (lệnh quét: lần khớp đầu tiên)
+	LEDGER_MARKET_SELL     = "MARKET_SELL"     // market_trades.id (đã trừ phí)
+	LEDGER_MARKET_FEE      = "MARKET_FEE"      // market_trades.id (ví phí hệ thống)
+	LEDGER_BID_ESCROW      = "BID_ESCROW"      // market_bids.id
+	LEDGER_BID_REFUND      = "BID_REFUND"      // market_bids.id
+	LEDGER_BONUS_CODE      = "BONUS_CODE"      // promo_bonus_codes.id (cũ, trước khi gộp vào chiến dịch)
+	LEDGER_PROMO           = "PROMO"           // promo_redemptions.id
+	LEDGER_SHOP_REWARD     = "SHOP_REWARD"     // shop_purchases.id
+	LEDGER_SEASON_PRIZE    = "SEASON_PRIZE"    // seasons.id
+	LEDGER_CHECKIN         = "CHECKIN"         // checkin_logs.id
+	LEDGER_MISSION         = "MISSION"         // mission_progresses.id
+	LEDGER_RECON_ADJUST    = "RECON_ADJUST"    // balance_drifts.id (ghi bù khi admin chấp nhận số dư lệch)
+)
+
+// 1 dòng biến động số dư (amount có dấu)
+type LedgerEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_ledger_user_time,priority:1" json:"userId"`
+	Currency  string    `gorm:"size:8;not null" json:"currency"`
+	Type      string    `gorm:"size:32;not null" json:"type"`
+	RefID     uint      `gorm:"not null;default:0" json:"refId"`
+	Amount    int64     `gorm:"not null" json:"amount"`
+	CreatedAt time.Time `gorm:"index:idx_ledger_user_time,priority:2" json:"createdAt"`
+}
+
+// Ghi 1 dòng sổ cái. Gọi trong cùng transaction với lệnh cộng/trừ số dư,
+// để sổ cái luôn khớp với users.coins / users.bonus_coins.
+func addLedger(tx *gorm.DB, uid uint, currency, typ string, ref uint, amount int64) error {
+	if amount == 0 || uid == 0 {
+		return nil
+	}
+	return tx.Create(&LedgerEntry{
+		UserID: uid, Currency: currency, Type: typ, RefID: ref, Amount: amount,
+	}).Error
+}
+
+// tổng biến động theo loại tiền của câu truy vấn q (đã lọc user)
+func ledgerSums(q *gorm.DB) (map[string]int64, error) {
+	var rs []struct {
+		Currency string
+		Total    int64
+	}
+	if err := q.Model(&LedgerEntry{}).
+		Select("currency, COALESCE(SUM(amount),0) AS total").
+		Group("currency").Scan(&rs).Error; err != nil {
+		return nil, err
+	}
+	m := map[string]int64{}
+	for _, r := range rs {
+		m[r.Currency] = r.Total
+	}
+	return m, nil
+}
+
+type StatementRow struct {
+	ID        uint      `json:"id"`
+	Currency  string    `json:"currency"`
+	Type      string    `json:"type"`
+	RefID     uint      `json:"refId"`
+	Amount    int64     `json:"amount"`
+	Balance   int64     `json:"balance"` // số dư (cùng loại tiền) ngay sau dòng này
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type StatementBalances struct {
+	Coins      int64 `json:"coins"`
+	BonusCoins int64 `json:"bonusCoins"`
+}
+
+func statementBalances(m map[string]int64) StatementBalances {
+	return StatementBalances{Coins: m[CUR_COIN], BonusCoins: m[CUR_BONUS]}
+}
+
+// GET /private/statement?from=&to=&currency=COIN|BONUS&cursor=&limit=
+// Dòng mới trước. Số dư được tính lùi từ số dư hiện tại nên không cần backfill dữ liệu cũ:
+// closing = hiện tại - biến động sau `to`, opening = closing - biến động trong kỳ.
+func statementHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	f, aerr := parseHistoryFilter(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	currency := strings.TrimSpace(c.Query("currency"))
+	if currency != "" && currency != CUR_COIN && currency != CUR_BONUS {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "currency", "detail", "chỉ nhận COIN|BONUS"))
+		return
+	}
+
+	var u User
+	if err := DB.Select("id, coins, bonus_coins").First(&u, uid).Error; err != nil {
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
+		return
+	}
+	current := map[string]int64{CUR_COIN: u.Coins, CUR_BONUS: u.BonusCoins}
+	mine := func() *gorm.DB { return DB.Where("user_id = ?", uid) }
+
+	// số dư đầu/cuối kỳ
+	closing := map[string]int64{CUR_COIN: u.Coins, CUR_BONUS: u.BonusCoins}
+	if f.To != nil {
+		after, err := ledgerSums(mine().Where("created_at >= ?", *f.To))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		for k := range closing {
+			closing[k] -= after[k]
+		}
+	}
+	period := mine()
+	if f.From != nil {
+		period = period.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		period = period.Where("created_at < ?", *f.To)
+	}
+	inPeriod, err := ledgerSums(period)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	opening := map[string]int64{}
+	for k, v := range closing {
+		opening[k] = v - inPeriod[k]
+	}
+
+	// trang hiện tại
+	q := mine()
+	if currency != "" {
+		q = q.Where("currency = ?", currency)
+	}
+	if f.Cursor > 0 {
+		q = q.Where("id < ?", f.Cursor)
+	}
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at < ?", *f.To)
+	}
+	var entries []LedgerEntry
+	if err := q.Order("id DESC").Limit(f.Limit + 1).Find(&entries).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	var next *uint
+	if len(entries) > f.Limit {
+		entries = entries[:f.Limit]
+		id := entries[f.Limit-1].ID
+		next = &id
+	}
+
+	// số dư sau dòng mới nhất của trang = hiện tại - mọi biến động mới hơn nó, rồi trừ lùi từng dòng
+	rows := make([]StatementRow, 0, len(entries))
+	if len(entries) > 0 {
+		newer, err := ledgerSums(mine().Where("id > ?", entries[0].ID))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		run := map[string]int64{}
+		for k, v := range current {
+			run[k] = v - newer[k]
+		}
+		for _, e := range entries {
+			rows = append(rows, StatementRow{
+				ID: e.ID, Currency: e.Currency, Type: e.Type, RefID: e.RefID,
+				Amount: e.Amount, Balance: run[e.Currency], CreatedAt: e.CreatedAt,
+			})
+			run[e.Currency] -= e.Amount
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"rows":       rows,
+		"nextCursor": next,
+		"opening":    statementBalances(opening),
+		"closing":    statementBalances(closing),
+	})
+}
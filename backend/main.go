@@ -32,6 +32,9 @@ const (
 	CORS_ORIGIN = "http://localhost:5173"
 	UPLOAD_DIR  = "uploads"
 	KYC_DIR     = "kyc_files"
+	EXPORT_DIR  = "data_exports" // file xuất dữ liệu cá nhân (KHÔNG public, tải qua link có token)
+	// "local": event realtime trong 1 tiến trình; "db": qua bảng stream_events (chạy nhiều instance)
+	EVENT_BROKER = "local"
 )
 
 /* ===== DB & MODELS ===== */
@@ -74,6 +77,10 @@ type AdminUserDetail struct {
 	KYCDob      string `json:"kycDob"`
 	HasKYCFront bool   `json:"hasKycFront"`
 	HasKYCBack  bool   `json:"hasKycBack"`
+
+	Status       string     `json:"status"`
+	StatusReason string     `json:"statusReason,omitempty"`
+	ClosedAt     *time.Time `json:"closedAt,omitempty"`
 }
 type DownlineRow struct {
 	ID        uint   `json:"id"`
@@ -96,7 +103,7 @@ type User struct {
 	AvatarURL    string `json:"avatarUrl"`
 	PasswordHash string `json:"-"`
 
-	Role                 string `gorm:"type:enum('admin','user');default:'user';index" json:"role"`
+	Role                 string `gorm:"type:enum('admin','user','system');default:'user';index" json:"role"`
 	Coins                int64  `gorm:"not null;default:0" json:"coins"`
 	TotalTopup           int64  `gorm:"not null;default:0" json:"totalTopup"`
 	VIPLevel             int    `gorm:"column:v_ip_level;not null;default:0" json:"vipLevel"`
@@ -123,6 +130,15 @@ type User struct {
 	// 🔹 Free spins (lượt quay miễn phí)
 	FreeSpins      int `gorm:"not null;default:0" json:"freeSpins"`
 	ChestOpenCount int `gorm:"not null;default:0"`
+
+	// trạng thái tài khoản (ACTIVE | FROZEN | BANNED | CLOSED); FROZEN chỉ còn xem, BANNED không dùng được tới StatusUntil,
+	// CLOSED đã ẩn danh hoá — xem restrictions.go
+	Status       string     `gorm:"size:10;not null;default:'ACTIVE'" json:"status"`
+	StatusReason string     `gorm:"size:255" json:"statusReason,omitempty"`
+	StatusUntil  *time.Time `json:"statusUntil,omitempty"` // FROZEN / BANNED tự hết hạn; nil = tới khi gỡ
+	ClosedAt     *time.Time `json:"closedAt,omitempty"`
+	// được xoá cứng tài khoản đã đóng (DELETE /admin/users/:id)
+	IsSuperAdmin bool `gorm:"not null;default:false" json:"-"`
 }
 type LeaderboardRow struct {
 	Rank     int    `json:"rank"`
@@ -150,11 +166,6 @@ type UpdateSecurityRequest struct {
 	NewTxnPin         string `json:"newTxnPin"`         // 6 số
 }
 
-type KycSubmitRequest struct {
-	FrontURL string `json:"frontUrl" binding:"required"` // đường dẫn tạm thời (trong /uploads)
-	BackURL  string `json:"backUrl"  binding:"required"`
-}
-
 // Nhật ký mua VIP
 type VipPurchaseTxn struct {
 	ID        uint      `gorm:"primaryKey"`
@@ -190,9 +201,6 @@ type ReferralReward struct {
 	Invitee User `gorm:"foreignKey:InviteeID"`
 }
 
-type BuyVipRequest struct {
-	Level int `json:"level" binding:"required,gt=0"`
-}
 type RedeemReq struct {
 	Code string `json:"code" binding:"required"`
 }
@@ -212,16 +220,13 @@ type TransferTxn struct {
 }
 
 type MarketRow struct {
-	ID           uint   `json:"id"`
-	Code         string `json:"code"`
-	Qty          int64  `json:"qty"`
-	PricePerUnit int64  `json:"pricePerUnit"`
-	SellerID     uint   `json:"sellerId"`
-	SellerUser   string `json:"sellerUsername"`
-}
-type UpdateKycRequest struct {
-	FrontPath string `json:"frontPath" binding:"required"` // có thể là URL "/uploads/..." -> sẽ lấy filename
-	BackPath  string `json:"backPath"  binding:"required"`
+	ID             uint       `json:"id"`
+	Code           string     `json:"code"`
+	Qty            int64      `json:"qty"`
+	PricePerUnit   int64      `json:"pricePerUnit"`
+	SellerID       uint       `json:"sellerId"`
+	SellerUsername string     `json:"sellerUsername"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
 }
 
 type TransferRow struct {
@@ -254,11 +259,11 @@ type TransferRequest struct {
 }
 
 type WithdrawRow struct {
-	ID         uint      `json:"id"`
-	Amount     int64     `json:"amount"`
-	Note       string    `json:"note"`
-	AdminEmail string    `json:"adminEmail"`
-	CreatedAt  time.Time `json:"createdAt"`
+	ID            uint      `json:"id"`
+	Amount        int64     `json:"amount"`
+	Note          string    `json:"note"`
+	AdminUsername string    `json:"adminUsername"`
+	CreatedAt     time.Time `json:"createdAt"`
 }
 type AdminUserRow struct {
 	ID         uint   `json:"id"`
@@ -297,14 +302,18 @@ type VipBuyRow struct {
 }
 
 type Notification struct {
-	ID        uint      `gorm:"primaryKey"             json:"id"`
-	UserID    uint      `gorm:"index;not null"         json:"userId"`
-	Title     string    `gorm:"size:120;not null"      json:"title"`
-	Body      string    `gorm:"size:500;not null"      json:"body"`
-	IsRead    bool      `gorm:"not null;default:false" json:"isRead"`
-	CreatedAt time.Time `json:"createdAt"`
-}
-
+	ID         uint       `gorm:"primaryKey"             json:"id"`
+	UserID     uint       `gorm:"index;not null"         json:"userId"`
+	Type       string     `gorm:"size:40"                json:"type"`
+	Category   string     `gorm:"size:16;not null;default:'system';index" json:"category"`
+	Title      string     `gorm:"size:120;not null"      json:"title"`
+	Body       string     `gorm:"size:500;not null"      json:"body"`
+	IsRead     bool       `gorm:"not null;default:false" json:"isRead"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// (cũ) gift code lượt quay — chỉ còn đọc để chuyển sang PromoCampaign (promos.go)
 type PromoCode struct {
 	ID             uint       `gorm:"primaryKey"`
 	Code           string     `gorm:"size:32;uniqueIndex;not null"`
@@ -349,13 +358,10 @@ type CoinTxn struct {
 }
 
 /* ===== DTO ===== */
-// Đăng ký: username, password, nickname(name), phone, ref (mã mời) — KHÔNG email
+// Đăng ký: username, password, ref (mã mời) — KHÔNG email; biệt danh/SĐT cập nhật sau ở profile
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6,max=100"`
-	Nickname string `json:"nickname"` // FE mới
-	Name     string `json:"name"`     // FE cũ (fallback)
-	Phone    string `json:"phone" binding:"required,min=8,max=20"`
+	Username string `json:"username"`
+	Password string `json:"password"`
 	Ref      string `json:"ref"` // mã mời (tuỳ chọn)
 }
 
@@ -397,6 +403,8 @@ type InventoryItem struct {
 	CreatedAt time.Time `json:"-"`
 	UpdatedAt time.Time `json:"-"`
 }
+
+// (cũ) code bonus coin — chỉ còn đọc để chuyển sang PromoCampaign (promos.go)
 type PromoBonusCode struct {
 	ID         uint       `gorm:"primaryKey"`
 	Code       string     `gorm:"size:32;uniqueIndex;not null"`
@@ -442,12 +450,13 @@ type DownlineDashboardResp struct {
 
 // bài đăng trên chợ
 type MarketListing struct {
-	ID           uint   `gorm:"primaryKey"`
-	SellerID     uint   `gorm:"index;not null"`
-	Code         string `gorm:"size:10;not null"` // DB1..DB7
-	Qty          int64  `gorm:"not null"`
-	PricePerUnit int64  `gorm:"not null"` // coin / 1 viên
-	IsActive     bool   `gorm:"not null;default:true"`
+	ID           uint       `gorm:"primaryKey"`
+	SellerID     uint       `gorm:"index;not null"`
+	Code         string     `gorm:"size:10;not null"` // DB1..DB7
+	Qty          int64      `gorm:"not null"`
+	PricePerUnit int64      `gorm:"not null"` // coin / 1 viên
+	IsActive     bool       `gorm:"not null;default:true"`
+	ExpiresAt    *time.Time `gorm:"index"` // NULL => không hết hạn
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 
@@ -507,11 +516,32 @@ func connectDB() {
 		&InventoryItem{}, &ChestTxn{}, &MarketListing{},
 		&Notification{},
 		&PromoCode{}, &PromoCodeUse{}, &PromoBonusCode{},
+		&ShopItem{}, &ShopPurchase{}, &VipVoucher{},
+		&MarketBid{}, &MarketTrade{}, &AppSetting{},
+		&StreamEvent{}, &StreamTicket{}, &Broadcast{},
+		&NotificationTemplate{}, &NotificationPref{},
+		&LedgerEntry{},
+		&Season{}, &SeasonPrize{}, &SeasonStanding{},
+		&CheckinDay{}, &CheckinState{}, &CheckinLog{},
+		&Mission{}, &MissionProgress{},
+		&PromoCampaign{}, &PromoCampaignCode{}, &PromoRedemption{},
+		&Job{}, &JobRun{},
+		&StatsDaily{}, &UserActiveDay{},
+		&BalanceCheckpoint{}, &BalanceDrift{},
+		&AccountClosure{}, &AccountCloseRequest{}, &DataExport{}, &UserRestriction{},
+		&TransferTier{},
 	); err != nil {
 		log.Fatal("❌ AutoMigrate error:", err)
 	}
 	collapseInventoryDuplicates()
 	seedVipTiers()
+	seedTransferTiers()
+	ensureSystemAccount()
+	ensureSuperAdmin()
+	seedCheckinCalendar()
+	if err := migrateLegacyPromoCodes(); err != nil {
+		log.Fatal("❌ migrate legacy promo codes error:", err)
+	}
 	fmt.Println("✅ DB migrated")
 }
 
@@ -589,7 +619,7 @@ func getUplines(tx *gorm.DB, start *uint, maxDepth int) ([]User, error) {
 		}
 		seen[*cur] = true
 		var u User
-		if err := tx.Select("id, username, coins, v_ip_level, referred_by").
+		if err := tx.Select("id, username, coins, v_ip_level, referred_by, status").
 			First(&u, *cur).Error; err != nil {
 			break
 		}
@@ -665,7 +695,7 @@ func overviewStatsHandler(c *gin.Context) {
 
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
@@ -683,7 +713,7 @@ func overviewStatsHandler(c *gin.Context) {
 	// b) KPI tổng phát sinh trong cây của bạn (bao gồm ADMIN)
 	f1IDs, allIDs, err := downlineIDsByDepth(DB, uid, 9)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Không lấy được thống kê"})
+		respondError(c, err)
 		return
 	}
 	var f1CommissionGross, systemCommissionGross int64
@@ -714,210 +744,86 @@ func overviewStatsHandler(c *gin.Context) {
 		"systemCommissionGross": systemCommissionGross,
 	})
 }
-func publicLeaderboardHandler(c *gin.Context) {
-	kind := strings.ToLower(c.DefaultQuery("kind", "f1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
-	if limit <= 0 || limit > 1000 {
-		limit = 100
-	}
-
-	// đổi TÊN CỘT ở đây nếu schema khác:
-	benefCol := "ct.beneficiary_id" // ví dụ: "ct.beneficiary_id" nếu bạn đặt khác
-	amountCol := "ct.amount"
-	depthCol := "ct.depth"
 
-	depthCond := fmt.Sprintf("%s = 1", depthCol)
-	if kind != "f1" {
-		depthCond = fmt.Sprintf("%s BETWEEN 1 AND 9", depthCol)
-	}
-
-	type aggRow struct {
-		UserID   uint
-		Username string
-		Score    int64
-	}
-	var agg []aggRow
-
-	// Tổng hoa hồng theo user
-	err := DB.Table("commission_txns AS ct").
-		Select(fmt.Sprintf("%s AS user_id, u.username, SUM(%s) AS score", benefCol, amountCol)).
-		Joins("JOIN users u ON u.id = "+benefCol).
-		Where("u.role = ?", "user").
-		Where(depthCond).
-		Group("user_id, u.username").
-		Order("score DESC").
-		Limit(limit).
-		Scan(&agg).Error
-
-	if err != nil {
-		// LOG lỗi thật để biết vì sao 500 (sai cột/bảng, v.v.)
-		log.Println("leaderboard query error:", err)
-		// Tránh vỡ FE: trả rỗng
-		c.JSON(200, gin.H{"rows": []LeaderboardRow{}})
-		return
-	}
-
-	rows := make([]LeaderboardRow, 0, len(agg))
-	for i, r := range agg {
-		rows = append(rows, LeaderboardRow{
-			Rank: i + 1, Username: r.Username, Score: r.Score,
-		})
-	}
-	c.JSON(200, gin.H{"rows": rows})
+/* ===== MIDDLEWARE ===== */
+// POST không đổi dữ liệu: tài khoản FROZEN (chỉ xem) vẫn gọi được
+var frozenAllowedPosts = map[string]bool{
+	"/private/events/ticket": true, // vé mở luồng SSE
 }
-func privateMyLeaderboardHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	kind := strings.ToLower(c.DefaultQuery("kind", "f1"))
-
-	// lấy username (không lỗi cũng tiếp tục)
-	var uname string
-	_ = DB.Table("users").Select("username").Where("id = ?", uid).Scan(&uname).Error
-
-	// ĐỔI tên cột nếu schema bạn khác
-	benefCol := "ct.beneficiary_id"
-	amountCol := "ct.amount"
-	depthCol := "ct.depth"
-
-	depthCond := fmt.Sprintf("%s = 1", depthCol) // F1
-	if kind != "f1" {
-		depthCond = fmt.Sprintf("%s BETWEEN 1 AND 9", depthCol) // Hệ thống
-	}
-
-	// Tổng điểm của chính mình (không phụ thuộc role, vì người dùng hiện tại là user)
-	var my struct{ Score int64 }
-	_ = DB.Table("commission_txns AS ct").
-		Where(benefCol+" = ?", uid).
-		Where(depthCond).
-		Select("COALESCE(SUM(" + amountCol + "),0) AS score").
-		Scan(&my).Error
-
-	// Subquery tổng điểm MỖI USER THƯỜNG (exclude admin)
-	sub := DB.Table("commission_txns AS ct").
-		Joins("JOIN users u ON u.id = "+benefCol).
-		Where("u.role = ?", "user").
-		Where(depthCond).
-		Select(benefCol + " AS user_id, SUM(" + amountCol + ") AS score").
-		Group("user_id")
-
-	// Rank = 1 + số người (user thường) có điểm > mình
-	var higher int64
-	if err := DB.Table("(?) AS t", sub).
-		Where("t.score > ?", my.Score).
-		Count(&higher).Error; err != nil {
-		log.Println("my leaderboard rank error:", err)
-		c.JSON(200, MyRankResp{Rank: 0, Username: uname, Score: my.Score})
-		return
-	}
-
-	c.JSON(200, MyRankResp{
-		Rank:     int(higher) + 1, // FE hiển thị # nếu > 100
-		Username: uname,
-		Score:    my.Score,
-	})
+
+func isWriteRequest(c *gin.Context) bool {
+	return c.Request.Method != http.MethodGet && !frozenAllowedPosts[c.FullPath()]
 }
 
-/* ===== MIDDLEWARE ===== */
 func authRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
+		// EventSource (SSE) không gửi được header -> vé dùng 1 lần ?ticket= (không nhận JWT trên URL)
+		if h == "" && c.FullPath() == "/private/events" && c.Query("ticket") != "" {
+			uid, ok := redeemStreamTicket(c.Query("ticket"))
+			if !ok {
+				respondError(c, apiError(ERR_TOKEN_INVALID))
+				return
+			}
+			st, reason, until, err := accountStatus(uid)
+			if err != nil {
+				respondError(c, err)
+				return
+			}
+			if err := accountStatusError(st, reason, until, isWriteRequest(c)); err != nil {
+				respondError(c, err)
+				return
+			}
+			c.Set("claims", jwt.MapClaims{"sub": float64(uid)})
+			c.Next()
+			return
+		}
 		if !strings.HasPrefix(h, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
+			respondError(c, apiError(ERR_UNAUTHORIZED))
 			return
 		}
 		tokenStr := h[7:]
 		t, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) { return []byte(JWT_SECRET), nil })
 		if err != nil || !t.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token không hợp lệ"})
+			respondError(c, apiError(ERR_TOKEN_INVALID))
 			return
 		}
 		if claims, ok := t.Claims.(jwt.MapClaims); ok {
 			c.Set("claims", claims)
+			if sub, ok := claims["sub"].(float64); ok {
+				markUserActive(uint(sub))
+				// đã đóng / bị cấm: token cũ hết hiệu lực; bị khoá: chỉ còn xem
+				st, reason, until, err := accountStatus(uint(sub))
+				if err != nil {
+					respondError(c, err)
+					return
+				}
+				if err := accountStatusError(st, reason, until, isWriteRequest(c)); err != nil {
+					respondError(c, err)
+					return
+				}
+			}
 		}
 		c.Next()
 	}
 }
 
-func redeemBonusCodeHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-
-	var body struct {
-		Code string `json:"code"`
-	}
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(400, gin.H{"error": "Thiếu mã code"})
-		return
-	}
-	code := strings.ToUpper(strings.TrimSpace(body.Code)) // 👈 quan trọng
-
-	var p PromoBonusCode
-	if err := DB.Where("code = ? AND is_active = ?", code, true).First(&p).Error; err != nil {
-		c.JSON(400, gin.H{"error": "Code không tồn tại hoặc đã bị vô hiệu"})
-		return
-	}
-	if p.ExpiresAt != nil && time.Now().UTC().After(*p.ExpiresAt) {
-		c.JSON(400, gin.H{"error": "Code đã hết hạn"})
-		return
-	}
-	if p.UsedCount >= p.MaxUses {
-		c.JSON(400, gin.H{"error": "Code đã được sử dụng"})
-		return
-	}
-
-	err := DB.Transaction(func(tx *gorm.DB) error {
-		// tăng used_count (optimistic)
-		res := tx.Model(&PromoBonusCode{}).
-			Where("id = ? AND used_count = ?", p.ID, p.UsedCount).
-			UpdateColumn("used_count", gorm.Expr("used_count + 1"))
-		if res.Error != nil {
-			return res.Error
-		}
-		if res.RowsAffected == 0 {
-			return errors.New("conflict")
-		}
-
-		// cộng bonus_coins
-		return tx.Model(&User{}).
-			Where("id = ?", uid).
-			UpdateColumn("bonus_coins", gorm.Expr("COALESCE(bonus_coins,0)+?", p.BonusCoins)).Error
-	})
-	if err != nil {
-		c.JSON(400, gin.H{"error": "Không thể sử dụng code"})
-		return
-	}
-
-	c.JSON(200, gin.H{
-		"message":    "Nhận coin bonus thành công",
-		"bonusCoins": p.BonusCoins,
-	})
-}
-
 func adminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		val, ok := c.Get("claims")
 		if !ok {
-			c.AbortWithStatusJSON(401, gin.H{"error": "Unauthorized"})
+			respondError(c, apiError(ERR_UNAUTHORIZED))
 			return
 		}
 		role, _ := val.(jwt.MapClaims)["role"].(string)
 		if role != "admin" {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden: admin only"})
+			respondError(c, apiError(ERR_ADMIN_ONLY))
 			return
 		}
 		c.Next()
 	}
 }
 
-/* ===== HISTORIES ===== */
-func topupHistoryHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var rows []TopupRow
-	DB.Table("coin_txns t").
-		Select("t.id, t.amount, t.note, t.created_at, a.username AS admin_email").
-		Joins("LEFT JOIN users a ON a.id = t.admin_id").
-		Where("t.user_id = ?", uid).
-		Order("t.id DESC").Scan(&rows)
-	c.JSON(200, gin.H{"rows": rows})
-}
 func isInSubtree(ownerID, targetID uint) (bool, error) {
 	if ownerID == targetID {
 		return true, nil
@@ -950,11 +856,11 @@ func downlineDashboardHandler(c *gin.Context) {
 	// bắt buộc nằm trong F1..F9 của owner
 	ok, err := isInSubtree(ownerID, targetID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Lỗi kiểm tra quyền xem"})
+		respondError(c, err)
 		return
 	}
 	if !ok {
-		c.JSON(403, gin.H{"error": "Không có quyền xem người này"})
+		respondError(c, apiError(ERR_FORBIDDEN))
 		return
 	}
 
@@ -972,7 +878,7 @@ func downlineDashboardHandler(c *gin.Context) {
 	var u User
 	if err := DB.Select("id, username, coins, bonus_coins, v_ip_level").
 		First(&u, targetID).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
@@ -1039,67 +945,29 @@ func downlineDashboardHandler(c *gin.Context) {
 	c.JSON(200, resp)
 }
 
-func vipHistoryHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var rows []VipBuyRow
-	DB.Model(&VipPurchaseTxn{}).
-		Where("user_id = ?", uid).
-		Order("id DESC").Scan(&rows)
-	c.JSON(200, gin.H{"rows": rows})
-}
-
-func transferHistoryHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var rows []TransferRow
-	DB.Table("transfer_txns t").
-		Select(`
-			t.id,
-			IF(t.from_id = ?, 'out', 'in') AS direction,
-			t.amount, t.fee, t.created_at,
-			 COALESCE(t.note, '') AS note,  
-			CASE WHEN t.from_id = ? THEN to_u.username ELSE from_u.username END AS counterpart`,
-			uid, uid).
-		Joins("LEFT JOIN users from_u ON from_u.id = t.from_id").
-		Joins("LEFT JOIN users to_u   ON to_u.id   = t.to_id").
-		Where("t.from_id = ? OR t.to_id = ?", uid, uid).
-		Order("t.id DESC").Scan(&rows)
-	c.JSON(200, gin.H{"rows": rows})
-}
-
-func myCommissionHistoryHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	rows := []CommissionRow{}
-	DB.Table("commission_txns ct").
-		Select(`ct.id, b.username AS buyer_username, ct.depth, ct.percent, ct.amount, ct.kind, ct.vip_level_bought AS vip_level, ct.created_at`).
-		Joins("LEFT JOIN users b ON b.id = ct.buyer_id").
-		Where("ct.beneficiary_id = ?", uid).
-		Order("ct.id DESC").
-		Scan(&rows)
-	c.JSON(200, gin.H{"rows": rows})
-}
 func changePasswordHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)); err != nil {
-		c.JSON(400, gin.H{"error": "Mật khẩu hiện tại không đúng"})
+		respondError(c, apiError(ERR_PASSWORD_INCORRECT))
 		return
 	}
 
 	newHash, _ := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err := DB.Model(&user).Update("password_hash", string(newHash)).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Không thể cập nhật mật khẩu"})
+		respondError(c, err)
 		return
 	}
 
@@ -1110,26 +978,26 @@ func updateSecurityHandler(c *gin.Context) {
 
 	var req UpdateSecurityRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 
 	var u User
 	if err := DB.First(&u, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
 	// Đổi/đặt mật khẩu cấp 2
 	if strings.TrimSpace(req.NewSecondPassword) != "" {
 		if len(req.NewSecondPassword) < 6 {
-			c.JSON(400, gin.H{"error": "Mật khẩu cấp 2 tối thiểu 6 ký tự"})
+			respondError(c, apiError(ERR_SECOND_PASSWORD_TOO_SHORT))
 			return
 		}
 		// Nếu đã có mật khẩu cấp 2 trước đó thì yêu cầu nhập cũ để xác nhận
 		if u.SecondPasswordHash != "" {
 			if err := bcrypt.CompareHashAndPassword([]byte(u.SecondPasswordHash), []byte(req.OldSecondPassword)); err != nil {
-				c.JSON(400, gin.H{"error": "Mật khẩu cấp 2 cũ không đúng"})
+				respondError(c, apiError(ERR_SECOND_PASSWORD_INVALID))
 				return
 			}
 		}
@@ -1141,7 +1009,7 @@ func updateSecurityHandler(c *gin.Context) {
 	if strings.TrimSpace(req.NewTxnPin) != "" {
 		pin := req.NewTxnPin
 		if len(pin) != 6 || strings.Trim(pin, "0123456789") != "" {
-			c.JSON(400, gin.H{"error": "Mã bảo mật (PIN) phải gồm đúng 6 chữ số"})
+			respondError(c, apiError(ERR_PIN_FORMAT))
 			return
 		}
 		hash, _ := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
@@ -1149,7 +1017,7 @@ func updateSecurityHandler(c *gin.Context) {
 	}
 
 	if err := DB.Save(&u).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Cập nhật bảo mật thất bại"})
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "Cập nhật bảo mật thành công"})
@@ -1158,7 +1026,7 @@ func updateSecurityHandler(c *gin.Context) {
 func forgotPasswordHandler(c *gin.Context) {
 	var req ForgotPasswordReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 
@@ -1166,25 +1034,25 @@ func forgotPasswordHandler(c *gin.Context) {
 
 	var u User
 	if err := DB.Where("LOWER(username) = ?", uname).First(&u).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Tài khoản không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
 	if u.SecondPasswordHash == "" {
-		c.JSON(400, gin.H{"error": "Bạn chưa thiết lập mật khẩu cấp 2. Vui lòng liên hệ hỗ trợ"})
+		respondError(c, apiError(ERR_SECOND_PASSWORD_NOT_SET))
 		return
 	}
 
 	// xác minh mật khẩu cấp 2
 	if err := bcrypt.CompareHashAndPassword([]byte(u.SecondPasswordHash), []byte(req.SecPassword)); err != nil {
-		c.JSON(400, gin.H{"error": "Mật khẩu cấp 2 không đúng"})
+		respondError(c, apiError(ERR_SECOND_PASSWORD_INVALID))
 		return
 	}
 
 	// cập nhật mật khẩu đăng nhập
 	hash, _ := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err := DB.Model(&u).Update("password_hash", string(hash)).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Không thể cập nhật mật khẩu"})
+		respondError(c, err)
 		return
 	}
 
@@ -1194,12 +1062,12 @@ func adminUserDetailHandler(c *gin.Context) {
 	uidStr := c.Param("id")
 	uid64, err := strconv.ParseUint(uidStr, 10, 64)
 	if err != nil || uid64 == 0 {
-		c.JSON(400, gin.H{"error": "ID không hợp lệ"})
+		respondError(c, apiError(ERR_INVALID_ID))
 		return
 	}
 	var u User
 	if err := DB.First(&u, uint(uid64)).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 	out := AdminUserDetail{
@@ -1207,6 +1075,7 @@ func adminUserDetailHandler(c *gin.Context) {
 		Role: u.Role, Coins: u.Coins, TotalTopup: u.TotalTopup, VIPLevel: u.VIPLevel,
 		KYCStatus: u.KYCStatus, KYCFullName: u.KYCFullName, KYCNumber: u.KYCNumber, KYCDob: u.KYCDob,
 		HasKYCFront: u.KYCFrontPath != "", HasKYCBack: u.KYCBackPath != "",
+		Status: u.Status, StatusReason: u.StatusReason, ClosedAt: u.ClosedAt,
 	}
 	c.JSON(200, gin.H{"user": out})
 }
@@ -1238,7 +1107,7 @@ func kycSubmitHandler(c *gin.Context) {
 
 	// Parse form
 	if err := c.Request.ParseMultipartForm(16 << 20); err != nil {
-		c.JSON(400, gin.H{"error": "Dữ liệu không hợp lệ"})
+		respondError(c, apiError(ERR_INVALID_INPUT))
 		return
 	}
 	fullName := strings.TrimSpace(c.PostForm("fullName"))
@@ -1246,7 +1115,7 @@ func kycSubmitHandler(c *gin.Context) {
 	number := strings.TrimSpace(c.PostForm("number")) // Số CCCD
 
 	if fullName == "" || dob == "" || number == "" {
-		c.JSON(400, gin.H{"error": "Vui lòng nhập Họ và tên, Ngày sinh và Số CCCD"})
+		respondError(c, apiError(ERR_KYC_FIELDS_REQUIRED))
 		return
 	}
 
@@ -1254,7 +1123,7 @@ func kycSubmitHandler(c *gin.Context) {
 	front, errF := c.FormFile("front")
 	back, errB := c.FormFile("back")
 	if errF != nil || errB != nil {
-		c.JSON(400, gin.H{"error": "Thiếu ảnh mặt trước / mặt sau CCCD"})
+		respondError(c, apiError(ERR_KYC_FILES_REQUIRED))
 		return
 	}
 
@@ -1267,17 +1136,17 @@ func kycSubmitHandler(c *gin.Context) {
 	pBack := filepath.Join(kycAbs, fBack)
 
 	if err := c.SaveUploadedFile(front, pFront); err != nil {
-		c.JSON(500, gin.H{"error": "Lưu ảnh CCCD (mặt trước) thất bại"})
+		respondError(c, err)
 		return
 	}
 	if err := c.SaveUploadedFile(back, pBack); err != nil {
 		_ = os.Remove(pFront)
-		c.JSON(500, gin.H{"error": "Lưu ảnh CCCD (mặt sau) thất bại"})
+		respondError(c, err)
 		return
 	}
 
 	// Cập nhật user -> auto VERIFIED
-	if err := DB.Transaction(func(tx *gorm.DB) error {
+	if err := withEvents(func(tx *gorm.DB) error {
 		var u User
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, uid).Error; err != nil {
 			return err
@@ -1291,9 +1160,17 @@ func kycSubmitHandler(c *gin.Context) {
 			"kyc_back_path":  fBack,
 		}
 
+		// chỉ tính nhiệm vụ ở lần xác minh đầu tiên
+		if u.KYCStatus != "VERIFIED" {
+			if err := domainEvent(tx, uid, DE_KYC_VERIFIED, 1); err != nil {
+				return err
+			}
+		}
+
+		emitEvent(tx, uid, EV_KYC_DECIDED, gin.H{"status": "VERIFIED"})
 		return tx.Model(&u).Updates(updates).Error
 	}); err != nil {
-		c.JSON(500, gin.H{"error": "Cập nhật KYC thất bại"})
+		respondError(c, err)
 		return
 	}
 
@@ -1303,12 +1180,12 @@ func adminServeKyc(c *gin.Context, side string) {
 	uidStr := c.Param("uid")
 	uid64, err := strconv.ParseUint(uidStr, 10, 64)
 	if err != nil || uid64 == 0 {
-		c.JSON(400, gin.H{"error": "ID không hợp lệ"})
+		respondError(c, apiError(ERR_INVALID_ID))
 		return
 	}
 	var u User
 	if err := DB.First(&u, uint(uid64)).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 	var file string
@@ -1318,7 +1195,7 @@ func adminServeKyc(c *gin.Context, side string) {
 		file = u.KYCBackPath
 	}
 	if file == "" {
-		c.JSON(404, gin.H{"error": "Chưa có ảnh " + side})
+		respondError(c, apiError(ERR_KYC_IMAGE_NOT_FOUND, "side", side))
 		return
 	}
 	p := filepath.Join(kycAbs, file)
@@ -1333,7 +1210,7 @@ func adminServeKycFront(c *gin.Context) {
 	uid := uint(uid64)
 	var u User
 	if err := DB.Select("kyc_front_path").First(&u, uid).Error; err != nil || u.KYCFrontPath == "" {
-		c.JSON(404, gin.H{"error": "Không có ảnh KYC front"})
+		respondError(c, apiError(ERR_KYC_IMAGE_NOT_FOUND, "side", "front"))
 		return
 	}
 	path := filepath.Join(kycAbs, u.KYCFrontPath)
@@ -1345,7 +1222,7 @@ func adminServeKycBack(c *gin.Context) {
 	uid := uint(uid64)
 	var u User
 	if err := DB.Select("kyc_back_path").First(&u, uid).Error; err != nil || u.KYCBackPath == "" {
-		c.JSON(404, gin.H{"error": "Không có ảnh KYC back"})
+		respondError(c, apiError(ERR_KYC_IMAGE_NOT_FOUND, "side", "back"))
 		return
 	}
 	path := filepath.Join(kycAbs, u.KYCBackPath)
@@ -1379,23 +1256,23 @@ func collapseInventoryDuplicates() {
 
 /* ===== AUTH & PROFILE ===== */
 func registerHandler(c *gin.Context) {
-	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Ref      string `json:"ref"`
-	}
+	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 
 	username := strings.ToLower(strings.TrimSpace(req.Username))
 	if username == "" {
-		c.JSON(400, gin.H{"error": "Thiếu username"})
+		respondError(c, apiError(ERR_USERNAME_REQUIRED))
+		return
+	}
+	if username == SYSTEM_USERNAME {
+		respondError(c, apiError(ERR_USERNAME_TAKEN))
 		return
 	}
 	if req.Password == "" {
-		c.JSON(400, gin.H{"error": "Thiếu mật khẩu"})
+		respondError(c, apiError(ERR_PASSWORD_REQUIRED))
 		return
 	}
 
@@ -1403,7 +1280,7 @@ func registerHandler(c *gin.Context) {
 	var exists int64
 	_ = DB.Model(&User{}).Where("username = ?", username).Count(&exists)
 	if exists > 0 {
-		c.JSON(409, gin.H{"error": "Username đã tồn tại"})
+		respondError(c, apiError(ERR_USERNAME_TAKEN))
 		return
 	}
 
@@ -1454,7 +1331,7 @@ func registerHandler(c *gin.Context) {
 
 		return nil
 	}); err != nil {
-		c.JSON(500, gin.H{"error": "Không thể tạo người dùng"})
+		respondError(c, err)
 		return
 	}
 
@@ -1478,18 +1355,21 @@ func invAdd(tx *gorm.DB, userID uint, code string, qty int64) error {
 }
 
 func invSub(tx *gorm.DB, userID uint, code string, qty int64) error {
+	if qty <= 0 {
+		return apiError(ERR_INVALID_INPUT, "field", "qty", "detail", "phải > 0")
+	}
 	// Khóa hàng tồn
 	var it InventoryItem
 	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("user_id = ? AND code = ?", userID, code).
 		First(&it).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("vật phẩm %s không đủ", code)
+			return apiError(ERR_INSUFFICIENT_ITEMS, "code", code, "have", 0)
 		}
 		return err
 	}
 	if it.Qty < qty {
-		return fmt.Errorf("vật phẩm %s không đủ", code)
+		return apiError(ERR_INSUFFICIENT_ITEMS, "code", code, "have", it.Qty)
 	}
 	// Trừ có điều kiện để tránh âm do race
 	return tx.Model(&InventoryItem{}).
@@ -1515,39 +1395,78 @@ func addToInventory(tx *gorm.DB, userID uint, code string, qty int64) error {
 }
 
 // trả về: result:"COIN"|"DRAGON_BALL", code, amount, coins, inv(map)
+type ChestOpenResult struct {
+	Result               string         `json:"result"` // "DRAGON_BALL" | "EVENT_CARD"
+	Code                 *string        `json:"code,omitempty"`
+	Amount               int64          `json:"amount"` // số lượng item nhận được
+	Coins                int64          `json:"coins"`
+	BonusCoins           int64          `json:"bonusCoins"`
+	FreeSpins            int            `json:"freeSpins"`
+	Inv                  map[string]int `json:"inv"`
+	UsedFreeSpin         bool           `json:"used_free_spin,omitempty"`
+	RemainingFreeSpins   int            `json:"remaining_free_spins,omitempty"`
+	MilestoneRewarded    bool           `json:"milestoneRewarded,omitempty"`
+	MilestoneRewardCoins int64          `json:"milestoneRewardCoins,omitempty"`
+	ChestOpens           int64          `json:"chest_opens"`
+	RemainingUntilBonus  int64          `json:"remaining_until_bonus"`
+}
+
+// bảng rơi của rương (trọng số /700): 10% DB1..DB7 chia đều, 90% thẻ sự kiện EV x1..5 chia đều.
+// /admin/stats so tỉ lệ rơi thực tế với bảng này.
+type chestDrop struct {
+	Kind   string // "DRAGON_BALL" | "EVENT_CARD"
+	Code   string
+	Amount int64
+	Weight int
+}
+
+var chestDropTable = func() []chestDrop {
+	var t []chestDrop
+	for i := 1; i <= 7; i++ {
+		t = append(t, chestDrop{"DRAGON_BALL", fmt.Sprintf("DB%d", i), 1, 10})
+	}
+	for n := int64(1); n <= 5; n++ {
+		t = append(t, chestDrop{"EVENT_CARD", "EV", n, 126})
+	}
+	return t
+}()
+
+func chestDropWeightTotal() int {
+	total := 0
+	for _, d := range chestDropTable {
+		total += d.Weight
+	}
+	return total
+}
+
+func rollChestDrop() chestDrop {
+	r := mathrand.Intn(chestDropWeightTotal())
+	for _, d := range chestDropTable {
+		if r < d.Weight {
+			return d
+		}
+		r -= d.Weight
+	}
+	return chestDropTable[len(chestDropTable)-1]
+}
+
 func chestOpenHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
-	type result struct {
-		Result               string         `json:"result"` // "DRAGON_BALL" | "EVENT_CARD"
-		Code                 *string        `json:"code,omitempty"`
-		Amount               int64          `json:"amount"` // số lượng item nhận được
-		Coins                int64          `json:"coins"`
-		BonusCoins           int64          `json:"bonusCoins"`
-		FreeSpins            int            `json:"freeSpins"`
-		Inv                  map[string]int `json:"inv"`
-		UsedFreeSpin         bool           `json:"used_free_spin,omitempty"`
-		RemainingFreeSpins   int            `json:"remaining_free_spins,omitempty"`
-		MilestoneRewarded    bool           `json:"milestoneRewarded,omitempty"`
-		MilestoneRewardCoins int64          `json:"milestoneRewardCoins,omitempty"`
-		ChestOpens           int64          `json:"chest_opens"`
-		RemainingUntilBonus  int64          `json:"remaining_until_bonus"`
-	}
-
-	var out result
+	var out ChestOpenResult
 	out.Inv = map[string]int{}
 
 	const spinCost int64 = 50        // phí mở nếu không có freeSpin
 	const milestoneEvery int64 = 100 // mốc thưởng theo lượt mở
 	const milestoneReward int64 = 1000
 
-	if err := DB.Transaction(func(tx *gorm.DB) error {
+	if err := withEvents(func(tx *gorm.DB) error {
 		// Khóa hàng user
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, uid).Error; err != nil {
 			return err
@@ -1555,10 +1474,10 @@ func chestOpenHandler(c *gin.Context) {
 
 		// ✅ CHẶT CHẼ: nếu không có freeSpins và tổng (bonus+coins) < spinCost → chặn luôn
 		if user.FreeSpins <= 0 && user.BonusCoins+user.Coins < spinCost {
-			return fmt.Errorf("Số dư không đủ")
+			return apiError(ERR_INSUFFICIENT_BALANCE)
 		}
 
-		// 1) Trừ freeSpins hoặc bonus/coins
+		// 1) Trừ freeSpins (phí bonus/coins trừ ở bước 3, sau khi có mã giao dịch)
 		usedFree := false
 		if user.FreeSpins > 0 {
 			if err := tx.Model(&User{}).
@@ -1568,29 +1487,12 @@ func chestOpenHandler(c *gin.Context) {
 			}
 			user.FreeSpins -= 1
 			usedFree = true
-		} else {
-			// dùng bonus trước, còn thiếu trừ coins
-			if err := spendForSystem(tx, &user, spinCost); err != nil {
-				return err
-			}
 		}
 		out.UsedFreeSpin = usedFree
 
-		// 2) Random phần thưởng: 10% DB1..DB7, 90% thẻ sự kiện EV (1..5)
-		rewardKind := "EVENT_CARD"
-		var rewardCode string
-		var rewardAmt int64
-
-		r := mathrand.Intn(100) // 0..99
-		if r < 10 {
-			rewardKind = "DRAGON_BALL"
-			rewardCode = fmt.Sprintf("DB%d", 1+mathrand.Intn(7))
-			rewardAmt = 1
-		} else {
-			rewardKind = "EVENT_CARD"
-			rewardCode = "EV"
-			rewardAmt = int64(1 + mathrand.Intn(5)) // 1..5
-		}
+		// 2) Random phần thưởng theo chestDropTable
+		drop := rollChestDrop()
+		rewardKind, rewardCode, rewardAmt := drop.Kind, drop.Code, drop.Amount
 
 		// Cộng item vào túi
 		if err := addToInventory(tx, user.ID, rewardCode, rewardAmt); err != nil {
@@ -1603,13 +1505,18 @@ func chestOpenHandler(c *gin.Context) {
 		if !usedFree {
 			cost = spinCost
 		}
-		if err := tx.Create(&ChestTxn{
+		chest := ChestTxn{
 			UserID:       user.ID,
 			Cost:         cost,
 			RewardKind:   rewardKind,
 			RewardCode:   rewardCode,
 			RewardAmount: rewardAmt,
-		}).Error; err != nil {
+		}
+		if err := tx.Create(&chest).Error; err != nil {
+			return err
+		}
+		// dùng bonus trước, còn thiếu trừ coins
+		if err := spendForSystem(tx, &user, cost, LEDGER_CHEST_OPEN, chest.ID); err != nil {
 			return err
 		}
 
@@ -1619,6 +1526,9 @@ func chestOpenHandler(c *gin.Context) {
 			Update("chest_open_count", gorm.Expr("chest_open_count + 1")).Error; err != nil {
 			return fmt.Errorf("increase chest_open_count: %w", err)
 		}
+		if err := domainEvent(tx, user.ID, DE_CHEST_OPEN, 1); err != nil {
+			return err
+		}
 
 		// 5) Đọc lại coins/bonus/freeSpins/count
 		if err := tx.Select("coins, bonus_coins, free_spins, chest_open_count").
@@ -1635,14 +1545,15 @@ func chestOpenHandler(c *gin.Context) {
 				Update("coins", gorm.Expr("coins + ?", milestoneReward)).Error; err != nil {
 				return fmt.Errorf("milestone add coins: %w", err)
 			}
+			if err := addLedger(tx, user.ID, CUR_COIN, LEDGER_CHEST_MILESTONE, chest.ID, milestoneReward); err != nil {
+				return err
+			}
 			out.MilestoneRewarded = true
 			out.MilestoneRewardCoins = milestoneReward
 
-			_ = tx.Create(&Notification{
-				UserID: user.ID,
-				Title:  "Chúc mừng đạt mốc mở rương!",
-				Body:   fmt.Sprintf("Bạn đạt %d lượt mở rương và nhận %d coin thưởng.", user.ChestOpenCount, milestoneReward),
-			}).Error
+			_ = notify(tx, user.ID, NT_CHEST_MILESTONE, map[string]any{
+				"count": user.ChestOpenCount, "reward": milestoneReward,
+			})
 
 			// reload coins
 			if err := tx.Select("coins").First(&user, user.ID).Error; err != nil {
@@ -1669,14 +1580,10 @@ func chestOpenHandler(c *gin.Context) {
 		out.ChestOpens = int64(user.ChestOpenCount)
 		out.RemainingUntilBonus = remaining
 
+		emitBalanceChanged(tx, user.ID, "chest")
 		return nil
 	}); err != nil {
-		msg := err.Error()
-		if strings.Contains(msg, "Số dư không đủ") {
-			c.JSON(400, gin.H{"error": msg})
-			return
-		}
-		c.JSON(500, gin.H{"error": "Mở rương thất bại"})
+		respondError(c, err)
 		return
 	}
 
@@ -1694,20 +1601,20 @@ func mergeDragonBallsHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
-	if err := DB.Transaction(func(tx *gorm.DB) error {
+	if err := withEvents(func(tx *gorm.DB) error {
 		// đủ DB1..DB7 mỗi loại >=1?
 		need := []string{"DB1", "DB2", "DB3", "DB4", "DB5", "DB6", "DB7"}
 		for _, code := range need {
 			var it InventoryItem
 			if err := tx.Where("user_id=? AND code=?", uid, code).First(&it).Error; err != nil {
-				return fmt.Errorf("Thiếu %s", code)
+				return apiError(ERR_DRAGON_BALL_MISSING, "code", code)
 			}
 			if it.Qty < 1 {
-				return fmt.Errorf("Thiếu %s", code)
+				return apiError(ERR_DRAGON_BALL_MISSING, "code", code)
 			}
 		}
 		// trừ mỗi loại 1
@@ -1717,9 +1624,13 @@ func mergeDragonBallsHandler(c *gin.Context) {
 			}
 		}
 		// cộng thưởng
-		return tx.Model(&user).Update("coins", gorm.Expr("coins + ?", MERGE_REWARD)).Error
+		emitBalanceChanged(tx, uid, "merge")
+		if err := tx.Model(&user).Update("coins", gorm.Expr("coins + ?", MERGE_REWARD)).Error; err != nil {
+			return err
+		}
+		return addLedger(tx, uid, CUR_COIN, LEDGER_MERGE_REWARD, 0, MERGE_REWARD)
 	}); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -1727,35 +1638,40 @@ func mergeDragonBallsHandler(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Hợp nhất thành công", "coins": user.Coins})
 }
 
+type MarketListRequest struct {
+	Code         string `json:"code"`
+	Qty          int64  `json:"qty" binding:"required,gt=0,lte=1000000"`
+	PricePerUnit int64  `json:"pricePerUnit" binding:"required,gt=0,lte=1000000000000"`
+	TTLHours     int64  `json:"ttlHours" binding:"gte=0"` // tuỳ chọn, tối đa market.listing_ttl_hours
+}
+
 // POST /private/market/list  { code:"DB1", qty:1, pricePerUnit:500 }
 func marketListHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 
-	// Request body
-	var req struct {
-		Code         string `json:"code"`
-		Qty          int64  `json:"qty"`
-		PricePerUnit int64  `json:"pricePerUnit"`
-	}
+	var req MarketListRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Dữ liệu không hợp lệ"})
+		respondError(c, invalidInput(err))
 		return
 	}
 	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
-	if req.Code == "" || req.Qty <= 0 || req.PricePerUnit <= 0 {
-		c.JSON(400, gin.H{"error": "Dữ liệu không hợp lệ"})
+	if req.Code == "" {
+		respondError(c, apiError(ERR_INVALID_INPUT))
+		return
+	}
+	if !mulFits(req.Qty, req.PricePerUnit) {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "pricePerUnit", "detail", "qty × pricePerUnit quá lớn"))
 		return
 	}
 
 	// ✅ Chỉ cho phép DB1..DB7 hoặc EV
-	isDB := strings.HasPrefix(req.Code, "DB") && len(req.Code) == 3 && req.Code[2] >= '1' && req.Code[2] <= '7'
-	isEV := req.Code == "EV"
-	if !(isDB || isEV) {
-		c.JSON(400, gin.H{"error": "Mã vật phẩm không hợp lệ (chỉ DB1..DB7 hoặc EV)"})
+	if !isTradableCode(req.Code) {
+		respondError(c, apiError(ERR_ITEM_CODE_INVALID))
 		return
 	}
 
-	if err := DB.Transaction(func(tx *gorm.DB) error {
+	var fills []MarketFill
+	if err := withEvents(func(tx *gorm.DB) error {
 		// 🔒 Khoá row inventory của user+code để chống race
 		var inv InventoryItem
 		if err := tx.
@@ -1763,13 +1679,13 @@ func marketListHandler(c *gin.Context) {
 			Where("user_id = ? AND code = ?", uid, req.Code).
 			First(&inv).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("vật phẩm %s không đủ (còn 0)", req.Code)
+				return apiError(ERR_INSUFFICIENT_ITEMS, "code", req.Code, "have", 0)
 			}
 			return err
 		}
 
 		if inv.Qty < req.Qty {
-			return fmt.Errorf("vật phẩm %s không đủ (còn %d)", req.Code, inv.Qty)
+			return apiError(ERR_INSUFFICIENT_ITEMS, "code", req.Code, "have", inv.Qty)
 		}
 
 		// ✅ Trừ tồn kho an toàn (điều kiện qty >= req.Qty ngay trong SQL)
@@ -1781,7 +1697,7 @@ func marketListHandler(c *gin.Context) {
 		}
 		if res.RowsAffected == 0 {
 			// Ai đó vừa trừ mất trong race khác
-			return fmt.Errorf("vật phẩm %s không đủ", req.Code)
+			return apiError(ERR_INSUFFICIENT_ITEMS, "code", req.Code)
 		}
 
 		// Tạo listing
@@ -1791,51 +1707,52 @@ func marketListHandler(c *gin.Context) {
 			Qty:          req.Qty,
 			PricePerUnit: req.PricePerUnit,
 			IsActive:     true,
+			ExpiresAt:    listingExpiry(req.TTLHours),
 		}
 		if err := tx.Create(&ml).Error; err != nil {
 			return err
 		}
-		return nil
+		if err := domainEvent(tx, uid, DE_MARKET_LIST, 1); err != nil {
+			return err
+		}
+
+		// Khớp ngay với các lệnh mua đang chờ
+		var err error
+		fills, err = matchListingAgainstBids(tx, &ml)
+		return err
 	}); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "Đã đăng bán"})
+	c.JSON(200, gin.H{"message": "Đã đăng bán", "fills": fills})
 }
 
 // GET /market?code=DB1
 // GET /market?code=DB1
 func marketQueryHandler(c *gin.Context) {
 	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
-	q := DB.Model(&MarketListing{}).Where("is_active = 1 AND qty > 0")
+	q := DB.Model(&MarketListing{}).Scopes(liveListings)
 	if code != "" {
-		q = q.Where("code = ?", code)
+		q = q.Where("market_listings.code = ?", code)
 	}
 
-	type Row struct {
-		ID           uint   `json:"id"`
-		Code         string `json:"code"`
-		Qty          int64  `json:"qty"`
-		PricePerUnit int64  `json:"pricePerUnit"`
-		SellerID     uint   `json:"sellerId"`
-		SellerEmail  string `json:"sellerEmail"` // alias username cho FE
-	}
-	var rows []Row
+	var rows []MarketRow
 	q.Order("price_per_unit asc, id asc").
 		Joins("LEFT JOIN users u ON u.id = market_listings.seller_id").
-		Select("market_listings.id, market_listings.code, market_listings.qty, market_listings.price_per_unit, u.id as seller_id, u.username as seller_email").
+		Select("market_listings.id, market_listings.code, market_listings.qty, market_listings.price_per_unit, u.id as seller_id, u.username as seller_username, market_listings.expires_at").
 		Scan(&rows)
 	c.JSON(200, gin.H{"rows": rows})
 }
 
-// Trừ amount cho các tác vụ hệ thống: ưu tiên BonusCoins rồi mới Coins
-func spendForSystem(tx *gorm.DB, u *User, amount int64) error {
+// Trừ amount cho các tác vụ hệ thống: ưu tiên BonusCoins rồi mới Coins.
+// Ghi sổ cái tách riêng phần bonus và phần coins với cùng typ/ref.
+func spendForSystem(tx *gorm.DB, u *User, amount int64, typ string, ref uint) error {
 	if amount <= 0 {
 		return nil
 	}
 	if u.BonusCoins+u.Coins < amount {
-		return fmt.Errorf("Số dư không đủ")
+		return apiError(ERR_INSUFFICIENT_BALANCE)
 	}
 	useBonus := u.BonusCoins
 	if useBonus > amount {
@@ -1851,7 +1768,10 @@ func spendForSystem(tx *gorm.DB, u *User, amount int64) error {
 	}
 	u.BonusCoins -= useBonus
 	u.Coins -= left
-	return nil
+	if err := addLedger(tx, u.ID, CUR_BONUS, typ, ref, -useBonus); err != nil {
+		return err
+	}
+	return addLedger(tx, u.ID, CUR_COIN, typ, ref, -left)
 }
 
 // gen 12 ký tự A-Za-z0-9
@@ -1864,26 +1784,6 @@ func genPromoCode(n int) string {
 	}
 	return string(b)
 }
-func cleanupExpiredPromoCodes() {
-	now := time.Now()
-
-	// 1) set inactive nếu hết hạn hoặc dùng hết
-	_ = DB.Model(&PromoCode{}).
-		Where("(expires_at IS NOT NULL AND expires_at <= ?) OR (max_uses IS NOT NULL AND used_count >= max_uses)", now).
-		Update("is_active", false).Error
-
-	// 2) xoá cứng các code inactive để danh sách gọn (nếu muốn chỉ ẩn, bỏ phần Delete này)
-	_ = DB.Where("is_active = 0").Delete(&PromoCode{}).Error
-}
-
-type createCodeReq struct {
-	FreeSpins int `json:"freeSpins"` // mặc định 10
-}
-type createCodeRes struct {
-	Code      string `json:"code"`
-	FreeSpins int    `json:"freeSpins"`
-}
-
 func myNotificationsHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 	unreadOnly := strings.TrimSpace(c.Query("unreadOnly")) == "1"
@@ -1915,7 +1815,7 @@ func markMyNotificationsReadHandler(c *gin.Context) {
 	if err := DB.Model(&Notification{}).
 		Where("user_id = ? AND is_read = 0", uid).
 		Update("is_read", true).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Cập nhật thất bại"})
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "Đã đánh dấu đã đọc"})
@@ -1930,379 +1830,68 @@ func genPromoString(n int) string {
 	return string(b)
 }
 
-type CreatePromoReq struct {
-	RewardFreeSpin int  `json:"rewardFreeSpin"`          // mặc định 10
-	DurationHours  *int `json:"durationHours,omitempty"` // chỉ áp dụng với UNLIMITED (nil => 24h)
-	MaxUses        *int `json:"maxUses,omitempty"`       // nil => vô hạn; =1 => one-use
-	Count          int  `json:"count"`                   // số code muốn tạo (mặc định 1). Với one-use có thể 10/50/100/500
+type MarketBuyRequest struct {
+	ListingID uint  `json:"listingId"`
+	Qty       int64 `json:"qty"`
 }
 
-// POST /admin/promo-codes
-func adminCreatePromoCodeHandler(c *gin.Context) {
-	// Yêu cầu adminRequired() đã gắn ở router
-	claims := c.MustGet("claims").(jwt.MapClaims)
-	adminID := uint(claims["sub"].(float64))
-
-	var req CreatePromoReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Dữ liệu không hợp lệ"})
-		return
-	}
-
-	// Mặc định
-	if req.RewardFreeSpin <= 0 {
-		req.RewardFreeSpin = 10
-	}
-	// Count: mặc định 1, chặn quá lớn để an toàn
-	if req.Count <= 0 {
-		req.Count = 1
-	}
-	if req.Count > 1000 {
-		req.Count = 1000
-	}
-
-	// Phân loại theo MaxUses & DurationHours:
-	// - ONE-USE vô hạn: MaxUses=1, ExpiresAt = NULL
-	// - UNLIMITED có hạn: MaxUses=nil, ExpiresAt = now + duration (mặc định 24h)
-	var expiresAt *time.Time
-	if req.MaxUses == nil {
-		// unlimited uses
-		dh := 24
-		if req.DurationHours != nil && *req.DurationHours > 0 {
-			dh = *req.DurationHours
-		}
-		t := time.Now().UTC().Add(time.Duration(dh) * time.Hour)
-		expiresAt = &t
-	} else {
-		// có MaxUses: nếu =1 và muốn vô thời hạn -> expiresAt = nil
-		if *req.MaxUses < 1 {
-			c.JSON(400, gin.H{"error": "MaxUses phải >= 1 hoặc bỏ trống để vô hạn"})
-			return
-		}
-		expiresAt = nil
-	}
-
-	type Out struct {
-		Codes []string `json:"codes"`
-	}
-	out := Out{Codes: make([]string, 0, req.Count)}
-
-	err := DB.Transaction(func(tx *gorm.DB) error {
-		for i := 0; i < req.Count; i++ {
-			code, err := randCode(12)
-			if err != nil {
-				return fmt.Errorf("gen code: %w", err)
-			}
-			pc := PromoCode{
-				Code:           code,
-				RewardFreeSpin: req.RewardFreeSpin,
-				MaxUses:        req.MaxUses, // nil => vô hạn; 1 => one-use
-				UsedCount:      0,
-				ExpiresAt:      expiresAt, // nil => vô thời hạn
-				IsActive:       true,
-				CreatedBy:      &adminID,
-			}
-			if err := tx.Create(&pc).Error; err != nil {
-				// tránh đụng unique, nếu trùng thì thử lại một ít lần
-				if strings.Contains(strings.ToLower(err.Error()), "duplicate") {
-					i--
-					continue
-				}
-				return err
-			}
-			out.Codes = append(out.Codes, pc.Code)
-		}
-		return nil
-	})
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Không tạo được code: " + err.Error()})
-		return
-	}
-
-	c.JSON(200, gin.H{
-		"message":        "Đã tạo gift code",
-		"rewardFreeSpin": req.RewardFreeSpin,
-		"maxUses":        req.MaxUses, // nil hoặc 1
-		"expiresAt":      expiresAt,   // null nếu vô hạn
-		"count":          len(out.Codes),
-		"codes":          out.Codes, // trả về danh sách code đã tạo
-	})
-}
-
-// POST /admin/promo-bonus-codes
-func adminCreateBonusCodesHandler(c *gin.Context) {
-	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-
-	var body struct {
-		Count         int  `json:"count"`         // số code muốn tạo (mặc định 1)
-		BonusCoins    *int `json:"bonusCoins"`    // mặc định 10
-		DurationHours *int `json:"durationHours"` // nếu có: hết hạn sau N giờ
-	}
-	_ = c.BindJSON(&body)
-
-	count := body.Count
-	if count <= 0 || count > 500 {
-		count = 1
-	}
-
-	coins := 10
-	if body.BonusCoins != nil && *body.BonusCoins > 0 {
-		coins = *body.BonusCoins
-	}
-
-	var expiresAt *time.Time
-	if body.DurationHours != nil && *body.DurationHours > 0 {
-		t := time.Now().UTC().Add(time.Duration(*body.DurationHours) * time.Hour)
-		expiresAt = &t
-	}
-
-	codes := make([]string, 0, count)
-	for i := 0; i < count; i++ {
-		// sinh code + đảm bảo unique (thử tối đa 8 lần để tránh vòng lặp vô hạn)
-		var code string
-		for try := 0; try < 8; try++ {
-			val, err := randCode(10) // 👈 dùng helper của bạn: (string, error)
-			if err != nil {
-				c.JSON(500, gin.H{"error": "Không tạo được mã (rng error)"})
-				return
-			}
-			code = strings.ToUpper(val) // optional: chuẩn hoá về chữ hoa
-
-			var exists int64
-			if err := DB.Model(&PromoBonusCode{}).Where("code = ?", code).Count(&exists).Error; err != nil {
-				c.JSON(500, gin.H{"error": "Lỗi kiểm tra trùng code"})
-				return
-			}
-			if exists == 0 {
-				break
-			}
-			if try == 7 {
-				c.JSON(500, gin.H{"error": "Không thể tạo code unique, thử lại sau"})
-				return
-			}
-		}
-
-		p := PromoBonusCode{
-			Code:       code,
-			BonusCoins: coins,
-			MaxUses:    1, // one-shot
-			UsedCount:  0,
-			ExpiresAt:  expiresAt,
-			IsActive:   true,
-			CreatedBy:  &adminID,
-		}
-		if err := DB.Create(&p).Error; err != nil {
-			c.JSON(500, gin.H{"error": "Tạo code thất bại"})
-			return
-		}
-		codes = append(codes, code)
-	}
-
-	c.JSON(200, gin.H{
-		"message":   "Đã tạo code bonus",
-		"codes":     codes,
-		"bonus":     coins,
-		"count":     count,
-		"expiresAt": expiresAt,
-	})
-}
-
-// GET /admin/promo-codes
-func adminListActivePromoCodesHandler(c *gin.Context) {
-	nowUTC := time.Now().UTC()
-
-	// Map đúng shape FE đang dùng
-	type Row struct {
-		ID        uint       `json:"id"`
-		Code      string     `json:"code"`
-		Value     int        `json:"value"` // = RewardFreeSpin
-		ExpiresAt *time.Time `json:"expiresAt"`
-		CreatedAt time.Time  `json:"createdAt"`
-	}
-
-	var rows []Row
-
-	// Lọc:
-	// - còn hoạt động
-	// - chưa hết hạn (hoặc không có hạn)
-	// - chưa dùng hết lượt (MaxUses NULL => vô hạn)
-	err := DB.
-		Table("promo_codes").
-		Select(`
-			id,
-			code,
-			reward_free_spin AS value,
-			expires_at,
-			created_at
-		`).
-		Where("is_active = ?", true).
-		Where("(expires_at IS NULL OR expires_at > ?)", nowUTC).
-		Where("(max_uses IS NULL OR used_count < max_uses)").
-		Order("expires_at ASC, created_at DESC").
-		Limit(100).
-		Scan(&rows).Error
-
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Không lấy được danh sách code"})
-		return
-	}
-
-	c.JSON(200, gin.H{"rows": rows})
-}
-
-func redeemCodeHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var req RedeemReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	var outFreeSpins int
-
-	if err := DB.Transaction(func(tx *gorm.DB) error {
-		// lock code
-		var pc PromoCode
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("code = ? AND is_active = 1", req.Code).
-			First(&pc).Error; err != nil {
-			return fmt.Errorf("Code không tồn tại hoặc đã bị vô hiệu")
-		}
-
-		// còn hạn?
-		if pc.ExpiresAt != nil && time.Now().After(*pc.ExpiresAt) {
-			return fmt.Errorf("Code đã hết hạn")
-		}
-
-		// còn lượt dùng? (nil => vô hạn)
-		if pc.MaxUses != nil && pc.UsedCount >= *pc.MaxUses {
-			return fmt.Errorf("Code đã dùng hết")
-		}
-
-		// chặn user dùng lặp
-		// unique (promo_code_id, user_id)
-		use := PromoCodeUse{PromoCodeID: pc.ID, UserID: uid}
-		if err := tx.Create(&use).Error; err != nil {
-			// duplicate
-			if strings.Contains(strings.ToLower(err.Error()), "unique") {
-				return fmt.Errorf("Bạn đã nhập code này rồi")
-			}
-			return err
-		}
-
-		// cộng free spins cho user
-		if err := tx.Model(&User{}).
-			Where("id = ?", uid).
-			Update("free_spins", gorm.Expr("free_spins + ?", pc.RewardFreeSpin)).Error; err != nil {
-			return err
-		}
-
-		// tăng UsedCount
-		if err := tx.Model(&pc).Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
-			return err
-		}
-
-		// đọc lại để trả về số free_spins hiện tại
-		var u User
-		if err := tx.Select("free_spins").First(&u, uid).Error; err != nil {
-			return err
-		}
-		outFreeSpins = int(u.FreeSpins)
-
-		// tạo notification cho user
-		note := Notification{
-			UserID: uid,
-			Title:  "Nhận quà gift code",
-			Body:   fmt.Sprintf("Bạn được +%d lượt quay miễn phí (code %s).", pc.RewardFreeSpin, pc.Code),
-		}
-		_ = tx.Create(&note).Error
-
-		return nil
-	}); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(200, gin.H{
-		"message":   "Nhập code thành công",
-		"freeSpins": outFreeSpins,
-	})
-}
-
-// POST /private/market/buy { listingId, qty }
 // POST /private/market/buy { listingId, qty }
 func marketBuyHandler(c *gin.Context) {
 	buyerID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var req struct {
-		ListingID uint  `json:"listingId"`
-		Qty       int64 `json:"qty"`
-	}
+	var req MarketBuyRequest
 	if err := c.ShouldBindJSON(&req); err != nil || req.Qty <= 0 {
-		c.JSON(400, gin.H{"error": "Dữ liệu không hợp lệ"})
+		respondError(c, apiError(ERR_INVALID_INPUT))
 		return
 	}
 
-	if err := DB.Transaction(func(tx *gorm.DB) error {
+	var fill MarketFill
+	if err := withEvents(func(tx *gorm.DB) error {
 		var l MarketListing
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, req.ListingID).Error; err != nil {
-			return fmt.Errorf("Listing không tồn tại")
+			return apiError(ERR_LISTING_NOT_FOUND)
 		}
 		if !l.IsActive || l.Qty < req.Qty {
-			return fmt.Errorf("Số lượng không đủ")
+			return apiError(ERR_LISTING_QTY_INSUFFICIENT)
+		}
+		if l.ExpiresAt != nil && !l.ExpiresAt.After(time.Now()) {
+			return apiError(ERR_LISTING_EXPIRED)
 		}
 
 		// ❌ Không cho người bán tự mua
 		if l.SellerID == buyerID {
-			return fmt.Errorf("Bạn không thể mua sản phẩm của chính mình. Hãy rút lại nếu muốn.")
+			return apiError(ERR_SELF_TRADE)
 		}
 
-		var buyer, seller User
-		if err := tx.First(&buyer, buyerID).Error; err != nil {
-			return err
-		}
-		if err := tx.First(&seller, l.SellerID).Error; err != nil {
+		var buyer User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&buyer, buyerID).Error; err != nil {
 			return err
 		}
 
 		total := req.Qty * l.PricePerUnit
 		if buyer.Coins+buyer.BonusCoins < total {
-			return fmt.Errorf("Số dư không đủ")
-		}
-		// trừ tiền hệ thống ở phía người mua (ưu tiên bonus)
-		if err := spendForSystem(tx, &buyer, total); err != nil {
-			return err
-		}
-
-		// trừ người mua, cộng người bán
-		if err := tx.Model(&buyer).Update("coins", gorm.Expr("coins - ?", total)).Error; err != nil {
-			return err
+			return apiError(ERR_INSUFFICIENT_BALANCE)
 		}
-		if err := tx.Model(&seller).Update("coins", gorm.Expr("coins + ?", total)).Error; err != nil {
-			return err
-		}
-
-		// cộng vật phẩm cho buyer
-		if err := invAdd(tx, buyer.ID, l.Code, req.Qty); err != nil {
-			return err
-		}
-
-		// trừ số lượng listing
-		left := l.Qty - req.Qty
-		up := map[string]any{"qty": left}
-		if left == 0 {
-			up["is_active"] = false
+		// cộng người bán (trừ phí), cộng vật phẩm, trừ listing, ghi trade
+		fill = MarketFill{
+			ListingID: l.ID, SellerID: l.SellerID, BuyerID: buyer.ID,
+			Code: l.Code, Qty: req.Qty, PricePerUnit: l.PricePerUnit, Kind: TRADE_DIRECT,
 		}
-		if err := tx.Model(&l).Updates(up).Error; err != nil {
+		if err := settleMarketFill(tx, &l, &fill); err != nil {
 			return err
 		}
-
-		return nil
+		// trừ tiền người mua (ưu tiên bonus) — chỉ trừ 1 lần, sau khi có mã trade để ghi sổ cái
+		return spendForSystem(tx, &buyer, total, LEDGER_MARKET_BUY, fill.TradeID)
 	}); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "Mua thành công"})
+	c.JSON(200, gin.H{"message": "Mua thành công", "trade": fill})
+}
+
+type MarketWithdrawRequest struct {
+	ListingID uint   `json:"listingId"`
+	Qty       *int64 `json:"qty"` // optional
 }
 
 // POST /private/market/withdraw { listingId, qty? }
@@ -2310,25 +1899,22 @@ func marketBuyHandler(c *gin.Context) {
 // - Nếu truyền qty: rút đúng số lượng đó (không vượt quá phần còn lại).
 func marketWithdrawHandler(c *gin.Context) {
 	sellerID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var req struct {
-		ListingID uint   `json:"listingId"`
-		Qty       *int64 `json:"qty"` // optional
-	}
+	var req MarketWithdrawRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Dữ liệu không hợp lệ"})
+		respondError(c, apiError(ERR_INVALID_INPUT))
 		return
 	}
 
 	if err := DB.Transaction(func(tx *gorm.DB) error {
 		var l MarketListing
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, req.ListingID).Error; err != nil {
-			return fmt.Errorf("Listing không tồn tại")
+			return apiError(ERR_LISTING_NOT_FOUND)
 		}
 		if l.SellerID != sellerID {
-			return fmt.Errorf("Bạn không phải chủ bài đăng này")
+			return apiError(ERR_LISTING_NOT_OWNER)
 		}
 		if !l.IsActive || l.Qty <= 0 {
-			return fmt.Errorf("Listing đã hết hoặc không hoạt động")
+			return apiError(ERR_LISTING_INACTIVE)
 		}
 
 		// xác định lượng rút
@@ -2354,7 +1940,7 @@ func marketWithdrawHandler(c *gin.Context) {
 
 		return nil
 	}); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -2364,16 +1950,21 @@ func marketWithdrawHandler(c *gin.Context) {
 func loginHandler(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 	var user User
 	if err := DB.Where("username = ?", strings.ToLower(req.Username)).First(&user).Error; err != nil {
-		c.JSON(401, gin.H{"error": "Sai username hoặc mật khẩu"})
+		respondError(c, apiError(ERR_LOGIN_FAILED))
 		return
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(401, gin.H{"error": "Sai username hoặc mật khẩu"})
+		respondError(c, apiError(ERR_LOGIN_FAILED))
+		return
+	}
+	// bị cấm thì không cấp token (FROZEN vẫn đăng nhập để xem)
+	if st := effectiveStatus(user.Status, user.StatusUntil); st == ACCOUNT_BANNED {
+		respondError(c, accountStatusError(st, user.StatusReason, user.StatusUntil, false))
 		return
 	}
 	claims := jwt.MapClaims{
@@ -2390,34 +1981,24 @@ func meHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 	user.PasswordHash = ""
 	c.JSON(200, gin.H{"user": user})
 }
-func withdrawHistoryHandler(c *gin.Context) {
-	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var rows []WithdrawRow
-	DB.Table("withdraw_txns w").
-		Select("w.id, w.amount, w.note, w.created_at, a.username AS admin_email").
-		Joins("LEFT JOIN users a ON a.id = w.admin_id").
-		Where("w.user_id = ?", uid).
-		Order("w.id DESC").Scan(&rows)
-	c.JSON(200, gin.H{"rows": rows})
-}
 func updateProfileHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 
 	var req ProfileUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
@@ -2427,7 +2008,7 @@ func updateProfileHandler(c *gin.Context) {
 		"avatar_url": strings.TrimSpace(req.AvatarURL),
 	}
 	if err := DB.Model(&user).Updates(updates).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Không thể cập nhật hồ sơ"})
+		respondError(c, err)
 		return
 	}
 
@@ -2473,14 +2054,14 @@ func ensureKycDirAbs() (string, error) {
 func uploadAvatarHandler(c *gin.Context) {
 	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Không có file"})
+		respondError(c, apiError(ERR_FILE_REQUIRED))
 		return
 	}
 	filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(file.Filename))
 	savePath := filepath.Join(uploadsAbs, filename)
 
 	if err := c.SaveUploadedFile(file, savePath); err != nil {
-		c.JSON(500, gin.H{"error": "Lưu file thất bại"})
+		respondError(c, err)
 		return
 	}
 	url := "/uploads/" + filename
@@ -2500,7 +2081,7 @@ func getWalletHandler(c *gin.Context) {
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
 		// Trả 401 để FE tự logout
-		c.JSON(401, gin.H{"error": "UNAUTHORIZED"})
+		respondError(c, apiError(ERR_UNAUTHORIZED))
 		return
 	}
 
@@ -2531,38 +2112,46 @@ func adminWithdrawHandler(c *gin.Context) {
 
 	var req WithdrawRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 
 	var user User
 	if err := DB.First(&user, req.UserID).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
-	if err := DB.Transaction(func(tx *gorm.DB) error {
+	if err := withEvents(func(tx *gorm.DB) error {
 		// khoá hàng để tránh race
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
 			return err
 		}
 		if user.Coins < req.Amount {
-			return fmt.Errorf("Số dư không đủ (cần %d, hiện %d)", req.Amount, user.Coins)
+			return apiError(ERR_INSUFFICIENT_BALANCE, "need", req.Amount, "have", user.Coins)
+		}
+		if err := checkRestriction(tx, user.ID, RESTRICT_WITHDRAW); err != nil {
+			return err
 		}
 		// trừ coin
 		if err := tx.Model(&user).Update("coins", gorm.Expr("coins - ?", req.Amount)).Error; err != nil {
 			return err
 		}
+		emitBalanceChanged(tx, user.ID, "withdraw")
 		// log rút tiền
-		return tx.Create(&WithdrawTxn{
+		txn := WithdrawTxn{
 			UserID:  user.ID,
 			AdminID: adminID,
 			Amount:  req.Amount,
 			Note:    strings.TrimSpace(req.Note),
-		}).Error
+		}
+		if err := tx.Create(&txn).Error; err != nil {
+			return err
+		}
+		return addLedger(tx, user.ID, CUR_COIN, LEDGER_WITHDRAW, txn.ID, -req.Amount)
 	}); err != nil {
 		log.Println("withdraw error:", err)
-		c.JSON(500, gin.H{"error": "Rút coin thất bại: " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -2596,7 +2185,7 @@ func adminSearchUsersHandler(c *gin.Context) {
 
 	var users []User
 	if err := q.Order("v_ip_level DESC, id ASC").Find(&users).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Query failed"})
+		respondError(c, err)
 		return
 	}
 
@@ -2616,45 +2205,75 @@ func adminTopupHandler(c *gin.Context) {
 
 	var req TopupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 	var user User
 	if err := DB.First(&user, req.UserID).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
-	err := DB.Transaction(func(tx *gorm.DB) error {
+	err := withEvents(func(tx *gorm.DB) error {
 		if err := tx.Model(&user).Updates(map[string]interface{}{
 			"coins":       gorm.Expr("coins + ?", req.Amount),
 			"total_topup": gorm.Expr("total_topup + ?", req.Amount),
 		}).Error; err != nil {
 			return err
 		}
+		emitBalanceChanged(tx, user.ID, "topup")
 		txn := CoinTxn{UserID: user.ID, AdminID: adminID, Amount: req.Amount, Note: strings.TrimSpace(req.Note)}
-		return tx.Create(&txn).Error
+		if err := tx.Create(&txn).Error; err != nil {
+			return err
+		}
+		return addLedger(tx, user.ID, CUR_COIN, LEDGER_TOPUP, txn.ID, req.Amount)
 	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Nạp coin thất bại"})
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "Nạp coin thành công", "userId": user.ID})
 }
 
-// DELETE /admin/users/:id  (hard delete)
-func adminHardDeleteUserHandler(c *gin.Context) {
-	idStr := c.Param("id")
-	uid64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil || uid64 == 0 {
-		c.JSON(400, gin.H{"error": "ID không hợp lệ"})
+// bản ghi của người khác còn trỏ tới user (không được xoá khi purge)
+func userStillReferenced(tx *gorm.DB, uid uint) (bool, error) {
+	for _, q := range []struct {
+		model any
+		where string
+	}{
+		{&TransferTxn{}, "from_id = @id OR to_id = @id"},
+		{&CoinTxn{}, "admin_id = @id"},
+		{&WithdrawTxn{}, "admin_id = @id"},
+		{&CommissionTxn{}, "buyer_id = @id"},
+		{&ReferralReward{}, "invitee_id = @id"},
+		{&MarketTrade{}, "seller_id = @id OR buyer_id = @id"},
+	} {
+		var n int64
+		if err := tx.Model(q.model).Where(q.where, map[string]any{"id": uid}).Count(&n).Error; err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DELETE /admin/users/:id  (xoá cứng — superadmin, chỉ tài khoản đã đóng quá closure.purge_retention_days ngày)
+func adminPurgeUserHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	uid, ok := parseIDParam(c)
+	if !ok {
 		return
 	}
-	uid := uint(uid64)
 
 	var u User
 	if err := DB.First(&u, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
+		return
+	}
+	if err := checkPurgeable(u); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -2664,29 +2283,33 @@ func adminHardDeleteUserHandler(c *gin.Context) {
 			return fmt.Errorf("clear referred_by: %w", err)
 		}
 
-		// 2) dọn dữ liệu liên quan tới user ở mọi bảng
-		// lịch sử giao dịch coin/chuyển
-		if err := tx.Where("from_id = ? OR to_id = ?", uid, uid).Delete(&TransferTxn{}).Error; err != nil {
-			return fmt.Errorf("del transfer_txns: %w", err)
-		}
-		if err := tx.Where("user_id = ? OR admin_id = ?", uid, uid).Delete(&CoinTxn{}).Error; err != nil {
+		// 2) chỉ dọn dữ liệu của riêng user; bản ghi cũng thuộc lịch sử người khác (chuyển coin, hoa hồng của upline,
+		// thưởng giới thiệu của người mời, giao dịch chợ, nạp/rút do admin này duyệt) được giữ, vẫn trỏ tới bia mộ
+		if err := tx.Where("user_id = ?", uid).Delete(&CoinTxn{}).Error; err != nil {
 			return fmt.Errorf("del coin_txns: %w", err)
 		}
-		// mua VIP, hoa hồng
+		// mua VIP, hoa hồng user này nhận
 		if err := tx.Where("user_id = ?", uid).Delete(&VipPurchaseTxn{}).Error; err != nil {
 			return fmt.Errorf("del vip_purchase_txns: %w", err)
 		}
-		if err := tx.Where("buyer_id = ? OR beneficiary_id = ?", uid, uid).Delete(&CommissionTxn{}).Error; err != nil {
+		if err := tx.Where("beneficiary_id = ?", uid).Delete(&CommissionTxn{}).Error; err != nil {
 			return fmt.Errorf("del commission_txns: %w", err)
 		}
 		// rút coin
-		if err := tx.Where("user_id = ? OR admin_id = ?", uid, uid).Delete(&WithdrawTxn{}).Error; err != nil {
+		if err := tx.Where("user_id = ?", uid).Delete(&WithdrawTxn{}).Error; err != nil {
 			return fmt.Errorf("del withdraw_txns: %w", err)
 		}
-		// referral one-off
-		if err := tx.Where("inviter_id = ? OR invitee_id = ?", uid, uid).Delete(&ReferralReward{}).Error; err != nil {
+		// thưởng giới thiệu user này nhận
+		if err := tx.Where("inviter_id = ?", uid).Delete(&ReferralReward{}).Error; err != nil {
 			return fmt.Errorf("del referral_rewards: %w", err)
 		}
+		// chợ: lệnh mua & listing đã được giải phóng lúc đóng tài khoản
+		if err := tx.Where("buyer_id = ?", uid).Delete(&MarketBid{}).Error; err != nil {
+			return fmt.Errorf("del market_bids: %w", err)
+		}
+		if err := tx.Where("seller_id = ?", uid).Delete(&MarketListing{}).Error; err != nil {
+			return fmt.Errorf("del market_listings: %w", err)
+		}
 		// túi đồ, log mở rương, thông báo
 		if err := tx.Where("user_id = ?", uid).Delete(&InventoryItem{}).Error; err != nil {
 			return fmt.Errorf("del inventory_items: %w", err)
@@ -2694,6 +2317,18 @@ func adminHardDeleteUserHandler(c *gin.Context) {
 		if err := tx.Where("user_id = ?", uid).Delete(&ChestTxn{}).Error; err != nil {
 			return fmt.Errorf("del chest_txns: %w", err)
 		}
+		if err := tx.Where("user_id = ?", uid).Delete(&LedgerEntry{}).Error; err != nil {
+			return fmt.Errorf("del ledger_entries: %w", err)
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&CheckinLog{}).Error; err != nil {
+			return fmt.Errorf("del checkin_logs: %w", err)
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&CheckinState{}).Error; err != nil {
+			return fmt.Errorf("del checkin_states: %w", err)
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&MissionProgress{}).Error; err != nil {
+			return fmt.Errorf("del mission_progresses: %w", err)
+		}
 		if err := tx.Where("user_id = ?", uid).Delete(&Notification{}).Error; err != nil {
 			return fmt.Errorf("del notifications: %w", err)
 		}
@@ -2702,31 +2337,47 @@ func adminHardDeleteUserHandler(c *gin.Context) {
 			// bảng này có thể rỗng; vẫn nên trả lỗi rõ ràng nếu có
 			return fmt.Errorf("del promo_code_uses: %w", err)
 		}
-		// chợ: rút & xoá listing còn lại
-		// (chỉ cần delete; nếu muốn trả vật phẩm thì đã xoá inventory rồi)
-		if err := tx.Where("seller_id = ?", uid).Delete(&MarketListing{}).Error; err != nil {
-			return fmt.Errorf("del market_listings: %w", err)
+		if err := tx.Where("user_id = ?", uid).Delete(&PromoRedemption{}).Error; err != nil {
+			return fmt.Errorf("del promo_redemptions: %w", err)
+		}
+		// đối soát số dư
+		if err := tx.Where("user_id = ?", uid).Delete(&BalanceCheckpoint{}).Error; err != nil {
+			return fmt.Errorf("del balance_checkpoints: %w", err)
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&BalanceDrift{}).Error; err != nil {
+			return fmt.Errorf("del balance_drifts: %w", err)
+		}
+		// file xuất dữ liệu đã hết hạn từ lâu (job data_exports.build xoá file)
+		if err := tx.Where("user_id = ?", uid).Delete(&DataExport{}).Error; err != nil {
+			return fmt.Errorf("del data_exports: %w", err)
+		}
+		if err := tx.Where("user_id = ?", uid).Delete(&AccountCloseRequest{}).Error; err != nil {
+			return fmt.Errorf("del account_close_requests: %w", err)
+		}
+		// hồ sơ đóng tài khoản được giữ lại (không có thông tin cá nhân)
+		if err := tx.Model(&AccountClosure{}).Where("user_id = ?", uid).
+			Updates(map[string]any{"purged_at": time.Now(), "purged_by": adminID}).Error; err != nil {
+			return fmt.Errorf("mark account_closure: %w", err)
 		}
 
-		// 3) xoá cứng user
-		if err := tx.Unscoped().Delete(&User{}, uid).Error; err != nil {
-			return fmt.Errorf("del user: %w", err)
+		// 3) xoá cứng user nếu không còn bản ghi của người khác trỏ tới; còn thì giữ dòng users đã ẩn danh làm bia mộ
+		referenced, err := userStillReferenced(tx, uid)
+		if err != nil {
+			return err
+		}
+		if !referenced {
+			if err := tx.Unscoped().Delete(&User{}, uid).Error; err != nil {
+				return fmt.Errorf("del user: %w", err)
+			}
 		}
 		return nil
 	}); err != nil {
 		log.Println("admin hard delete error:", err)
-		c.JSON(500, gin.H{"error": "Xoá tài khoản thất bại"})
+		respondError(c, err)
 		return
 	}
 
-	// 4) (tuỳ chọn) xoá file KYC trên đĩa sau khi TX thành công
-	if u.KYCFrontPath != "" {
-		_ = os.Remove(filepath.Join(kycAbs, u.KYCFrontPath))
-	}
-	if u.KYCBackPath != "" {
-		_ = os.Remove(filepath.Join(kycAbs, u.KYCBackPath))
-	}
-
+	// file KYC / ảnh đại diện đã xoá lúc đóng tài khoản
 	c.Status(204)
 }
 
@@ -2737,7 +2388,7 @@ func buyVipHandler(c *gin.Context) {
 
 	var user User
 	if err := DB.First(&user, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
@@ -2750,28 +2401,52 @@ func buyVipHandler(c *gin.Context) {
 	const vipInviteMilestoneReward int64 = 500 // thưởng mốc cho F1 khi đạt 10 direct VIP (chỉ 1 lần)
 
 	if user.VIPLevel >= 1 {
-		c.JSON(400, gin.H{"error": "Bạn đã là VIP"})
+		respondError(c, apiError(ERR_VIP_ALREADY))
 		return
 	}
+
+	// Phiếu giảm giá VIP (từ shop EV): dùng phiếu có % cao nhất
+	var voucher VipVoucher
+	if DB.Where("user_id = ? AND used_at IS NULL", user.ID).
+		Order("percent DESC, id ASC").First(&voucher).Error == nil {
+		price = price * int64(100-voucher.Percent) / 100
+	}
+
 	if user.Coins < price {
-		c.JSON(400, gin.H{"error": "Số dư không đủ"})
+		respondError(c, apiError(ERR_INSUFFICIENT_BALANCE))
 		return
 	}
 
-	if err := DB.Transaction(func(tx *gorm.DB) error {
+	if err := withEvents(func(tx *gorm.DB) error {
+		// 0) Đánh dấu đã dùng phiếu giảm giá (nếu có)
+		if voucher.ID != 0 {
+			res := tx.Model(&VipVoucher{}).
+				Where("id = ? AND used_at IS NULL", voucher.ID).
+				Update("used_at", time.Now())
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return apiError(ERR_CONFLICT)
+			}
+		}
+
 		// 1) Trừ coin & set VIP = 1
 		if err := tx.Model(&User{}).Where("id = ?", user.ID).
 			Update("coins", gorm.Expr("coins - ?", price)).Error; err != nil {
 			return err
 		}
+		emitBalanceChanged(tx, user.ID, "vip")
 		old := user.VIPLevel
 		if err := tx.Model(&User{}).Where("id = ?", user.ID).
 			Update("v_ip_level", 1).Error; err != nil {
 			return err
 		}
-		if err := tx.Create(&VipPurchaseTxn{
-			UserID: user.ID, Level: 1, Price: price, OldLevel: old,
-		}).Error; err != nil {
+		purchase := VipPurchaseTxn{UserID: user.ID, Level: 1, Price: price, OldLevel: old}
+		if err := tx.Create(&purchase).Error; err != nil {
+			return err
+		}
+		if err := addLedger(tx, user.ID, CUR_COIN, LEDGER_VIP_PURCHASE, purchase.ID, -price); err != nil {
 			return err
 		}
 
@@ -2782,6 +2457,16 @@ func buyVipHandler(c *gin.Context) {
 			return fmt.Errorf("award buyer free spins: %w", err)
 		}
 
+		// nhiệm vụ: người mua & người giới thiệu trực tiếp
+		if err := domainEvent(tx, user.ID, DE_VIP_BUY, 1); err != nil {
+			return err
+		}
+		if user.ReferredBy != nil {
+			if err := domainEvent(tx, *user.ReferredBy, DE_VIP_REFERRAL, 1); err != nil {
+				return err
+			}
+		}
+
 		// 3) Chia hoa hồng 9 tầng, mỗi tầng 10% — CHỈ trả cho upline đã VIP
 		uplines, _ := getUplines(tx, user.ReferredBy, 9)
 		allocated := 0
@@ -2789,7 +2474,7 @@ func buyVipHandler(c *gin.Context) {
 			if allocated >= 100 {
 				break
 			}
-			if up.VIPLevel < 1 {
+			if up.VIPLevel < 1 || up.Status == ACCOUNT_CLOSED {
 				continue
 			}
 			pct := 10
@@ -2802,10 +2487,15 @@ func buyVipHandler(c *gin.Context) {
 					Update("coins", gorm.Expr("coins + ?", amt)).Error; err != nil {
 					return fmt.Errorf("upline depth %d: %w", i+1, err)
 				}
-				if err := tx.Create(&CommissionTxn{
+				emitBalanceChanged(tx, up.ID, "commission")
+				cm := CommissionTxn{
 					BuyerID: user.ID, BeneficiaryID: &up.ID, Depth: i + 1,
 					Percent: pct, Amount: amt, Kind: "UPLINE", VipLevelBought: 1,
-				}).Error; err != nil {
+				}
+				if err := tx.Create(&cm).Error; err != nil {
+					return err
+				}
+				if err := addLedger(tx, up.ID, CUR_COIN, LEDGER_COMMISSION, cm.ID, amt); err != nil {
 					return err
 				}
 			}
@@ -2817,8 +2507,8 @@ func buyVipHandler(c *gin.Context) {
 			// lock hàng F1 để đọc/ghi cờ an toàn
 			var f1 User
 			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-				Select("id, coins, invite10_vip_bonus_paid").
-				First(&f1, *user.ReferredBy).Error; err == nil {
+				Select("id, coins, invite10_vip_bonus_paid, status").
+				First(&f1, *user.ReferredBy).Error; err == nil && f1.Status != ACCOUNT_CLOSED {
 
 				// Đếm số F1 đã VIP (đã bao gồm user hiện tại vì ở trên set VIP xong)
 				var directVipCount int64
@@ -2833,16 +2523,18 @@ func buyVipHandler(c *gin.Context) {
 							Update("coins", gorm.Expr("coins + ?", vipInviteMilestoneReward)).Error; err != nil {
 							return fmt.Errorf("award F1 milestone: %w", err)
 						}
+						if err := addLedger(tx, f1.ID, CUR_COIN, LEDGER_REFERRAL_BONUS, purchase.ID, vipInviteMilestoneReward); err != nil {
+							return err
+						}
+						emitBalanceChanged(tx, f1.ID, "commission")
 						if err := tx.Model(&User{}).
 							Where("id = ?", f1.ID).
 							Update("invite10_vip_bonus_paid", true).Error; err != nil {
 							return fmt.Errorf("mark F1 milestone paid: %w", err)
 						}
-						_ = tx.Create(&Notification{
-							UserID: f1.ID,
-							Title:  "Thưởng mốc mời bạn VIP",
-							Body:   fmt.Sprintf("Bạn đã có 10 người mua VIP trực tiếp. Thưởng +%d coin.", vipInviteMilestoneReward),
-						}).Error
+						_ = notify(tx, f1.ID, NT_VIP_INVITE_BONUS, map[string]any{
+							"count": 10, "reward": vipInviteMilestoneReward,
+						})
 					}
 				}
 			}
@@ -2867,7 +2559,7 @@ func buyVipHandler(c *gin.Context) {
 		}
 		return nil
 	}); err != nil {
-		c.JSON(500, gin.H{"error": "Mua VIP thất bại"})
+		respondError(c, err)
 		return
 	}
 
@@ -2879,35 +2571,59 @@ func buyVipHandler(c *gin.Context) {
 func listNotificationsHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 	unreadOnly := c.Query("unreadOnly") == "1"
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
 
-	q := DB.Where("user_id = ?", uid).Order("id DESC").Limit(50)
+	q := DB.Where("user_id = ?", uid).Order("id DESC").Limit(limit + 1)
 	if unreadOnly {
 		q = q.Where("is_read = 0")
 	}
+	// mặc định ẩn thông báo đã lưu trữ; ?archived=1 chỉ xem mục lưu trữ
+	if c.Query("archived") == "1" {
+		q = q.Where("archived_at IS NOT NULL")
+	} else {
+		q = q.Where("archived_at IS NULL")
+	}
+	if cat := strings.ToLower(strings.TrimSpace(c.Query("category"))); cat != "" {
+		q = q.Where("category = ?", cat)
+	}
+	// phân trang theo con trỏ: cursor = nextCursor của trang trước
+	if cur, err := strconv.ParseUint(c.Query("cursor"), 10, 64); err == nil && cur > 0 {
+		q = q.Where("id < ?", cur)
+	}
 
 	var rows []Notification
 	if err := q.Find(&rows).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Không lấy được thông báo"})
+		respondError(c, err)
 		return
 	}
+	var nextCursor *uint
+	if len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = &rows[limit-1].ID
+	}
 
 	var unread int64
-	DB.Model(&Notification{}).Where("user_id = ? AND is_read = 0", uid).Count(&unread)
+	DB.Model(&Notification{}).Where("user_id = ? AND is_read = 0 AND archived_at IS NULL", uid).Count(&unread)
 
 	c.JSON(200, gin.H{
-		"rows":   rows,
-		"unread": unread,
+		"rows":       rows,
+		"unread":     unread,
+		"nextCursor": nextCursor,
 	})
 }
 
+type MarkReadRequest struct {
+	IDs []uint `json:"ids"`
+}
+
 // PUT /private/notifications/mark-read  { ids?: number[] }
 // Nếu không gửi ids → mark read tất cả
 func markReadNotificationsHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
-	var req struct {
-		IDs []uint `json:"ids"`
-	}
-
+	var req MarkReadRequest
 	_ = c.ShouldBindJSON(&req) // optional
 
 	q := DB.Model(&Notification{}).Where("user_id = ?", uid).Where("is_read = 0")
@@ -2915,7 +2631,7 @@ func markReadNotificationsHandler(c *gin.Context) {
 		q = q.Where("id IN ?", req.IDs)
 	}
 	if err := q.Update("is_read", true).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Không thể cập nhật thông báo"})
+		respondError(c, err)
 		return
 	}
 
@@ -2926,7 +2642,7 @@ func markReadNotificationsHandler(c *gin.Context) {
 func transferHandler(c *gin.Context) {
 	var req TransferRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Dữ liệu không hợp lệ: " + err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 	toUsername := strings.ToLower(strings.TrimSpace(req.ToUsername))
@@ -2935,46 +2651,61 @@ func transferHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 	var from User
 	if err := DB.First(&from, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Người gửi không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
 	// Không tự chuyển cho chính mình
 	if toUsername == strings.ToLower(from.Username) {
-		c.JSON(400, gin.H{"error": "Không thể tự chuyển cho chính mình"})
+		respondError(c, apiError(ERR_TRANSFER_SELF))
 		return
 	}
 
 	// Kiểm tra đã đặt PIN chưa
 	if strings.TrimSpace(from.TxnPinHash) == "" {
-		c.JSON(400, gin.H{"error": "Bạn chưa thiết lập mã bảo mật (PIN). Hãy vào Hồ sơ > Bảo mật để đặt PIN 6 số."})
+		respondError(c, apiError(ERR_PIN_NOT_SET))
 		return
 	}
 	// So khớp PIN
 	if err := bcrypt.CompareHashAndPassword([]byte(from.TxnPinHash), []byte(req.TxnPin)); err != nil {
-		c.JSON(400, gin.H{"error": "Mã PIN không đúng"})
+		respondError(c, apiError(ERR_PIN_INVALID))
 		return
 	}
 
 	// Tìm người nhận theo username
 	var to User
 	if err := DB.Where("LOWER(username) = ?", toUsername).First(&to).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Người nhận không tồn tại"})
+		respondError(c, apiError(ERR_RECIPIENT_NOT_FOUND))
+		return
+	}
+	// ví phí hệ thống không nhận chuyển khoản; tài khoản đã đóng / bị khoá / bị cấm thì coin chuyển tới không dùng lại được
+	if to.Role == ROLE_SYSTEM {
+		respondError(c, apiError(ERR_RECIPIENT_NOT_FOUND))
+		return
+	}
+	if effectiveStatus(to.Status, to.StatusUntil) != ACCOUNT_ACTIVE {
+		respondError(c, apiError(ERR_RECIPIENT_UNAVAILABLE))
 		return
 	}
-
-	// Phí 1% (làm tròn lên)
-	fee := (req.Amount*1 + 99) / 100 // ceil(amount*0.01)
-	totalDebit := req.Amount + fee
 
 	// Giao dịch
-	if err := DB.Transaction(func(tx *gorm.DB) error {
-		// khoá người gửi
+	var fee, totalDebit int64
+	var limits TransferLimits
+	if err := withEvents(func(tx *gorm.DB) error {
+		// khoá người gửi (hạn mức tính trong cùng khoá nên 2 lệnh song song không vượt được)
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&from, from.ID).Error; err != nil {
 			return err
 		}
+		l, err := checkTransferLimits(tx, from, to.ID, req.Amount)
+		if err != nil {
+			return err
+		}
+		limits = l
+		// phí theo tier (làm tròn lên)
+		fee = l.Tier.fee(req.Amount)
+		totalDebit = req.Amount + fee
 		if from.Coins < totalDebit {
-			return fmt.Errorf("Số dư không đủ (cần %d, hiện %d)", totalDebit, from.Coins)
+			return apiError(ERR_INSUFFICIENT_BALANCE, "need", totalDebit, "have", from.Coins)
 		}
 		// trừ người gửi
 		if err := tx.Model(&from).Update("coins", gorm.Expr("coins - ?", totalDebit)).Error; err != nil {
@@ -2984,60 +2715,80 @@ func transferHandler(c *gin.Context) {
 		if err := tx.Model(&to).Update("coins", gorm.Expr("coins + ?", req.Amount)).Error; err != nil {
 			return err
 		}
+		emitBalanceChanged(tx, from.ID, "transfer.out")
+		emitBalanceChanged(tx, to.ID, "transfer.in")
 		// log
-		return tx.Create(&TransferTxn{
+		txn := TransferTxn{
 			FromID: from.ID, ToID: to.ID,
 			Amount: req.Amount, Fee: fee, Note: strings.TrimSpace(req.Note),
-		}).Error
+		}
+		if err := tx.Create(&txn).Error; err != nil {
+			return err
+		}
+		if err := addLedger(tx, from.ID, CUR_COIN, LEDGER_TRANSFER_OUT, txn.ID, -req.Amount); err != nil {
+			return err
+		}
+		if err := addLedger(tx, from.ID, CUR_COIN, LEDGER_TRANSFER_FEE, txn.ID, -fee); err != nil {
+			return err
+		}
+		if err := addLedger(tx, to.ID, CUR_COIN, LEDGER_TRANSFER_IN, txn.ID, req.Amount); err != nil {
+			return err
+		}
+		if err := creditSystemFee(tx, LEDGER_TRANSFER_FEE_IN, fee, txn.ID); err != nil {
+			return err
+		}
+		return domainEvent(tx, from.ID, DE_TRANSFER, 1)
 	}); err != nil {
-		c.JSON(500, gin.H{"error": "Chuyển coin thất bại: " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "Chuyển coin thành công", "fee": fee, "debit": totalDebit})
+	c.JSON(200, gin.H{"message": "Chuyển coin thành công", "fee": fee, "debit": totalDebit, "tier": limits.TierName})
 }
 
-type kycUpdateReq struct {
-	FullName string `json:"fullName"` // đổi key để khớp FE
+// FE gửi nickname + idNumber
+type KycUpdateRequest struct {
+	Nickname string `json:"nickname"`
 	IdNumber string `json:"idNumber"`
-	Dob      string `json:"issueDate"` // hoặc "dob" nếu muốn
 }
 
 func updateKycHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 
-	// FE gửi nickname + idNumber
-	type kycUpdateReq struct {
-		Nickname string `json:"nickname"`
-		IdNumber string `json:"idNumber"`
-	}
-	var req kycUpdateReq
+	var req KycUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, invalidInput(err))
 		return
 	}
 
 	name := strings.TrimSpace(req.Nickname)
 	num := strings.TrimSpace(req.IdNumber)
 	if name == "" || num == "" {
-		c.JSON(400, gin.H{"error": "Thiếu họ tên hoặc số CCCD"})
+		respondError(c, apiError(ERR_KYC_FIELDS_REQUIRED))
 		return
 	}
 
-	var u User
-	if err := DB.First(&u, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
-		return
-	}
+	if err := withEvents(func(tx *gorm.DB) error {
+		var u User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, uid).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		if u.KYCStatus != "VERIFIED" {
+			if err := domainEvent(tx, uid, DE_KYC_VERIFIED, 1); err != nil {
+				return err
+			}
+		}
 
-	// Lưu vào các cột KYC hiện có (không đổi schema)
-	up := map[string]any{
-		"kyc_full_name": name, // nhận nickname nhưng lưu vào full_name
-		"kyc_number":    num,
-		"kyc_status":    "VERIFIED", // khớp enum('NONE','VERIFIED')
-	}
-	if err := DB.Model(&u).Updates(up).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Cập nhật KYC thất bại"})
+		// Lưu vào các cột KYC hiện có (không đổi schema)
+		up := map[string]any{
+			"kyc_full_name": name, // nhận nickname nhưng lưu vào full_name
+			"kyc_number":    num,
+			"kyc_status":    "VERIFIED", // khớp enum('NONE','VERIFIED')
+		}
+		emitEvent(tx, uid, EV_KYC_DECIDED, gin.H{"status": "VERIFIED"})
+		return tx.Model(&u).Updates(up).Error
+	}); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, gin.H{"message": "Đã xác minh KYC"})
@@ -3052,7 +2803,7 @@ func adminGetKycImage(c *gin.Context) {
 
 	var u User
 	if err := DB.Select("kyc_front_path, kyc_back_path").First(&u, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
@@ -3063,7 +2814,7 @@ func adminGetKycImage(c *gin.Context) {
 		fname = u.KYCBackPath
 	}
 	if fname == "" {
-		c.JSON(404, gin.H{"error": "Chưa có ảnh"})
+		respondError(c, apiError(ERR_KYC_IMAGE_NOT_FOUND))
 		return
 	}
 
@@ -3079,7 +2830,7 @@ func referralInfoHandler(c *gin.Context) {
 	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
 	var u User
 	if err := DB.First(&u, uid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User không tồn tại"})
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
 		return
 	}
 
@@ -3153,7 +2904,7 @@ func dashboardOverviewHandler(c *gin.Context) {
 		}
 		return nil
 	}); err != nil {
-		c.JSON(500, gin.H{"error": "Lấy tổng quan thất bại"})
+		respondError(c, err)
 		return
 	}
 	c.JSON(200, out)
@@ -3195,7 +2946,7 @@ func myDownlinesHandler(c *gin.Context) {
 				Where("referred_by IN ?", currentLevel).
 				Select("id, username, v_ip_level , created_at").
 				Find(&users).Error; err != nil {
-				c.JSON(500, gin.H{"error": "Không tải được tuyến dưới"})
+				respondError(c, err)
 				return
 			}
 		}
@@ -3250,7 +3001,7 @@ func dashboardCommissionsHandler(c *gin.Context) {
 		Select("DAY(created_at) as d, COALESCE(SUM(amount),0) as s").
 		Group("d").Order("d").
 		Scan(&rows).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Lấy lịch hoa hồng thất bại"})
+		respondError(c, err)
 		return
 	}
 
@@ -3276,8 +3027,29 @@ func dashboardCommissionsHandler(c *gin.Context) {
 
 /* ===== MAIN & CORS ===== */
 func main() {
+	// go run . gen-client ../frontend/src/api.gen.ts
+	if len(os.Args) == 3 && os.Args[1] == "gen-client" {
+		if err := writeTSClientFile(os.Args[2]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// go run . reconcile  (đối soát số dư 1 lần, in kết quả)
+	if len(os.Args) == 2 && os.Args[1] == "reconcile" {
+		connectDB()
+		s, err := reconcileBalances()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("đã kiểm tra %d user, %d lệch, %d bị khoá\n", s.Checked, s.Drifted, s.Frozen)
+		return
+	}
+
 	connectDB()
-	cleanupExpiredPromoCodes()
+	startEventHub()
+	startJobScheduler()
+	startBroadcastWorker()
+	startLeaderboardWorker()
 
 	r := gin.Default()
 	r.MaxMultipartMemory = 16 << 20 // 16 MiB
@@ -3315,37 +3087,59 @@ func main() {
 		log.Println("⚠️  Không thể tạo thư mục KYC:", err)
 	}
 
+	// Tài liệu API (openapi.go)
+	r.GET("/openapi.json", openAPIHandler)
+
 	// Public
-	r.POST("/register", registerHandler)
-	r.POST("/login", loginHandler)
-	r.GET("/vip-tiers", getVipTiersHandler)
-	r.GET("/market", marketQueryHandler)
-	r.POST("/forgot-password", forgotPasswordHandler)
-	r.GET("/public/leaderboard", publicLeaderboardHandler)
+	pub := r.Group("", validateRequest())
+	pub.POST("/register", registerHandler)
+	pub.POST("/login", loginHandler)
+	pub.GET("/vip-tiers", getVipTiersHandler)
+	pub.GET("/market", marketQueryHandler)
+	pub.GET("/market/orderbook", marketOrderBookHandler)
+	pub.GET("/market/stats", marketStatsHandler)
+	pub.POST("/forgot-password", forgotPasswordHandler)
+	pub.GET("/public/leaderboard", publicLeaderboardHandler)
+	pub.GET("/public/seasons", publicListSeasonsHandler)
+	pub.GET("/public/seasons/:id", publicSeasonStandingsHandler)
+	pub.GET("/public/data-export/:token", downloadDataExportHandler)
 
 	// Private
 	priv := r.Group("/private")
-	priv.Use(authRequired())
+	priv.Use(authRequired(), validateRequest())
 	priv.GET("/me", meHandler)
 	priv.PUT("/profile", updateProfileHandler)
 	priv.GET("/wallet", getWalletHandler)
 	priv.POST("/upload", uploadAvatarHandler)
-	priv.POST("/transfer", transferHandler)
+	priv.POST("/transfer", restrictedBy(RESTRICT_TRANSFER), transferHandler)
+	priv.GET("/transfer/limits", myTransferLimitsHandler)
 	priv.GET("/referral-info", referralInfoHandler)
 	priv.POST("/buy-vip", buyVipHandler)
-	priv.GET("/history/withdraws", withdrawHistoryHandler)
-
-	priv.GET("/history/topups", topupHistoryHandler)
-	priv.GET("/history/transfers", transferHistoryHandler)
-	priv.GET("/history/vip", vipHistoryHandler)
-	priv.GET("/history/commissions", myCommissionHistoryHandler)
-	priv.POST("/chest-open", chestOpenHandler)
+	priv.GET("/history/withdraws", historyListHandler(withdrawHistory))
+	priv.GET("/history/withdraws/export", historyExportHandler(withdrawHistory))
+	priv.GET("/history/topups", historyListHandler(topupHistory))
+	priv.GET("/history/topups/export", historyExportHandler(topupHistory))
+	priv.GET("/history/transfers", historyListHandler(transferHistory))
+	priv.GET("/history/transfers/export", historyExportHandler(transferHistory))
+	priv.GET("/history/vip", historyListHandler(vipHistory))
+	priv.GET("/history/vip/export", historyExportHandler(vipHistory))
+	priv.GET("/history/commissions", historyListHandler(commissionHistory))
+	priv.GET("/history/commissions/export", historyExportHandler(commissionHistory))
+	priv.GET("/statement", statementHandler)
+	priv.POST("/chest-open", restrictedBy(RESTRICT_CHEST), chestOpenHandler)
 	priv.GET("/inventory", inventoryHandler)
 	priv.POST("/merge-dragon", mergeDragonBallsHandler)
 
-	priv.POST("/market/list", marketListHandler)
-	priv.POST("/market/buy", marketBuyHandler)
+	priv.POST("/market/list", restrictedBy(RESTRICT_MARKET), marketListHandler)
+	priv.POST("/market/buy", restrictedBy(RESTRICT_MARKET), marketBuyHandler)
 	priv.POST("/market/withdraw", marketWithdrawHandler)
+	priv.POST("/market/bids", restrictedBy(RESTRICT_MARKET), marketPlaceBidHandler)
+	priv.GET("/market/bids", marketMyBidsHandler)
+	priv.POST("/market/bids/:id/cancel", marketCancelBidHandler)
+	priv.POST("/market/market-order", restrictedBy(RESTRICT_MARKET), marketOrderHandler)
+	priv.GET("/market/trades", myMarketTradesHandler)
+	priv.GET("/market/listings", myMarketListingsHandler)
+	priv.PUT("/market/listings/:id", restrictedBy(RESTRICT_MARKET), marketEditListingHandler)
 	priv.POST("/change-password", changePasswordHandler)
 	priv.PUT("/change-password", changePasswordHandler)
 
@@ -3355,29 +3149,110 @@ func main() {
 	priv.PUT("/kyc", updateKycHandler)
 	priv.POST("/kyc", kycSubmitHandler)
 	priv.GET("/notifications", listNotificationsHandler)
+	priv.GET("/events", eventsStreamHandler)
+	priv.POST("/events/ticket", issueStreamTicketHandler)
 	priv.PUT("/notifications/mark-read", markReadNotificationsHandler)
+	priv.DELETE("/notifications/:id", deleteNotificationHandler)
+	priv.POST("/notifications/:id/archive", archiveNotificationHandler(true))
+	priv.POST("/notifications/:id/unarchive", archiveNotificationHandler(false))
+	priv.GET("/notification-preferences", getNotificationPrefsHandler)
+	priv.PUT("/notification-preferences", updateNotificationPrefsHandler)
 	priv.POST("/redeem-code", redeemCodeHandler) // 👈 user nhập code
 	priv.GET("/dashboard/overview", dashboardOverviewHandler)
 	priv.GET("/dashboard/commissions", dashboardCommissionsHandler)
-	priv.POST("/redeem-bonus-code", redeemBonusCodeHandler)
 	priv.GET("/downlines", myDownlinesHandler)
 	priv.GET("/downlines/:id/dashboard", downlineDashboardHandler)
 	priv.GET("/leaderboard/me", privateMyLeaderboardHandler)
+	priv.GET("/seasons/:id/me", mySeasonStandingHandler)
+	priv.GET("/checkin", checkinStatusHandler)
+	priv.POST("/checkin", checkinHandler)
+	priv.PUT("/checkin/timezone", checkinTimezoneHandler)
+	priv.GET("/missions", listMissionsHandler)
+	priv.POST("/missions/:id/claim", claimMissionHandler)
+	priv.GET("/shop", shopListHandler)
+	priv.POST("/shop/buy", shopBuyHandler)
+	priv.GET("/shop/purchases", shopMyPurchasesHandler)
+	priv.GET("/vip-vouchers", myVipVouchersHandler)
+	priv.GET("/data-export", listDataExportsHandler)
+	priv.POST("/data-export", requestDataExportHandler)
+	priv.POST("/account/close", selfCloseAccountHandler)
+	priv.GET("/account/restrictions", myRestrictionsHandler)
 
 	// Admin
 	admin := r.Group("/admin")
-	admin.Use(authRequired(), adminRequired())
+	admin.Use(authRequired(), adminRequired(), validateRequest())
 	admin.POST("/topup", adminTopupHandler)
 	admin.GET("/users", adminSearchUsersHandler)
-	admin.DELETE("/users/:id", adminHardDeleteUserHandler)
+	admin.POST("/users/:id/close", adminCloseAccountHandler)
+	admin.PUT("/users/:id/status", adminSetAccountStatusHandler)
+	admin.GET("/users/:id/restrictions", adminUserRestrictionsHandler)
+	admin.POST("/users/:id/restrictions", adminAddRestrictionHandler)
+	admin.DELETE("/users/:id/restrictions/:rid", adminLiftRestrictionHandler)
+	admin.DELETE("/users/:id", superAdminRequired(), adminPurgeUserHandler)
+	admin.GET("/account-closures", adminListAccountClosuresHandler)
+	admin.GET("/account-close-requests", adminListCloseRequestsHandler)
+	admin.POST("/account-close-requests/:id/approve", adminApproveCloseRequestHandler)
+	admin.POST("/account-close-requests/:id/reject", adminRejectCloseRequestHandler)
 	admin.POST("/withdraw", adminWithdrawHandler)
 	admin.GET("/kyc/:userId/front", adminServeKycFront)
 	admin.GET("/kyc/:userId/back", adminServeKycBack)
 	admin.GET("/kyc-file/:userId/:side", adminGetKycImage)
 	admin.GET("/users/:id", adminUserDetailHandler)
-	admin.POST("/promo-codes", adminCreatePromoCodeHandler)
-	admin.GET("/promo-codes", adminListActivePromoCodesHandler)
-	admin.POST("/promo-bonus-codes", adminCreateBonusCodesHandler)
+	admin.GET("/promo-campaigns", adminListPromoCampaignsHandler)
+	admin.POST("/promo-campaigns", adminCreatePromoCampaignHandler)
+	admin.POST("/promo-campaigns/:id/codes", adminCreatePromoCodesHandler)
+	admin.GET("/promo-campaigns/:id/redemptions", adminPromoRedemptionsHandler("campaign_id"))
+	admin.GET("/promo-campaigns/:id/export", adminExportPromoCodesHandler)
+	admin.POST("/promo-campaigns/:id/revoke", adminSetPromoCampaignHandler("is_active", func() any { return false }, "Đã thu hồi chiến dịch"))
+	admin.POST("/promo-campaigns/:id/reactivate", adminSetPromoCampaignHandler("is_active", func() any { return true }, "Đã kích hoạt lại chiến dịch"))
+	admin.POST("/promo-campaigns/:id/archive", adminSetPromoCampaignHandler("archived_at", func() any { return time.Now() }, "Đã lưu trữ chiến dịch"))
+	admin.POST("/promo-campaigns/:id/unarchive", adminSetPromoCampaignHandler("archived_at", func() any { return nil }, "Đã bỏ lưu trữ chiến dịch"))
+	admin.GET("/promo-codes", adminListPromoCodesHandler)
+	admin.GET("/promo-codes/:id/redemptions", adminPromoRedemptionsHandler("code_id"))
+	admin.POST("/promo-codes/:id/revoke", adminSetPromoCodeActiveHandler(false, "Đã thu hồi code"))
+	admin.POST("/promo-codes/:id/reactivate", adminSetPromoCodeActiveHandler(true, "Đã kích hoạt lại code"))
+	admin.GET("/shop/items", adminListShopItemsHandler)
+	admin.POST("/shop/items", adminCreateShopItemHandler)
+	admin.PUT("/shop/items/:id", adminUpdateShopItemHandler)
+	admin.GET("/shop/purchases", adminShopPurchasesHandler)
+	admin.GET("/market/listings", adminListMarketListingsHandler)
+	admin.POST("/market/listings/:id/cancel", adminCancelMarketListingHandler)
+	admin.GET("/transfer-tiers", adminListTransferTiersHandler)
+	admin.PUT("/transfer-tiers", adminUpsertTransferTierHandler)
+	admin.DELETE("/transfer-tiers/:id", adminDeleteTransferTierHandler)
+	admin.GET("/settings", adminListSettingsHandler)
+	admin.PUT("/settings", adminUpdateSettingHandler)
+	admin.POST("/notifications/broadcast", adminBroadcastHandler)
+	admin.GET("/notifications/broadcasts", adminListBroadcastsHandler)
+	admin.GET("/notifications/broadcasts/:id", adminGetBroadcastHandler)
+	admin.POST("/notifications/broadcasts/:id/cancel", adminCancelBroadcastHandler)
+	admin.GET("/notification-templates", adminListNotificationTemplatesHandler)
+	admin.PUT("/notification-templates", adminUpsertNotificationTemplateHandler)
+	admin.DELETE("/notification-templates", adminResetNotificationTemplateHandler)
+	admin.GET("/seasons", adminListSeasonsHandler)
+	admin.POST("/seasons", adminCreateSeasonHandler)
+	admin.PUT("/seasons/:id", adminUpdateSeasonHandler)
+	admin.POST("/seasons/:id/cancel", adminCancelSeasonHandler)
+	admin.GET("/checkin/calendar", adminCheckinCalendarHandler)
+	admin.PUT("/checkin/calendar", adminUpdateCheckinCalendarHandler)
+	admin.GET("/missions", adminListMissionsHandler)
+	admin.POST("/missions", adminCreateMissionHandler)
+	admin.PUT("/missions/:id", adminUpdateMissionHandler)
+	admin.GET("/stats", adminStatsHandler)
+	admin.GET("/jobs", adminListJobsHandler)
+	admin.PUT("/jobs/:name", adminUpdateJobHandler)
+	admin.GET("/jobs/:name/runs", adminJobRunsHandler)
+	admin.POST("/jobs/:name/run", adminRunJobHandler)
+	admin.POST("/jobs/:name/pause", adminPauseJobHandler(true, "Đã tạm dừng job"))
+	admin.POST("/jobs/:name/resume", adminPauseJobHandler(false, "Đã chạy lại job theo lịch"))
+	admin.GET("/reconciliation/drifts", adminListDriftsHandler)
+	admin.GET("/reconciliation/drifts/:id", adminGetDriftHandler)
+	admin.POST("/reconciliation/drifts/:id/resolve", adminResolveDriftHandler)
+
+	// mọi route phải có mô tả trong openapi_routes.go
+	if err := checkAPISpec(r.Routes()); err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Println("🚀 Server running at :" + PORT)
 	_ = r.Run(":" + PORT)
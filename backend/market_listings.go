@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== CHỢ: HẠN LISTING, SỬA LISTING & ADMIN KIỂM DUYỆT ===== */
+
+// listing còn bán được: đang active, còn hàng, chưa hết hạn
+func liveListings(db *gorm.DB) *gorm.DB {
+	return db.Where("market_listings.is_active = 1 AND market_listings.qty > 0").
+		Where("(market_listings.expires_at IS NULL OR market_listings.expires_at > ?)", time.Now())
+}
+
+// hạn mặc định của listing mới (nil => không hết hạn)
+func listingExpiry(ttlHours int64) *time.Time {
+	maxTTL := settingInt("market.listing_ttl_hours")
+	if ttlHours <= 0 || (maxTTL > 0 && ttlHours > maxTTL) {
+		ttlHours = maxTTL
+	}
+	if ttlHours <= 0 {
+		return nil
+	}
+	t := time.Now().Add(time.Duration(ttlHours) * time.Hour)
+	return &t
+}
+
+// Gỡ listing: trả phần còn lại về túi người bán (invAdd) và tắt listing.
+// l phải đã được khoá FOR UPDATE. Trả về số lượng đã hoàn.
+func closeListing(tx *gorm.DB, l *MarketListing) (int64, error) {
+	back := l.Qty
+	if back > 0 {
+		if err := invAdd(tx, l.SellerID, l.Code, back); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Model(l).Updates(map[string]any{"qty": 0, "is_active": false}).Error; err != nil {
+		return 0, err
+	}
+	l.Qty = 0
+	l.IsActive = false
+	return back, nil
+}
+
+// quét listing hết hạn, trả vật phẩm về túi người bán (job market.expire_listings)
+func expireMarketListings() error {
+	var ids []uint
+	if err := DB.Model(&MarketListing{}).
+		Where("is_active = 1 AND expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Order("id ASC").Limit(500).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	var errs []error
+	for _, id := range ids {
+		err := withEvents(func(tx *gorm.DB) error {
+			var l MarketListing
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, id).Error; err != nil {
+				return err
+			}
+			// có thể đã bị mua/rút trong lúc quét
+			if !l.IsActive || l.ExpiresAt == nil || l.ExpiresAt.After(time.Now()) {
+				return nil
+			}
+			back, err := closeListing(tx, &l)
+			if err != nil {
+				return err
+			}
+			if back > 0 {
+				_ = notify(tx, l.SellerID, NT_LISTING_EXPIRED, map[string]any{"id": l.ID, "code": l.Code, "qty": back})
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GET /private/market/listings (bài đăng của tôi)
+func myMarketListingsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	q := DB.Where("seller_id = ?", uid)
+	if c.Query("activeOnly") == "1" {
+		q = q.Where("is_active = 1 AND qty > 0")
+	}
+	var rows []MarketListing
+	q.Order("id DESC").Limit(200).Find(&rows)
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+type EditListingRequest struct {
+	PricePerUnit *int64 `json:"pricePerUnit" binding:"omitempty,gt=0,lte=1000000000000"`
+	Qty          *int64 `json:"qty" binding:"omitempty,gt=0,lte=1000000"`
+}
+
+// PUT /private/market/listings/:id { pricePerUnit?, qty? }
+// qty = số lượng còn bán mới (tăng thì trừ thêm từ túi, giảm thì trả lại túi)
+func marketEditListingHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return
+	}
+	var req EditListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if req.PricePerUnit == nil && req.Qty == nil {
+		respondError(c, apiError(ERR_INVALID_INPUT))
+		return
+	}
+
+	var l MarketListing
+	var fills []MarketFill
+	if err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, uint(id64)).Error; err != nil {
+			return apiError(ERR_LISTING_NOT_FOUND)
+		}
+		if l.SellerID != uid {
+			return apiError(ERR_LISTING_NOT_OWNER)
+		}
+		if !l.IsActive || l.Qty <= 0 {
+			return apiError(ERR_LISTING_INACTIVE)
+		}
+		// hết hạn nhưng job dọn chưa chạy: không cho sửa (sửa xong sẽ khớp lệnh ngay)
+		if l.ExpiresAt != nil && !time.Now().Before(*l.ExpiresAt) {
+			return apiError(ERR_LISTING_EXPIRED)
+		}
+
+		up := map[string]any{}
+		if req.Qty != nil && *req.Qty != l.Qty {
+			if diff := *req.Qty - l.Qty; diff > 0 {
+				if err := invSub(tx, uid, l.Code, diff); err != nil {
+					return err
+				}
+			} else {
+				if err := invAdd(tx, uid, l.Code, -diff); err != nil {
+					return err
+				}
+			}
+			up["qty"] = *req.Qty
+			l.Qty = *req.Qty
+		}
+		if req.PricePerUnit != nil {
+			up["price_per_unit"] = *req.PricePerUnit
+			l.PricePerUnit = *req.PricePerUnit
+		}
+		if !mulFits(l.Qty, l.PricePerUnit) {
+			return apiError(ERR_INVALID_INPUT, "field", "pricePerUnit", "detail", "qty × pricePerUnit quá lớn")
+		}
+		if len(up) > 0 {
+			if err := tx.Model(&l).Updates(up).Error; err != nil {
+				return err
+			}
+		}
+
+		// giá mới có thể khớp được lệnh mua đang chờ
+		var err error
+		fills, err = matchListingAgainstBids(tx, &l)
+		return err
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Đã cập nhật bài đăng", "listing": l, "fills": fills})
+}
+
+type AdminListingRow struct {
+	ID             uint       `json:"id"`
+	SellerID       uint       `json:"sellerId"`
+	SellerUsername string     `json:"sellerUsername"`
+	Code           string     `json:"code"`
+	Qty            int64      `json:"qty"`
+	PricePerUnit   int64      `json:"pricePerUnit"`
+	IsActive       bool       `json:"isActive"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// GET /admin/market/listings?code=&sellerId=&status=active|inactive|all
+func adminListMarketListingsHandler(c *gin.Context) {
+	q := DB.Table("market_listings l").
+		Select("l.id, l.seller_id, u.username AS seller_username, l.code, l.qty, l.price_per_unit, l.is_active, l.expires_at, l.created_at, l.updated_at").
+		Joins("LEFT JOIN users u ON u.id = l.seller_id")
+	switch strings.ToLower(c.DefaultQuery("status", "active")) {
+	case "active":
+		q = q.Where("l.is_active = 1 AND l.qty > 0")
+	case "inactive":
+		q = q.Where("l.is_active = 0 OR l.qty = 0")
+	}
+	if code := strings.ToUpper(strings.TrimSpace(c.Query("code"))); code != "" {
+		q = q.Where("l.code = ?", code)
+	}
+	if v, err := strconv.ParseUint(c.Query("sellerId"), 10, 64); err == nil && v > 0 {
+		q = q.Where("l.seller_id = ?", v)
+	}
+
+	rows := []AdminListingRow{}
+	if err := q.Order("l.id DESC").Limit(500).Scan(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+type CancelListingRequest struct {
+	Reason string `json:"reason"`
+}
+
+// POST /admin/market/listings/:id/cancel { reason }
+func adminCancelMarketListingHandler(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return
+	}
+	var req CancelListingRequest
+	_ = c.ShouldBindJSON(&req)
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "vi phạm quy định chợ"
+	}
+	if r := []rune(reason); len(r) > 200 {
+		reason = string(r[:200])
+	}
+
+	var back int64
+	if err := withEvents(func(tx *gorm.DB) error {
+		var l MarketListing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, uint(id64)).Error; err != nil {
+			return apiError(ERR_LISTING_NOT_FOUND)
+		}
+		if !l.IsActive || l.Qty <= 0 {
+			return apiError(ERR_LISTING_INACTIVE)
+		}
+		var err error
+		if back, err = closeListing(tx, &l); err != nil {
+			return err
+		}
+		return notify(tx, l.SellerID, NT_LISTING_REMOVED, map[string]any{
+			"id": l.ID, "code": l.Code, "reason": reason, "qty": back,
+		})
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Đã gỡ bài đăng", "returnedQty": back})
+}
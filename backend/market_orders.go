@@ -0,0 +1,477 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== CHỢ: LỆNH MUA (BID) & KHỚP LỆNH ===== */
+
+const (
+	BID_OPEN      = "OPEN"
+	BID_FILLED    = "FILLED"
+	BID_CANCELLED = "CANCELLED"
+)
+
+// lệnh mua giới hạn: mua tối đa Qty viên với giá <= MaxPrice / viên.
+// Coin được giữ (escrow) ngay khi đặt lệnh = phần chưa khớp * MaxPrice.
+type MarketBid struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BuyerID   uint      `gorm:"index;not null" json:"buyerId"`
+	Code      string    `gorm:"size:10;not null;index:idx_bid_book,priority:1" json:"code"`
+	Qty       int64     `gorm:"not null" json:"qty"`
+	FilledQty int64     `gorm:"not null;default:0" json:"filledQty"`
+	MaxPrice  int64     `gorm:"not null;index:idx_bid_book,priority:3" json:"maxPrice"` // coin / 1 viên
+	Escrow    int64     `gorm:"not null;default:0" json:"escrow"`                       // coin đang giữ
+	Status    string    `gorm:"size:10;not null;default:'OPEN';index:idx_bid_book,priority:2" json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	Buyer User `gorm:"foreignKey:BuyerID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"-"`
+}
+
+func (b *MarketBid) Remaining() int64 { return b.Qty - b.FilledQty }
+
+// a, b > 0 và a*b không tràn int64
+func mulFits(a, b int64) bool {
+	return a > 0 && b > 0 && a <= math.MaxInt64/b
+}
+
+// 1 lần khớp giữa người bán (listing) và người mua
+type MarketFill struct {
+	ListingID    uint   `json:"listingId"`
+	BidID        *uint  `json:"bidId,omitempty"`
+	SellerID     uint   `json:"sellerId"`
+	BuyerID      uint   `json:"buyerId"`
+	Code         string `json:"code"`
+	Qty          int64  `json:"qty"`
+	PricePerUnit int64  `json:"pricePerUnit"`
+	Fee          int64  `json:"fee"` // phí người bán
+	Kind         string `json:"kind"`
+	TradeID      uint   `json:"tradeId"`
+}
+
+// Thanh toán 1 lần khớp: cộng coin người bán (trừ phí), cộng phí cho hệ thống,
+// cộng vật phẩm người mua, trừ số lượng listing và ghi market_trades.
+// Việc trừ coin người mua do caller xử lý (escrow / trừ trực tiếp).
+func settleMarketFill(tx *gorm.DB, l *MarketListing, f *MarketFill) error {
+	total := f.Qty * f.PricePerUnit
+	f.Fee = marketSellerFee(total)
+	if err := tx.Model(&User{}).Where("id = ?", f.SellerID).
+		Update("coins", gorm.Expr("coins + ?", total-f.Fee)).Error; err != nil {
+		return err
+	}
+	if err := invAdd(tx, f.BuyerID, f.Code, f.Qty); err != nil {
+		return err
+	}
+	left := l.Qty - f.Qty
+	up := map[string]any{"qty": left}
+	if left == 0 {
+		up["is_active"] = false
+	}
+	if err := tx.Model(l).Updates(up).Error; err != nil {
+		return err
+	}
+	l.Qty = left
+	l.IsActive = left > 0
+	if err := recordMarketTrade(tx, f); err != nil {
+		return err
+	}
+	if err := addLedger(tx, f.SellerID, CUR_COIN, LEDGER_MARKET_SELL, f.TradeID, total-f.Fee); err != nil {
+		return err
+	}
+	if err := creditSystemFee(tx, LEDGER_MARKET_FEE, f.Fee, f.TradeID); err != nil {
+		return err
+	}
+
+	emitBalanceChanged(tx, f.SellerID, "market.sell")
+	emitBalanceChanged(tx, f.BuyerID, "market.buy")
+	emitEvent(tx, f.SellerID, EV_LISTING_SOLD, gin.H{
+		"listingId": f.ListingID, "tradeId": f.TradeID, "code": f.Code,
+		"qty": f.Qty, "pricePerUnit": f.PricePerUnit, "fee": f.Fee,
+		"net": total - f.Fee, "left": left,
+	})
+	return nil
+}
+
+func saveBidProgress(tx *gorm.DB, b *MarketBid) error {
+	if b.Remaining() == 0 {
+		b.Status = BID_FILLED
+	}
+	return tx.Model(b).Updates(map[string]any{
+		"filled_qty": b.FilledQty,
+		"escrow":     b.Escrow,
+		"status":     b.Status,
+	}).Error
+}
+
+// Khớp 1 lệnh mua mới với các listing đang bán (giá thấp trước, cũ trước).
+// Giá khớp = giá của listing (lệnh đang chờ); phần chênh lệch escrow được hoàn lại.
+func matchBidAgainstAsks(tx *gorm.DB, b *MarketBid) ([]MarketFill, error) {
+	fills := []MarketFill{}
+	var asks []MarketListing
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(liveListings).
+		Where("code = ? AND price_per_unit <= ? AND seller_id <> ?", b.Code, b.MaxPrice, b.BuyerID).
+		Order("price_per_unit ASC, id ASC").
+		Find(&asks).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range asks {
+		if b.Remaining() == 0 {
+			break
+		}
+		l := &asks[i]
+		q := min(b.Remaining(), l.Qty)
+		f := MarketFill{
+			ListingID: l.ID, BidID: &b.ID, SellerID: l.SellerID, BuyerID: b.BuyerID,
+			Code: b.Code, Qty: q, PricePerUnit: l.PricePerUnit, Kind: TRADE_BID,
+		}
+		if err := settleMarketFill(tx, l, &f); err != nil {
+			return nil, err
+		}
+		// hoàn phần chênh lệch giá cho người mua
+		if refund := q * (b.MaxPrice - l.PricePerUnit); refund > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", b.BuyerID).
+				Update("coins", gorm.Expr("coins + ?", refund)).Error; err != nil {
+				return nil, err
+			}
+			if err := addLedger(tx, b.BuyerID, CUR_COIN, LEDGER_BID_REFUND, b.ID, refund); err != nil {
+				return nil, err
+			}
+		}
+		b.FilledQty += q
+		b.Escrow -= q * b.MaxPrice
+		fills = append(fills, f)
+	}
+	if len(fills) > 0 {
+		if err := saveBidProgress(tx, b); err != nil {
+			return nil, err
+		}
+	}
+	return fills, nil
+}
+
+// Khớp 1 listing mới với các lệnh mua đang chờ (giá cao trước, cũ trước).
+// Giá khớp = giá của lệnh mua (lệnh đang chờ), trả từ escrow.
+func matchListingAgainstBids(tx *gorm.DB, l *MarketListing) ([]MarketFill, error) {
+	fills := []MarketFill{}
+	var bids []MarketBid
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ? AND status = ? AND max_price >= ? AND buyer_id <> ?",
+			l.Code, BID_OPEN, l.PricePerUnit, l.SellerID).
+		Order("max_price DESC, id ASC").
+		Find(&bids).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range bids {
+		if l.Qty == 0 {
+			break
+		}
+		b := &bids[i]
+		q := min(b.Remaining(), l.Qty)
+		if q <= 0 {
+			continue
+		}
+		f := MarketFill{
+			ListingID: l.ID, BidID: &b.ID, SellerID: l.SellerID, BuyerID: b.BuyerID,
+			Code: l.Code, Qty: q, PricePerUnit: b.MaxPrice, Kind: TRADE_BID,
+		}
+		if err := settleMarketFill(tx, l, &f); err != nil {
+			return nil, err
+		}
+		b.FilledQty += q
+		b.Escrow -= q * b.MaxPrice
+		if err := saveBidProgress(tx, b); err != nil {
+			return nil, err
+		}
+		fills = append(fills, f)
+	}
+	return fills, nil
+}
+
+// giới hạn để qty × maxPrice không tràn int64 (1e6 × 1e12 = 1e18)
+type PlaceBidRequest struct {
+	Code     string `json:"code"`
+	Qty      int64  `json:"qty" binding:"required,gt=0,lte=1000000"`
+	MaxPrice int64  `json:"maxPrice" binding:"required,gt=0,lte=1000000000000"`
+}
+
+// POST /private/market/bids { code, qty, maxPrice }
+func marketPlaceBidHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req PlaceBidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if !mulFits(req.Qty, req.MaxPrice) {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "maxPrice", "detail", "qty × maxPrice quá lớn"))
+		return
+	}
+	if !isTradableCode(req.Code) {
+		respondError(c, apiError(ERR_ITEM_CODE_INVALID))
+		return
+	}
+
+	var bid MarketBid
+	var fills []MarketFill
+	if err := withEvents(func(tx *gorm.DB) error {
+		// 🔒 khoá người mua & giữ coin (chỉ dùng coins, không dùng bonus để hoàn lại chính xác)
+		var buyer User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&buyer, uid).Error; err != nil {
+			return err
+		}
+		escrow := req.Qty * req.MaxPrice
+		if buyer.Coins < escrow {
+			return apiError(ERR_INSUFFICIENT_BALANCE, "need", escrow, "have", buyer.Coins)
+		}
+		if err := tx.Model(&User{}).Where("id = ?", uid).
+			Update("coins", gorm.Expr("coins - ?", escrow)).Error; err != nil {
+			return err
+		}
+
+		bid = MarketBid{
+			BuyerID: uid, Code: req.Code, Qty: req.Qty,
+			MaxPrice: req.MaxPrice, Escrow: escrow, Status: BID_OPEN,
+		}
+		if err := tx.Create(&bid).Error; err != nil {
+			return err
+		}
+		if err := addLedger(tx, uid, CUR_COIN, LEDGER_BID_ESCROW, bid.ID, -escrow); err != nil {
+			return err
+		}
+		emitBalanceChanged(tx, uid, "market.bid")
+
+		var err error
+		fills, err = matchBidAgainstAsks(tx, &bid)
+		return err
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Đã đặt lệnh mua", "bid": bid, "fills": fills})
+}
+
+// GET /private/market/bids?status=OPEN
+func marketMyBidsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	q := DB.Where("buyer_id = ?", uid)
+	if st := strings.ToUpper(strings.TrimSpace(c.Query("status"))); st != "" {
+		q = q.Where("status = ?", st)
+	}
+	var rows []MarketBid
+	q.Order("id DESC").Limit(200).Find(&rows)
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// POST /private/market/bids/:id/cancel
+func marketCancelBidHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return
+	}
+
+	var refund int64
+	if err := withEvents(func(tx *gorm.DB) error {
+		var b MarketBid
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&b, uint(id64)).Error; err != nil {
+			return apiError(ERR_BID_NOT_FOUND)
+		}
+		if b.BuyerID != uid {
+			return apiError(ERR_BID_NOT_OWNER)
+		}
+		if b.Status != BID_OPEN {
+			return apiError(ERR_BID_CLOSED)
+		}
+		refund = b.Escrow
+		if refund > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", uid).
+				Update("coins", gorm.Expr("coins + ?", refund)).Error; err != nil {
+				return err
+			}
+			if err := addLedger(tx, uid, CUR_COIN, LEDGER_BID_REFUND, b.ID, refund); err != nil {
+				return err
+			}
+			emitBalanceChanged(tx, uid, "market.bid_cancel")
+		}
+		return tx.Model(&b).Updates(map[string]any{"status": BID_CANCELLED, "escrow": 0}).Error
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Đã huỷ lệnh mua", "refund": refund})
+}
+
+// 1 mức giá trong sổ lệnh (gộp các lệnh cùng giá)
+type OrderBookLevel struct {
+	Price  int64 `json:"price"`
+	Qty    int64 `json:"qty"`
+	Orders int64 `json:"orders"`
+}
+
+// GET /market/orderbook?code=DB3&depth=20
+func marketOrderBookHandler(c *gin.Context) {
+	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
+	if !isTradableCode(code) {
+		respondError(c, apiError(ERR_ITEM_CODE_INVALID))
+		return
+	}
+	depth, _ := strconv.Atoi(c.DefaultQuery("depth", "20"))
+	if depth <= 0 || depth > 100 {
+		depth = 20
+	}
+
+	asks := []OrderBookLevel{}
+	bids := []OrderBookLevel{}
+
+	if err := DB.Model(&MarketListing{}).Scopes(liveListings).
+		Select("price_per_unit AS price, SUM(qty) AS qty, COUNT(*) AS orders").
+		Where("code = ?", code).
+		Group("price_per_unit").Order("price ASC").Limit(depth).
+		Scan(&asks).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := DB.Model(&MarketBid{}).
+		Select("max_price AS price, SUM(qty - filled_qty) AS qty, COUNT(*) AS orders").
+		Where("code = ? AND status = ?", code, BID_OPEN).
+		Group("max_price").Order("price DESC").Limit(depth).
+		Scan(&bids).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"code": code, "asks": asks, "bids": bids})
+}
+
+const (
+	ORDER_FOK = "FOK" // fill-or-kill: khớp đủ hoặc huỷ toàn bộ
+	ORDER_IOC = "IOC" // immediate-or-cancel: khớp được bao nhiêu lấy bấy nhiêu
+)
+
+type MarketOrderRequest struct {
+	Code     string `json:"code"`
+	Qty      int64  `json:"qty" binding:"required,gt=0,lte=1000000"`
+	MaxTotal int64  `json:"maxTotal" binding:"gte=0,lte=1000000000000000"` // 0 => không giới hạn (chỉ giới hạn bởi số dư)
+	Mode     string `json:"mode"`                                          // mặc định FOK
+}
+
+// POST /private/market/market-order { code, qty, maxTotal?, mode: FOK|IOC }
+// Quét các listing rẻ nhất của mã đến khi đủ qty hoặc chạm maxTotal.
+func marketOrderHandler(c *gin.Context) {
+	buyerID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req MarketOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if !isTradableCode(req.Code) {
+		respondError(c, apiError(ERR_ITEM_CODE_INVALID))
+		return
+	}
+	req.Mode = strings.ToUpper(strings.TrimSpace(req.Mode))
+	if req.Mode == "" {
+		req.Mode = ORDER_FOK
+	}
+	if req.Mode != ORDER_FOK && req.Mode != ORDER_IOC {
+		respondError(c, apiError(ERR_ORDER_MODE_INVALID))
+		return
+	}
+
+	var fills []MarketFill
+	var filledQty, spent int64
+	if err := withEvents(func(tx *gorm.DB) error {
+		var buyer User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&buyer, buyerID).Error; err != nil {
+			return err
+		}
+		budget := buyer.Coins + buyer.BonusCoins
+		if req.MaxTotal > 0 && req.MaxTotal < budget {
+			budget = req.MaxTotal
+		}
+
+		// 🔒 khoá listing theo thứ tự giá (rẻ trước), cũ trước
+		var asks []MarketListing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(liveListings).
+			Where("code = ? AND seller_id <> ?", req.Code, buyerID).
+			Order("price_per_unit ASC, id ASC").
+			Find(&asks).Error; err != nil {
+			return err
+		}
+
+		// Lập kế hoạch khớp trước, chưa ghi gì
+		type plan struct {
+			l *MarketListing
+			q int64
+		}
+		plans := []plan{}
+		for i := range asks {
+			left := req.Qty - filledQty
+			if left == 0 {
+				break
+			}
+			l := &asks[i]
+			q := min(left, l.Qty)
+			if !mulFits(q, l.PricePerUnit) {
+				return apiError(ERR_INVALID_INPUT, "field", "qty", "detail", "qty × giá quá lớn")
+			}
+			if afford := (budget - spent) / l.PricePerUnit; q > afford {
+				q = afford
+			}
+			if q <= 0 {
+				break // giá tăng dần nên listing sau cũng không mua nổi
+			}
+			plans = append(plans, plan{l, q})
+			filledQty += q
+			spent += q * l.PricePerUnit
+		}
+
+		if filledQty == 0 {
+			return apiError(ERR_ORDER_NO_LIQUIDITY)
+		}
+		if req.Mode == ORDER_FOK && filledQty < req.Qty {
+			return apiError(ERR_ORDER_NOT_FILLED, "filled", filledQty, "qty", req.Qty)
+		}
+
+		for _, p := range plans {
+			f := MarketFill{
+				ListingID: p.l.ID, SellerID: p.l.SellerID, BuyerID: buyerID,
+				Code: req.Code, Qty: p.q, PricePerUnit: p.l.PricePerUnit, Kind: TRADE_SWEEP,
+			}
+			if err := settleMarketFill(tx, p.l, &f); err != nil {
+				return err
+			}
+			fills = append(fills, f)
+		}
+		// trừ tiền người mua (ưu tiên bonus như mua thường); sổ cái gắn với lần khớp đầu tiên
+		return spendForSystem(tx, &buyer, spent, LEDGER_MARKET_BUY, fills[0].TradeID)
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	avg := spent / filledQty
+	c.JSON(200, gin.H{
+		"message":   "Mua thành công",
+		"mode":      req.Mode,
+		"filledQty": filledQty,
+		"totalCost": spent,
+		"avgPrice":  avg,
+		"fills":     fills,
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestPlaceBidRequestBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     PlaceBidRequest
+		wantErr bool
+	}{
+		{"hợp lệ", PlaceBidRequest{Code: "DB1", Qty: 5, MaxPrice: 100}, false},
+		{"giới hạn trên", PlaceBidRequest{Code: "DB1", Qty: 1_000_000, MaxPrice: 1_000_000_000_000}, false},
+		{"qty = 0", PlaceBidRequest{Code: "DB1", Qty: 0, MaxPrice: 100}, true},
+		{"qty âm", PlaceBidRequest{Code: "DB1", Qty: -1, MaxPrice: 100}, true},
+		{"qty quá lớn", PlaceBidRequest{Code: "DB1", Qty: 1_000_001, MaxPrice: 100}, true},
+		{"giá = 0", PlaceBidRequest{Code: "DB1", Qty: 1, MaxPrice: 0}, true},
+		{"giá âm", PlaceBidRequest{Code: "DB1", Qty: 1, MaxPrice: -100}, true},
+		{"giá quá lớn", PlaceBidRequest{Code: "DB1", Qty: 1, MaxPrice: 1_000_000_000_001}, true},
+	}
+	for _, tt := range tests {
+		err := binding.Validator.ValidateStruct(&tt.req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMarketBidRemaining(t *testing.T) {
+	tests := []struct {
+		qty, filled, want int64
+	}{
+		{10, 0, 10},
+		{10, 4, 6},
+		{10, 10, 0},
+	}
+	for _, tt := range tests {
+		b := MarketBid{Qty: tt.qty, FilledQty: tt.filled}
+		if got := b.Remaining(); got != tt.want {
+			t.Errorf("Remaining(qty=%d, filled=%d) = %d, want %d", tt.qty, tt.filled, got, tt.want)
+		}
+	}
+}
+
+func TestMarketOrderRequestBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     MarketOrderRequest
+		wantErr bool
+	}{
+		{"hợp lệ", MarketOrderRequest{Code: "DB1", Qty: 5}, false},
+		{"có maxTotal", MarketOrderRequest{Code: "DB1", Qty: 5, MaxTotal: 1_000}, false},
+		{"giới hạn trên", MarketOrderRequest{Code: "DB1", Qty: 1_000_000, MaxTotal: 1_000_000_000_000_000}, false},
+		{"qty = 0", MarketOrderRequest{Code: "DB1", Qty: 0}, true},
+		{"qty âm", MarketOrderRequest{Code: "DB1", Qty: -5}, true},
+		{"qty quá lớn", MarketOrderRequest{Code: "DB1", Qty: 1_000_001}, true},
+		{"maxTotal âm", MarketOrderRequest{Code: "DB1", Qty: 1, MaxTotal: -1}, true},
+		{"maxTotal quá lớn", MarketOrderRequest{Code: "DB1", Qty: 1, MaxTotal: 1_000_000_000_000_001}, true},
+	}
+	for _, tt := range tests {
+		err := binding.Validator.ValidateStruct(&tt.req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMulFits(t *testing.T) {
+	tests := []struct {
+		a, b int64
+		want bool
+	}{
+		{1, 1, true},
+		{1_000_000, 1_000_000_000_000, true},
+		{math.MaxInt64, 1, true},
+		{math.MaxInt64/2 + 1, 2, false},
+		{3_037_000_500, 3_037_000_500, false}, // > sqrt(MaxInt64)
+		{0, 5, false},
+		{5, -1, false},
+	}
+	for _, tt := range tests {
+		if got := mulFits(tt.a, tt.b); got != tt.want {
+			t.Errorf("mulFits(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMarketListRequestBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     MarketListRequest
+		wantErr bool
+	}{
+		{"hợp lệ", MarketListRequest{Code: "DB1", Qty: 2, PricePerUnit: 100}, false},
+		{"giới hạn trên", MarketListRequest{Code: "DB1", Qty: 1_000_000, PricePerUnit: 1_000_000_000_000}, false},
+		{"qty = 0", MarketListRequest{Code: "DB1", Qty: 0, PricePerUnit: 100}, true},
+		{"qty quá lớn", MarketListRequest{Code: "DB1", Qty: 1_000_001, PricePerUnit: 100}, true},
+		{"giá = 0", MarketListRequest{Code: "DB1", Qty: 1}, true},
+		{"giá quá lớn", MarketListRequest{Code: "DB1", Qty: 1, PricePerUnit: 1_000_000_000_001}, true},
+		{"ttl âm", MarketListRequest{Code: "DB1", Qty: 1, PricePerUnit: 1, TTLHours: -1}, true},
+	}
+	for _, tt := range tests {
+		err := binding.Validator.ValidateStruct(&tt.req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== CHỢ: LỊCH SỬ KHỚP, PHÍ & BIỂU ĐỒ GIÁ ===== */
+
+// nguồn gốc lần khớp
+const (
+	TRADE_DIRECT = "DIRECT" // mua trực tiếp theo listingId
+	TRADE_BID    = "BID"    // khớp với lệnh mua
+	TRADE_SWEEP  = "SWEEP"  // lệnh thị trường (market-order)
+)
+
+// 1 dòng = 1 lần khớp, dùng làm biên nhận cho cả 2 bên và dữ liệu giá
+type MarketTrade struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ListingID    uint      `gorm:"index;not null" json:"listingId"`
+	BidID        *uint     `gorm:"index" json:"bidId,omitempty"`
+	SellerID     uint      `gorm:"index;not null" json:"sellerId"`
+	BuyerID      uint      `gorm:"index;not null" json:"buyerId"`
+	Code         string    `gorm:"size:10;not null;index:idx_trade_code_time,priority:1" json:"code"`
+	Qty          int64     `gorm:"not null" json:"qty"`
+	PricePerUnit int64     `gorm:"not null" json:"pricePerUnit"`
+	Total        int64     `gorm:"not null" json:"total"` // qty * price (người mua trả)
+	Fee          int64     `gorm:"not null" json:"fee"`   // phí người bán trả (vào tài khoản hệ thống)
+	Kind         string    `gorm:"size:10;not null" json:"kind"`
+	CreatedAt    time.Time `gorm:"index:idx_trade_code_time,priority:2" json:"createdAt"`
+}
+
+// phí người bán: ceil(total * bps / 10000)
+func marketSellerFee(total int64) int64 {
+	return bpsFee(total, settingInt("market.seller_fee_bps"))
+}
+
+func bpsFee(total, bps int64) int64 {
+	if bps <= 0 || total <= 0 {
+		return 0
+	}
+	return (total*bps + 9999) / 10000
+}
+
+func recordMarketTrade(tx *gorm.DB, f *MarketFill) error {
+	t := MarketTrade{
+		ListingID: f.ListingID, BidID: f.BidID,
+		SellerID: f.SellerID, BuyerID: f.BuyerID,
+		Code: f.Code, Qty: f.Qty, PricePerUnit: f.PricePerUnit,
+		Total: f.Qty * f.PricePerUnit, Fee: f.Fee, Kind: f.Kind,
+	}
+	if err := tx.Create(&t).Error; err != nil {
+		return err
+	}
+	f.TradeID = t.ID
+	if err := domainEvent(tx, f.BuyerID, DE_MARKET_BUY, 1); err != nil {
+		return err
+	}
+	return domainEvent(tx, f.SellerID, DE_MARKET_SELL, 1)
+}
+
+// biên nhận khớp lệnh nhìn từ phía người dùng
+type MyTradeRow struct {
+	ID           uint      `json:"id"`
+	Side         string    `json:"side"` // "buy" | "sell"
+	Code         string    `json:"code"`
+	Qty          int64     `json:"qty"`
+	PricePerUnit int64     `json:"pricePerUnit"`
+	Total        int64     `json:"total"`
+	Fee          int64     `json:"fee"`
+	Net          int64     `json:"net"` // coin thực nhận (+) / thực trả (-)
+	Kind         string    `json:"kind"`
+	Counterpart  string    `json:"counterpart"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// GET /private/market/trades?side=buy|sell&code=DB1&limit=100
+func myMarketTradesHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	side := strings.ToLower(strings.TrimSpace(c.Query("side")))
+	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	q := DB.Table("market_trades t").
+		Select(`t.id, t.code, t.qty, t.price_per_unit, t.total, t.kind, t.created_at,
+			IF(t.seller_id = ?, 'sell', 'buy') AS side,
+			IF(t.seller_id = ?, t.fee, 0) AS fee,
+			IF(t.seller_id = ?, t.total - t.fee, -t.total) AS net,
+			CASE WHEN t.seller_id = ? THEN b.username ELSE s.username END AS counterpart`,
+			uid, uid, uid, uid).
+		Joins("LEFT JOIN users s ON s.id = t.seller_id").
+		Joins("LEFT JOIN users b ON b.id = t.buyer_id")
+	switch side {
+	case "buy":
+		q = q.Where("t.buyer_id = ?", uid)
+	case "sell":
+		q = q.Where("t.seller_id = ?", uid)
+	default:
+		q = q.Where("t.buyer_id = ? OR t.seller_id = ?", uid, uid)
+	}
+	if code != "" {
+		q = q.Where("t.code = ?", code)
+	}
+
+	rows := []MyTradeRow{}
+	if err := q.Order("t.id DESC").Limit(limit).Scan(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// khung nến hỗ trợ
+var candleIntervals = map[string]time.Duration{
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+type Candle struct {
+	Time   time.Time `json:"time"` // thời điểm mở nến
+	Open   int64     `json:"open"`
+	High   int64     `json:"high"`
+	Low    int64     `json:"low"`
+	Close  int64     `json:"close"`
+	Volume int64     `json:"volume"` // số viên
+	Value  int64     `json:"value"`  // tổng coin
+}
+
+// GET /market/stats?code=DB3&interval=1h&limit=48
+func marketStatsHandler(c *gin.Context) {
+	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
+	if !isTradableCode(code) {
+		respondError(c, apiError(ERR_ITEM_CODE_INVALID))
+		return
+	}
+	interval := c.DefaultQuery("interval", "1h")
+	step, ok := candleIntervals[interval]
+	if !ok {
+		respondError(c, apiError(ERR_INTERVAL_INVALID))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "48"))
+	if limit <= 0 || limit > 500 {
+		limit = 48
+	}
+
+	now := time.Now()
+
+	// giá khớp gần nhất
+	var last MarketTrade
+	var lastPrice *int64
+	if DB.Where("code = ?", code).Order("id DESC").First(&last).Error == nil {
+		lastPrice = &last.PricePerUnit
+	}
+
+	// 24h
+	var day struct {
+		Volume int64
+		Value  int64
+		High   int64
+		Low    int64
+		Trades int64
+	}
+	DB.Model(&MarketTrade{}).
+		Select("COALESCE(SUM(qty),0) AS volume, COALESCE(SUM(total),0) AS value, COALESCE(MAX(price_per_unit),0) AS high, COALESCE(MIN(price_per_unit),0) AS low, COUNT(*) AS trades").
+		Where("code = ? AND created_at >= ?", code, now.Add(-24*time.Hour)).
+		Scan(&day)
+
+	// nến: gom theo khung trong Go (dữ liệu ít, tránh phụ thuộc hàm SQL)
+	start := now.Truncate(step).Add(-time.Duration(limit-1) * step)
+	var trades []MarketTrade
+	DB.Select("price_per_unit, qty, total, created_at").
+		Where("code = ? AND created_at >= ?", code, start).
+		Order("created_at ASC, id ASC").Find(&trades)
+
+	candles := []Candle{}
+	for _, t := range trades {
+		bucket := t.CreatedAt.Truncate(step)
+		n := len(candles)
+		if n == 0 || !candles[n-1].Time.Equal(bucket) {
+			candles = append(candles, Candle{
+				Time: bucket, Open: t.PricePerUnit, High: t.PricePerUnit, Low: t.PricePerUnit,
+			})
+			n++
+		}
+		cd := &candles[n-1]
+		cd.High = max(cd.High, t.PricePerUnit)
+		cd.Low = min(cd.Low, t.PricePerUnit)
+		cd.Close = t.PricePerUnit
+		cd.Volume += t.Qty
+		cd.Value += t.Total
+	}
+
+	c.JSON(200, gin.H{
+		"code":      code,
+		"lastPrice": lastPrice,
+		"volume24h": day.Volume,
+		"value24h":  day.Value,
+		"high24h":   day.High,
+		"low24h":    day.Low,
+		"trades24h": day.Trades,
+		"interval":  interval,
+		"candles":   candles,
+	})
+}
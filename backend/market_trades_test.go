@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestBpsFee(t *testing.T) {
+	tests := []struct {
+		total, bps, want int64
+	}{
+		{0, 200, 0},
+		{-5, 200, 0},
+		{100, 0, 0},
+		{100, -1, 0},
+		{1, 200, 1}, // làm tròn lên
+		{50, 200, 1},
+		{100, 200, 2},
+		{10_000, 200, 200},
+		{10_001, 200, 201},
+		{1_000_000_000_000, 200, 20_000_000_000},
+	}
+	for _, tt := range tests {
+		if got := bpsFee(tt.total, tt.bps); got != tt.want {
+			t.Errorf("bpsFee(%d, %d) = %d, want %d", tt.total, tt.bps, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== NHIỆM VỤ: SỰ KIỆN NGHIỆP VỤ -> TIẾN ĐỘ -> NHẬN THƯỞNG ===== */
+
+// sự kiện nghiệp vụ; handler phát trong CÙNG transaction với hành động gốc (rollback thì không tính)
+const (
+	DE_CHEST_OPEN   = "chest.open"
+	DE_MARKET_LIST  = "market.list"
+	DE_MARKET_BUY   = "market.buy"   // mỗi lần khớp, phía mua
+	DE_MARKET_SELL  = "market.sell"  // mỗi lần khớp, phía bán
+	DE_VIP_BUY      = "vip.buy"      // chính user mua VIP
+	DE_VIP_REFERRAL = "vip.referral" // người được user giới thiệu trực tiếp mua VIP
+	DE_KYC_VERIFIED = "kyc.verified"
+	DE_CHECKIN      = "checkin"
+	DE_TRANSFER     = "transfer"
+)
+
+type DomainEventDef struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// danh sách sự kiện admin chọn được khi tạo nhiệm vụ
+var domainEventDefs = []DomainEventDef{
+	{DE_CHEST_OPEN, "Mở rương"},
+	{DE_MARKET_LIST, "Đăng bán vật phẩm trên chợ"},
+	{DE_MARKET_BUY, "Mua được vật phẩm trên chợ"},
+	{DE_MARKET_SELL, "Bán được vật phẩm trên chợ"},
+	{DE_VIP_BUY, "Mua VIP"},
+	{DE_VIP_REFERRAL, "Người được giới thiệu mua VIP"},
+	{DE_KYC_VERIFIED, "Xác minh KYC"},
+	{DE_CHECKIN, "Điểm danh"},
+	{DE_TRANSFER, "Chuyển coin"},
+}
+
+// bộ theo dõi nhận sự kiện nghiệp vụ; lỗi trả về làm rollback cả hành động gốc
+var domainEventHandlers = []func(tx *gorm.DB, uid uint, typ string, n int64) error{
+	trackMissions,
+}
+
+// Phát sự kiện nghiệp vụ typ (n lần) của user uid tới mọi bộ theo dõi.
+func domainEvent(tx *gorm.DB, uid uint, typ string, n int64) error {
+	if uid == 0 || n <= 0 {
+		return nil
+	}
+	for _, h := range domainEventHandlers {
+		if err := h(tx, uid, typ, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chu kỳ nhiệm vụ (giờ server, giống bảng xếp hạng)
+const (
+	MISSION_DAILY  = "DAILY"  // làm lại mỗi ngày từ 00:00
+	MISSION_WEEKLY = "WEEKLY" // làm lại mỗi tuần từ 00:00 thứ Hai
+	MISSION_ONCE   = "ONCE"   // 1 lần duy nhất
+)
+
+type Mission struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Title       string `gorm:"size:120;not null" json:"title"`
+	Description string `gorm:"size:500" json:"description"`
+	Event       string `gorm:"size:32;not null;index:idx_mission_event,priority:2" json:"event"`
+	Target      int64  `gorm:"not null" json:"target"`
+	Period      string `gorm:"size:10;not null" json:"period"`
+	RewardBundle
+	SortOrder int        `gorm:"not null;default:0" json:"sortOrder"`
+	IsActive  bool       `gorm:"not null;default:true;index:idx_mission_event,priority:1" json:"isActive"`
+	StartsAt  *time.Time `json:"startsAt"` // nil = không giới hạn
+	EndsAt    *time.Time `json:"endsAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// tiến độ của 1 user với 1 nhiệm vụ trong 1 chu kỳ
+type MissionProgress struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;uniqueIndex:uniq_mission_progress,priority:1" json:"-"`
+	MissionID   uint       `gorm:"not null;uniqueIndex:uniq_mission_progress,priority:2" json:"missionId"`
+	PeriodKey   string     `gorm:"size:16;not null;uniqueIndex:uniq_mission_progress,priority:3" json:"periodKey"`
+	Progress    int64      `gorm:"not null;default:0" json:"progress"`
+	CompletedAt *time.Time `json:"completedAt"`
+	ClaimedAt   *time.Time `json:"claimedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// khoá chu kỳ hiện tại & thời điểm làm mới (nil với nhiệm vụ 1 lần)
+func missionPeriod(period string, now time.Time) (string, *time.Time) {
+	switch period {
+	case MISSION_DAILY:
+		from := leaderboardSince(LB_DAY, now)
+		next := from.AddDate(0, 0, 1)
+		return from.Format("2006-01-02"), &next
+	case MISSION_WEEKLY:
+		from := leaderboardSince(LB_WEEK, now)
+		next := from.AddDate(0, 0, 7)
+		return "W" + from.Format("2006-01-02"), &next
+	}
+	return "once", nil
+}
+
+// nhiệm vụ đang mở tại thời điểm now
+func activeMissions(q *gorm.DB, now time.Time) *gorm.DB {
+	return q.Where("is_active = ?", true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now)
+}
+
+// cộng tiến độ mọi nhiệm vụ đang mở theo dõi sự kiện typ
+func trackMissions(tx *gorm.DB, uid uint, typ string, n int64) error {
+	now := time.Now()
+	var ms []Mission
+	if err := activeMissions(tx, now).Where("event = ?", typ).Find(&ms).Error; err != nil {
+		return err
+	}
+	for _, m := range ms {
+		key, _ := missionPeriod(m.Period, now)
+		// 🔒 tạo (nếu chưa có) rồi khoá dòng tiến độ để 2 sự kiện song song không ghi đè nhau
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&MissionProgress{UserID: uid, MissionID: m.ID, PeriodKey: key}).Error; err != nil {
+			return err
+		}
+		var p MissionProgress
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND mission_id = ? AND period_key = ?", uid, m.ID, key).
+			First(&p).Error; err != nil {
+			return err
+		}
+		if p.CompletedAt != nil {
+			continue
+		}
+		up := map[string]any{"progress": min(p.Progress+n, m.Target)}
+		if p.Progress+n >= m.Target {
+			up["completed_at"] = now
+			emitEvent(tx, uid, EV_MISSION_COMPLETED, gin.H{"missionId": m.ID, "title": m.Title})
+		}
+		if err := tx.Model(&p).Updates(up).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseMissionID(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+/* ----- người dùng ----- */
+
+type MissionView struct {
+	Mission
+	PeriodKey string     `json:"periodKey"`
+	Progress  int64      `json:"progress"`
+	Completed bool       `json:"completed"`
+	Claimed   bool       `json:"claimed"`
+	ResetsAt  *time.Time `json:"resetsAt"` // nil = nhiệm vụ 1 lần
+}
+
+// GET /private/missions — nhiệm vụ đang mở kèm tiến độ chu kỳ hiện tại của tôi
+func listMissionsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	now := time.Now()
+
+	var ms []Mission
+	if err := activeMissions(DB, now).Order("sort_order ASC, id ASC").Find(&ms).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	var ps []MissionProgress
+	if len(ms) > 0 {
+		ids := make([]uint, 0, len(ms))
+		keys := []string{}
+		for _, m := range ms {
+			ids = append(ids, m.ID)
+			if key, _ := missionPeriod(m.Period, now); !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+		// chỉ chu kỳ hiện tại, không kéo lịch sử các ngày/tuần trước
+		if err := DB.Where("user_id = ? AND mission_id IN ? AND period_key IN ?", uid, ids, keys).Find(&ps).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	rows := make([]MissionView, 0, len(ms))
+	for _, m := range ms {
+		key, resets := missionPeriod(m.Period, now)
+		v := MissionView{Mission: m, PeriodKey: key, ResetsAt: resets}
+		if i := slices.IndexFunc(ps, func(p MissionProgress) bool { return p.MissionID == m.ID && p.PeriodKey == key }); i >= 0 {
+			p := ps[i]
+			v.Progress = min(p.Progress, m.Target)
+			v.Completed = p.CompletedAt != nil || p.Progress >= m.Target
+			v.Claimed = p.ClaimedAt != nil
+		}
+		rows = append(rows, v)
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// POST /private/missions/:id/claim — nhận thưởng nhiệm vụ đã hoàn thành trong chu kỳ hiện tại
+// (nhiệm vụ ngày/tuần không nhận trước khi làm mới thì mất)
+func claimMissionHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	id, ok := parseMissionID(c)
+	if !ok {
+		return
+	}
+
+	var m Mission
+	if err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.First(&m, id).Error; err != nil {
+			return apiError(ERR_MISSION_NOT_FOUND)
+		}
+		key, _ := missionPeriod(m.Period, time.Now())
+		var p MissionProgress
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND mission_id = ? AND period_key = ?", uid, m.ID, key).
+			First(&p).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apiError(ERR_MISSION_NOT_COMPLETED, "progress", 0, "target", m.Target)
+			}
+			return err
+		}
+		if p.ClaimedAt != nil {
+			return apiError(ERR_MISSION_CLAIMED)
+		}
+		// admin có thể đã hạ target sau khi user đạt tiến độ
+		if p.CompletedAt == nil && p.Progress < m.Target {
+			return apiError(ERR_MISSION_NOT_COMPLETED, "progress", p.Progress, "target", m.Target)
+		}
+		if err := tx.Model(&p).Update("claimed_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return grantReward(tx, uid, m.RewardBundle, LEDGER_MISSION, p.ID)
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã nhận thưởng nhiệm vụ", "reward": m.RewardBundle})
+}
+
+/* ----- admin: nhiệm vụ là dữ liệu ----- */
+
+type MissionRequest struct {
+	Title       string `json:"title" binding:"required,max=120"`
+	Description string `json:"description" binding:"max=500"`
+	Event       string `json:"event" binding:"required"` // xem events trong GET /admin/missions
+	Target      int64  `json:"target" binding:"required,gte=1"`
+	Period      string `json:"period" binding:"required"` // DAILY | WEEKLY | ONCE
+	RewardBundle
+	SortOrder int        `json:"sortOrder"`
+	IsActive  *bool      `json:"isActive"` // mặc định true
+	StartsAt  *time.Time `json:"startsAt"`
+	EndsAt    *time.Time `json:"endsAt"`
+}
+
+func (r *MissionRequest) normalize() error {
+	r.Title = strings.TrimSpace(r.Title)
+	r.Description = strings.TrimSpace(r.Description)
+	r.Event = strings.ToLower(strings.TrimSpace(r.Event))
+	r.Period = strings.ToUpper(strings.TrimSpace(r.Period))
+	if r.Title == "" {
+		return apiError(ERR_MISSION_INVALID, "field", "title")
+	}
+	if !slices.ContainsFunc(domainEventDefs, func(d DomainEventDef) bool { return d.Type == r.Event }) {
+		return apiError(ERR_MISSION_INVALID, "field", "event")
+	}
+	if r.Period != MISSION_DAILY && r.Period != MISSION_WEEKLY && r.Period != MISSION_ONCE {
+		return apiError(ERR_MISSION_INVALID, "field", "period")
+	}
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return apiError(ERR_MISSION_INVALID, "field", "endsAt")
+	}
+	if f := r.invalidField(); f != "" {
+		return apiError(ERR_MISSION_INVALID, "field", f)
+	}
+	return nil
+}
+
+func (r MissionRequest) fields() map[string]any {
+	active := r.IsActive == nil || *r.IsActive
+	return map[string]any{
+		"title": r.Title, "description": r.Description, "event": r.Event,
+		"target": r.Target, "period": r.Period,
+		"coins": r.Coins, "bonus_coins": r.BonusCoins, "free_spins": r.FreeSpins,
+		"item_code": r.ItemCode, "item_qty": r.ItemQty,
+		"sort_order": r.SortOrder, "is_active": active,
+		"starts_at": r.StartsAt, "ends_at": r.EndsAt,
+	}
+}
+
+// GET /admin/missions — mọi nhiệm vụ + danh sách sự kiện dùng được
+func adminListMissionsHandler(c *gin.Context) {
+	var rows []Mission
+	DB.Order("sort_order ASC, id ASC").Find(&rows)
+	c.JSON(200, gin.H{"rows": rows, "events": domainEventDefs})
+}
+
+// POST /admin/missions { title, description, event, target, period, coins, bonusCoins, freeSpins, itemCode, itemQty, sortOrder, isActive, startsAt, endsAt }
+func adminCreateMissionHandler(c *gin.Context) {
+	var req MissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+	m := Mission{
+		Title: req.Title, Description: req.Description, Event: req.Event,
+		Target: req.Target, Period: req.Period, RewardBundle: req.RewardBundle,
+		SortOrder: req.SortOrder, IsActive: req.IsActive == nil || *req.IsActive,
+		StartsAt: req.StartsAt, EndsAt: req.EndsAt,
+	}
+	if err := DB.Create(&m).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã tạo nhiệm vụ", "mission": m})
+}
+
+// PUT /admin/missions/:id — sửa (tiến độ đã có giữ nguyên; isActive=false để tắt thay vì xoá)
+func adminUpdateMissionHandler(c *gin.Context) {
+	id, ok := parseMissionID(c)
+	if !ok {
+		return
+	}
+	var req MissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+	res := DB.Model(&Mission{}).Where("id = ?", id).Updates(req.fields())
+	if res.Error != nil {
+		respondError(c, res.Error)
+		return
+	}
+	var m Mission
+	if err := DB.First(&m, id).Error; err != nil {
+		respondError(c, apiError(ERR_MISSION_NOT_FOUND))
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã cập nhật nhiệm vụ", "mission": m})
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== THÔNG BÁO: MẪU THEO LOẠI EVENT, DANH MỤC & TUỲ CHỌN NGƯỜI DÙNG ===== */
+
+// danh mục thông báo (người dùng tắt được theo danh mục, trừ system)
+const (
+	NOTI_FINANCE  = "finance"
+	NOTI_GAME     = "game"
+	NOTI_REFERRAL = "referral"
+	NOTI_SYSTEM   = "system"
+)
+
+var notificationCategories = []string{NOTI_FINANCE, NOTI_GAME, NOTI_REFERRAL, NOTI_SYSTEM}
+
+// loại thông báo (khoá mẫu)
+const (
+	NT_CHEST_MILESTONE     = "chest.milestone"
+	NT_VIP_INVITE_BONUS    = "vip.invite_bonus"
+	NT_PROMO_REDEEMED      = "promo.redeemed"
+	NT_PROMO_CODES_RENAMED = "promo.codes_renamed" // gửi admin
+	NT_LISTING_EXPIRED     = "market.listing_expired"
+	NT_LISTING_REMOVED     = "market.listing_removed"
+	NT_SEASON_PRIZE        = "season.prize"
+	NT_RECON_DRIFT         = "recon.drift" // gửi admin
+	NT_ACCOUNT_FROZEN      = "account.frozen"
+	NT_DATA_EXPORT_READY   = "account.data_export_ready"
+	NT_CLOSE_REQUEST       = "account.close_request" // gửi admin
+	NT_CLOSE_REJECTED      = "account.close_rejected"
+	NT_ACCOUNT_STATUS      = "account.status"
+	NT_ACCOUNT_RESTRICTED  = "account.restricted"
+	NT_RESTRICTION_LIFTED  = "account.restriction_lifted"
+	NT_ADMIN_BROADCAST     = "admin.broadcast" // nội dung do admin soạn, không dùng mẫu
+)
+
+// giới hạn cột notifications.title / body
+const (
+	notificationTitleMax = 120
+	notificationBodyMax  = 500
+)
+
+type notificationText struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type notificationDef struct {
+	Category string
+	Texts    map[string]notificationText // locale -> mẫu
+}
+
+// Mẫu mặc định. Biến dạng {ten_bien}, admin có thể ghi đè theo từng ngôn ngữ.
+var notificationDefs = map[string]notificationDef{
+	NT_CHEST_MILESTONE: {NOTI_GAME, map[string]notificationText{
+		"vi": {"Chúc mừng đạt mốc mở rương!", "Bạn đạt {count} lượt mở rương và nhận {reward} coin thưởng."},
+		"en": {"Chest milestone reached!", "You reached {count} chest opens and received {reward} coins."},
+	}},
+	NT_VIP_INVITE_BONUS: {NOTI_REFERRAL, map[string]notificationText{
+		"vi": {"Thưởng mốc mời bạn VIP", "Bạn đã có {count} người mua VIP trực tiếp. Thưởng +{reward} coin."},
+		"en": {"VIP referral milestone", "{count} of your direct referrals bought VIP. Reward +{reward} coins."},
+	}},
+	NT_PROMO_REDEEMED: {NOTI_GAME, map[string]notificationText{
+		"vi": {"Nhận quà gift code", "Bạn đã nhập code {code} ({campaign}). Phần thưởng đã được cộng vào tài khoản."},
+		"en": {"Gift code redeemed", "You redeemed code {code} ({campaign}). Your reward has been credited to your account."},
+	}},
+	NT_PROMO_CODES_RENAMED: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Code cũ bị đổi tên", "{count} code {source} trùng code khác khi chuyển sang chiến dịch (không phân biệt hoa/thường) nên đã được đổi tên: {codes}"},
+		"en": {"Legacy codes renamed", "{count} {source} codes collided with other codes during migration (codes are case-insensitive) and were renamed: {codes}"},
+	}},
+	NT_LISTING_EXPIRED: {NOTI_FINANCE, map[string]notificationText{
+		"vi": {"Bài đăng đã hết hạn", "Bài đăng #{id} ({code}) đã hết hạn, {qty} vật phẩm đã được trả về túi."},
+		"en": {"Listing expired", "Listing #{id} ({code}) has expired, {qty} items were returned to your inventory."},
+	}},
+	NT_LISTING_REMOVED: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Bài đăng bị gỡ", "Bài đăng #{id} ({code}) đã bị quản trị viên gỡ: {reason}. {qty} vật phẩm đã được trả về túi."},
+		"en": {"Listing removed", "Listing #{id} ({code}) was removed by an administrator: {reason}. {qty} items were returned to your inventory."},
+	}},
+	NT_SEASON_PRIZE: {NOTI_GAME, map[string]notificationText{
+		"vi": {"Thưởng mùa giải {season}", "Bạn đạt hạng #{rank} ({score} điểm). Phần thưởng đã được cộng vào tài khoản."},
+		"en": {"{season} season reward", "You finished #{rank} ({score} points). Your reward has been credited to your account."},
+	}},
+	NT_RECON_DRIFT: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Số dư lệch sổ cái", "User {username} (#{userId}) lệch {drift} {currency} (sổ cái {expected}, thực tế {actual}). Xem lệch #{id}."},
+		"en": {"Balance drift detected", "User {username} (#{userId}) is off by {drift} {currency} (ledger {expected}, actual {actual}). See drift #{id}."},
+	}},
+	NT_ACCOUNT_FROZEN: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Tài khoản tạm khoá", "Số dư tài khoản của bạn đang được kiểm tra nên tạm thời chỉ xem được. Vui lòng liên hệ hỗ trợ."},
+		"en": {"Account temporarily frozen", "Your balance is under review, so your account is read-only for now. Please contact support."},
+	}},
+	NT_DATA_EXPORT_READY: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Dữ liệu của bạn đã sẵn sàng", "Tải file dữ liệu cá nhân tại {url} (hết hạn lúc {expiresAt})."},
+		"en": {"Your data export is ready", "Download your personal data at {url} (link expires at {expiresAt})."},
+	}},
+	NT_CLOSE_REQUEST: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Yêu cầu đóng tài khoản", "User #{userId} yêu cầu đóng tài khoản và chi trả {coins} coin (yêu cầu #{id})."},
+		"en": {"Account closure request", "User #{userId} asked to close their account with a payout of {coins} coins (request #{id})."},
+	}},
+	NT_CLOSE_REJECTED: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Yêu cầu đóng tài khoản bị từ chối", "Tài khoản của bạn đã được mở khoá. Lý do: {note}"},
+		"en": {"Account closure request rejected", "Your account has been unlocked. Reason: {note}"},
+	}},
+	NT_ACCOUNT_STATUS: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Trạng thái tài khoản: {status}", "Tài khoản của bạn chuyển sang {status} (ACTIVE: bình thường, FROZEN: chỉ xem, BANNED: không dùng được) tới {until}. Lý do: {reason}"},
+		"en": {"Account status: {status}", "Your account is now {status} (ACTIVE: normal, FROZEN: read-only, BANNED: no access) until {until}. Reason: {reason}"},
+	}},
+	NT_ACCOUNT_RESTRICTED: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Tài khoản bị hạn chế {kind}", "Bạn tạm thời không dùng được chức năng {kind} tới {until}. Lý do: {reason}"},
+		"en": {"Account restricted: {kind}", "You cannot use {kind} until {until}. Reason: {reason}"},
+	}},
+	NT_RESTRICTION_LIFTED: {NOTI_SYSTEM, map[string]notificationText{
+		"vi": {"Đã gỡ hạn chế {kind}", "Bạn có thể dùng lại chức năng {kind}."},
+		"en": {"Restriction lifted: {kind}", "You can use {kind} again."},
+	}},
+}
+
+// bản ghi đè mẫu của admin
+type NotificationTemplate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Type      string    `gorm:"size:40;not null;uniqueIndex:uniq_tpl_type_locale" json:"type"`
+	Locale    string    `gorm:"size:8;not null;uniqueIndex:uniq_tpl_type_locale" json:"locale"`
+	Title     string    `gorm:"size:120;not null" json:"title"`
+	Body      string    `gorm:"size:500;not null" json:"body"`
+	UpdatedBy uint      `json:"updatedBy"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// tuỳ chọn thông báo của user (không có dòng => mặc định)
+type NotificationPref struct {
+	UserID    uint      `gorm:"primaryKey" json:"-"`
+	Locale    string    `gorm:"size:8;not null;default:'vi'" json:"locale"`
+	Muted     string    `gorm:"size:100" json:"-"` // danh mục bị tắt, phân tách bằng dấu phẩy
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (p NotificationPref) mutedList() []string {
+	out := []string{}
+	for _, s := range strings.Split(p.Muted, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func loadNotificationPref(tx *gorm.DB, uid uint) NotificationPref {
+	p := NotificationPref{UserID: uid, Locale: supportedLocales[0]}
+	_ = tx.Where("user_id = ?", uid).Limit(1).Find(&p).Error
+	if !slices.Contains(supportedLocales, p.Locale) {
+		p.Locale = supportedLocales[0]
+	}
+	return p
+}
+
+// mẫu đang dùng: bản admin ghi đè > mặc định đúng ngôn ngữ > mặc định tiếng Việt
+func notificationTemplateFor(tx *gorm.DB, typ, locale string) (notificationText, error) {
+	def, ok := notificationDefs[typ]
+	if !ok {
+		return notificationText{}, fmt.Errorf("unknown notification type %q", typ)
+	}
+	var o NotificationTemplate
+	if tx.Where("type = ? AND locale = ?", typ, locale).Limit(1).Find(&o).Error == nil && o.ID != 0 {
+		return notificationText{o.Title, o.Body}, nil
+	}
+	if t, ok := def.Texts[locale]; ok {
+		return t, nil
+	}
+	return def.Texts[supportedLocales[0]], nil
+}
+
+func renderNotificationText(s string, vars map[string]any, maxLen int) string {
+	s = fillPlaceholders(s, vars)
+	if r := []rune(s); len(r) > maxLen {
+		s = string(r[:maxLen])
+	}
+	return s
+}
+
+// Tạo thông báo theo mẫu & ngôn ngữ của user; bỏ qua nếu user đã tắt danh mục đó.
+func notify(tx *gorm.DB, uid uint, typ string, vars map[string]any) error {
+	pref := loadNotificationPref(tx, uid)
+	cat := notificationDefs[typ].Category
+	if cat != NOTI_SYSTEM && slices.Contains(pref.mutedList(), cat) {
+		return nil
+	}
+	t, err := notificationTemplateFor(tx, typ, pref.Locale)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&Notification{
+		UserID:   uid,
+		Type:     typ,
+		Category: cat,
+		Title:    renderNotificationText(t.Title, vars, notificationTitleMax),
+		Body:     renderNotificationText(t.Body, vars, notificationBodyMax),
+	}).Error
+}
+
+/* ----- người dùng ----- */
+
+func notificationIDParam(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// DELETE /private/notifications/:id
+func deleteNotificationHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	id, ok := notificationIDParam(c)
+	if !ok {
+		return
+	}
+	res := DB.Where("id = ? AND user_id = ?", id, uid).Delete(&Notification{})
+	if res.Error != nil {
+		respondError(c, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		respondError(c, apiError(ERR_NOTIFICATION_NOT_FOUND))
+		return
+	}
+	c.Status(204)
+}
+
+// POST /private/notifications/:id/archive (lưu trữ = ẩn khỏi hộp thư & coi như đã đọc)
+// POST /private/notifications/:id/unarchive
+func archiveNotificationHandler(archive bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+		id, ok := notificationIDParam(c)
+		if !ok {
+			return
+		}
+		var n Notification
+		if err := DB.Select("id").Where("id = ? AND user_id = ?", id, uid).First(&n).Error; err != nil {
+			respondError(c, apiError(ERR_NOTIFICATION_NOT_FOUND))
+			return
+		}
+		up := map[string]any{"archived_at": nil}
+		if archive {
+			up = map[string]any{"archived_at": time.Now(), "is_read": true}
+		}
+		if err := DB.Model(&n).Updates(up).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+		c.Status(204)
+	}
+}
+
+// GET /private/notification-preferences
+func getNotificationPrefsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	p := loadNotificationPref(DB, uid)
+	c.JSON(200, gin.H{
+		"locale":     p.Locale,
+		"muted":      p.mutedList(),
+		"categories": notificationCategories,
+		"locales":    supportedLocales,
+	})
+}
+
+type NotificationPrefRequest struct {
+	Locale *string   `json:"locale"`
+	Muted  *[]string `json:"muted"`
+}
+
+// PUT /private/notification-preferences { locale?, muted?: ["game", ...] }
+func updateNotificationPrefsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req NotificationPrefRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apiError(ERR_INVALID_INPUT))
+		return
+	}
+
+	p := loadNotificationPref(DB, uid)
+	if req.Locale != nil {
+		loc := strings.ToLower(strings.TrimSpace(*req.Locale))
+		if !slices.Contains(supportedLocales, loc) {
+			respondError(c, apiError(ERR_LOCALE_UNSUPPORTED))
+			return
+		}
+		p.Locale = loc
+	}
+	if req.Muted != nil {
+		muted := []string{}
+		for _, m := range *req.Muted {
+			m = strings.ToLower(strings.TrimSpace(m))
+			if m == NOTI_SYSTEM {
+				respondError(c, apiError(ERR_CATEGORY_NOT_MUTABLE))
+				return
+			}
+			if !slices.Contains(notificationCategories, m) {
+				respondError(c, apiError(ERR_CATEGORY_INVALID, "category", m))
+				return
+			}
+			if !slices.Contains(muted, m) {
+				muted = append(muted, m)
+			}
+		}
+		p.Muted = strings.Join(muted, ",")
+	}
+
+	if err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"locale", "muted", "updated_at"}),
+	}).Create(&p).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã lưu tuỳ chọn thông báo", "locale": p.Locale, "muted": p.mutedList()})
+}
+
+/* ----- admin ----- */
+
+type NotificationTemplateRow struct {
+	Type      string                      `json:"type"`
+	Category  string                      `json:"category"`
+	Defaults  map[string]notificationText `json:"defaults"`
+	Overrides []NotificationTemplate      `json:"overrides"`
+}
+
+// GET /admin/notification-templates
+func adminListNotificationTemplatesHandler(c *gin.Context) {
+	var overrides []NotificationTemplate
+	DB.Find(&overrides)
+
+	rows := []NotificationTemplateRow{}
+	for typ, def := range notificationDefs {
+		r := NotificationTemplateRow{Type: typ, Category: def.Category, Defaults: def.Texts, Overrides: []NotificationTemplate{}}
+		for _, o := range overrides {
+			if o.Type == typ {
+				r.Overrides = append(r.Overrides, o)
+			}
+		}
+		rows = append(rows, r)
+	}
+	slices.SortFunc(rows, func(a, b NotificationTemplateRow) int { return strings.Compare(a.Type, b.Type) })
+	c.JSON(200, gin.H{"rows": rows, "locales": supportedLocales})
+}
+
+type NotificationTemplateRequest struct {
+	Type   string `json:"type"`
+	Locale string `json:"locale"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// PUT /admin/notification-templates { type, locale, title, body }
+func adminUpsertNotificationTemplateHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req NotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apiError(ERR_INVALID_INPUT))
+		return
+	}
+	req.Type = strings.TrimSpace(req.Type)
+	req.Locale = strings.ToLower(strings.TrimSpace(req.Locale))
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	if _, ok := notificationDefs[req.Type]; !ok {
+		respondError(c, apiError(ERR_TEMPLATE_TYPE_UNKNOWN))
+		return
+	}
+	if !slices.Contains(supportedLocales, req.Locale) {
+		respondError(c, apiError(ERR_LOCALE_UNSUPPORTED))
+		return
+	}
+	if req.Title == "" || req.Body == "" ||
+		len([]rune(req.Title)) > notificationTitleMax || len([]rune(req.Body)) > notificationBodyMax {
+		respondError(c, apiError(ERR_CONTENT_TOO_LONG))
+		return
+	}
+
+	t := NotificationTemplate{Type: req.Type, Locale: req.Locale, Title: req.Title, Body: req.Body, UpdatedBy: adminID}
+	if err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "type"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "body", "updated_by", "updated_at"}),
+	}).Create(&t).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã lưu mẫu thông báo"})
+}
+
+// DELETE /admin/notification-templates?type=&locale= (quay về mẫu mặc định)
+func adminResetNotificationTemplateHandler(c *gin.Context) {
+	res := DB.Where("type = ? AND locale = ?", c.Query("type"), strings.ToLower(c.Query("locale"))).
+		Delete(&NotificationTemplate{})
+	if res.Error != nil {
+		respondError(c, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		respondError(c, apiError(ERR_TEMPLATE_NOT_OVERRIDDEN))
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã khôi phục mẫu mặc định"})
+}
@@ -0,0 +1,710 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ===== OPENAPI 3: TÀI LIỆU API, KIỂM TRA REQUEST THEO SPEC ===== */
+
+// Mỗi route đăng ký trong main() phải có 1 apiRoute tương ứng (xem apiRoutes trong
+// openapi_routes.go). Schema body/response sinh từ chính các DTO mà handler dùng,
+// nên đổi DTO là spec, middleware kiểm tra & client TS đổi theo.
+
+type apiSchema struct {
+	Ref                  string                `json:"$ref,omitempty"`
+	Type                 string                `json:"type,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Description          string                `json:"description,omitempty"`
+	Nullable             bool                  `json:"nullable,omitempty"`
+	Enum                 []string              `json:"enum,omitempty"`
+	Minimum              *int64                `json:"minimum,omitempty"`
+	Maximum              *int64                `json:"maximum,omitempty"`
+	MinLength            *int                  `json:"minLength,omitempty"`
+	MaxLength            *int                  `json:"maxLength,omitempty"`
+	Items                *apiSchema            `json:"items,omitempty"`
+	Properties           map[string]*apiSchema `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	AdditionalProperties *apiSchema            `json:"additionalProperties,omitempty"`
+
+	order []string // thứ tự field như trong struct (json map không giữ thứ tự)
+}
+
+type apiParam struct {
+	Name        string     `json:"name"`
+	In          string     `json:"in"` // query | path
+	Required    bool       `json:"required,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Schema      *apiSchema `json:"schema"`
+}
+
+// khai báo 1 route cho spec
+type apiRoute struct {
+	Method     string
+	Path       string // kiểu gin (:id), spec tự đổi thành {id}
+	ID         string // operationId, cũng là tên hàm trong client TS
+	Tag        string
+	Summary    string
+	Auth       string     // "" (public) | "user" | "admin"
+	Query      []apiParam // dùng qInt / qStr / qFlag
+	Body       any        // DTO của JSON body (giá trị rỗng), nil => không có body
+	Form       []apiParam // field multipart/form-data (file: schema binary)
+	Status     int        // mặc định 200
+	Resp       any        // mẫu response: DTO / gin.H (dùng optional() cho key có thể vắng), nil => không có body
+	Produces   string     // mặc định application/json
+	Deprecated bool       // route alias giữ cho FE cũ
+}
+
+const (
+	mimeJSON   = "application/json"
+	mimeSSE    = "text/event-stream"
+	mimeBinary = "application/octet-stream"
+)
+
+// đánh dấu key có thể không có trong response gin.H
+type optionalField struct{ v any }
+
+func optional(v any) optionalField { return optionalField{v} }
+
+func qInt(name, desc string) apiParam {
+	return apiParam{Name: name, In: "query", Description: desc, Schema: &apiSchema{Type: "integer", Format: "int64"}}
+}
+
+func qStr(name, desc string, enum ...string) apiParam {
+	return apiParam{Name: name, In: "query", Description: desc, Schema: &apiSchema{Type: "string", Enum: enum}}
+}
+
+// cờ dạng ?x=1
+func qFlag(name, desc string) apiParam {
+	return qStr(name, desc, "0", "1")
+}
+
+func fileField(name string) apiParam {
+	return apiParam{Name: name, Required: true, Schema: &apiSchema{Type: "string", Format: "binary"}}
+}
+
+func formField(name string, required bool) apiParam {
+	return apiParam{Name: name, Required: required, Schema: &apiSchema{Type: "string"}}
+}
+
+/* ----- sinh schema từ kiểu Go ----- */
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type specBuilder struct {
+	components map[string]*apiSchema
+}
+
+func refSchema(name string) *apiSchema {
+	return &apiSchema{Ref: "#/components/schemas/" + name}
+}
+
+// tên component: tên kiểu Go, viết hoa chữ đầu (kiểu unexported vẫn dùng được)
+func componentName(t reflect.Type) string {
+	n := t.Name()
+	return strings.ToUpper(n[:1]) + n[1:]
+}
+
+// request = true: field bắt buộc theo tag binding:"required";
+// request = false (response): field bắt buộc nếu không có omitempty.
+func (b *specBuilder) typeSchema(t reflect.Type, request bool) *apiSchema {
+	if t == timeType {
+		return &apiSchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := *b.typeSchema(t.Elem(), request)
+		s.Nullable = true
+		return &s
+	case reflect.Bool:
+		return &apiSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &apiSchema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &apiSchema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := int64(0)
+		return &apiSchema{Type: "integer", Format: "int64", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &apiSchema{Type: "number"}
+	case reflect.String:
+		return &apiSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &apiSchema{Type: "array", Items: b.typeSchema(t.Elem(), request)}
+	case reflect.Map:
+		return &apiSchema{Type: "object", AdditionalProperties: b.typeSchema(t.Elem(), request)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t, request)
+		}
+		name := componentName(t)
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = &apiSchema{} // giữ chỗ, tránh đệ quy vô hạn
+			*b.components[name] = *b.structSchema(t, request)
+		}
+		return refSchema(name)
+	}
+	return &apiSchema{} // interface{}: kiểu bất kỳ
+}
+
+type specField struct {
+	name     string
+	depth    int
+	schema   *apiSchema
+	required bool
+}
+
+// gom field theo luật encoding/json: field nhúng được làm phẳng, field nông hơn thắng
+func (b *specBuilder) collectFields(t reflect.Type, request bool, depth int, out *[]specField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.collectFields(ft, request, depth+1, out)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := b.typeSchema(ft, request)
+		omitempty := strings.Contains(opts, "omitempty")
+		if omitempty && ft.Kind() == reflect.Pointer {
+			s.Nullable = false // nil bị bỏ hẳn khỏi JSON, không bao giờ là null
+		}
+		required := !request && !omitempty
+		if binding := f.Tag.Get("binding"); binding != "" {
+			if s.Ref == "" {
+				copied := *s
+				s = &copied
+			}
+			if applyBinding(s, binding) {
+				required = true
+			}
+		}
+		idx := slices.IndexFunc(*out, func(x specField) bool { return x.name == name })
+		switch {
+		case idx < 0:
+			*out = append(*out, specField{name, depth, s, required})
+		case (*out)[idx].depth > depth:
+			(*out)[idx] = specField{name, depth, s, required}
+		}
+	}
+}
+
+func (b *specBuilder) structSchema(t reflect.Type, request bool) *apiSchema {
+	var fields []specField
+	b.collectFields(t, request, 0, &fields)
+	s := &apiSchema{Type: "object", Properties: map[string]*apiSchema{}}
+	for _, f := range fields {
+		s.Properties[f.name] = f.schema
+		s.order = append(s.order, f.name)
+		if f.required {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return s
+}
+
+// dịch tag binding (validator của gin) sang ràng buộc schema, trả về true nếu required
+func applyBinding(s *apiSchema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		n, _ := strconv.ParseInt(v, 10, 64)
+		isString := s.Type == "string"
+		switch k {
+		case "required":
+			required = true
+		case "gt":
+			n++
+			s.Minimum = &n
+		case "gte":
+			s.Minimum = &n
+		case "lt":
+			n--
+			s.Maximum = &n
+		case "lte":
+			s.Maximum = &n
+		case "min", "max", "len":
+			if isString {
+				l := int(n)
+				if k != "max" {
+					s.MinLength = &l
+				}
+				if k != "min" {
+					s.MaxLength = &l
+				}
+			} else if k == "max" {
+				s.Maximum = &n
+			} else {
+				s.Minimum = &n
+			}
+		case "oneof":
+			s.Enum = strings.Fields(v)
+		}
+	}
+	return required
+}
+
+// schema của mẫu response
+func (b *specBuilder) sampleSchema(v any) *apiSchema {
+	h, ok := v.(gin.H)
+	if !ok {
+		return b.typeSchema(reflect.TypeOf(v), false)
+	}
+	s := &apiSchema{Type: "object", Properties: map[string]*apiSchema{}}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		val := h[k]
+		opt, isOpt := val.(optionalField)
+		if isOpt {
+			val = opt.v
+		}
+		if val == nil {
+			s.Properties[k] = &apiSchema{}
+		} else {
+			s.Properties[k] = b.sampleSchema(val)
+		}
+		s.order = append(s.order, k)
+		if !isOpt {
+			s.Required = append(s.Required, k)
+		}
+	}
+	return s
+}
+
+/* ----- tài liệu OpenAPI ----- */
+
+type apiOperation struct {
+	route      apiRoute
+	pathParams []apiParam
+	body       *apiSchema // JSON body
+	resp       *apiSchema
+}
+
+type apiSpecDoc struct {
+	components map[string]*apiSchema
+	ops        []*apiOperation
+	byRoute    map[string]*apiOperation // "METHOD /gin/path"
+	json       []byte
+}
+
+var (
+	apiSpecOnce sync.Once
+	apiSpecVal  *apiSpecDoc
+)
+
+func apiSpec() *apiSpecDoc {
+	apiSpecOnce.Do(func() { apiSpecVal = buildAPISpec(apiRoutes) })
+	return apiSpecVal
+}
+
+// "/admin/kyc-file/:userId/:side" => "/admin/kyc-file/{userId}/{side}" + danh sách tham số path
+func openAPIPath(p string) (string, []apiParam) {
+	var params []apiParam
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, ":") {
+			continue
+		}
+		name := part[1:]
+		s := &apiSchema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "Id") {
+			one := int64(1)
+			s = &apiSchema{Type: "integer", Format: "int64", Minimum: &one}
+		}
+		if name == "side" {
+			s.Enum = []string{"front", "back"}
+		}
+		params = append(params, apiParam{Name: name, In: "path", Required: true, Schema: s})
+		parts[i] = "{" + name + "}"
+	}
+	return strings.Join(parts, "/"), params
+}
+
+func buildAPISpec(routes []apiRoute) *apiSpecDoc {
+	b := &specBuilder{components: map[string]*apiSchema{}}
+	doc := &apiSpecDoc{components: b.components, byRoute: map[string]*apiOperation{}}
+
+	paths := map[string]map[string]any{}
+	for _, r := range routes {
+		op := &apiOperation{route: r}
+		path, pathParams := openAPIPath(r.Path)
+		op.pathParams = pathParams
+		if r.Body != nil {
+			op.body = b.typeSchema(reflect.TypeOf(r.Body), true)
+		}
+		if r.Resp != nil {
+			op.resp = b.sampleSchema(r.Resp)
+		}
+		doc.ops = append(doc.ops, op)
+		doc.byRoute[r.Method+" "+r.Path] = op
+
+		o := map[string]any{
+			"operationId": r.ID,
+			"tags":        []string{r.Tag},
+			"summary":     r.Summary,
+		}
+		if r.Deprecated {
+			o["deprecated"] = true
+		}
+		if params := append(slices.Clone(pathParams), r.Query...); len(params) > 0 {
+			o["parameters"] = params
+		}
+		switch {
+		case op.body != nil:
+			o["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{mimeJSON: map[string]any{"schema": op.body}},
+			}
+		case len(r.Form) > 0:
+			form := &apiSchema{Type: "object", Properties: map[string]*apiSchema{}}
+			for _, f := range r.Form {
+				form.Properties[f.Name] = f.Schema
+				if f.Required {
+					form.Required = append(form.Required, f.Name)
+				}
+			}
+			o["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"multipart/form-data": map[string]any{"schema": form}},
+			}
+		}
+		if r.Auth != "" {
+			o["security"] = []map[string][]string{{"bearerAuth": {}}}
+		}
+
+		status := r.Status
+		if status == 0 {
+			status = 200
+		}
+		ok := map[string]any{"description": "OK"}
+		switch {
+		case r.Produces == mimeSSE:
+			ok["content"] = map[string]any{mimeSSE: map[string]any{"schema": &apiSchema{Type: "string"}}}
+		case r.Produces == mimeBinary:
+			ok["content"] = map[string]any{mimeBinary: map[string]any{"schema": &apiSchema{Type: "string", Format: "binary"}}}
+		case op.resp != nil:
+			ok["content"] = map[string]any{mimeJSON: map[string]any{"schema": op.resp}}
+		default:
+			status = 204
+			ok["description"] = "No Content"
+		}
+		o["responses"] = map[string]any{
+			strconv.Itoa(status): ok,
+			"default": map[string]any{
+				"description": "Lỗi (xem code)",
+				"content":     map[string]any{mimeJSON: map[string]any{"schema": refSchema("Error")}},
+			},
+		}
+
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(r.Method)] = o
+	}
+
+	codes := make([]string, 0, len(errorDefs))
+	for code := range errorDefs {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	b.components["Error"] = &apiSchema{
+		Type: "object",
+		Properties: map[string]*apiSchema{
+			"error":  {Type: "string", Description: "thông báo đã dịch theo Accept-Language / ?lang="},
+			"code":   {Type: "string", Enum: codes},
+			"params": {Type: "object", AdditionalProperties: &apiSchema{}},
+		},
+		Required: []string{"error", "code"},
+		order:    []string{"error", "code", "params"},
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "Trade API", "version": "1.0.0"},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	})
+	if err != nil {
+		panic(err) // chỉ xảy ra khi apiRoutes khai báo sai kiểu
+	}
+	doc.json = raw
+	return doc
+}
+
+// GET /openapi.json
+func openAPIHandler(c *gin.Context) {
+	c.Data(200, "application/json; charset=utf-8", apiSpec().json)
+}
+
+// so khớp route thật của gin với apiRoutes (bỏ qua static file)
+func checkAPISpec(routes gin.RoutesInfo) error {
+	spec := apiSpec()
+	var missing, stale []string
+	registered := map[string]bool{}
+	for _, r := range routes {
+		if strings.Contains(r.Path, "*") {
+			continue
+		}
+		key := r.Method + " " + r.Path
+		registered[key] = true
+		if spec.byRoute[key] == nil {
+			missing = append(missing, key)
+		}
+	}
+	for key := range spec.byRoute {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	if len(missing)+len(stale) == 0 {
+		return nil
+	}
+	slices.Sort(missing)
+	slices.Sort(stale)
+	return fmt.Errorf("openapi lệch với router: thiếu spec %v, spec thừa %v", missing, stale)
+}
+
+/* ----- kiểm tra request theo spec ----- */
+
+func (d *apiSpecDoc) resolve(s *apiSchema) *apiSchema {
+	for s != nil && s.Ref != "" {
+		nullable := s.Nullable
+		s = d.components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if nullable && s != nil && !s.Nullable {
+			c := *s
+			c.Nullable = true
+			s = &c
+		}
+	}
+	return s
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// kiểm tra giá trị JSON (đã decode với UseNumber) theo schema
+func (d *apiSpecDoc) checkValue(field string, v any, s *apiSchema) *AppError {
+	s = d.resolve(s)
+	if s == nil || (s.Type == "" && s.Ref == "") {
+		return nil
+	}
+	bad := func(detail string) *AppError {
+		return apiError(ERR_INVALID_INPUT, "field", field, "detail", detail)
+	}
+	if v == nil {
+		if s.Nullable {
+			return nil
+		}
+		return bad("không được null")
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return bad("phải là object")
+		}
+		for _, k := range s.Required {
+			if val, has := obj[k]; !has || val == nil {
+				return apiError(ERR_INVALID_INPUT, "field", joinField(field, k), "detail", "bắt buộc")
+			}
+		}
+		for k, val := range obj {
+			ps, known := s.Properties[k]
+			if !known {
+				if s.AdditionalProperties == nil {
+					continue // field lạ: bỏ qua như encoding/json
+				}
+				ps = s.AdditionalProperties
+			}
+			// field không bắt buộc gửi null = coi như không gửi
+			if val == nil && !slices.Contains(s.Required, k) {
+				continue
+			}
+			if err := d.checkValue(joinField(field, k), val, ps); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return bad("phải là mảng")
+		}
+		for i, item := range arr {
+			if err := d.checkValue(fmt.Sprintf("%s[%d]", field, i), item, s.Items); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return bad("phải là chuỗi")
+		}
+		return d.checkString(field, str, s)
+	case "integer":
+		num, ok := v.(json.Number)
+		if !ok {
+			return bad("phải là số nguyên")
+		}
+		n, err := strconv.ParseInt(num.String(), 10, 64)
+		if err != nil {
+			return bad("phải là số nguyên")
+		}
+		return checkRange(field, n, s)
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return bad("phải là số")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return bad("phải là true/false")
+		}
+	}
+	return nil
+}
+
+func (d *apiSpecDoc) checkString(field, str string, s *apiSchema) *AppError {
+	bad := func(detail string) *AppError {
+		return apiError(ERR_INVALID_INPUT, "field", field, "detail", detail)
+	}
+	n := len([]rune(str))
+	if s.MinLength != nil && n < *s.MinLength {
+		return bad(fmt.Sprintf("tối thiểu %d ký tự", *s.MinLength))
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		return bad(fmt.Sprintf("tối đa %d ký tự", *s.MaxLength))
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+		return bad("chỉ nhận " + strings.Join(s.Enum, "|"))
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return bad("thời gian phải theo RFC3339")
+		}
+	}
+	return nil
+}
+
+func checkRange(field string, n int64, s *apiSchema) *AppError {
+	if s.Minimum != nil && n < *s.Minimum {
+		return apiError(ERR_INVALID_INPUT, "field", field, "detail", fmt.Sprintf("phải >= %d", *s.Minimum))
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		return apiError(ERR_INVALID_INPUT, "field", field, "detail", fmt.Sprintf("phải <= %d", *s.Maximum))
+	}
+	return nil
+}
+
+// tham số path/query (luôn là chuỗi)
+func (d *apiSpecDoc) checkParam(p apiParam, raw string) *AppError {
+	if p.Schema.Type == "integer" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			if p.In == "path" {
+				return apiError(ERR_INVALID_ID)
+			}
+			return apiError(ERR_INVALID_INPUT, "field", p.Name, "detail", "phải là số nguyên")
+		}
+		if p.In == "path" && p.Schema.Minimum != nil && n < *p.Schema.Minimum {
+			return apiError(ERR_INVALID_ID)
+		}
+		return checkRange(p.Name, n, p.Schema)
+	}
+	return d.checkString(p.Name, raw, p.Schema)
+}
+
+func (d *apiSpecDoc) checkRequest(c *gin.Context, op *apiOperation) *AppError {
+	for _, p := range op.pathParams {
+		if err := d.checkParam(p, c.Param(p.Name)); err != nil {
+			return err
+		}
+	}
+	for _, p := range op.route.Query {
+		raw, has := c.GetQuery(p.Name)
+		if !has || raw == "" {
+			if p.Required {
+				return apiError(ERR_INVALID_INPUT, "field", p.Name, "detail", "bắt buộc")
+			}
+			continue
+		}
+		if err := d.checkParam(p, raw); err != nil {
+			return err
+		}
+	}
+	if op.body == nil || c.Request.Body == nil {
+		return nil
+	}
+	raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20))
+	if err != nil {
+		return apiError(ERR_INVALID_INPUT, "detail", "body quá lớn")
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	if len(bytes.TrimSpace(raw)) == 0 {
+		raw = []byte("{}") // body rỗng: chỉ lỗi nếu có field bắt buộc
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return apiError(ERR_INVALID_INPUT, "detail", "JSON không hợp lệ")
+	}
+	if err := d.checkValue("", v, op.body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// middleware kiểm tra path/query/body theo spec; gắn sau authRequired để 401 được ưu tiên
+func validateRequest() gin.HandlerFunc {
+	spec := apiSpec()
+	return func(c *gin.Context) {
+		op := spec.byRoute[c.Request.Method+" "+c.FullPath()]
+		if op == nil {
+			c.Next()
+			return
+		}
+		if err := spec.checkRequest(c, op); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
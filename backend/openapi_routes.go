@@ -0,0 +1,441 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ===== DANH SÁCH ROUTE CHO OPENAPI ===== */
+
+// Thêm route trong main() thì phải thêm dòng tương ứng ở đây,
+// nếu không server sẽ dừng khi khởi động (checkAPISpec).
+
+var apiRoutes = []apiRoute{
+	{Method: "GET", Path: "/openapi.json", ID: "openapi", Tag: "meta", Summary: "Tài liệu OpenAPI 3 (file này)",
+		Resp: map[string]any{}},
+
+	// Public
+	{Method: "POST", Path: "/register", ID: "register", Tag: "auth", Summary: "Đăng ký tài khoản",
+		Body: RegisterRequest{}, Status: 201, Resp: gin.H{"message": ""}},
+	{Method: "POST", Path: "/login", ID: "login", Tag: "auth", Summary: "Đăng nhập, trả JWT",
+		Body: LoginRequest{}, Resp: AuthResponse{}},
+	{Method: "GET", Path: "/vip-tiers", ID: "vipTiers", Tag: "vip", Summary: "Bảng cấp VIP",
+		Resp: gin.H{"tiers": []VipTier{}}},
+	{Method: "GET", Path: "/market", ID: "marketQuery", Tag: "market", Summary: "Danh sách bài đăng bán đang mở",
+		Query: []apiParam{qStr("code", "lọc theo mã vật phẩm")},
+		Resp:  gin.H{"rows": []MarketRow{}}},
+	{Method: "GET", Path: "/market/orderbook", ID: "marketOrderBook", Tag: "market", Summary: "Sổ lệnh gộp theo giá",
+		Query: []apiParam{qStr("code", "mã vật phẩm"), qInt("depth", "số mức giá mỗi bên (mặc định 20, tối đa 100)")},
+		Resp:  gin.H{"code": "", "asks": []OrderBookLevel{}, "bids": []OrderBookLevel{}}},
+	{Method: "GET", Path: "/market/stats", ID: "marketStats", Tag: "market", Summary: "Thống kê 24h và nến giá",
+		Query: []apiParam{qStr("code", "mã vật phẩm"), qStr("interval", "khung nến (mặc định 1h)"), qInt("limit", "số nến (mặc định 48)")},
+		Resp: gin.H{
+			"code": "", "lastPrice": (*int64)(nil), "volume24h": int64(0), "value24h": int64(0),
+			"high24h": int64(0), "low24h": int64(0), "trades24h": int64(0), "interval": "", "candles": []Candle{},
+		}},
+	{Method: "POST", Path: "/forgot-password", ID: "forgotPassword", Tag: "auth", Summary: "Đặt lại mật khẩu bằng mật khẩu cấp 2",
+		Body: ForgotPasswordReq{}, Resp: gin.H{"message": ""}},
+	{Method: "GET", Path: "/public/leaderboard", ID: "publicLeaderboard", Tag: "referral", Summary: "Bảng xếp hạng (tính sẵn mỗi phút)",
+		Query: append(leaderboardQuery, qInt("limit", "mặc định 100, tối đa 1000")),
+		Resp:  gin.H{"rows": []LeaderboardRow{}, "kind": "", "window": "", "updatedAt": time.Time{}}},
+	{Method: "GET", Path: "/public/seasons", ID: "seasons", Tag: "seasons", Summary: "Danh sách mùa giải (kèm bảng giải thưởng)",
+		Query: []apiParam{qStr("status", "OPEN | CLOSED")},
+		Resp:  gin.H{"rows": []Season{}}},
+	{Method: "GET", Path: "/public/data-export/:token", ID: "downloadDataExport", Tag: "account", Summary: "Tải file xuất dữ liệu cá nhân (link trong thông báo, có hạn)",
+		Produces: mimeBinary},
+	{Method: "GET", Path: "/public/seasons/:id", ID: "seasonStandings", Tag: "seasons", Summary: "Bảng xếp hạng mùa giải (đã chốt hoặc tạm tính)",
+		Query: []apiParam{qInt("limit", "mặc định 100, tối đa 1000")},
+		Resp:  gin.H{"season": Season{}, "final": false, "rows": []SeasonStanding{}}},
+
+	// Private: tài khoản
+	{Method: "GET", Path: "/private/me", ID: "me", Tag: "account", Auth: "user", Summary: "Thông tin tài khoản",
+		Resp: gin.H{"user": User{}}},
+	{Method: "PUT", Path: "/private/profile", ID: "updateProfile", Tag: "account", Auth: "user", Summary: "Cập nhật hồ sơ",
+		Body: ProfileUpdateRequest{}, Resp: gin.H{"user": User{}, "message": ""}},
+	{Method: "POST", Path: "/private/upload", ID: "uploadAvatar", Tag: "account", Auth: "user", Summary: "Tải ảnh đại diện",
+		Form: []apiParam{fileField("file")}, Resp: gin.H{"url": ""}},
+	{Method: "PUT", Path: "/private/change-password", ID: "changePassword", Tag: "account", Auth: "user", Summary: "Đổi mật khẩu",
+		Body: ChangePasswordRequest{}, Resp: gin.H{"message": ""}},
+	{Method: "POST", Path: "/private/change-password", ID: "changePasswordLegacy", Tag: "account", Auth: "user", Summary: "Đổi mật khẩu (alias cũ)",
+		Body: ChangePasswordRequest{}, Resp: gin.H{"message": ""}, Deprecated: true},
+	{Method: "PUT", Path: "/private/security", ID: "updateSecurity", Tag: "account", Auth: "user", Summary: "Đặt mật khẩu cấp 2 / PIN giao dịch",
+		Body: UpdateSecurityRequest{}, Resp: gin.H{"message": ""}},
+	{Method: "POST", Path: "/private/update-security", ID: "updateSecurityLegacy", Tag: "account", Auth: "user", Summary: "Đặt mật khẩu cấp 2 / PIN (alias cũ)",
+		Body: UpdateSecurityRequest{}, Resp: gin.H{"message": ""}, Deprecated: true},
+	{Method: "POST", Path: "/private/kyc-submit", ID: "kycSubmit", Tag: "account", Auth: "user", Summary: "Gửi KYC kèm ảnh CCCD",
+		Form: kycForm, Resp: gin.H{"message": "", "status": ""}},
+	{Method: "POST", Path: "/private/kyc", ID: "kycSubmitLegacy", Tag: "account", Auth: "user", Summary: "Gửi KYC (alias cũ)",
+		Form: kycForm, Resp: gin.H{"message": "", "status": ""}, Deprecated: true},
+	{Method: "PUT", Path: "/private/kyc", ID: "updateKyc", Tag: "account", Auth: "user", Summary: "Xác minh KYC nhanh (nickname + số CCCD)",
+		Body: KycUpdateRequest{}, Resp: gin.H{"message": ""}},
+	{Method: "GET", Path: "/private/data-export", ID: "myDataExports", Tag: "account", Auth: "user", Summary: "Các lần xuất dữ liệu cá nhân gần đây (kèm link tải)",
+		Resp: gin.H{"rows": []DataExportView{}}},
+	{Method: "POST", Path: "/private/data-export", ID: "requestDataExport", Tag: "account", Auth: "user", Summary: "Yêu cầu xuất dữ liệu cá nhân (tạo nền, 1 lần/24 giờ)",
+		Status: 202, Resp: gin.H{"message": "", "export": DataExportView{}}},
+	{Method: "POST", Path: "/private/account/close", ID: "closeMyAccount", Tag: "account", Auth: "user", Summary: "Đóng tài khoản (PIN + mật khẩu cấp 2; còn coin thì yêu cầu chi trả)",
+		Body: SelfCloseAccountRequest{}, Resp: gin.H{"message": "", "closed": false, "request": (*AccountCloseRequest)(nil)}},
+	{Method: "GET", Path: "/private/account/restrictions", ID: "myRestrictions", Tag: "account", Auth: "user", Summary: "Trạng thái tài khoản & các hạn chế đang áp dụng (lý do, hạn)",
+		Resp: AccountRestrictions{}},
+
+	// Private: ví & lịch sử
+	{Method: "GET", Path: "/private/wallet", ID: "wallet", Tag: "wallet", Auth: "user", Summary: "Số dư và tiến độ rương",
+		Resp: gin.H{
+			"coins": int64(0), "totalTopup": int64(0), "vipLevel": 0, "freeSpins": 0,
+			"chestOpens": 0, "remainingUntilBonus": 0, "bonusCoins": int64(0), "totalCoins": int64(0),
+		}},
+	{Method: "POST", Path: "/private/transfer", ID: "transfer", Tag: "wallet", Auth: "user", Summary: "Chuyển coin cho user khác",
+		Body: TransferRequest{}, Resp: gin.H{"message": "", "fee": int64(0), "debit": int64(0), "tier": ""}},
+	{Method: "GET", Path: "/private/transfer/limits", ID: "transferLimits", Tag: "wallet", Auth: "user", Summary: "Hạn mức chuyển coin theo VIP/KYC: phí, đã dùng, còn lại",
+		Resp: TransferLimits{}},
+	{Method: "POST", Path: "/private/buy-vip", ID: "buyVip", Tag: "vip", Auth: "user", Summary: "Mua VIP 1",
+		Resp: gin.H{"message": "", "level": 0, "coins": int64(0)}},
+	{Method: "GET", Path: "/private/vip-vouchers", ID: "myVipVouchers", Tag: "vip", Auth: "user", Summary: "Phiếu giảm giá VIP của tôi",
+		Resp: gin.H{"rows": []VipVoucher{}}},
+	{Method: "GET", Path: "/private/history/withdraws", ID: "withdrawHistory", Tag: "wallet", Auth: "user", Summary: "Lịch sử rút coin",
+		Query: historyQuery(), Resp: historyPage([]WithdrawRow{})},
+	{Method: "GET", Path: "/private/history/withdraws/export", ID: "exportWithdrawHistory", Tag: "wallet", Auth: "user", Summary: "Xuất lịch sử rút coin (CSV/XLSX)",
+		Query: historyExportQuery(), Produces: mimeBinary},
+	{Method: "GET", Path: "/private/history/topups", ID: "topupHistory", Tag: "wallet", Auth: "user", Summary: "Lịch sử nạp coin",
+		Query: historyQuery(), Resp: historyPage([]TopupRow{})},
+	{Method: "GET", Path: "/private/history/topups/export", ID: "exportTopupHistory", Tag: "wallet", Auth: "user", Summary: "Xuất lịch sử nạp coin (CSV/XLSX)",
+		Query: historyExportQuery(), Produces: mimeBinary},
+	{Method: "GET", Path: "/private/history/transfers", ID: "transferHistory", Tag: "wallet", Auth: "user", Summary: "Lịch sử chuyển coin",
+		Query: historyQuery(qDirection), Resp: historyPage([]TransferRow{})},
+	{Method: "GET", Path: "/private/history/transfers/export", ID: "exportTransferHistory", Tag: "wallet", Auth: "user", Summary: "Xuất lịch sử chuyển coin (CSV/XLSX)",
+		Query: historyExportQuery(qDirection), Produces: mimeBinary},
+	{Method: "GET", Path: "/private/history/vip", ID: "vipHistory", Tag: "vip", Auth: "user", Summary: "Lịch sử mua VIP (amount = giá)",
+		Query: historyQuery(), Resp: historyPage([]VipBuyRow{})},
+	{Method: "GET", Path: "/private/history/vip/export", ID: "exportVipHistory", Tag: "vip", Auth: "user", Summary: "Xuất lịch sử mua VIP (CSV/XLSX)",
+		Query: historyExportQuery(), Produces: mimeBinary},
+	{Method: "GET", Path: "/private/history/commissions", ID: "myCommissions", Tag: "referral", Auth: "user", Summary: "Hoa hồng đã nhận",
+		Query: historyQuery(qKind, qDepth), Resp: historyPage([]CommissionRow{})},
+	{Method: "GET", Path: "/private/history/commissions/export", ID: "exportMyCommissions", Tag: "referral", Auth: "user", Summary: "Xuất hoa hồng đã nhận (CSV/XLSX)",
+		Query: historyExportQuery(qKind, qDepth), Produces: mimeBinary},
+	{Method: "GET", Path: "/private/statement", ID: "statement", Tag: "wallet", Auth: "user", Summary: "Sao kê coin/bonus kèm số dư chạy, số dư đầu/cuối kỳ",
+		Query: []apiParam{
+			qStr("from", "YYYY-MM-DD hoặc RFC3339"), qStr("to", "YYYY-MM-DD (tính hết ngày) hoặc RFC3339"),
+			qStr("currency", "COIN | BONUS (mặc định cả hai)", CUR_COIN, CUR_BONUS),
+			qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"),
+		},
+		Resp: gin.H{
+			"rows": []StatementRow{}, "nextCursor": (*uint)(nil),
+			"opening": StatementBalances{}, "closing": StatementBalances{},
+		}},
+	{Method: "POST", Path: "/private/redeem-code", ID: "redeemCode", Tag: "promo", Auth: "user", Summary: "Nhập gift code (mọi chiến dịch)",
+		Body: RedeemReq{}, Resp: gin.H{"message": "", "campaign": "", "reward": RewardBundle{}}},
+
+	// Private: trò chơi
+	{Method: "POST", Path: "/private/chest-open", ID: "chestOpen", Tag: "game", Auth: "user", Summary: "Mở rương",
+		Resp: ChestOpenResult{}},
+	{Method: "GET", Path: "/private/inventory", ID: "inventory", Tag: "game", Auth: "user", Summary: "Túi đồ",
+		Resp: gin.H{"items": []InventoryItem{}}},
+	{Method: "POST", Path: "/private/merge-dragon", ID: "mergeDragon", Tag: "game", Auth: "user", Summary: "Hợp nhất 7 viên ngọc rồng",
+		Resp: gin.H{"message": "", "coins": int64(0)}},
+
+	// Private: chợ
+	{Method: "POST", Path: "/private/market/list", ID: "marketCreate", Tag: "market", Auth: "user", Summary: "Đăng bán vật phẩm",
+		Body: MarketListRequest{}, Resp: gin.H{"message": "", "fills": []MarketFill{}}},
+	{Method: "POST", Path: "/private/market/buy", ID: "marketBuy", Tag: "market", Auth: "user", Summary: "Mua từ 1 bài đăng",
+		Body: MarketBuyRequest{}, Resp: gin.H{"message": "", "trade": MarketFill{}}},
+	{Method: "POST", Path: "/private/market/withdraw", ID: "marketWithdraw", Tag: "market", Auth: "user", Summary: "Rút vật phẩm về túi",
+		Body: MarketWithdrawRequest{}, Resp: gin.H{"message": ""}},
+	{Method: "POST", Path: "/private/market/bids", ID: "marketPlaceBid", Tag: "market", Auth: "user", Summary: "Đặt lệnh mua giới hạn",
+		Body: PlaceBidRequest{}, Resp: gin.H{"message": "", "bid": MarketBid{}, "fills": []MarketFill{}}},
+	{Method: "GET", Path: "/private/market/bids", ID: "marketMyBids", Tag: "market", Auth: "user", Summary: "Lệnh mua của tôi",
+		Query: []apiParam{qStr("status", "OPEN | FILLED | CANCELLED")},
+		Resp:  gin.H{"rows": []MarketBid{}}},
+	{Method: "POST", Path: "/private/market/bids/:id/cancel", ID: "marketCancelBid", Tag: "market", Auth: "user", Summary: "Huỷ lệnh mua",
+		Resp: gin.H{"message": "", "refund": int64(0)}},
+	{Method: "POST", Path: "/private/market/market-order", ID: "marketOrder", Tag: "market", Auth: "user", Summary: "Lệnh mua thị trường",
+		Body: MarketOrderRequest{},
+		Resp: gin.H{"message": "", "mode": "", "filledQty": int64(0), "totalCost": int64(0), "avgPrice": int64(0), "fills": []MarketFill{}}},
+	{Method: "GET", Path: "/private/market/trades", ID: "myMarketTrades", Tag: "market", Auth: "user", Summary: "Giao dịch khớp của tôi",
+		Query: []apiParam{qStr("side", "buy | sell"), qStr("code", "mã vật phẩm"), qInt("limit", "mặc định 100")},
+		Resp:  gin.H{"rows": []MyTradeRow{}}},
+	{Method: "GET", Path: "/private/market/listings", ID: "myMarketListings", Tag: "market", Auth: "user", Summary: "Bài đăng của tôi",
+		Query: []apiParam{qFlag("activeOnly", "1 = chỉ bài còn hiệu lực")},
+		Resp:  gin.H{"rows": []MarketListing{}}},
+	{Method: "PUT", Path: "/private/market/listings/:id", ID: "marketEditListing", Tag: "market", Auth: "user", Summary: "Sửa giá / số lượng bài đăng",
+		Body: EditListingRequest{}, Resp: gin.H{"message": "", "listing": MarketListing{}, "fills": []MarketFill{}}},
+
+	// Private: thông báo
+	{Method: "GET", Path: "/private/notifications", ID: "notifications", Tag: "notifications", Auth: "user", Summary: "Danh sách thông báo (phân trang cursor)",
+		Query: []apiParam{
+			qFlag("unreadOnly", "1 = chỉ chưa đọc"), qFlag("archived", "1 = hộp lưu trữ"),
+			qStr("category", "finance | game | referral | system"), qInt("cursor", "id nhỏ nhất trang trước"), qInt("limit", "mặc định 50"),
+		},
+		Resp: gin.H{"rows": []Notification{}, "unread": int64(0), "nextCursor": (*uint)(nil)}},
+	{Method: "GET", Path: "/private/events", ID: "events", Tag: "notifications", Auth: "user", Summary: "Luồng sự kiện realtime (SSE)",
+		Query:    []apiParam{qStr("ticket", "vé 1 lần từ POST /private/events/ticket khi EventSource không gửi được header"), qInt("lastEventId", "nối lại từ sự kiện này")},
+		Produces: mimeSSE},
+	{Method: "POST", Path: "/private/events/ticket", ID: "eventsTicket", Tag: "notifications", Auth: "user", Summary: "Vé dùng 1 lần (1 phút) để mở luồng SSE",
+		Resp: gin.H{"ticket": "", "expiresAt": time.Time{}}},
+	{Method: "PUT", Path: "/private/notifications/mark-read", ID: "markNotificationsRead", Tag: "notifications", Auth: "user", Summary: "Đánh dấu đã đọc (không gửi ids = tất cả)",
+		Body: MarkReadRequest{}},
+	{Method: "DELETE", Path: "/private/notifications/:id", ID: "deleteNotification", Tag: "notifications", Auth: "user", Summary: "Xoá thông báo"},
+	{Method: "POST", Path: "/private/notifications/:id/archive", ID: "archiveNotification", Tag: "notifications", Auth: "user", Summary: "Lưu trữ thông báo"},
+	{Method: "POST", Path: "/private/notifications/:id/unarchive", ID: "unarchiveNotification", Tag: "notifications", Auth: "user", Summary: "Bỏ lưu trữ thông báo"},
+	{Method: "GET", Path: "/private/notification-preferences", ID: "notificationPrefs", Tag: "notifications", Auth: "user", Summary: "Tuỳ chọn thông báo",
+		Resp: gin.H{"locale": "", "muted": []string{}, "categories": []string{}, "locales": []string{}}},
+	{Method: "PUT", Path: "/private/notification-preferences", ID: "updateNotificationPrefs", Tag: "notifications", Auth: "user", Summary: "Lưu tuỳ chọn thông báo",
+		Body: NotificationPrefRequest{}, Resp: gin.H{"message": "", "locale": "", "muted": []string{}}},
+
+	// Private: giới thiệu
+	{Method: "GET", Path: "/private/referral-info", ID: "referralInfo", Tag: "referral", Auth: "user", Summary: "Mã mời và số người đã mời",
+		Resp: gin.H{"code": "", "link": "", "count": int64(0), "total": int64(0)}},
+	{Method: "GET", Path: "/private/dashboard/overview", ID: "dashboardOverview", Tag: "referral", Auth: "user", Summary: "Tổng quan hệ thống F1..F9",
+		Resp: DashboardOverview{}},
+	{Method: "GET", Path: "/private/dashboard/commissions", ID: "dashboardCommissions", Tag: "referral", Auth: "user", Summary: "Hoa hồng theo ngày trong tháng",
+		Query: []apiParam{qInt("year", "mặc định năm hiện tại"), qInt("month", "1..12, mặc định tháng hiện tại")},
+		Resp:  MonthlyCommissionResp{}},
+	{Method: "GET", Path: "/private/downlines", ID: "myDownlines", Tag: "referral", Auth: "user", Summary: "Danh sách tuyến dưới",
+		Query: []apiParam{qInt("depth", "1..9, bỏ trống = tất cả")},
+		Resp:  gin.H{"rows": []DownlineRow{}}},
+	{Method: "GET", Path: "/private/downlines/:id/dashboard", ID: "downlineDashboard", Tag: "referral", Auth: "user", Summary: "Tổng quan của 1 tuyến dưới",
+		Query: []apiParam{qStr("month", "YYYY-MM, mặc định tháng hiện tại")},
+		Resp:  DownlineDashboardResp{}},
+	{Method: "GET", Path: "/private/leaderboard/me", ID: "myLeaderboardRank", Tag: "referral", Auth: "user", Summary: "Thứ hạng của tôi",
+		Query: leaderboardQuery,
+		Resp:  MyRankResp{}},
+	{Method: "GET", Path: "/private/seasons/:id/me", ID: "mySeasonStanding", Tag: "seasons", Auth: "user", Summary: "Hạng & phần thưởng của tôi trong mùa giải",
+		Resp: gin.H{"final": false, "standing": (*SeasonStanding)(nil)}},
+	{Method: "GET", Path: "/private/checkin", ID: "checkinStatus", Tag: "checkin", Auth: "user", Summary: "Trạng thái điểm danh, chuỗi ngày & lịch thưởng",
+		Resp: CheckinStatus{}},
+	{Method: "POST", Path: "/private/checkin", ID: "checkin", Tag: "checkin", Auth: "user", Summary: "Điểm danh hôm nay (gọi lại trong ngày không nhận thêm)",
+		Resp: gin.H{"message": "", "alreadyCheckedIn": false, "checkin": CheckinLog{}}},
+	{Method: "PUT", Path: "/private/checkin/timezone", ID: "checkinTimezone", Tag: "checkin", Auth: "user", Summary: "Đổi múi giờ điểm danh (7 ngày/lần)",
+		Body: CheckinTimezoneRequest{}, Resp: gin.H{"message": "", "timezone": ""}},
+	{Method: "GET", Path: "/private/missions", ID: "missions", Tag: "missions", Auth: "user", Summary: "Nhiệm vụ đang mở & tiến độ chu kỳ hiện tại",
+		Resp: gin.H{"rows": []MissionView{}}},
+	{Method: "POST", Path: "/private/missions/:id/claim", ID: "claimMission", Tag: "missions", Auth: "user", Summary: "Nhận thưởng nhiệm vụ đã hoàn thành",
+		Resp: gin.H{"message": "", "reward": RewardBundle{}}},
+
+	// Private: shop
+	{Method: "GET", Path: "/private/shop", ID: "shopList", Tag: "shop", Auth: "user", Summary: "Quà đang mở đổi",
+		Resp: gin.H{"rows": []ShopItemView{}}},
+	{Method: "POST", Path: "/private/shop/buy", ID: "shopBuy", Tag: "shop", Auth: "user", Summary: "Đổi quà",
+		Body: ShopBuyRequest{}, Resp: gin.H{"message": "", "purchase": ShopPurchase{}}},
+	{Method: "GET", Path: "/private/shop/purchases", ID: "shopMyPurchases", Tag: "shop", Auth: "user", Summary: "Lịch sử đổi quà",
+		Resp: gin.H{"rows": []ShopPurchaseRow{}}},
+
+	// Admin
+	{Method: "POST", Path: "/admin/topup", ID: "adminTopup", Tag: "admin", Auth: "admin", Summary: "Nạp coin cho user",
+		Body: TopupRequest{}, Resp: gin.H{"message": "", "userId": uint(0)}},
+	{Method: "POST", Path: "/admin/withdraw", ID: "adminWithdraw", Tag: "admin", Auth: "admin", Summary: "Rút coin của user",
+		Body: WithdrawRequest{}, Resp: gin.H{"message": "", "userId": uint(0)}},
+	{Method: "GET", Path: "/admin/users", ID: "adminUsers", Tag: "admin", Auth: "admin", Summary: "Tìm user",
+		Query: []apiParam{
+			qStr("vipLevel", "cấp VIP"), qStr("nickname", "tên hiển thị"),
+			qStr("username", "tên đăng nhập"), qStr("email", "alias cũ của username"),
+		},
+		Resp: gin.H{"rows": []AdminUserRow{}}},
+	{Method: "GET", Path: "/admin/users/:id", ID: "adminUserDetail", Tag: "admin", Auth: "admin", Summary: "Chi tiết user",
+		Resp: gin.H{"user": AdminUserDetail{}}},
+	{Method: "POST", Path: "/admin/users/:id/close", ID: "adminCloseAccount", Tag: "admin", Auth: "admin", Summary: "Đóng tài khoản: ẩn danh hoá, giữ lịch sử tài chính",
+		Body: CloseAccountRequest{}, Resp: gin.H{"message": "", "closure": AccountClosure{}}},
+	{Method: "PUT", Path: "/admin/users/:id/status", ID: "adminSetAccountStatus", Tag: "admin", Auth: "admin", Summary: "Khoá (FROZEN) / cấm (BANNED) / mở lại tài khoản, kèm lý do & hạn",
+		Body: AccountStatusRequest{}, Resp: gin.H{"message": "", "account": AccountRestrictions{}}},
+	{Method: "GET", Path: "/admin/users/:id/restrictions", ID: "adminUserRestrictions", Tag: "admin", Auth: "admin", Summary: "Trạng thái & lịch sử hạn chế của user",
+		Resp: AccountRestrictions{}},
+	{Method: "POST", Path: "/admin/users/:id/restrictions", ID: "adminAddRestriction", Tag: "admin", Auth: "admin", Summary: "Hạn chế chức năng TRANSFER | MARKET | CHEST | WITHDRAW",
+		Body: RestrictionRequest{}, Resp: gin.H{"message": "", "restriction": UserRestriction{}}},
+	{Method: "DELETE", Path: "/admin/users/:id/restrictions/:rid", ID: "adminLiftRestriction", Tag: "admin", Auth: "admin", Summary: "Gỡ hạn chế",
+		Resp: gin.H{"message": "", "restriction": UserRestriction{}}},
+	{Method: "DELETE", Path: "/admin/users/:id", ID: "adminDeleteUser", Tag: "admin", Auth: "admin", Summary: "Xoá hẳn tài khoản đã đóng quá hạn lưu trữ (superadmin)"},
+	{Method: "GET", Path: "/admin/account-closures", ID: "adminAccountClosures", Tag: "admin", Auth: "admin", Summary: "Hồ sơ đóng tài khoản",
+		Query: []apiParam{qStr("purgeable", "1 ⇒ chỉ hồ sơ đã quá hạn lưu trữ, chưa xoá cứng"), qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200")},
+		Resp:  gin.H{"rows": []AccountClosure{}, "nextCursor": (*uint)(nil), "retentionDays": int64(0)}},
+	{Method: "GET", Path: "/admin/account-close-requests", ID: "adminCloseRequests", Tag: "admin", Auth: "admin", Summary: "Yêu cầu đóng tài khoản chờ chi trả",
+		Query: []apiParam{
+			qStr("status", "PENDING | APPROVED | REJECTED | all (mặc định PENDING)", CLOSE_REQ_PENDING, CLOSE_REQ_APPROVED, CLOSE_REQ_REJECTED, "all"),
+			qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"),
+		},
+		Resp: historyPage([]AccountCloseRequest{})},
+	{Method: "POST", Path: "/admin/account-close-requests/:id/approve", ID: "adminApproveCloseRequest", Tag: "admin", Auth: "admin", Summary: "Đã chi trả: rút toàn bộ coin & đóng tài khoản",
+		Body: CloseRequestDecision{}, Resp: gin.H{"message": "", "request": AccountCloseRequest{}, "closure": AccountClosure{}}},
+	{Method: "POST", Path: "/admin/account-close-requests/:id/reject", ID: "adminRejectCloseRequest", Tag: "admin", Auth: "admin", Summary: "Từ chối yêu cầu đóng tài khoản, mở khoá",
+		Body: CloseRequestDecision{}, Resp: gin.H{"message": "", "request": AccountCloseRequest{}}},
+	{Method: "GET", Path: "/admin/kyc/:userId/front", ID: "adminKycFront", Tag: "admin", Auth: "admin", Summary: "Ảnh CCCD mặt trước",
+		Produces: mimeBinary},
+	{Method: "GET", Path: "/admin/kyc/:userId/back", ID: "adminKycBack", Tag: "admin", Auth: "admin", Summary: "Ảnh CCCD mặt sau",
+		Produces: mimeBinary},
+	{Method: "GET", Path: "/admin/kyc-file/:userId/:side", ID: "adminKycImage", Tag: "admin", Auth: "admin", Summary: "Ảnh CCCD theo mặt",
+		Produces: mimeBinary},
+	{Method: "GET", Path: "/admin/promo-campaigns", ID: "adminPromoCampaigns", Tag: "promo", Auth: "admin", Summary: "Chiến dịch gift code",
+		Query: []apiParam{
+			qPromoStatus, qStr("q", "tên chiến dịch hoặc code"),
+			qStr("from", "ngày tạo từ (YYYY-MM-DD hoặc RFC3339)"), qStr("to", "ngày tạo đến (tính hết ngày)"),
+			qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"),
+		},
+		Resp: historyPage([]PromoCampaignRow{})},
+	{Method: "POST", Path: "/admin/promo-campaigns", ID: "adminCreatePromoCampaign", Tag: "promo", Auth: "admin", Summary: "Tạo chiến dịch & sinh code",
+		Body: PromoCampaignRequest{}, Resp: gin.H{"message": "", "campaign": PromoCampaign{}, "codes": []string{}}},
+	{Method: "POST", Path: "/admin/promo-campaigns/:id/codes", ID: "adminCreatePromoCodes", Tag: "promo", Auth: "admin", Summary: "Sinh thêm code cho chiến dịch",
+		Body: PromoCodesRequest{}, Resp: gin.H{"message": "", "count": 0, "codes": []string{}}},
+	{Method: "GET", Path: "/admin/promo-campaigns/:id/redemptions", ID: "adminPromoCampaignRedemptions", Tag: "promo", Auth: "admin", Summary: "Lượt nhập code của chiến dịch",
+		Query: promoRedemptionsQuery, Resp: historyPage([]PromoRedemptionRow{})},
+	{Method: "GET", Path: "/admin/promo-campaigns/:id/export", ID: "adminExportPromoCodes", Tag: "promo", Auth: "admin", Summary: "Xuất lô code (CSV hoặc trang in kèm QR)",
+		Query:    []apiParam{qStr("format", "mặc định csv", "csv", "html"), qStr("status", "mặc định active; all = mọi code")},
+		Produces: mimeBinary},
+	{Method: "POST", Path: "/admin/promo-campaigns/:id/revoke", ID: "adminRevokePromoCampaign", Tag: "promo", Auth: "admin", Summary: "Thu hồi chiến dịch (mọi code ngừng nhận)",
+		Resp: gin.H{"message": "", "campaign": PromoCampaign{}}},
+	{Method: "POST", Path: "/admin/promo-campaigns/:id/reactivate", ID: "adminReactivatePromoCampaign", Tag: "promo", Auth: "admin", Summary: "Kích hoạt lại chiến dịch",
+		Resp: gin.H{"message": "", "campaign": PromoCampaign{}}},
+	{Method: "POST", Path: "/admin/promo-campaigns/:id/archive", ID: "adminArchivePromoCampaign", Tag: "promo", Auth: "admin", Summary: "Lưu trữ chiến dịch (thay cho xoá)",
+		Resp: gin.H{"message": "", "campaign": PromoCampaign{}}},
+	{Method: "POST", Path: "/admin/promo-campaigns/:id/unarchive", ID: "adminUnarchivePromoCampaign", Tag: "promo", Auth: "admin", Summary: "Bỏ lưu trữ chiến dịch",
+		Resp: gin.H{"message": "", "campaign": PromoCampaign{}}},
+	{Method: "GET", Path: "/admin/promo-codes", ID: "adminPromoCodes", Tag: "promo", Auth: "admin", Summary: "Danh sách code",
+		Query: []apiParam{
+			qInt("campaignId", "lọc theo chiến dịch (khi có, mặc định gồm cả code đã lưu trữ)"), qPromoStatus, qStr("q", "một phần code"),
+			qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"),
+		},
+		Resp: historyPage([]PromoCodeRow{})},
+	{Method: "GET", Path: "/admin/promo-codes/:id/redemptions", ID: "adminPromoCodeRedemptions", Tag: "promo", Auth: "admin", Summary: "Ai đã nhập code, lúc nào",
+		Query: promoRedemptionsQuery, Resp: historyPage([]PromoRedemptionRow{})},
+	{Method: "POST", Path: "/admin/promo-codes/:id/revoke", ID: "adminRevokePromoCode", Tag: "promo", Auth: "admin", Summary: "Thu hồi 1 code",
+		Resp: gin.H{"message": "", "code": PromoCampaignCode{}}},
+	{Method: "POST", Path: "/admin/promo-codes/:id/reactivate", ID: "adminReactivatePromoCode", Tag: "promo", Auth: "admin", Summary: "Kích hoạt lại 1 code",
+		Resp: gin.H{"message": "", "code": PromoCampaignCode{}}},
+	{Method: "GET", Path: "/admin/shop/items", ID: "adminShopItems", Tag: "shop", Auth: "admin", Summary: "Tất cả quà",
+		Resp: gin.H{"rows": []ShopItem{}}},
+	{Method: "POST", Path: "/admin/shop/items", ID: "adminCreateShopItem", Tag: "shop", Auth: "admin", Summary: "Tạo quà",
+		Body: ShopItemRequest{}, Resp: gin.H{"message": "", "item": ShopItem{}}},
+	{Method: "PUT", Path: "/admin/shop/items/:id", ID: "adminUpdateShopItem", Tag: "shop", Auth: "admin", Summary: "Sửa quà",
+		Body: ShopItemRequest{}, Resp: gin.H{"message": "", "item": ShopItem{}}},
+	{Method: "GET", Path: "/admin/shop/purchases", ID: "adminShopPurchases", Tag: "shop", Auth: "admin", Summary: "Lịch sử đổi quà toàn hệ thống",
+		Query: []apiParam{qInt("itemId", "lọc theo quà"), qInt("userId", "lọc theo user")},
+		Resp:  gin.H{"rows": []AdminShopPurchaseRow{}}},
+	{Method: "GET", Path: "/admin/market/listings", ID: "adminMarketListings", Tag: "market", Auth: "admin", Summary: "Bài đăng trên chợ",
+		Query: []apiParam{qStr("status", "active | inactive | all (mặc định active)"), qStr("code", "mã vật phẩm"), qInt("sellerId", "lọc theo người bán")},
+		Resp:  gin.H{"rows": []AdminListingRow{}}},
+	{Method: "POST", Path: "/admin/market/listings/:id/cancel", ID: "adminCancelMarketListing", Tag: "market", Auth: "admin", Summary: "Gỡ bài đăng, trả hàng cho người bán",
+		Body: CancelListingRequest{}, Resp: gin.H{"message": "", "returnedQty": int64(0)}},
+	{Method: "GET", Path: "/admin/transfer-tiers", ID: "adminTransferTiers", Tag: "admin", Auth: "admin", Summary: "Hạn mức & phí chuyển coin theo VIP/KYC",
+		Resp: gin.H{"rows": []TransferTier{}}},
+	{Method: "PUT", Path: "/admin/transfer-tiers", ID: "adminUpsertTransferTier", Tag: "admin", Auth: "admin", Summary: "Tạo / sửa hạn mức theo (minVip, kyc)",
+		Body: TransferTierRequest{}, Resp: gin.H{"message": "", "tier": TransferTier{}}},
+	{Method: "DELETE", Path: "/admin/transfer-tiers/:id", ID: "adminDeleteTransferTier", Tag: "admin", Auth: "admin", Summary: "Xoá hạn mức (trừ tier gốc VIP 0, chưa KYC)",
+		Resp: gin.H{"message": ""}},
+	{Method: "GET", Path: "/admin/settings", ID: "adminSettings", Tag: "admin", Auth: "admin", Summary: "Cấu hình runtime",
+		Resp: gin.H{"settings": map[string]string{}, "defaults": map[string]string{}}},
+	{Method: "PUT", Path: "/admin/settings", ID: "adminUpdateSetting", Tag: "admin", Auth: "admin", Summary: "Lưu 1 cấu hình",
+		Body: SettingUpdateRequest{}, Resp: gin.H{"message": "", "key": "", "value": ""}},
+	{Method: "POST", Path: "/admin/notifications/broadcast", ID: "adminSendBroadcast", Tag: "notifications", Auth: "admin", Summary: "Gửi thông báo hàng loạt (dryRun: chỉ đếm)",
+		Body: BroadcastRequest{},
+		Resp: gin.H{
+			"recipients": optional(int64(0)), "message": optional(""),
+			"broadcast": optional(Broadcast{}), "segment": optional(BroadcastSegment{}),
+		}},
+	{Method: "GET", Path: "/admin/notifications/broadcasts", ID: "adminBroadcasts", Tag: "notifications", Auth: "admin", Summary: "Danh sách broadcast",
+		Query: []apiParam{qStr("status", "SCHEDULED | RUNNING | DONE | CANCELLED | FAILED")},
+		Resp:  gin.H{"rows": []broadcastView{}}},
+	{Method: "GET", Path: "/admin/notifications/broadcasts/:id", ID: "adminBroadcastDetail", Tag: "notifications", Auth: "admin", Summary: "Chi tiết broadcast",
+		Resp: broadcastView{}},
+	{Method: "POST", Path: "/admin/notifications/broadcasts/:id/cancel", ID: "adminCancelBroadcast", Tag: "notifications", Auth: "admin", Summary: "Huỷ broadcast chưa gửi xong",
+		Resp: gin.H{"message": ""}},
+	{Method: "GET", Path: "/admin/notification-templates", ID: "adminNotificationTemplates", Tag: "notifications", Auth: "admin", Summary: "Mẫu thông báo (mặc định + ghi đè)",
+		Resp: gin.H{"rows": []NotificationTemplateRow{}, "locales": []string{}}},
+	{Method: "PUT", Path: "/admin/notification-templates", ID: "adminUpsertNotificationTemplate", Tag: "notifications", Auth: "admin", Summary: "Ghi đè mẫu thông báo",
+		Body: NotificationTemplateRequest{}, Resp: gin.H{"message": ""}},
+	{Method: "DELETE", Path: "/admin/notification-templates", ID: "adminResetNotificationTemplate", Tag: "notifications", Auth: "admin", Summary: "Khôi phục mẫu mặc định",
+		Query: []apiParam{qStr("type", "loại thông báo"), qStr("locale", "vi | en")},
+		Resp:  gin.H{"message": ""}},
+	{Method: "GET", Path: "/admin/seasons", ID: "adminSeasons", Tag: "seasons", Auth: "admin", Summary: "Danh sách mùa giải",
+		Query: []apiParam{qStr("status", "OPEN | CLOSED | CANCELLED")},
+		Resp:  gin.H{"rows": []Season{}}},
+	{Method: "POST", Path: "/admin/seasons", ID: "adminCreateSeason", Tag: "seasons", Auth: "admin", Summary: "Tạo mùa giải",
+		Body: SeasonRequest{}, Resp: gin.H{"message": "", "season": Season{}}},
+	{Method: "PUT", Path: "/admin/seasons/:id", ID: "adminUpdateSeason", Tag: "seasons", Auth: "admin", Summary: "Sửa mùa giải chưa bắt đầu",
+		Body: SeasonRequest{}, Resp: gin.H{"message": "", "season": Season{}}},
+	{Method: "POST", Path: "/admin/seasons/:id/cancel", ID: "adminCancelSeason", Tag: "seasons", Auth: "admin", Summary: "Huỷ mùa giải chưa kết thúc",
+		Resp: gin.H{"message": ""}},
+	{Method: "GET", Path: "/admin/checkin/calendar", ID: "adminCheckinCalendar", Tag: "checkin", Auth: "admin", Summary: "Lịch thưởng điểm danh",
+		Resp: gin.H{"days": []CheckinDay{}}},
+	{Method: "PUT", Path: "/admin/checkin/calendar", ID: "adminUpdateCheckinCalendar", Tag: "checkin", Auth: "admin", Summary: "Thay lịch thưởng điểm danh",
+		Body: CheckinCalendarRequest{}, Resp: gin.H{"message": "", "days": []CheckinDay{}}},
+	{Method: "GET", Path: "/admin/missions", ID: "adminMissions", Tag: "missions", Auth: "admin", Summary: "Mọi nhiệm vụ & danh sách sự kiện",
+		Resp: gin.H{"rows": []Mission{}, "events": []DomainEventDef{}}},
+	{Method: "POST", Path: "/admin/missions", ID: "adminCreateMission", Tag: "missions", Auth: "admin", Summary: "Tạo nhiệm vụ",
+		Body: MissionRequest{}, Resp: gin.H{"message": "", "mission": Mission{}}},
+	{Method: "PUT", Path: "/admin/missions/:id", ID: "adminUpdateMission", Tag: "missions", Auth: "admin", Summary: "Sửa / bật tắt nhiệm vụ",
+		Body: MissionRequest{}, Resp: gin.H{"message": "", "mission": Mission{}}},
+	{Method: "GET", Path: "/admin/stats", ID: "adminStats", Tag: "admin", Auth: "admin", Summary: "Kinh tế: nguồn vào/ra, coin lưu hành, hoạt động theo ngày",
+		Query: []apiParam{qStr("from", "YYYY-MM-DD (mặc định 29 ngày trước)"), qStr("to", "YYYY-MM-DD (mặc định hôm nay), tối đa 366 ngày")},
+		Resp:  AdminStats{}},
+	{Method: "GET", Path: "/admin/jobs", ID: "adminJobs", Tag: "jobs", Auth: "admin", Summary: "Job nền & trạng thái",
+		Resp: gin.H{"rows": []JobView{}}},
+	{Method: "PUT", Path: "/admin/jobs/:name", ID: "adminUpdateJob", Tag: "jobs", Auth: "admin", Summary: "Đổi lịch cron của job",
+		Body: JobScheduleRequest{}, Resp: gin.H{"message": "", "job": Job{}}},
+	{Method: "GET", Path: "/admin/jobs/:name/runs", ID: "adminJobRuns", Tag: "jobs", Auth: "admin", Summary: "Lịch sử chạy job",
+		Query: []apiParam{
+			qStr("status", "RUNNING | OK | FAILED", JOB_RUNNING, JOB_OK, JOB_FAILED),
+			qStr("from", "YYYY-MM-DD hoặc RFC3339"), qStr("to", "YYYY-MM-DD (tính hết ngày) hoặc RFC3339"),
+			qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"),
+		},
+		Resp: historyPage([]JobRun{})},
+	{Method: "POST", Path: "/admin/jobs/:name/run", ID: "adminRunJob", Tag: "jobs", Auth: "admin", Summary: "Chạy job ngay",
+		Resp: gin.H{"message": ""}},
+	{Method: "POST", Path: "/admin/jobs/:name/pause", ID: "adminPauseJob", Tag: "jobs", Auth: "admin", Summary: "Tạm dừng job",
+		Resp: gin.H{"message": "", "job": Job{}}},
+	{Method: "POST", Path: "/admin/jobs/:name/resume", ID: "adminResumeJob", Tag: "jobs", Auth: "admin", Summary: "Chạy lại job theo lịch",
+		Resp: gin.H{"message": "", "job": Job{}}},
+	{Method: "GET", Path: "/admin/reconciliation/drifts", ID: "adminBalanceDrifts", Tag: "admin", Auth: "admin", Summary: "Số dư lệch sổ cái (đối soát)",
+		Query: []apiParam{
+			qStr("status", "OPEN | RESOLVED | all (mặc định OPEN)", DRIFT_OPEN, DRIFT_RESOLVED, "all"), qInt("userId", "lọc theo user"),
+			qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"),
+		},
+		Resp: historyPage([]BalanceDriftRow{})},
+	{Method: "GET", Path: "/admin/reconciliation/drifts/:id", ID: "adminBalanceDrift", Tag: "admin", Auth: "admin", Summary: "Chi tiết lệch: sổ cái sau mốc khớp cuối & bản ghi nguồn không khớp",
+		Resp: gin.H{"drift": BalanceDrift{}, "checkpoint": BalanceCheckpoint{}, "ledger": []LedgerEntry{}, "sources": []ReconSourceRow{}}},
+	{Method: "POST", Path: "/admin/reconciliation/drifts/:id/resolve", ID: "adminResolveBalanceDrift", Tag: "admin", Auth: "admin", Summary: "Xử lý lệch: ACCEPT (ghi bù sổ cái) | FIX (trả số dư về sổ cái)",
+		Body: ResolveDriftRequest{}, Resp: gin.H{"message": "", "drift": BalanceDrift{}, "applied": int64(0), "unfrozen": false}},
+}
+
+var kycForm = []apiParam{
+	formField("fullName", true), formField("dob", true), formField("number", true),
+	fileField("front"), fileField("back"),
+}
+
+// leaderboards.go
+var leaderboardQuery = []apiParam{
+	qStr("kind", "f1 | system | chest | market | vip_referrals (mặc định f1)"),
+	qStr("window", "day | week | month | all (mặc định all)"),
+}
+
+// promo_admin.go
+var (
+	qPromoStatus          = qStr("status", "active | scheduled | expired | exhausted | revoked | archived | all (mặc định: mọi trạng thái trừ archived)")
+	promoRedemptionsQuery = []apiParam{
+		qStr("from", "YYYY-MM-DD hoặc RFC3339"), qStr("to", "YYYY-MM-DD (tính hết ngày) hoặc RFC3339"),
+		qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"),
+	}
+)
+
+// bộ lọc chung của /private/history/* (history.go)
+var (
+	qDirection = qStr("direction", "in | out", "in", "out")
+	qKind      = qStr("kind", "UPLINE | ADMIN")
+	qDepth     = qInt("depth", "tầng 1..9")
+)
+
+func historyFilterQuery(extra ...apiParam) []apiParam {
+	return append([]apiParam{
+		qStr("from", "YYYY-MM-DD hoặc RFC3339"), qStr("to", "YYYY-MM-DD (tính hết ngày) hoặc RFC3339"),
+		qInt("minAmount", "số tiền >="), qInt("maxAmount", "số tiền <="),
+	}, extra...)
+}
+
+func historyQuery(extra ...apiParam) []apiParam {
+	return append(historyFilterQuery(extra...),
+		qInt("cursor", "nextCursor của trang trước"), qInt("limit", "mặc định 50, tối đa 200"))
+}
+
+func historyExportQuery(extra ...apiParam) []apiParam {
+	return append(historyFilterQuery(extra...), qStr("format", "mặc định csv", "csv", "xlsx"))
+}
+
+func historyPage(rows any) gin.H {
+	return gin.H{"rows": rows, "nextCursor": (*uint)(nil)}
+}
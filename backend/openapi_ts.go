@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+/* ===== SINH CLIENT TYPESCRIPT TỪ SPEC ===== */
+
+// go run . gen-client ../frontend/src/api.gen.ts
+func writeTSClientFile(path string) error {
+	return os.WriteFile(path, []byte(tsClient(apiSpec())), 0o644)
+}
+
+func tsPropName(name string) string {
+	for i, r := range name {
+		ok := r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')
+		if !ok {
+			return "'" + name + "'"
+		}
+	}
+	return name
+}
+
+// giá trị indent đặc biệt: viết object trên 1 dòng (dùng trong chữ ký hàm của client)
+const inline = "inline"
+
+func tsPropOrder(s *apiSchema) []string {
+	if len(s.order) == len(s.Properties) {
+		return s.order
+	}
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// kiểu TS của schema; indent dùng cho object lồng nhau
+func tsType(s *apiSchema, indent string) string {
+	t := tsBaseType(s, indent)
+	if s != nil && s.Nullable {
+		return t + " | null"
+	}
+	return t
+}
+
+func tsBaseType(s *apiSchema, indent string) string {
+	if s == nil {
+		return "unknown"
+	}
+	if s.Ref != "" {
+		return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "binary" {
+			return "Blob"
+		}
+		if len(s.Enum) > 0 {
+			quoted := make([]string, len(s.Enum))
+			for i, e := range s.Enum {
+				quoted[i] = "'" + e + "'"
+			}
+			return strings.Join(quoted, " | ")
+		}
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := tsType(s.Items, indent)
+		if strings.Contains(item, "|") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		if len(s.Properties) == 0 {
+			if s.AdditionalProperties != nil {
+				return "Record<string, " + tsType(s.AdditionalProperties, indent) + ">"
+			}
+			return "Record<string, unknown>"
+		}
+		fields := make([]string, 0, len(s.Properties))
+		for _, k := range tsPropOrder(s) {
+			opt := "?"
+			if slices.Contains(s.Required, k) {
+				opt = ""
+			}
+			next := inline
+			if indent != inline {
+				next = indent + "  "
+			}
+			fields = append(fields, fmt.Sprintf("%s%s: %s", tsPropName(k), opt, tsType(s.Properties[k], next)))
+		}
+		if indent == inline {
+			return "{ " + strings.Join(fields, "; ") + " }"
+		}
+		return "{\n" + indent + "  " + strings.Join(fields, ";\n"+indent+"  ") + ";\n" + indent + "}"
+	}
+	return "unknown"
+}
+
+func tsClient(spec *apiSpecDoc) string {
+	var b strings.Builder
+	b.WriteString("// Code generated by `go run . gen-client` (backend/openapi_ts.go). DO NOT EDIT.\n")
+	b.WriteString("// Nguồn: apiRoutes trong backend/openapi_routes.go và các DTO của handler.\n\n")
+
+	names := make([]string, 0, len(spec.components))
+	for n := range spec.components {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	for _, n := range names {
+		fmt.Fprintf(&b, "export type %s = %s;\n\n", n, tsType(spec.components[n], ""))
+	}
+
+	b.WriteString(`export type ApiRequestInit = {
+  query?: Record<string, string | number | boolean | undefined | null>;
+  body?: unknown;
+  form?: FormData;
+  blob?: boolean;
+};
+
+// hàm gửi request thật (fetch + token + xử lý lỗi) do app cung cấp
+export type ApiRequest = <T>(method: string, path: string, init?: ApiRequestInit) => Promise<T>;
+
+export function createApiClient(request: ApiRequest) {
+  return {
+`)
+	for _, op := range spec.ops {
+		r := op.route
+		if r.Produces == mimeSSE {
+			continue // EventSource, không đi qua request()
+		}
+		var args, init []string
+		path := r.Path
+		for _, p := range op.pathParams {
+			args = append(args, p.Name+": "+tsType(p.Schema, inline))
+			path = strings.Replace(path, ":"+p.Name, "${"+p.Name+"}", 1)
+		}
+		switch {
+		case op.body != nil:
+			args = append(args, "body: "+tsType(op.body, inline))
+			init = append(init, "body")
+		case len(r.Form) > 0:
+			fields := make([]string, len(r.Form))
+			for i, f := range r.Form {
+				fields[i] = f.Name
+			}
+			args = append(args, "form: FormData /* "+strings.Join(fields, ", ")+" */")
+			init = append(init, "form")
+		}
+		if len(r.Query) > 0 {
+			q := &apiSchema{Type: "object", Properties: map[string]*apiSchema{}}
+			for _, p := range r.Query {
+				q.Properties[p.Name] = p.Schema
+				q.order = append(q.order, p.Name)
+				if p.Required {
+					q.Required = append(q.Required, p.Name)
+				}
+			}
+			args = append(args, "query?: "+tsType(q, inline))
+			init = append(init, "query")
+		}
+		resp := "void"
+		switch {
+		case r.Produces == mimeBinary:
+			resp = "Blob"
+			init = append(init, "blob: true")
+		case op.resp != nil:
+			resp = tsType(op.resp, inline)
+		}
+
+		doc := r.Summary
+		if r.Deprecated {
+			doc += " @deprecated"
+		}
+		fmt.Fprintf(&b, "    /** %s %s — %s */\n", r.Method, r.Path, doc)
+		quote := "'"
+		if strings.Contains(path, "${") {
+			quote = "`"
+		}
+		call := fmt.Sprintf("request<%s>('%s', %s%s%s", resp, r.Method, quote, path, quote)
+		if len(init) > 0 {
+			call += ", { " + strings.Join(init, ", ") + " }"
+		}
+		fmt.Fprintf(&b, "    %s: (%s) =>\n      %s),\n", r.ID, strings.Join(args, ", "), call)
+	}
+	b.WriteString("  };\n}\n\nexport type ApiClient = ReturnType<typeof createApiClient>;\n")
+	return b.String()
+}
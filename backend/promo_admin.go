@@ -0,0 +1,444 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+/* ===== QUẢN LÝ GIFT CODE: DANH SÁCH, LƯỢT NHẬP, THU HỒI, LƯU TRỮ, XUẤT FILE ===== */
+
+// trạng thái suy ra (không lưu DB), xét theo thứ tự — điều kiện đầu tiên đúng thắng
+const (
+	PROMO_ST_ARCHIVED  = "archived"
+	PROMO_ST_REVOKED   = "revoked"
+	PROMO_ST_SCHEDULED = "scheduled"
+	PROMO_ST_EXPIRED   = "expired"
+	PROMO_ST_EXHAUSTED = "exhausted"
+	PROMO_ST_ACTIVE    = "active"
+)
+
+type promoStatusCond struct {
+	Status string
+	SQL    string // mỗi ? là thời điểm hiện tại
+}
+
+// p = promo_campaigns
+var promoCampaignStatusConds = []promoStatusCond{
+	{PROMO_ST_ARCHIVED, "p.archived_at IS NOT NULL"},
+	{PROMO_ST_REVOKED, "p.is_active = 0"},
+	{PROMO_ST_SCHEDULED, "p.starts_at IS NOT NULL AND p.starts_at > ?"},
+	{PROMO_ST_EXPIRED, "p.expires_at IS NOT NULL AND p.expires_at <= ?"},
+	{PROMO_ST_EXHAUSTED, "p.max_redemptions IS NOT NULL AND p.used_count >= p.max_redemptions"},
+}
+
+// c = promo_campaign_codes (join p); code mang trạng thái của chiến dịch nếu chiến dịch không còn hiệu lực
+var promoCodeStatusConds = []promoStatusCond{
+	{PROMO_ST_ARCHIVED, "p.archived_at IS NOT NULL"},
+	{PROMO_ST_REVOKED, "c.is_active = 0 OR p.is_active = 0"},
+	{PROMO_ST_SCHEDULED, "p.starts_at IS NOT NULL AND p.starts_at > ?"},
+	{PROMO_ST_EXPIRED, "p.expires_at IS NOT NULL AND p.expires_at <= ?"},
+	{PROMO_ST_EXHAUSTED, "(c.max_uses IS NOT NULL AND c.used_count >= c.max_uses) OR (p.max_redemptions IS NOT NULL AND p.used_count >= p.max_redemptions)"},
+}
+
+// biểu thức CASE trả về trạng thái + tham số tương ứng
+func promoStatusExpr(conds []promoStatusCond, now time.Time) (string, []any) {
+	var b strings.Builder
+	var args []any
+	b.WriteString("(CASE")
+	for _, cd := range conds {
+		fmt.Fprintf(&b, " WHEN %s THEN '%s'", cd.SQL, cd.Status)
+		for range strings.Count(cd.SQL, "?") {
+			args = append(args, now)
+		}
+	}
+	fmt.Fprintf(&b, " ELSE '%s' END)", PROMO_ST_ACTIVE)
+	return b.String(), args
+}
+
+// đọc ?status= ; rỗng = mọi trạng thái trừ archived
+func parsePromoStatus(c *gin.Context) (string, *AppError) {
+	st := strings.ToLower(strings.TrimSpace(c.Query("status")))
+	switch st {
+	case "", "all", PROMO_ST_ARCHIVED, PROMO_ST_REVOKED, PROMO_ST_SCHEDULED, PROMO_ST_EXPIRED, PROMO_ST_EXHAUSTED, PROMO_ST_ACTIVE:
+		return st, nil
+	}
+	return "", apiError(ERR_INVALID_INPUT, "field", "status",
+		"detail", "chỉ nhận active|scheduled|expired|exhausted|revoked|archived|all")
+}
+
+func filterPromoStatus(q *gorm.DB, conds []promoStatusCond, status string) *gorm.DB {
+	expr, args := promoStatusExpr(conds, time.Now())
+	switch status {
+	case "all":
+		return q
+	case "":
+		return q.Where("p.archived_at IS NULL")
+	}
+	return q.Where(expr+" = ?", append(args, status)...)
+}
+
+/* ----- danh sách ----- */
+
+type PromoCampaignRow struct {
+	PromoCampaign
+	CodeCount int64  `json:"codeCount"`
+	Status    string `json:"status"`
+}
+
+// GET /admin/promo-campaigns?status=&q=&from=&to=&cursor=&limit= — from/to lọc theo ngày tạo
+func adminListPromoCampaignsHandler(c *gin.Context) {
+	f, aerr := parseHistoryFilter(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	status, aerr := parsePromoStatus(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+
+	expr, args := promoStatusExpr(promoCampaignStatusConds, time.Now())
+	q := DB.Table("promo_campaigns p").
+		Select("p.*, (SELECT COUNT(*) FROM promo_campaign_codes c WHERE c.campaign_id = p.id) AS code_count, "+expr+" AS status", args...)
+	q = filterPromoStatus(q, promoCampaignStatusConds, status)
+	if s := strings.TrimSpace(c.Query("q")); s != "" {
+		// tìm theo tên chiến dịch hoặc 1 code thuộc chiến dịch
+		q = q.Where("p.name LIKE ? OR p.id IN (SELECT campaign_id FROM promo_campaign_codes WHERE code = ?)",
+			"%"+s+"%", normalizePromoCode(s))
+	}
+	if f.From != nil {
+		q = q.Where("p.created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("p.created_at < ?", *f.To)
+	}
+	if f.Cursor > 0 {
+		q = q.Where("p.id < ?", f.Cursor)
+	}
+
+	rows := []PromoCampaignRow{}
+	if err := q.Order("p.id DESC").Limit(f.Limit + 1).Scan(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	var next *uint
+	if len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		next = &rows[f.Limit-1].ID
+	}
+	c.JSON(200, gin.H{"rows": rows, "nextCursor": next})
+}
+
+type PromoCodeRow struct {
+	ID         uint       `json:"id"`
+	CampaignID uint       `json:"campaignId"`
+	Campaign   string     `json:"campaign"`
+	Code       string     `json:"code"`
+	MaxUses    *int       `json:"maxUses"`
+	UsedCount  int        `json:"usedCount"`
+	IsActive   bool       `json:"isActive"`
+	Status     string     `json:"status"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RewardBundle
+}
+
+// code (kèm thông tin chiến dịch) theo bộ lọc; cursor = c.id giảm dần
+func promoCodeQuery(campaignID uint, status, search string) *gorm.DB {
+	expr, args := promoStatusExpr(promoCodeStatusConds, time.Now())
+	q := DB.Table("promo_campaign_codes c").
+		Joins("JOIN promo_campaigns p ON p.id = c.campaign_id").
+		Select("c.id, c.campaign_id, p.name AS campaign, c.code, c.max_uses, c.used_count, c.is_active, "+
+			"p.expires_at, c.created_at, p.coins, p.bonus_coins, p.free_spins, p.item_code, p.item_qty, "+expr+" AS status", args...)
+	q = filterPromoStatus(q, promoCodeStatusConds, status)
+	if campaignID > 0 {
+		q = q.Where("c.campaign_id = ?", campaignID)
+	}
+	if search != "" {
+		q = q.Where("c.code LIKE ?", "%"+normalizePromoCode(search)+"%")
+	}
+	return q
+}
+
+func pagePromoCodes(q *gorm.DB, cursor uint, limit int) ([]PromoCodeRow, *uint, error) {
+	if cursor > 0 {
+		q = q.Where("c.id < ?", cursor)
+	}
+	rows := []PromoCodeRow{}
+	if err := q.Order("c.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	var next *uint
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next = &rows[limit-1].ID
+	}
+	return rows, next, nil
+}
+
+// GET /admin/promo-codes?campaignId=&status=&q=&cursor=&limit=
+func adminListPromoCodesHandler(c *gin.Context) {
+	f, aerr := parseHistoryFilter(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	status, aerr := parsePromoStatus(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	campaignID, _ := strconv.ParseUint(c.Query("campaignId"), 10, 64)
+	if campaignID > 0 && status == "" {
+		status = "all" // xem 1 chiến dịch cụ thể thì hiện cả code đã lưu trữ
+	}
+	rows, next, err := pagePromoCodes(promoCodeQuery(uint(campaignID), status, strings.TrimSpace(c.Query("q"))), f.Cursor, f.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"rows": rows, "nextCursor": next})
+}
+
+type PromoRedemptionRow struct {
+	ID         uint   `json:"id"`
+	CampaignID uint   `json:"campaignId"`
+	CodeID     uint   `json:"codeId"`
+	Code       string `json:"code"`
+	UserID     uint   `json:"userId"`
+	Username   string `json:"username"`
+	RewardBundle
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GET /admin/promo-codes/:id/redemptions | /admin/promo-campaigns/:id/redemptions?cursor=&limit=&from=&to= — ai nhập, lúc nào
+func adminPromoRedemptionsHandler(col string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parsePromoCampaignID(c)
+		if !ok {
+			return
+		}
+		f, aerr := parseHistoryFilter(c)
+		if aerr != nil {
+			respondError(c, aerr)
+			return
+		}
+		q := DB.Table("promo_redemptions r").
+			Joins("LEFT JOIN users u ON u.id = r.user_id").
+			Select("r.id, r.campaign_id, r.code_id, r.code, r.user_id, u.username, r.coins, r.bonus_coins, r.free_spins, r.item_code, r.item_qty, r.created_at").
+			Where("r."+col+" = ?", id)
+		if f.Cursor > 0 {
+			q = q.Where("r.id < ?", f.Cursor)
+		}
+		if f.From != nil {
+			q = q.Where("r.created_at >= ?", *f.From)
+		}
+		if f.To != nil {
+			q = q.Where("r.created_at < ?", *f.To)
+		}
+		rows := []PromoRedemptionRow{}
+		if err := q.Order("r.id DESC").Limit(f.Limit + 1).Scan(&rows).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+		var next *uint
+		if len(rows) > f.Limit {
+			rows = rows[:f.Limit]
+			next = &rows[f.Limit-1].ID
+		}
+		c.JSON(200, gin.H{"rows": rows, "nextCursor": next})
+	}
+}
+
+/* ----- thu hồi / kích hoạt lại / lưu trữ ----- */
+
+// POST /admin/promo-campaigns/:id/{revoke|reactivate|archive|unarchive}
+func adminSetPromoCampaignHandler(field string, value func() any, msg string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parsePromoCampaignID(c)
+		if !ok {
+			return
+		}
+		var camp PromoCampaign
+		if err := DB.First(&camp, id).Error; err != nil {
+			respondError(c, apiError(ERR_PROMO_CAMPAIGN_NOT_FOUND))
+			return
+		}
+		if err := DB.Model(&camp).Update(field, value()).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(200, gin.H{"message": msg, "campaign": camp})
+	}
+}
+
+// POST /admin/promo-codes/:id/{revoke|reactivate} — chỉ 1 code, chiến dịch giữ nguyên
+func adminSetPromoCodeActiveHandler(active bool, msg string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parsePromoCampaignID(c)
+		if !ok {
+			return
+		}
+		var pc PromoCampaignCode
+		if err := DB.First(&pc, id).Error; err != nil {
+			respondError(c, apiError(ERR_PROMO_NOT_FOUND))
+			return
+		}
+		if err := DB.Model(&pc).Update("is_active", active).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(200, gin.H{"message": msg, "code": pc})
+	}
+}
+
+// Lưu trữ (ẩn khỏi danh sách mặc định) các chiến dịch đã hết hạn quá promo.archive_after_days ngày.
+// Không xoá gì: code & lượt nhập vẫn tra cứu được với ?status=archived. Chạy bằng job promo.archive.
+func archiveEndedPromoCampaigns() error {
+	days := settingInt("promo.archive_after_days")
+	if days <= 0 {
+		return nil
+	}
+	now := time.Now()
+	return DB.Model(&PromoCampaign{}).
+		Where("archived_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", now.AddDate(0, 0, -int(days))).
+		Update("archived_at", now).Error
+}
+
+/* ----- xuất lô code: CSV hoặc trang in kèm QR ----- */
+
+var promoExportColumns = []string{"code", "campaign", "reward", "status", "maxUses", "usedCount", "expiresAt", "createdAt", "redeemUrl"}
+
+// link nhập code theo mẫu promo.redeem_url ({code} được thay bằng code); mẫu rỗng = chỉ code
+func promoRedeemURL(tpl, code string) string {
+	if tpl == "" {
+		return ""
+	}
+	return strings.ReplaceAll(tpl, "{code}", code)
+}
+
+type promoPrintCard struct {
+	Code, Reward, Expires string
+	QR                    template.HTML
+}
+
+var promoPrintHeadTpl = template.Must(template.New("head").Parse(`<!DOCTYPE html>
+<html lang="vi"><head><meta charset="utf-8"><title>{{.}}</title>
+<style>
+body{font-family:sans-serif;margin:12px}
+h1{font-size:16px}
+.grid{display:flex;flex-wrap:wrap;gap:8px}
+.card{width:180px;border:1px dashed #999;padding:8px;text-align:center;page-break-inside:avoid}
+.card svg{width:140px;height:140px}
+.code{font:bold 15px monospace;letter-spacing:1px}
+.small{font-size:11px;color:#555}
+</style></head><body><h1>{{.}}</h1><div class="grid">
+`))
+
+var promoPrintCardTpl = template.Must(template.New("card").Parse(`<div class="card">{{.QR}}<div class="code">{{.Code}}</div><div class="small">{{.Reward}}</div>{{if .Expires}}<div class="small">HSD: {{.Expires}}</div>{{end}}</div>
+`))
+
+// GET /admin/promo-campaigns/:id/export?format=csv|html&status= — mặc định xuất code đang dùng được (status=active)
+func adminExportPromoCodesHandler(c *gin.Context) {
+	id, ok := parsePromoCampaignID(c)
+	if !ok {
+		return
+	}
+	var camp PromoCampaign
+	if err := DB.First(&camp, id).Error; err != nil {
+		respondError(c, apiError(ERR_PROMO_CAMPAIGN_NOT_FOUND))
+		return
+	}
+	status, aerr := parsePromoStatus(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	if status == "" {
+		status = PROMO_ST_ACTIVE
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	var tw tableWriter
+	switch format {
+	case "csv":
+		tw = newCSVTable(c.Writer)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="promo-%d-%s.csv"`, camp.ID, time.Now().Format("20060102")))
+	case "html":
+		c.Header("Content-Type", "text/html; charset=utf-8")
+	default:
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "format", "detail", "chỉ nhận csv|html"))
+		return
+	}
+	c.Status(200)
+
+	if tw != nil {
+		if err := tw.Header(promoExportColumns); err != nil {
+			return
+		}
+	} else if err := promoPrintHeadTpl.Execute(c.Writer, camp.Name); err != nil {
+		return
+	}
+
+	// đọc từng lô theo cursor, ghi thẳng ra response
+	urlTpl := strings.TrimSpace(settingString("promo.redeem_url"))
+	q := promoCodeQuery(camp.ID, status, "")
+	var cursor uint
+	for written := 0; written < historyExportMax; {
+		rows, next, err := pagePromoCodes(q.Session(&gorm.Session{}), cursor, historyExportBatch)
+		if err != nil {
+			_ = c.Error(err) // header đã gửi, không đổi được status
+			break
+		}
+		for _, r := range rows {
+			url := promoRedeemURL(urlTpl, r.Code)
+			if tw != nil {
+				var maxUses, expires any = "", ""
+				if r.MaxUses != nil {
+					maxUses = *r.MaxUses
+				}
+				if r.ExpiresAt != nil {
+					expires = *r.ExpiresAt
+				}
+				if err := tw.Row([]any{r.Code, r.Campaign, r.RewardBundle.summary(), r.Status, maxUses, r.UsedCount,
+					expires, r.CreatedAt, url}); err != nil {
+					return // client đóng kết nối
+				}
+				continue
+			}
+			card := promoPrintCard{Code: r.Code, Reward: r.RewardBundle.summary()}
+			if r.ExpiresAt != nil {
+				card.Expires = r.ExpiresAt.Format("02/01/2006 15:04")
+			}
+			payload := url
+			if payload == "" {
+				payload = r.Code
+			}
+			if qr, err := qrEncode(payload); err == nil {
+				card.QR = template.HTML(qr.svg())
+			}
+			if err := promoPrintCardTpl.Execute(c.Writer, card); err != nil {
+				return
+			}
+		}
+		written += len(rows)
+		c.Writer.Flush()
+		if next == nil {
+			break
+		}
+		cursor = *next
+	}
+	if tw != nil {
+		_ = tw.Close()
+	} else {
+		_, _ = c.Writer.WriteString("</div></body></html>\n")
+	}
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== CHIẾN DỊCH KHUYẾN MÃI: GIFT CODE HỢP NHẤT ===== */
+
+// nguồn dữ liệu cũ đã chuyển sang chiến dịch
+const (
+	PROMO_LEGACY_SPINS = "promo_codes"       // gift code lượt quay
+	PROMO_LEGACY_BONUS = "promo_bonus_codes" // code bonus coin
+)
+
+const promoCodeLen = 12 // độ dài code sinh ngẫu nhiên
+
+// code tự đặt: chữ hoa, số, _ và -
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{4,32}$`)
+
+// 1 chiến dịch = phần thưởng + điều kiện + giới hạn, dùng chung cho mọi code thuộc nó
+type PromoCampaign struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"size:120;not null" json:"name"`
+	RewardBundle
+
+	// điều kiện nhận
+	MinVIPLevel         int  `gorm:"column:min_vip_level;not null;default:0" json:"minVipLevel"`
+	RequireKYC          bool `gorm:"column:require_kyc;not null;default:false" json:"requireKyc"`
+	MinAccountAgeHours  int  `gorm:"not null;default:0" json:"minAccountAgeHours"`  // tài khoản tạo ít nhất N giờ
+	NewUsersWithinHours int  `gorm:"not null;default:0" json:"newUsersWithinHours"` // > 0: chỉ tài khoản tạo trong N giờ gần đây
+
+	// giới hạn (mỗi code vẫn chỉ nhận 1 lần/user)
+	PerUserLimit   int  `gorm:"not null;default:1" json:"perUserLimit"` // số code của chiến dịch 1 user được nhập (0 = không giới hạn)
+	MaxRedemptions *int `json:"maxRedemptions"`                         // tổng lượt toàn chiến dịch (nil = vô hạn)
+	UsedCount      int  `gorm:"not null;default:0" json:"usedCount"`
+
+	StartsAt     *time.Time `json:"startsAt"`
+	ExpiresAt    *time.Time `gorm:"index" json:"expiresAt"`
+	IsActive     bool       `gorm:"not null;default:true" json:"isActive"` // false = đã thu hồi
+	ArchivedAt   *time.Time `gorm:"index" json:"archivedAt"`               // đã lưu trữ: ẩn khỏi danh sách, không nhập được
+	LegacySource string     `gorm:"size:20" json:"legacySource,omitempty"`
+	CreatedBy    *uint      `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+const promoCodeMaxLen = 32 // = size cột promo_campaign_codes.code
+
+type PromoCampaignCode struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CampaignID uint      `gorm:"not null;index" json:"campaignId"`
+	Code       string    `gorm:"size:32;not null;uniqueIndex" json:"code"` // luôn chữ hoa
+	MaxUses    *int      `json:"maxUses"`                                  // nil = vô hạn
+	UsedCount  int       `gorm:"not null;default:0" json:"usedCount"`
+	IsActive   bool      `gorm:"not null;default:true" json:"isActive"`
+	LegacyID   uint      `gorm:"not null;default:0" json:"-"` // id ở bảng cũ (chỉ code được chuyển sang)
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// 1 lượt nhập code (lưu lại phần thưởng đã cộng)
+type PromoRedemption struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	CampaignID uint   `gorm:"not null;index:idx_promo_red_campaign_user,priority:1" json:"campaignId"`
+	CodeID     uint   `gorm:"not null;uniqueIndex:uniq_promo_red_code_user,priority:1" json:"codeId"`
+	UserID     uint   `gorm:"not null;uniqueIndex:uniq_promo_red_code_user,priority:2;index:idx_promo_red_campaign_user,priority:2" json:"userId"`
+	Code       string `gorm:"size:32;not null" json:"code"`
+	RewardBundle
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func normalizePromoCode(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// kiểm tra điều kiện nhận của user
+func (p PromoCampaign) checkEligible(u User, now time.Time) error {
+	if u.VIPLevel < p.MinVIPLevel {
+		return apiError(ERR_PROMO_VIP_REQUIRED, "level", p.MinVIPLevel)
+	}
+	if p.RequireKYC && u.KYCStatus != "VERIFIED" {
+		return apiError(ERR_PROMO_KYC_REQUIRED)
+	}
+	age := now.Sub(u.CreatedAt)
+	if p.MinAccountAgeHours > 0 && age < time.Duration(p.MinAccountAgeHours)*time.Hour {
+		return apiError(ERR_PROMO_ACCOUNT_TOO_NEW, "hours", p.MinAccountAgeHours)
+	}
+	if p.NewUsersWithinHours > 0 && age > time.Duration(p.NewUsersWithinHours)*time.Hour {
+		return apiError(ERR_PROMO_NEW_USERS_ONLY)
+	}
+	return nil
+}
+
+// POST /private/redeem-code { code } — endpoint nhập code duy nhất (không phân biệt hoa thường)
+func redeemCodeHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req RedeemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	code := normalizePromoCode(req.Code)
+	if code == "" {
+		respondError(c, apiError(ERR_PROMO_CODE_REQUIRED))
+		return
+	}
+
+	var camp PromoCampaign
+	if err := withEvents(func(tx *gorm.DB) error {
+		// 🔒 khoá code rồi chiến dịch (giữ đúng giới hạn tổng & theo user khi nhập song song)
+		var pc PromoCampaignCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code = ?", code).First(&pc).Error; err != nil {
+			return apiError(ERR_PROMO_NOT_FOUND)
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&camp, pc.CampaignID).Error; err != nil {
+			return apiError(ERR_PROMO_NOT_FOUND)
+		}
+		if !pc.IsActive || !camp.IsActive || camp.ArchivedAt != nil {
+			return apiError(ERR_PROMO_NOT_FOUND)
+		}
+
+		now := time.Now()
+		if camp.StartsAt != nil && now.Before(*camp.StartsAt) {
+			return apiError(ERR_PROMO_NOT_STARTED, "startsAt", camp.StartsAt.Format("2006-01-02 15:04"))
+		}
+		if camp.ExpiresAt != nil && !now.Before(*camp.ExpiresAt) {
+			return apiError(ERR_PROMO_EXPIRED)
+		}
+		if (pc.MaxUses != nil && pc.UsedCount >= *pc.MaxUses) ||
+			(camp.MaxRedemptions != nil && camp.UsedCount >= *camp.MaxRedemptions) {
+			return apiError(ERR_PROMO_EXHAUSTED)
+		}
+
+		var u User
+		if err := tx.Select("id, v_ip_level, kyc_status, created_at").First(&u, uid).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		if err := camp.checkEligible(u, now); err != nil {
+			return err
+		}
+
+		var used int64
+		if err := tx.Model(&PromoRedemption{}).Where("code_id = ? AND user_id = ?", pc.ID, uid).Count(&used).Error; err != nil {
+			return err
+		}
+		if used > 0 {
+			return apiError(ERR_PROMO_ALREADY_USED)
+		}
+		if camp.PerUserLimit > 0 {
+			if err := tx.Model(&PromoRedemption{}).Where("campaign_id = ? AND user_id = ?", camp.ID, uid).Count(&used).Error; err != nil {
+				return err
+			}
+			if used >= int64(camp.PerUserLimit) {
+				return apiError(ERR_PROMO_USER_LIMIT, "limit", camp.PerUserLimit)
+			}
+		}
+
+		red := PromoRedemption{CampaignID: camp.ID, CodeID: pc.ID, UserID: uid, Code: pc.Code, RewardBundle: camp.RewardBundle}
+		if err := tx.Create(&red).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&pc).Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&camp).Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+			return err
+		}
+		if err := grantReward(tx, uid, camp.RewardBundle, LEDGER_PROMO, red.ID); err != nil {
+			return err
+		}
+		_ = notify(tx, uid, NT_PROMO_REDEEMED, map[string]any{"code": pc.Code, "campaign": camp.Name})
+		return nil
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Nhập code thành công", "campaign": camp.Name, "reward": camp.RewardBundle})
+}
+
+/* ----- admin ----- */
+
+type PromoCodesRequest struct {
+	Count   int    `json:"count" binding:"gte=0,lte=1000"` // số code sinh ngẫu nhiên (mặc định 1)
+	Code    string `json:"code"`                           // tự đặt 1 code (vd TET2026), bỏ qua count
+	MaxUses *int   `json:"maxUses"`                        // lượt dùng mỗi code (nil = vô hạn, 1 = dùng 1 lần)
+}
+
+type PromoCampaignRequest struct {
+	Name string `json:"name" binding:"required,max=120"`
+	RewardBundle
+	MinVIPLevel         int               `json:"minVipLevel" binding:"gte=0"`
+	RequireKYC          bool              `json:"requireKyc"`
+	MinAccountAgeHours  int               `json:"minAccountAgeHours" binding:"gte=0"`
+	NewUsersWithinHours int               `json:"newUsersWithinHours" binding:"gte=0"`
+	PerUserLimit        *int              `json:"perUserLimit"` // mặc định 1; 0 = không giới hạn
+	MaxRedemptions      *int              `json:"maxRedemptions"`
+	StartsAt            *time.Time        `json:"startsAt"`
+	ExpiresAt           *time.Time        `json:"expiresAt"`
+	Codes               PromoCodesRequest `json:"codes"`
+}
+
+func (r *PromoCampaignRequest) normalize() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return apiError(ERR_PROMO_INVALID, "field", "name")
+	}
+	if f := r.invalidField(); f != "" {
+		return apiError(ERR_PROMO_INVALID, "field", f)
+	}
+	if r.PerUserLimit != nil && *r.PerUserLimit < 0 {
+		return apiError(ERR_PROMO_INVALID, "field", "perUserLimit")
+	}
+	if r.MaxRedemptions != nil && *r.MaxRedemptions < 1 {
+		return apiError(ERR_PROMO_INVALID, "field", "maxRedemptions")
+	}
+	if r.ExpiresAt != nil && (!r.ExpiresAt.After(time.Now()) || (r.StartsAt != nil && !r.ExpiresAt.After(*r.StartsAt))) {
+		return apiError(ERR_PROMO_INVALID, "field", "expiresAt")
+	}
+	return r.Codes.normalize()
+}
+
+func (r *PromoCodesRequest) normalize() error {
+	r.Code = normalizePromoCode(r.Code)
+	if r.Code != "" && !promoCodePattern.MatchString(r.Code) {
+		return apiError(ERR_PROMO_INVALID, "field", "codes.code")
+	}
+	if r.MaxUses != nil && *r.MaxUses < 1 {
+		return apiError(ERR_PROMO_MAX_USES_INVALID)
+	}
+	if r.Count <= 0 || r.Code != "" {
+		r.Count = 1
+	}
+	return nil
+}
+
+// Sinh code cho chiến dịch (trong transaction); code ngẫu nhiên trùng thì sinh lại, code tự đặt trùng thì báo lỗi.
+func createPromoCodes(tx *gorm.DB, campaignID uint, r PromoCodesRequest) ([]string, error) {
+	codes := make([]string, 0, r.Count)
+	for len(codes) < r.Count {
+		code := r.Code
+		for try := 0; code == ""; try++ {
+			if try == 8 {
+				return nil, apiError(ERR_PROMO_GENERATION_FAILED)
+			}
+			val, err := randCode(promoCodeLen)
+			if err != nil {
+				return nil, apiError(ERR_PROMO_GENERATION_FAILED).wrap(err)
+			}
+			var exists int64
+			if err := tx.Model(&PromoCampaignCode{}).Where("code = ?", strings.ToUpper(val)).Count(&exists).Error; err != nil {
+				return nil, err
+			}
+			if exists == 0 {
+				code = strings.ToUpper(val)
+			}
+		}
+		if r.Code != "" {
+			var exists int64
+			if err := tx.Model(&PromoCampaignCode{}).Where("code = ?", code).Count(&exists).Error; err != nil {
+				return nil, err
+			}
+			if exists > 0 {
+				return nil, apiError(ERR_PROMO_CODE_TAKEN, "code", code)
+			}
+		}
+		if err := tx.Create(&PromoCampaignCode{
+			CampaignID: campaignID, Code: code, MaxUses: r.MaxUses, IsActive: true,
+		}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func parsePromoCampaignID(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// POST /admin/promo-campaigns { name, coins, bonusCoins, freeSpins, itemCode, itemQty, minVipLevel, requireKyc, minAccountAgeHours, newUsersWithinHours, perUserLimit, maxRedemptions, startsAt, expiresAt, codes: { count, code, maxUses } }
+func adminCreatePromoCampaignHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req PromoCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	camp := PromoCampaign{
+		Name: req.Name, RewardBundle: req.RewardBundle,
+		MinVIPLevel: req.MinVIPLevel, RequireKYC: req.RequireKYC,
+		MinAccountAgeHours: req.MinAccountAgeHours, NewUsersWithinHours: req.NewUsersWithinHours,
+		PerUserLimit: 1, MaxRedemptions: req.MaxRedemptions,
+		StartsAt: req.StartsAt, ExpiresAt: req.ExpiresAt, IsActive: true, CreatedBy: &adminID,
+	}
+	if req.PerUserLimit != nil {
+		camp.PerUserLimit = *req.PerUserLimit
+	}
+	var codes []string
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&camp).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = createPromoCodes(tx, camp.ID, req.Codes)
+		return err
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã tạo chiến dịch", "campaign": camp, "codes": codes})
+}
+
+// POST /admin/promo-campaigns/:id/codes { count, code, maxUses } — sinh thêm code cho chiến dịch
+func adminCreatePromoCodesHandler(c *gin.Context) {
+	id, ok := parsePromoCampaignID(c)
+	if !ok {
+		return
+	}
+	var req PromoCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var codes []string
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		var camp PromoCampaign
+		if err := tx.First(&camp, id).Error; err != nil {
+			return apiError(ERR_PROMO_CAMPAIGN_NOT_FOUND)
+		}
+		var err error
+		codes, err = createPromoCodes(tx, camp.ID, req)
+		return err
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã tạo code", "count": len(codes), "codes": codes})
+}
+
+/* ----- chuyển dữ liệu cũ ----- */
+
+// Chuyển gift code cũ sang chiến dịch. Code cùng phần thưởng & hạn được gom 1 chiến dịch
+// (thường là cùng 1 lần sinh). Mỗi code vẫn 1 lần/user, không giới hạn số code/user như trước.
+// Chạy mỗi lần khởi động; dòng cũ chuyển xong bị xoá khỏi bảng cũ trong cùng transaction nên không chuyển 2 lần.
+// code cũ đổi tên khi chuyển sang promo_campaign_codes
+type legacyPromoRename struct {
+	Source   string
+	LegacyID uint
+	From, To string
+}
+
+// code chữ hoa chưa bị dùng: trùng (khác hoa/thường, code bonus trùng code lượt quay, trùng code chiến dịch)
+// thì thêm hậu tố -2, -3…
+func uniqueLegacyPromoCode(code string, taken map[string]bool) string {
+	base := normalizePromoCode(code)
+	out := base
+	for n := 2; taken[out]; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		out = base[:min(len(base), promoCodeMaxLen-len(suffix))] + suffix
+	}
+	taken[out] = true
+	return out
+}
+
+func migrateLegacyPromoCodes() error {
+	type legacyCode struct {
+		ID        uint
+		Code      string
+		Reward    RewardBundle
+		MaxUses   *int
+		UsedCount int
+		ExpiresAt *time.Time
+		IsActive  bool
+		CreatedBy *uint
+		CreatedAt time.Time
+	}
+	var spins []PromoCode
+	var bonus []PromoBonusCode
+	if err := DB.Find(&spins).Error; err != nil {
+		return fmt.Errorf("promo_codes: %w", err)
+	}
+	if err := DB.Find(&bonus).Error; err != nil {
+		return fmt.Errorf("promo_bonus_codes: %w", err)
+	}
+	if len(spins) == 0 && len(bonus) == 0 {
+		return nil
+	}
+	// kiểm tra trùng trước khi ghi: unique index trên code sẽ làm hỏng cả transaction
+	var existing []string
+	if err := DB.Model(&PromoCampaignCode{}).Pluck("code", &existing).Error; err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		taken[c] = true
+	}
+	sources := map[string][]legacyCode{}
+	for _, p := range spins {
+		sources[PROMO_LEGACY_SPINS] = append(sources[PROMO_LEGACY_SPINS], legacyCode{
+			p.ID, p.Code, RewardBundle{FreeSpins: p.RewardFreeSpin}, p.MaxUses, p.UsedCount, p.ExpiresAt, p.IsActive, p.CreatedBy, p.CreatedAt,
+		})
+	}
+	for _, p := range bonus {
+		maxUses := p.MaxUses
+		sources[PROMO_LEGACY_BONUS] = append(sources[PROMO_LEGACY_BONUS], legacyCode{
+			p.ID, p.Code, RewardBundle{BonusCoins: int64(p.BonusCoins)}, &maxUses, p.UsedCount, p.ExpiresAt, p.IsActive, p.CreatedBy, p.CreatedAt,
+		})
+	}
+
+	for _, source := range []string{PROMO_LEGACY_SPINS, PROMO_LEGACY_BONUS} {
+		olds := sources[source]
+		if len(olds) == 0 {
+			continue
+		}
+		var renamed []legacyPromoRename
+		if err := withEvents(func(tx *gorm.DB) error {
+			camps := map[string]*PromoCampaign{}
+			for _, o := range olds {
+				key := fmt.Sprintf("%d|%d|none", o.Reward.FreeSpins, o.Reward.BonusCoins)
+				if o.ExpiresAt != nil {
+					key = fmt.Sprintf("%d|%d|%d", o.Reward.FreeSpins, o.Reward.BonusCoins, o.ExpiresAt.Unix())
+				}
+				camp := camps[key]
+				if camp == nil {
+					name := fmt.Sprintf("Gift code cũ: +%d lượt quay", o.Reward.FreeSpins)
+					if source == PROMO_LEGACY_BONUS {
+						name = fmt.Sprintf("Code bonus cũ: +%d bonus coin", o.Reward.BonusCoins)
+					}
+					camp = &PromoCampaign{
+						Name: name, RewardBundle: o.Reward, PerUserLimit: 0,
+						ExpiresAt: o.ExpiresAt, IsActive: true, LegacySource: source, CreatedBy: o.CreatedBy,
+					}
+					if err := tx.Create(camp).Error; err != nil {
+						return err
+					}
+					camps[key] = camp
+				}
+				pc := PromoCampaignCode{
+					CampaignID: camp.ID, Code: uniqueLegacyPromoCode(o.Code, taken), MaxUses: o.MaxUses,
+					UsedCount: o.UsedCount, IsActive: o.IsActive, LegacyID: o.ID, CreatedAt: o.CreatedAt,
+				}
+				if pc.Code != normalizePromoCode(o.Code) {
+					renamed = append(renamed, legacyPromoRename{source, o.ID, o.Code, pc.Code})
+				}
+				if err := tx.Create(&pc).Error; err != nil {
+					return fmt.Errorf("code %s: %w", o.Code, err)
+				}
+				camp.UsedCount += o.UsedCount
+
+				// lượt dùng cũ (chỉ gift code lượt quay có lưu theo user)
+				if source == PROMO_LEGACY_SPINS {
+					var uses []PromoCodeUse
+					if err := tx.Where("promo_code_id = ?", o.ID).Find(&uses).Error; err != nil {
+						return err
+					}
+					for _, use := range uses {
+						if err := tx.Create(&PromoRedemption{
+							CampaignID: camp.ID, CodeID: pc.ID, UserID: use.UserID, Code: pc.Code,
+							RewardBundle: o.Reward, CreatedAt: use.UsedAt,
+						}).Error; err != nil {
+							return err
+						}
+					}
+				}
+			}
+			for _, camp := range camps {
+				if err := tx.Model(camp).Update("used_count", camp.UsedCount).Error; err != nil {
+					return err
+				}
+			}
+			if err := notifyLegacyPromoRenames(tx, renamed); err != nil {
+				return err
+			}
+			ids := make([]uint, 0, len(olds))
+			for _, o := range olds {
+				ids = append(ids, o.ID)
+			}
+			if source == PROMO_LEGACY_BONUS {
+				return tx.Where("id IN ?", ids).Delete(&PromoBonusCode{}).Error
+			}
+			if err := tx.Where("promo_code_id IN ?", ids).Delete(&PromoCodeUse{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&PromoCode{}).Error
+		}); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		for _, r := range renamed {
+			log.Printf("⚠️  %s #%d: code %q trùng, đổi thành %s", r.Source, r.LegacyID, r.From, r.To)
+		}
+		log.Printf("✅ migrated %d codes from %s (%d đổi tên)", len(olds), source, len(renamed))
+	}
+	return nil
+}
+
+// báo admin danh sách code cũ bị đổi tên (người giữ code cũ cần được báo code mới)
+func notifyLegacyPromoRenames(tx *gorm.DB, renamed []legacyPromoRename) error {
+	if len(renamed) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(renamed))
+	for _, r := range renamed {
+		list = append(list, r.From+" → "+r.To)
+	}
+	codes := strings.Join(list, ", ")
+	if len(codes) > 300 {
+		codes = truncate(codes, 300) + "…"
+	}
+	var admins []uint
+	if err := tx.Model(&User{}).Where("role = 'admin'").Pluck("id", &admins).Error; err != nil {
+		return err
+	}
+	for _, aid := range admins {
+		if err := notify(tx, aid, NT_PROMO_CODES_RENAMED, map[string]any{
+			"count": len(renamed), "source": renamed[0].Source, "codes": codes,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUniqueLegacyPromoCode(t *testing.T) {
+	long := strings.Repeat("X", promoCodeMaxLen)
+	taken := map[string]bool{"SPRING": true, long: true}
+	tests := []struct{ code, want string }{
+		{"summer", "SUMMER"},
+		{"Summer", "SUMMER-2"}, // chỉ khác hoa/thường
+		{" SUMMER ", "SUMMER-3"},
+		{"spring", "SPRING-2"}, // trùng code chiến dịch đã có
+		{long, strings.Repeat("X", promoCodeMaxLen-2) + "-2"},
+	}
+	for _, tt := range tests {
+		got := uniqueLegacyPromoCode(tt.code, taken)
+		if got != tt.want {
+			t.Errorf("uniqueLegacyPromoCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+		if len(got) > promoCodeMaxLen {
+			t.Errorf("uniqueLegacyPromoCode(%q) dài %d > %d", tt.code, len(got), promoCodeMaxLen)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/* ===== MÃ QR (byte mode, mức sửa lỗi M, version 1..10) — đủ cho code/link in phiếu, không cần thư viện ngoài ===== */
+
+const qrMaxVersion = 10
+
+// mức M: số codeword sửa lỗi mỗi block & số block, theo version (chỉ số 0 bỏ trống)
+var (
+	qrECCPerBlock = [qrMaxVersion + 1]int{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26}
+	qrNumBlocks   = [qrMaxVersion + 1]int{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5}
+)
+
+var errQRTooLong = errors.New("qr: nội dung quá dài")
+
+type qrCode struct {
+	size     int
+	modules  [][]bool // [y][x], true = ô đen
+	function [][]bool // ô thuộc mẫu cố định (không đặt dữ liệu, không áp mask)
+}
+
+// Mã hoá text (UTF-8, byte mode) thành QR nhỏ nhất vừa đủ, tự chọn mask có điểm phạt thấp nhất.
+func qrEncode(text string) (*qrCode, error) {
+	return qrEncodeMask(text, -1)
+}
+
+// mask < 0: tự chọn; 0..7: dùng đúng mask đó (test so với ký hiệu mẫu)
+func qrEncodeMask(text string, mask int) (*qrCode, error) {
+	data := []byte(text)
+	ver := 1
+	for ; ver <= qrMaxVersion; ver++ {
+		if 4+qrCountBits(ver)+8*len(data) <= qrDataCodewords(ver)*8 {
+			break
+		}
+	}
+	if ver > qrMaxVersion {
+		return nil, errQRTooLong
+	}
+
+	// chuỗi bit: mode 0100, độ dài, dữ liệu, kết thúc, đệm
+	var bits []bool
+	put := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (v>>i)&1 == 1)
+		}
+	}
+	put(0x4, 4)
+	put(len(data), qrCountBits(ver))
+	for _, b := range data {
+		put(int(b), 8)
+	}
+	capBits := qrDataCodewords(ver) * 8
+	put(0, min(4, capBits-len(bits)))
+	put(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capBits; pad ^= 0xEC ^ 0x11 {
+		put(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, b := range bits {
+		if b {
+			codewords[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+
+	q := &qrCode{size: ver*4 + 17}
+	q.modules = make([][]bool, q.size)
+	q.function = make([][]bool, q.size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, q.size)
+		q.function[i] = make([]bool, q.size)
+	}
+	q.drawFunctionPatterns(ver)
+	q.drawCodewords(qrAddECC(ver, codewords))
+
+	if mask < 0 {
+		bestScore := -1
+		for m := 0; m < 8; m++ {
+			q.applyMask(m)
+			q.drawFormatBits(m)
+			if s := q.penalty(); bestScore < 0 || s < bestScore {
+				mask, bestScore = m, s
+			}
+			q.applyMask(m) // XOR lần 2 = bỏ mask
+		}
+	}
+	q.applyMask(mask)
+	q.drawFormatBits(mask)
+	return q, nil
+}
+
+func qrCountBits(ver int) int {
+	if ver <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func qrAlignmentPositions(ver int) []int {
+	if ver == 1 {
+		return nil
+	}
+	n := ver/7 + 2
+	step := (ver*8 + n*3 + 5) / (n*4 - 4) * 2
+	out := make([]int, n)
+	out[0] = 6
+	for i, pos := n-1, ver*4+10; i >= 1; i, pos = i-1, pos-step {
+		out[i] = pos
+	}
+	return out
+}
+
+// số ô dữ liệu (kể cả ECC) sau khi trừ mẫu cố định
+func qrRawModules(ver int) int {
+	n := (16*ver+128)*ver + 64
+	if ver >= 2 {
+		a := ver/7 + 2
+		n -= (25*a-10)*a - 55
+		if ver >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+func qrDataCodewords(ver int) int {
+	return qrRawModules(ver)/8 - qrECCPerBlock[ver]*qrNumBlocks[ver]
+}
+
+/* ----- Reed-Solomon trên GF(256), đa thức 0x11D ----- */
+
+func qrGFMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func qrRSDivisor(degree int) []byte {
+	out := make([]byte, degree)
+	out[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range out {
+			out[j] = qrGFMul(out[j], root)
+			if j+1 < len(out) {
+				out[j] ^= out[j+1]
+			}
+		}
+		root = qrGFMul(root, 0x02)
+	}
+	return out
+}
+
+func qrRSRemainder(data, divisor []byte) []byte {
+	out := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ out[0]
+		copy(out, out[1:])
+		out[len(out)-1] = 0
+		for i, d := range divisor {
+			out[i] ^= qrGFMul(d, factor)
+		}
+	}
+	return out
+}
+
+// chia block, thêm ECC rồi xen kẽ các block theo cột
+func qrAddECC(ver int, data []byte) []byte {
+	numBlocks, eccLen := qrNumBlocks[ver], qrECCPerBlock[ver]
+	raw := qrRawModules(ver) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+	div := qrRSDivisor(eccLen)
+
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := range blocks {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		dat := data[k : k+n]
+		k += n
+		b := append([]byte{}, dat...)
+		if i < numShort {
+			b = append(b, 0) // chỗ trống để các block cùng độ dài, bỏ qua khi xen kẽ
+		}
+		blocks[i] = append(b, qrRSRemainder(dat, div)...)
+	}
+	out := make([]byte, 0, raw)
+	for i := range blocks[0] {
+		for j, b := range blocks {
+			if i != shortLen-eccLen || j >= numShort {
+				out = append(out, b[i])
+			}
+		}
+	}
+	return out
+}
+
+/* ----- vẽ ma trận ----- */
+
+func (q *qrCode) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+func (q *qrCode) drawFunctionPatterns(ver int) {
+	for i := 0; i < q.size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	for _, p := range [][2]int{{3, 3}, {q.size - 4, 3}, {3, q.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := p[0]+dx, p[1]+dy
+				if x >= 0 && x < q.size && y >= 0 && y < q.size {
+					d := max(abs(dx), abs(dy))
+					q.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+	pos := qrAlignmentPositions(ver)
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // trùng finder
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(pos[i]+dx, pos[j]+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	q.drawFormatBits(0) // giữ chỗ, vẽ lại sau khi chọn mask
+	if ver >= 7 {
+		bits := qrVersionBits(ver)
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 == 1
+			a, b := q.size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+}
+
+// 18 bit version (từ version 7), BCH(18,6)
+func qrVersionBits(ver int) int {
+	rem := ver
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return ver<<12 | rem
+}
+
+// 15 bit định dạng (mức M = 00) + mask, BCH(15,5)
+func qrFormatBits(mask int) int {
+	data := mask // mức M: 2 bit đầu = 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func (q *qrCode) drawFormatBits(mask int) {
+	bits := qrFormatBits(mask)
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	q.set(8, q.size-8, true) // ô đen cố định
+}
+
+// đặt dữ liệu theo đường zigzag 2 cột từ góc phải dưới
+func (q *qrCode) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = (data[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			var inv bool
+			switch mask {
+			case 0:
+				inv = (x+y)%2 == 0
+			case 1:
+				inv = y%2 == 0
+			case 2:
+				inv = x%3 == 0
+			case 3:
+				inv = (x+y)%3 == 0
+			case 4:
+				inv = (x/3+y/2)%2 == 0
+			case 5:
+				inv = x*y%2+x*y%3 == 0
+			case 6:
+				inv = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				inv = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if inv && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// điểm phạt theo 4 quy tắc của chuẩn (chuỗi cùng màu, khối 2x2, mẫu giống finder, tỉ lệ đen)
+func (q *qrCode) penalty() int {
+	n := q.size
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+	score := 0
+	finder := []bool{true, false, true, true, true, false, true}
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			for x := 0; x+7 <= n; x++ {
+				match := true
+				for k, v := range finder {
+					if at(x+k, y, vertical) != v {
+						match = false
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+				light := func(from, to int) bool {
+					for k := from; k < to; k++ {
+						if k >= 0 && k < n && at(k, y, vertical) {
+							return false
+						}
+					}
+					return true
+				}
+				if light(x-4, x) || light(x+7, x+11) {
+					score += 40
+				}
+			}
+		}
+	}
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return score + max(k, 0)*10
+}
+
+// SVG vuông, viền trắng 4 ô theo chuẩn; kích thước hiển thị do CSS/thuộc tính width quyết định
+func (q *qrCode) svg() string {
+	var b strings.Builder
+	dim := q.size + 8
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, dim, dim)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, dim, dim)
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+4, y+4)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
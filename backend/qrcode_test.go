@@ -0,0 +1,231 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// ký hiệu mẫu (mức M, mask cố định) sinh bằng bộ mã hoá QR độc lập của Kazuhiko Arase; # = ô đen
+var qrReferenceSymbols = []struct {
+	name string
+	text string
+	mask int
+	want string
+}{
+	{"version 1", "HELLO WORLD", 2, `
+#######.....#.#######
+#.....#..#.#..#.....#
+#.###.#.###.#.#.###.#
+#.###.#.#.#.#.#.###.#
+#.###.#.#.#.#.#.###.#
+#.....#.##.#..#.....#
+#######.#.#.#.#######
+........#.#..........
+#.#####...##..#####..
+.##....#.#######.##..
+#.##..##....###..###.
+.###.#..######..###..
+#...#.##.##.##....#.#
+........###.#....#...
+#######..#.#..#...##.
+#.....#.###..#.#.####
+#.###.#.#..#...#..#.#
+#.###.#.#...######...
+#.###.#.##..#..#..#..
+#.....#...#.##..###..
+#######.#.###...#.##.`},
+	// version 8: có bit version, 2 block ngắn + 2 block dài
+	{"version 8", "https://example.com/promo/redeem?code=SUMMER2026-ABCDEFGH&utm_source=print&utm_medium=qr&utm_campaign=summer-festival-2026&lang=vi-VN&x=1", 6, `
+#######.####.##...#####.#..#.##.###.##..#.#######
+#.....#.#..#.##....#####.#####....#..####.#.....#
+#.###.#.###..####...###.#.....###....#.##.#.###.#
+#.###.#..##.##......#.##.####..##..###.#..#.###.#
+#.###.#.#.#.##.##..#.######.#.#.#..###....#.###.#
+#.....#......#.####.###...#.##......#.#...#.....#
+#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#######
+.........#.####.#.#..##...#####..#..#.#.#........
+#..#######..##.######.#####.#.#...######.#..#.###
+#..#....###.###.##.##.#..##########.#.###.#.#####
+#.....###.#....#.#..###......#.###..#..#....##..#
+#.#.....####...#.###..#......#####.#.###.#..###..
+.##.###.#.#.....#.#.##..##..#.#.#.###.#..#..##.##
+###.##...#...###..###..##.#.######.##.#...#.#..#.
+##..#######...#.#.....#######..#..###.#.#.....###
+.##.##.#..#########.#.##.####.##.#....##..#####.#
+###.####..#.########....####..#.#...###.##.#####.
+.....#..###.#..##.##.#..##.####..###.#..####.###.
+####.##.........##.......#.#####..#.##.#....###.#
+####...###..#.###..###...#####.....#.##..##..#...
+#.....#####..####.####.##...##.....##.....##...##
+##.......#.###.#.##.#.###.##################.###.
+.#..######..#..#..#.#.#####.##.#.##.#...#####...#
+..#.#...###..#######..#...##...########.#...###.#
+#####.#.######..##.#.##.#.#.#.#.##..#.#.#.#.###.#
+#####...##.#...##...###...######.#.#..#.#...#####
+..#.#####.....#..###..#####..##..##.##.######.###
+....##.####.##.#...#########...#.#....##....#####
+##.#.####......#..#.###.###...###.#.####.###.....
+####.#.###..#..#.####..##..#..########.#.#...#.#.
+###..######....#...#....#.#..#...#.....#####.##.#
+...###..###...##..#..#..##...#...#.#...###.##..##
+#.....#.#.#####.##..#....#.......#.#####.#.###...
+..####.....##.##.#.#######...#...######..#.#####.
+.###..###.##.###.#.####.###.######.....#######..#
+.....#..#....###..##..#...##....##...#####.####.#
+....#.###.##..#.#.#...#....##.#.#...#.#######..#.
+##.#....###.......#.#.#.#.#.###.##....#.....####.
+.#...##..##..#.#...##..##......#.#.##.###.#...###
+.###....#.###..#..##.####....#...##.#.##.#.#.##..
+###...###..#..#...#..######.....#.#.##########..#
+........#...##.#.#.#..#...##..#..###.#.##...##...
+#######.#.####.##.#...#.#.###..#.######.#.#.#..##
+#.....#.#..#...##.....#...#.####.....####...##.##
+#.###.#.##.#.#...##...########.#...####.######.##
+#.###.#.#####...###.#....#.#.######.#.#....#.#..#
+#.###.#...##.#.#.####...#...##.#.####..#..###..#.
+#.....#..##.#.#.##......#######.##...#....#...###
+#######.#..#...##.##..#.#####.####.##...#..##...#`},
+}
+
+func qrRows(q *qrCode) string {
+	var b strings.Builder
+	for y := 0; y < q.size; y++ {
+		b.WriteByte('\n')
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestQREncodeReferenceSymbols(t *testing.T) {
+	for _, tt := range qrReferenceSymbols {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := qrEncodeMask(tt.text, tt.mask)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := qrRows(q); got != tt.want {
+				t.Errorf("ký hiệu khác mẫu:\ngot:%s\nwant:%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// mask tự chọn vẫn phải ra ký hiệu đúng chuẩn: đọc mask từ bit định dạng rồi so với ký hiệu cùng mask
+func TestQREncodeAutoMask(t *testing.T) {
+	for _, tt := range qrReferenceSymbols {
+		auto, err := qrEncode(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var format int
+		for i := 0; i <= 5; i++ {
+			if auto.modules[i][8] {
+				format |= 1 << i
+			}
+		}
+		mask := -1
+		for m := 0; m < 8; m++ {
+			if qrFormatBits(m)&0x3F == format {
+				mask = m
+			}
+		}
+		if mask < 0 {
+			t.Fatalf("%s: bit định dạng %06b không khớp mask nào", tt.name, format)
+		}
+		fixed, _ := qrEncodeMask(tt.text, mask)
+		if qrRows(auto) != qrRows(fixed) {
+			t.Errorf("%s: mask tự chọn %d khác ký hiệu mask cố định", tt.name, mask)
+		}
+	}
+}
+
+func TestQRFormatBits(t *testing.T) {
+	// bảng bit định dạng mức M trong ISO/IEC 18004
+	want := []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+	for mask, w := range want {
+		if got := qrFormatBits(mask); got != w {
+			t.Errorf("qrFormatBits(%d) = %#x, want %#x", mask, got, w)
+		}
+	}
+}
+
+func TestQRVersionBits(t *testing.T) {
+	tests := []struct{ ver, want int }{
+		{7, 0x07C94},
+		{8, 0x085BC},
+		{9, 0x09A99},
+		{10, 0x0A4D3},
+	}
+	for _, tt := range tests {
+		if got := qrVersionBits(tt.ver); got != tt.want {
+			t.Errorf("qrVersionBits(%d) = %#x, want %#x", tt.ver, got, tt.want)
+		}
+	}
+}
+
+func TestQRReedSolomon(t *testing.T) {
+	// đa thức sinh bậc 7 (bỏ hệ số đầu = 1)
+	if got, want := qrRSDivisor(7), []byte{127, 122, 154, 164, 11, 68, 117}; !slices.Equal(got, want) {
+		t.Errorf("qrRSDivisor(7) = %v, want %v", got, want)
+	}
+	// ví dụ "HELLO WORLD" 1-M (alphanumeric): 16 codeword dữ liệu -> 10 codeword sửa lỗi
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := qrRSRemainder(data, qrRSDivisor(10)); !slices.Equal(got, want) {
+		t.Errorf("qrRSRemainder = %v, want %v", got, want)
+	}
+}
+
+func TestQRVersionTables(t *testing.T) {
+	tests := []struct {
+		ver       int
+		dataWords int
+		align     []int
+	}{
+		{1, 16, nil},
+		{2, 28, []int{6, 18}},
+		{5, 86, []int{6, 30}},
+		{7, 124, []int{6, 22, 38}},
+		{8, 154, []int{6, 24, 42}},
+		{10, 216, []int{6, 28, 50}},
+	}
+	for _, tt := range tests {
+		if got := qrDataCodewords(tt.ver); got != tt.dataWords {
+			t.Errorf("qrDataCodewords(%d) = %d, want %d", tt.ver, got, tt.dataWords)
+		}
+		if got := qrAlignmentPositions(tt.ver); !slices.Equal(got, tt.align) {
+			t.Errorf("qrAlignmentPositions(%d) = %v, want %v", tt.ver, got, tt.align)
+		}
+	}
+}
+
+func TestQREncodeVersionChoice(t *testing.T) {
+	tests := []struct {
+		n    int // số byte
+		size int
+	}{
+		{1, 21},
+		{14, 21}, // sức chứa byte mode của 1-M
+		{15, 25},
+		{213, 57}, // 10-M
+	}
+	for _, tt := range tests {
+		q, err := qrEncode(strings.Repeat("a", tt.n))
+		if err != nil {
+			t.Fatalf("%d byte: %v", tt.n, err)
+		}
+		if q.size != tt.size {
+			t.Errorf("%d byte: size = %d, want %d", tt.n, q.size, tt.size)
+		}
+	}
+	if _, err := qrEncode(strings.Repeat("a", 214)); err != errQRTooLong {
+		t.Errorf("214 byte: err = %v, want errQRTooLong", err)
+	}
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== ĐỐI SOÁT SỐ DƯ: SỐ DƯ THỰC TẾ vs SỔ CÁI, BÁO LỆCH & KHOÁ TÀI KHOẢN ===== */
+
+const (
+	DRIFT_OPEN     = "OPEN"
+	DRIFT_RESOLVED = "RESOLVED"
+
+	DRIFT_ACCEPT = "ACCEPT" // giữ số dư hiện tại, ghi bù sổ cái (RECON_ADJUST)
+	DRIFT_FIX    = "FIX"    // đưa số dư về đúng sổ cái
+	DRIFT_AUTO   = "AUTO"   // tự hết lệch (vd đã sửa tay)
+)
+
+const reconBatch = 500
+
+// Mốc đã đối soát khớp của 1 user: số dư tại mốc + dòng sổ cái cuối đã tính.
+// Lần đầu: user có từ trước khi có sổ cái thì số dư đầu (Opening*) tính lại từ bản ghi nguồn (xem reconOpeningCheckpoint).
+type BalanceCheckpoint struct {
+	UserID       uint      `gorm:"primaryKey;autoIncrement:false" json:"userId"`
+	LedgerID     uint      `gorm:"not null;default:0" json:"ledgerId"`
+	Coins        int64     `gorm:"not null;default:0" json:"coins"`
+	BonusCoins   int64     `gorm:"not null;default:0" json:"bonusCoins"`
+	OpeningCoins int64     `gorm:"not null;default:0" json:"openingCoins"`
+	OpeningBonus int64     `gorm:"not null;default:0" json:"openingBonus"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// 1 lần lệch (mỗi user + loại tiền chỉ có 1 dòng OPEN, các lần quét sau cập nhật dòng đó)
+type BalanceDrift struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"userId"`
+	Currency     string     `gorm:"size:8;not null" json:"currency"`
+	Expected     int64      `gorm:"not null" json:"expected"`               // theo sổ cái
+	Actual       int64      `gorm:"not null" json:"actual"`                 // users.coins / bonus_coins
+	Drift        int64      `gorm:"not null" json:"drift"`                  // actual - expected
+	FromLedgerID uint       `gorm:"not null;default:0" json:"fromLedgerId"` // mốc khớp cuối: lệch phát sinh sau dòng này
+	ToLedgerID   uint       `gorm:"not null;default:0" json:"toLedgerId"`
+	Status       string     `gorm:"size:10;not null;index" json:"status"`
+	Resolution   string     `gorm:"size:10" json:"resolution,omitempty"`
+	Note         string     `gorm:"size:255" json:"note,omitempty"`
+	Frozen       bool       `gorm:"not null;default:false" json:"frozen"` // tài khoản bị khoá do lần lệch này
+	ResolvedBy   *uint      `json:"resolvedBy,omitempty"`
+	DetectedAt   time.Time  `json:"detectedAt"`
+	LastSeenAt   time.Time  `json:"lastSeenAt"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty"`
+}
+
+func balanceColumn(currency string) string {
+	if currency == CUR_BONUS {
+		return "bonus_coins"
+	}
+	return "coins"
+}
+
+/* ----- đối soát (job recon.balances, hoặc: go run . reconcile) ----- */
+
+type reconSummary struct {
+	Checked int
+	Drifted int
+	Frozen  int
+}
+
+type reconRow struct {
+	ID                uint
+	CreatedAt         time.Time
+	Coins, BonusCoins int64
+	HasCheckpoint     bool
+	CpLedgerID        uint
+	CpCoins, CpBonus  int64
+	DeltaCoin         int64
+	DeltaBonus        int64
+	MaxLedgerID       uint
+}
+
+func reconcileJob() error {
+	s, err := reconcileBalances()
+	if err == nil && s.Drifted > 0 {
+		log.Printf("đối soát: %d user, %d lệch, %d bị khoá", s.Checked, s.Drifted, s.Frozen)
+	}
+	return err
+}
+
+// Với mỗi user: kỳ vọng = số dư tại mốc + biến động sổ cái sau mốc. Khớp thì dời mốc lên,
+// lệch thì ghi/cập nhật balance_drifts, báo admin và (nếu vượt ngưỡng) khoá tài khoản.
+func reconcileBalances() (reconSummary, error) {
+	var sum reconSummary
+	started := time.Now()
+	ledgerStart, err := ledgerStartAt(DB)
+	if err != nil {
+		return sum, err
+	}
+	alertAt := settingInt("recon.alert_threshold")
+	freezeAt := settingInt("recon.freeze_threshold")
+
+	var cursor uint
+	for {
+		var rows []reconRow
+		// đọc số dư & sổ cái trong cùng 1 transaction (cùng snapshot) để không bắt nhầm giao dịch đang ghi dở
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Table("users u").
+				Select(`u.id, MAX(u.created_at) AS created_at, MAX(u.coins) AS coins, MAX(u.bonus_coins) AS bonus_coins,
+					MAX(cp.user_id IS NOT NULL) AS has_checkpoint, COALESCE(MAX(cp.ledger_id),0) AS cp_ledger_id,
+					COALESCE(MAX(cp.coins),0) AS cp_coins, COALESCE(MAX(cp.bonus_coins),0) AS cp_bonus,
+					COALESCE(SUM(CASE WHEN l.currency = ? THEN l.amount END),0) AS delta_coin,
+					COALESCE(SUM(CASE WHEN l.currency = ? THEN l.amount END),0) AS delta_bonus,
+					COALESCE(MAX(l.id),0) AS max_ledger_id`, CUR_COIN, CUR_BONUS).
+				Joins("LEFT JOIN balance_checkpoints cp ON cp.user_id = u.id").
+				Joins("LEFT JOIN ledger_entries l ON l.user_id = u.id AND l.id > COALESCE(cp.ledger_id, 0)").
+				Where("u.id > ?", cursor).Group("u.id").Order("u.id ASC").Limit(reconBatch).
+				Scan(&rows).Error; err != nil {
+				return err
+			}
+			for _, r := range rows {
+				if err := reconcileUser(tx, r, ledgerStart, &sum); err != nil {
+					return fmt.Errorf("user %d: %w", r.ID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return sum, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		cursor = rows[len(rows)-1].ID
+	}
+
+	// báo admin / khoá tài khoản cho các lần lệch mới phát hiện hoặc vừa đổi trong lượt này
+	var drifts []BalanceDrift
+	if err := DB.Where("status = ? AND last_seen_at >= ? AND frozen = ?", DRIFT_OPEN, started, false).
+		Find(&drifts).Error; err != nil {
+		return sum, err
+	}
+	for _, d := range drifts {
+		if err := alertDrift(d, alertAt, freezeAt, &sum); err != nil {
+			log.Println("drift alert", d.ID, "error:", err)
+		}
+	}
+	return sum, nil
+}
+
+func reconcileUser(tx *gorm.DB, r reconRow, ledgerStart *time.Time, sum *reconSummary) error {
+	sum.Checked++
+	now := time.Now()
+	lastLedger := max(r.CpLedgerID, r.MaxLedgerID)
+	cp := BalanceCheckpoint{UserID: r.ID, LedgerID: lastLedger, Coins: r.Coins, BonusCoins: r.BonusCoins, CheckedAt: now}
+
+	if !r.HasCheckpoint {
+		base, ok, err := reconOpeningCheckpoint(tx, r.ID, r.CreatedAt, ledgerStart, r.BonusCoins, r.DeltaBonus)
+		if err != nil {
+			return err
+		}
+		if ok {
+			r.CpCoins, r.CpBonus = base.Coins, base.BonusCoins
+			cp.OpeningCoins, cp.OpeningBonus = r.Coins-r.DeltaCoin, r.BonusCoins-r.DeltaBonus
+		}
+	}
+
+	expected := map[string]int64{CUR_COIN: r.CpCoins + r.DeltaCoin, CUR_BONUS: r.CpBonus + r.DeltaBonus}
+	actual := map[string]int64{CUR_COIN: r.Coins, CUR_BONUS: r.BonusCoins}
+	drifted := false
+	for _, cur := range []string{CUR_COIN, CUR_BONUS} {
+		var open BalanceDrift
+		found := tx.Where("user_id = ? AND currency = ? AND status = ?", r.ID, cur, DRIFT_OPEN).Limit(1).Find(&open).RowsAffected > 0
+		d := actual[cur] - expected[cur]
+		switch {
+		case d == 0 && found:
+			if err := tx.Model(&open).Updates(map[string]any{
+				"status": DRIFT_RESOLVED, "resolution": DRIFT_AUTO, "resolved_at": now, "last_seen_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		case d != 0 && found:
+			drifted = true
+			if open.Drift == d && open.ToLedgerID == lastLedger {
+				continue // không đổi: giữ last_seen_at cũ để không báo lại
+			}
+			if err := tx.Model(&open).Updates(map[string]any{
+				"expected": expected[cur], "actual": actual[cur], "drift": d, "to_ledger_id": lastLedger, "last_seen_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		case d != 0:
+			drifted = true
+			if err := tx.Create(&BalanceDrift{
+				UserID: r.ID, Currency: cur, Expected: expected[cur], Actual: actual[cur], Drift: d,
+				FromLedgerID: r.CpLedgerID, ToLedgerID: lastLedger, Status: DRIFT_OPEN, DetectedAt: now, LastSeenAt: now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if drifted {
+		sum.Drifted++
+		return nil // giữ mốc cũ cho tới khi xử lý xong
+	}
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&cp).Error
+}
+
+func ledgerStartAt(tx *gorm.DB) (*time.Time, error) {
+	var at *time.Time
+	err := tx.Model(&LedgerEntry{}).Select("MIN(created_at)").Scan(&at).Error
+	return at, err
+}
+
+// Mốc ảo (LedgerID 0) cho user có từ trước sổ cái và chưa từng khớp: tổng coin + bonus kỳ vọng tính lại từ
+// bản ghi nguồn trước khi có sổ cái. Bản ghi cũ không cho biết phần nào trả bằng bonus nên bonus nhận theo
+// số dư hiện tại, mọi chênh lệch quy vào coin. ok=false: user tạo sau khi có sổ cái (mốc 0 như bình thường).
+func reconOpeningCheckpoint(tx *gorm.DB, uid uint, createdAt time.Time, ledgerStart *time.Time, bonus, deltaBonus int64) (BalanceCheckpoint, bool, error) {
+	cp := BalanceCheckpoint{UserID: uid}
+	if ledgerStart != nil && !createdAt.Before(*ledgerStart) {
+		return cp, false, nil
+	}
+	before := time.Now()
+	if ledgerStart != nil {
+		before = *ledgerStart
+	}
+	var total int64
+	for _, s := range slices.Concat(reconSources, reconOpeningSources) {
+		var v int64
+		q := tx.Table(s.Table+" s").Select("COALESCE(SUM("+s.Amount+"),0)").
+			Where("s."+s.UserCol+" = ? AND s.created_at < ?", uid, before)
+		if s.Where != "" {
+			q = q.Where(s.Where)
+		}
+		if err := q.Scan(&v).Error; err != nil {
+			return cp, false, fmt.Errorf("opening %s: %w", s.Name, err)
+		}
+		total += v
+	}
+	cp.BonusCoins = bonus - deltaBonus
+	cp.Coins = total - cp.BonusCoins
+	return cp, true, nil
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func alertDrift(d BalanceDrift, alertAt, freezeAt int64, sum *reconSummary) error {
+	amount := absInt64(d.Drift)
+	if alertAt <= 0 || amount < alertAt {
+		return nil
+	}
+	var u User
+	if err := DB.Unscoped().Select("id, username, status").First(&u, d.UserID).Error; err != nil {
+		return err
+	}
+	return withEvents(func(tx *gorm.DB) error {
+		var admins []uint
+		if err := tx.Model(&User{}).Where("role = 'admin'").Pluck("id", &admins).Error; err != nil {
+			return err
+		}
+		for _, aid := range admins {
+			if err := notify(tx, aid, NT_RECON_DRIFT, map[string]any{
+				"id": d.ID, "userId": u.ID, "username": u.Username, "currency": d.Currency,
+				"drift": d.Drift, "expected": d.Expected, "actual": d.Actual,
+			}); err != nil {
+				return err
+			}
+		}
+		if freezeAt <= 0 || amount < freezeAt || u.Status == ACCOUNT_FROZEN {
+			return nil
+		}
+		if err := setAccountStatus(tx, u.ID, ACCOUNT_FROZEN, driftFreezeReason(d.ID), nil); err != nil {
+			return err
+		}
+		sum.Frozen++
+		if err := tx.Model(&BalanceDrift{}).Where("id = ?", d.ID).Update("frozen", true).Error; err != nil {
+			return err
+		}
+		return notify(tx, u.ID, NT_ACCOUNT_FROZEN, map[string]any{})
+	})
+}
+
+/* ----- bản ghi nguồn không khớp sổ cái ----- */
+
+// mỗi bản ghi giao dịch phải có dòng sổ cái (type ∈ Types, ref_id = id) với tổng = Amount
+type reconSource struct {
+	Name    string
+	Table   string
+	UserCol string
+	Amount  string // biểu thức SQL trên s
+	Where   string
+	Types   []string
+}
+
+var reconSources = []reconSource{
+	{"topup", "coin_txns", "user_id", "s.amount", "", []string{LEDGER_TOPUP}},
+	{"withdraw", "withdraw_txns", "user_id", "-s.amount", "", []string{LEDGER_WITHDRAW}},
+	{"transfer_out", "transfer_txns", "from_id", "-(s.amount + s.fee)", "", []string{LEDGER_TRANSFER_OUT, LEDGER_TRANSFER_FEE}},
+	{"transfer_in", "transfer_txns", "to_id", "s.amount", "", []string{LEDGER_TRANSFER_IN}},
+	{"vip_purchase", "vip_purchase_txns", "user_id", "-s.price", "", []string{LEDGER_VIP_PURCHASE}},
+	{"commission", "commission_txns", "beneficiary_id", "s.amount", "s.kind = 'UPLINE'", []string{LEDGER_COMMISSION}},
+	{"chest_open", "chest_txns", "user_id", "-s.cost", "", []string{LEDGER_CHEST_OPEN}},
+	{"market_sell", "market_trades", "seller_id", "s.total - s.fee", "", []string{LEDGER_MARKET_SELL}},
+}
+
+// bản ghi nguồn chỉ dùng tính số dư đầu trước khi có sổ cái (sau đó đã có dòng sổ cái riêng)
+var reconOpeningSources = []reconSource{
+	{"chest_reward", "chest_txns", "user_id", "s.reward_amount", "s.reward_kind = 'COIN'", nil},
+}
+
+type ReconSourceRow struct {
+	Source    string    `json:"source"`
+	RefID     uint      `json:"refId"`
+	Expected  int64     `json:"expected"` // biến động theo bản ghi nguồn
+	Recorded  int64     `json:"recorded"` // tổng trong sổ cái
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func reconSourceMismatches(uid uint, since time.Time) ([]ReconSourceRow, error) {
+	out := []ReconSourceRow{}
+	for _, s := range reconSources {
+		var rows []ReconSourceRow
+		q := DB.Table(s.Table+" s").
+			Select(fmt.Sprintf(`? AS source, s.id AS ref_id, %s AS expected, s.created_at,
+				COALESCE((SELECT SUM(l.amount) FROM ledger_entries l WHERE l.user_id = s.%s AND l.type IN ? AND l.ref_id = s.id),0) AS recorded`,
+				s.Amount, s.UserCol), s.Name, s.Types).
+			Where("s."+s.UserCol+" = ? AND s.created_at >= ?", uid, since)
+		if s.Where != "" {
+			q = q.Where(s.Where)
+		}
+		if err := q.Having("expected <> recorded").Order("s.id ASC").Limit(100).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		out = append(out, rows...)
+	}
+	return out, nil
+}
+
+/* ----- admin ----- */
+
+type BalanceDriftRow struct {
+	BalanceDrift
+	Username string `json:"username"`
+}
+
+// GET /admin/reconciliation/drifts?status=OPEN|RESOLVED|all&userId=&cursor=&limit=
+func adminListDriftsHandler(c *gin.Context) {
+	f, aerr := parseHistoryFilter(c)
+	if aerr != nil {
+		respondError(c, aerr)
+		return
+	}
+	q := DB.Table("balance_drifts d").Select("d.*, u.username").Joins("LEFT JOIN users u ON u.id = d.user_id")
+	switch st := strings.ToUpper(strings.TrimSpace(c.DefaultQuery("status", DRIFT_OPEN))); st {
+	case "ALL":
+	case DRIFT_OPEN, DRIFT_RESOLVED:
+		q = q.Where("d.status = ?", st)
+	default:
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "status", "detail", "chỉ nhận OPEN|RESOLVED|all"))
+		return
+	}
+	if uid := c.Query("userId"); uid != "" {
+		q = q.Where("d.user_id = ?", uid)
+	}
+	if f.Cursor > 0 {
+		q = q.Where("d.id < ?", f.Cursor)
+	}
+	rows := []BalanceDriftRow{}
+	if err := q.Order("d.id DESC").Limit(f.Limit + 1).Scan(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	var next *uint
+	if len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		next = &rows[f.Limit-1].ID
+	}
+	c.JSON(200, gin.H{"rows": rows, "nextCursor": next})
+}
+
+func parseDriftID(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+func loadDrift(c *gin.Context) (BalanceDrift, bool) {
+	var d BalanceDrift
+	id, ok := parseDriftID(c)
+	if !ok {
+		return d, false
+	}
+	if err := DB.First(&d, id).Error; err != nil {
+		respondError(c, apiError(ERR_DRIFT_NOT_FOUND))
+		return d, false
+	}
+	return d, true
+}
+
+// GET /admin/reconciliation/drifts/:id ⇒ lần lệch + các dòng sổ cái sau mốc khớp cuối + bản ghi nguồn không khớp sổ cái
+func adminGetDriftHandler(c *gin.Context) {
+	d, ok := loadDrift(c)
+	if !ok {
+		return
+	}
+	var cp BalanceCheckpoint
+	DB.Limit(1).Find(&cp, "user_id = ?", d.UserID)
+
+	ledger := []LedgerEntry{}
+	if err := DB.Where("user_id = ? AND id > ?", d.UserID, d.FromLedgerID).Order("id ASC").Limit(500).Find(&ledger).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	// bản ghi nguồn từ lúc khớp lần cuối
+	var since time.Time
+	if d.FromLedgerID > 0 {
+		var from LedgerEntry
+		if err := DB.Select("created_at").First(&from, d.FromLedgerID).Error; err == nil {
+			since = from.CreatedAt
+		}
+	}
+	sources, err := reconSourceMismatches(d.UserID, since)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"drift": d, "checkpoint": cp, "ledger": ledger, "sources": sources})
+}
+
+// lý do khoá do lệch số dư: chỉ mở khoá khi tài khoản đang bị khoá đúng vì lệch này
+func driftFreezeReason(id uint) string {
+	return fmt.Sprintf("Đối soát số dư lệch #%d", id)
+}
+
+type ResolveDriftRequest struct {
+	Action   string `json:"action" binding:"required"` // ACCEPT | FIX
+	Note     string `json:"note" binding:"max=255"`
+	Unfreeze bool   `json:"unfreeze"` // mở khoá tài khoản nếu đang bị khoá vì lệch này
+}
+
+// POST /admin/reconciliation/drifts/:id/resolve { action, note, unfreeze }
+func adminResolveDriftHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	d, ok := loadDrift(c)
+	if !ok {
+		return
+	}
+	var req ResolveDriftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	req.Action = strings.ToUpper(strings.TrimSpace(req.Action))
+	if req.Action != DRIFT_ACCEPT && req.Action != DRIFT_FIX {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "action", "detail", "chỉ nhận ACCEPT|FIX"))
+		return
+	}
+
+	var applied int64
+	var unfrozen bool
+	if err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&d, d.ID).Error; err != nil {
+			return apiError(ERR_DRIFT_NOT_FOUND)
+		}
+		if d.Status != DRIFT_OPEN {
+			return apiError(ERR_DRIFT_RESOLVED)
+		}
+		// 🔒 tính lại độ lệch lúc này (có thể đã đổi từ lần quét)
+		var u User
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, d.UserID).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		var cp BalanceCheckpoint
+		if tx.Limit(1).Find(&cp, "user_id = ?", u.ID).RowsAffected == 0 {
+			// chưa từng khớp: cùng mốc ảo như lúc quét
+			ledgerStart, err := ledgerStartAt(tx)
+			if err != nil {
+				return err
+			}
+			var deltaBonus int64
+			if err := tx.Model(&LedgerEntry{}).Select("COALESCE(SUM(amount),0)").
+				Where("user_id = ? AND currency = ?", u.ID, CUR_BONUS).Scan(&deltaBonus).Error; err != nil {
+				return err
+			}
+			if base, ok, err := reconOpeningCheckpoint(tx, u.ID, u.CreatedAt, ledgerStart, u.BonusCoins, deltaBonus); err != nil {
+				return err
+			} else if ok {
+				cp = base
+			}
+		}
+		var delta int64
+		if err := tx.Model(&LedgerEntry{}).Select("COALESCE(SUM(amount),0)").
+			Where("user_id = ? AND currency = ? AND id > ?", u.ID, d.Currency, cp.LedgerID).Scan(&delta).Error; err != nil {
+			return err
+		}
+		actual, expected := u.Coins, cp.Coins+delta
+		if d.Currency == CUR_BONUS {
+			actual, expected = u.BonusCoins, cp.BonusCoins+delta
+		}
+		applied = actual - expected
+
+		if applied != 0 {
+			switch req.Action {
+			case DRIFT_ACCEPT:
+				// số dư đúng, sổ cái thiếu: ghi bù
+				if err := addLedger(tx, u.ID, d.Currency, LEDGER_RECON_ADJUST, d.ID, applied); err != nil {
+					return err
+				}
+			case DRIFT_FIX:
+				// sổ cái đúng: trả số dư về như sổ cái (không ghi sổ cái vì phần lệch chưa từng được ghi)
+				col := balanceColumn(d.Currency)
+				if err := tx.Model(&User{}).Where("id = ?", u.ID).
+					Update(col, gorm.Expr(col+" - ?", applied)).Error; err != nil {
+					return err
+				}
+				emitBalanceChanged(tx, u.ID, "reconciliation")
+			}
+		}
+		now := time.Now()
+		if err := tx.Model(&d).Updates(map[string]any{
+			"status": DRIFT_RESOLVED, "resolution": req.Action, "note": strings.TrimSpace(req.Note),
+			"resolved_by": adminID, "resolved_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		// khoá vì lý do khác (vd chờ chi trả đóng tài khoản, admin khoá tay) thì giữ nguyên
+		if req.Unfreeze && u.Status == ACCOUNT_FROZEN && u.StatusReason == driftFreezeReason(d.ID) {
+			unfrozen = true
+			return setAccountStatus(tx, u.ID, ACCOUNT_ACTIVE, "", nil)
+		}
+		return nil
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã xử lý lệch số dư", "drift": d, "applied": applied, "unfrozen": unfrozen})
+}
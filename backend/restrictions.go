@@ -0,0 +1,361 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+/* ===== TRẠNG THÁI TÀI KHOẢN (KHOÁ / CẤM) & HẠN CHẾ TỪNG CHỨC NĂNG ===== */
+
+const (
+	ACCOUNT_ACTIVE = "ACTIVE"
+	ACCOUNT_FROZEN = "FROZEN" // chỉ xem, không thao tác được
+	ACCOUNT_BANNED = "BANNED" // không đăng nhập / gọi API được
+	ACCOUNT_CLOSED = "CLOSED" // đã đóng & ẩn danh hoá (account_closure.go)
+)
+
+// hạn chế từng chức năng (tài khoản vẫn ACTIVE)
+const (
+	RESTRICT_TRANSFER = "TRANSFER" // chuyển coin đi
+	RESTRICT_MARKET   = "MARKET"   // đăng bán, mua, đặt lệnh, sửa listing trên chợ
+	RESTRICT_CHEST    = "CHEST"    // mở rương
+	RESTRICT_WITHDRAW = "WITHDRAW" // rút coin (admin rút / chi trả khi đóng tài khoản)
+)
+
+var restrictionKinds = []string{RESTRICT_TRANSFER, RESTRICT_MARKET, RESTRICT_CHEST, RESTRICT_WITHDRAW}
+
+type UserRestriction struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	Kind      string     `gorm:"size:10;not null" json:"kind"`
+	Reason    string     `gorm:"size:255;not null" json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // nil = tới khi gỡ
+	CreatedBy uint       `gorm:"not null" json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LiftedAt  *time.Time `json:"liftedAt,omitempty"`
+	LiftedBy  *uint      `json:"liftedBy,omitempty"`
+}
+
+func (r UserRestriction) active(now time.Time) bool {
+	return r.LiftedAt == nil && (r.ExpiresAt == nil || r.ExpiresAt.After(now))
+}
+
+func activeRestrictions(db *gorm.DB) *gorm.DB {
+	return db.Where("lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+}
+
+/* ----- trạng thái (authRequired) ----- */
+
+const accountStatusTTL = 30 * time.Second
+
+var accountStatusCache = struct {
+	sync.Mutex
+	m map[uint]accountStatusEntry
+}{m: map[uint]accountStatusEntry{}}
+
+type accountStatusEntry struct {
+	status, reason string
+	until          *time.Time
+	at             time.Time
+}
+
+// Trạng thái hiệu lực, cache 30 giây/instance. setAccountStatus phát event account.status sau commit,
+// mọi instance nhận qua Hub (EVENT_BROKER=db: trễ theo chu kỳ poll) và xoá cache của user đó;
+// TTL chỉ là lưới an toàn khi event bị lỡ. FROZEN / BANNED đã qua statusUntil được coi như ACTIVE.
+// DB lỗi: dùng lại bản cache cũ nếu có, không thì trả lỗi (không bao giờ mặc định ACTIVE).
+func accountStatus(uid uint) (string, string, *time.Time, error) {
+	accountStatusCache.Lock()
+	e, ok := accountStatusCache.m[uid]
+	accountStatusCache.Unlock()
+	if !ok || time.Since(e.at) >= accountStatusTTL {
+		var u User
+		err := DB.Select("id, status, status_reason, status_until").First(&u, uid).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// đã xoá cứng: token cũ không còn hiệu lực
+			u.Status = ACCOUNT_CLOSED
+		case err != nil && ok:
+			return effectiveStatus(e.status, e.until), e.reason, e.until, nil
+		case err != nil:
+			return "", "", nil, err
+		}
+		e = accountStatusEntry{u.Status, u.StatusReason, u.StatusUntil, time.Now()}
+		accountStatusCache.Lock()
+		accountStatusCache.m[uid] = e
+		accountStatusCache.Unlock()
+	}
+	return effectiveStatus(e.status, e.until), e.reason, e.until, nil
+}
+
+func effectiveStatus(status string, until *time.Time) string {
+	if (status == ACCOUNT_FROZEN || status == ACCOUNT_BANNED) && until != nil && !until.After(time.Now()) {
+		return ACCOUNT_ACTIVE
+	}
+	return status
+}
+
+func forgetAccountStatus(uid uint) {
+	accountStatusCache.Lock()
+	delete(accountStatusCache.m, uid)
+	accountStatusCache.Unlock()
+}
+
+func setAccountStatus(tx *gorm.DB, uid uint, status, reason string, until *time.Time) error {
+	if err := tx.Model(&User{}).Where("id = ?", uid).
+		Updates(map[string]any{"status": status, "status_reason": reason, "status_until": until}).Error; err != nil {
+		return err
+	}
+	forgetAccountStatus(uid)
+	// sau commit: các instance khác xoá cache, FE của user nhận trạng thái mới
+	emitEvent(tx, uid, EV_ACCOUNT_STATUS, gin.H{"status": status, "reason": reason, "until": until})
+	return nil
+}
+
+// lỗi tương ứng trạng thái (nil nếu được phép); write = request thay đổi dữ liệu
+func accountStatusError(status, reason string, until *time.Time, write bool) error {
+	switch {
+	case status == ACCOUNT_CLOSED:
+		return apiError(ERR_ACCOUNT_CLOSED)
+	case status == ACCOUNT_BANNED:
+		return apiError(ERR_ACCOUNT_BANNED, "reason", reason, "until", untilText(until))
+	case status == ACCOUNT_FROZEN && write:
+		return apiError(ERR_ACCOUNT_FROZEN, "reason", reason, "until", untilText(until))
+	}
+	return nil
+}
+
+func untilText(t *time.Time) string {
+	if t == nil {
+		return "khi được gỡ"
+	}
+	return t.Format(exportTimeLayout)
+}
+
+/* ----- hạn chế chức năng ----- */
+
+// lỗi RESTRICTED nếu user đang bị hạn chế kind
+func checkRestriction(db *gorm.DB, uid uint, kind string) error {
+	var r UserRestriction
+	if activeRestrictions(db).Where("user_id = ? AND kind = ?", uid, kind).
+		Order("id DESC").Limit(1).Find(&r).RowsAffected == 0 {
+		return nil
+	}
+	return apiError(ERR_ACCOUNT_RESTRICTED, "kind", kind, "reason", r.Reason, "until", untilText(r.ExpiresAt))
+}
+
+// middleware cho route user: chặn nếu đang bị hạn chế kind
+func restrictedBy(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+		if err := checkRestriction(DB, uid, kind); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+type AccountRestrictions struct {
+	Status       string            `json:"status"`
+	StatusReason string            `json:"statusReason,omitempty"`
+	StatusUntil  *time.Time        `json:"statusUntil,omitempty"`
+	Restrictions []UserRestriction `json:"restrictions"`
+}
+
+func loadAccountRestrictions(uid uint, history bool) (AccountRestrictions, error) {
+	var u User
+	out := AccountRestrictions{Restrictions: []UserRestriction{}}
+	if err := DB.Select("id, status, status_reason, status_until").First(&u, uid).Error; err != nil {
+		return out, apiError(ERR_USER_NOT_FOUND)
+	}
+	out.Status = effectiveStatus(u.Status, u.StatusUntil)
+	if out.Status != ACCOUNT_ACTIVE {
+		out.StatusReason, out.StatusUntil = u.StatusReason, u.StatusUntil
+	}
+	q := DB.Where("user_id = ?", uid)
+	if !history {
+		q = activeRestrictions(q)
+	}
+	err := q.Order("id DESC").Limit(100).Find(&out.Restrictions).Error
+	return out, err
+}
+
+// GET /private/account/restrictions — trạng thái & hạn chế đang áp dụng (kèm lý do, hạn)
+func myRestrictionsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	out, err := loadAccountRestrictions(uid, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, out)
+}
+
+/* ----- admin ----- */
+
+// GET /admin/users/:id/restrictions — trạng thái + mọi hạn chế (kể cả đã gỡ/hết hạn)
+func adminUserRestrictionsHandler(c *gin.Context) {
+	uid, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	out, err := loadAccountRestrictions(uid, true)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, out)
+}
+
+type AccountStatusRequest struct {
+	Status string     `json:"status" binding:"required"` // ACTIVE | FROZEN | BANNED
+	Reason string     `json:"reason" binding:"max=255"`
+	Until  *time.Time `json:"until"` // hết hạn thì tự về ACTIVE; bỏ trống = tới khi gỡ
+}
+
+// PUT /admin/users/:id/status { status, reason, until }
+func adminSetAccountStatusHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	uid, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var req AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	req.Status = strings.ToUpper(strings.TrimSpace(req.Status))
+	req.Reason = strings.TrimSpace(req.Reason)
+	if !slices.Contains([]string{ACCOUNT_ACTIVE, ACCOUNT_FROZEN, ACCOUNT_BANNED}, req.Status) {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "status", "detail", "chỉ nhận ACTIVE|FROZEN|BANNED"))
+		return
+	}
+	if req.Status != ACCOUNT_ACTIVE && req.Reason == "" {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "reason", "detail", "bắt buộc khi khoá / cấm"))
+		return
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "until", "detail", "phải ở tương lai"))
+		return
+	}
+	if req.Status == ACCOUNT_ACTIVE {
+		req.Reason, req.Until = "", nil
+	}
+	if uid == adminID {
+		respondError(c, apiError(ERR_FORBIDDEN))
+		return
+	}
+
+	if err := withEvents(func(tx *gorm.DB) error {
+		var u User
+		if err := tx.Select("id, role, status").First(&u, uid).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		if u.Role == ROLE_SYSTEM {
+			return apiError(ERR_FORBIDDEN)
+		}
+		if u.Status == ACCOUNT_CLOSED {
+			return apiError(ERR_ACCOUNT_CLOSED)
+		}
+		if err := setAccountStatus(tx, uid, req.Status, req.Reason, req.Until); err != nil {
+			return err
+		}
+		return notify(tx, uid, NT_ACCOUNT_STATUS, map[string]any{
+			"status": req.Status, "reason": req.Reason, "until": untilText(req.Until),
+		})
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	out, _ := loadAccountRestrictions(uid, false)
+	c.JSON(200, gin.H{"message": "Đã cập nhật trạng thái tài khoản", "account": out})
+}
+
+type RestrictionRequest struct {
+	Kind      string     `json:"kind" binding:"required"` // TRANSFER | MARKET | CHEST | WITHDRAW
+	Reason    string     `json:"reason" binding:"required,max=255"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// POST /admin/users/:id/restrictions { kind, reason, expiresAt } — đã có hạn chế cùng loại thì thay lý do / hạn
+func adminAddRestrictionHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	uid, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var req RestrictionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	req.Kind = strings.ToUpper(strings.TrimSpace(req.Kind))
+	if !slices.Contains(restrictionKinds, req.Kind) {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "kind", "detail", strings.Join(restrictionKinds, "|")))
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "expiresAt", "detail", "phải ở tương lai"))
+		return
+	}
+
+	var r UserRestriction
+	if err := withEvents(func(tx *gorm.DB) error {
+		var u User
+		if err := tx.Select("id, status").First(&u, uid).Error; err != nil {
+			return apiError(ERR_USER_NOT_FOUND)
+		}
+		if u.Status == ACCOUNT_CLOSED {
+			return apiError(ERR_ACCOUNT_CLOSED)
+		}
+		if activeRestrictions(tx).Where("user_id = ? AND kind = ?", uid, req.Kind).Limit(1).Find(&r).RowsAffected > 0 {
+			if err := tx.Model(&r).Updates(map[string]any{"reason": strings.TrimSpace(req.Reason), "expires_at": req.ExpiresAt}).Error; err != nil {
+				return err
+			}
+		} else {
+			r = UserRestriction{UserID: uid, Kind: req.Kind, Reason: strings.TrimSpace(req.Reason), ExpiresAt: req.ExpiresAt, CreatedBy: adminID}
+			if err := tx.Create(&r).Error; err != nil {
+				return err
+			}
+		}
+		return notify(tx, uid, NT_ACCOUNT_RESTRICTED, map[string]any{
+			"kind": r.Kind, "reason": r.Reason, "until": untilText(r.ExpiresAt),
+		})
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã áp dụng hạn chế", "restriction": r})
+}
+
+// DELETE /admin/users/:id/restrictions/:rid — gỡ hạn chế
+func adminLiftRestrictionHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	uid, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var r UserRestriction
+	if err := withEvents(func(tx *gorm.DB) error {
+		if err := activeRestrictions(tx).Where("id = ? AND user_id = ?", c.Param("rid"), uid).First(&r).Error; err != nil {
+			return apiError(ERR_RESTRICTION_NOT_FOUND)
+		}
+		now := time.Now()
+		if err := tx.Model(&r).Updates(map[string]any{"lifted_at": now, "lifted_by": adminID}).Error; err != nil {
+			return err
+		}
+		return notify(tx, uid, NT_RESTRICTION_LIFTED, map[string]any{"kind": r.Kind})
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã gỡ hạn chế", "restriction": r})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+/* ===== GÓI THƯỞNG DÙNG CHUNG (mùa giải, điểm danh, ...) ===== */
+
+// gói thưởng; nhúng vào model để lưu thành các cột coins, bonus_coins, free_spins, item_code, item_qty
+type RewardBundle struct {
+	Coins      int64  `gorm:"not null;default:0" json:"coins"`
+	BonusCoins int64  `gorm:"not null;default:0" json:"bonusCoins"`
+	FreeSpins  int    `gorm:"not null;default:0" json:"freeSpins"`
+	ItemCode   string `gorm:"size:10" json:"itemCode,omitempty"` // DB1..DB7 | EV
+	ItemQty    int64  `gorm:"not null;default:0" json:"itemQty"`
+}
+
+func (r RewardBundle) empty() bool {
+	return r.Coins == 0 && r.BonusCoins == 0 && r.FreeSpins == 0 && r.ItemQty == 0
+}
+
+// chuẩn hoá & kiểm tra; trả về tên trường sai ("" nếu hợp lệ)
+func (r *RewardBundle) invalidField() string {
+	r.ItemCode = strings.ToUpper(strings.TrimSpace(r.ItemCode))
+	if r.Coins < 0 || r.BonusCoins < 0 || r.FreeSpins < 0 || r.ItemQty < 0 || r.empty() {
+		return "reward"
+	}
+	if (r.ItemQty > 0) != (r.ItemCode != "") || (r.ItemCode != "" && !isTradableCode(r.ItemCode)) {
+		return "itemCode"
+	}
+	return ""
+}
+
+// mô tả ngắn, vd "+100 coin, +10 lượt quay, +1 DB3"
+func (r RewardBundle) summary() string {
+	parts := []string{}
+	if r.Coins > 0 {
+		parts = append(parts, fmt.Sprintf("+%d coin", r.Coins))
+	}
+	if r.BonusCoins > 0 {
+		parts = append(parts, fmt.Sprintf("+%d bonus coin", r.BonusCoins))
+	}
+	if r.FreeSpins > 0 {
+		parts = append(parts, fmt.Sprintf("+%d lượt quay", r.FreeSpins))
+	}
+	if r.ItemQty > 0 {
+		parts = append(parts, fmt.Sprintf("+%d %s", r.ItemQty, r.ItemCode))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// nhân mọi số lượng với pct/100 (làm tròn xuống, mục > 0 giữ tối thiểu 1)
+func (r RewardBundle) scaled(pct int64) RewardBundle {
+	mul := func(v int64) int64 {
+		if v <= 0 {
+			return v
+		}
+		return max(v*pct/100, 1)
+	}
+	r.Coins = mul(r.Coins)
+	r.BonusCoins = mul(r.BonusCoins)
+	r.FreeSpins = int(mul(int64(r.FreeSpins)))
+	r.ItemQty = mul(r.ItemQty)
+	return r
+}
+
+// Cộng gói thưởng cho user (trong transaction); coins/bonus ghi sổ cái với typ/ref.
+func grantReward(tx *gorm.DB, uid uint, r RewardBundle, typ string, ref uint) error {
+	up := map[string]any{}
+	if r.Coins > 0 {
+		up["coins"] = gorm.Expr("coins + ?", r.Coins)
+	}
+	if r.BonusCoins > 0 {
+		up["bonus_coins"] = gorm.Expr("bonus_coins + ?", r.BonusCoins)
+	}
+	if r.FreeSpins > 0 {
+		up["free_spins"] = gorm.Expr("free_spins + ?", r.FreeSpins)
+	}
+	if len(up) > 0 {
+		if err := tx.Model(&User{}).Where("id = ?", uid).Updates(up).Error; err != nil {
+			return err
+		}
+		emitBalanceChanged(tx, uid, strings.ToLower(typ))
+	}
+	if err := addLedger(tx, uid, CUR_COIN, typ, ref, r.Coins); err != nil {
+		return err
+	}
+	if err := addLedger(tx, uid, CUR_BONUS, typ, ref, r.BonusCoins); err != nil {
+		return err
+	}
+	if r.ItemQty > 0 {
+		return addToInventory(tx, uid, r.ItemCode, r.ItemQty)
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== MÙA GIẢI: BẢNG XẾP HẠNG CÓ THỜI HẠN & TỰ ĐỘNG TRẢ THƯỞNG ===== */
+
+const (
+	SEASON_OPEN      = "OPEN"      // chưa kết thúc (sắp diễn ra hoặc đang diễn ra)
+	SEASON_CLOSED    = "CLOSED"    // đã chốt bảng & trả thưởng
+	SEASON_CANCELLED = "CANCELLED" // admin huỷ, không trả thưởng
+)
+
+const seasonStandingsLimit = 100 // số dòng mặc định khi xem bảng mùa giải
+
+// giải thưởng cho hạng RankFrom..RankTo
+type SeasonPrize struct {
+	ID       uint `gorm:"primaryKey" json:"-"`
+	SeasonID uint `gorm:"not null;index" json:"-"`
+	RankFrom int  `gorm:"not null" json:"rankFrom"`
+	RankTo   int  `gorm:"not null" json:"rankTo"`
+	RewardBundle
+}
+
+type Season struct {
+	ID        uint          `gorm:"primaryKey" json:"id"`
+	Name      string        `gorm:"size:120;not null" json:"name"`
+	Metric    string        `gorm:"size:20;not null" json:"metric"` // = kind của bảng xếp hạng
+	StartsAt  time.Time     `gorm:"not null" json:"startsAt"`
+	EndsAt    time.Time     `gorm:"not null;index:idx_season_due,priority:2" json:"endsAt"`
+	Status    string        `gorm:"size:12;not null;index:idx_season_due,priority:1" json:"status"`
+	Prizes    []SeasonPrize `gorm:"foreignKey:SeasonID" json:"prizes"`
+	CreatedBy uint          `gorm:"not null" json:"createdBy"`
+	ClosedAt  *time.Time    `json:"closedAt"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// bảng xếp hạng cuối mùa (chốt lúc đóng mùa) kèm phần thưởng đã trả
+type SeasonStanding struct {
+	ID       uint   `gorm:"primaryKey" json:"-"`
+	SeasonID uint   `gorm:"not null;uniqueIndex:uniq_season_user,priority:1;index:idx_season_rank,priority:1" json:"-"`
+	UserID   uint   `gorm:"not null;uniqueIndex:uniq_season_user,priority:2" json:"-"`
+	Username string `gorm:"size:191" json:"username"`
+	Rank     int    `gorm:"column:rank_no;not null;index:idx_season_rank,priority:2" json:"rank"`
+	Score    int64  `gorm:"not null" json:"score"`
+	RewardBundle
+}
+
+// giải thưởng cho 1 hạng (nil nếu không có)
+func seasonPrizeFor(prizes []SeasonPrize, rank int) *RewardBundle {
+	for _, p := range prizes {
+		if rank >= p.RankFrom && rank <= p.RankTo {
+			return &p.RewardBundle
+		}
+	}
+	return nil
+}
+
+// xếp hạng (đồng điểm đồng hạng) + gắn giải thưởng
+func seasonStandings(s Season, entries []leaderboardEntry) []SeasonStanding {
+	out := make([]SeasonStanding, 0, len(entries))
+	rank := 0
+	for i, e := range entries {
+		if i == 0 || e.Score < entries[i-1].Score {
+			rank = i + 1
+		}
+		st := SeasonStanding{SeasonID: s.ID, UserID: e.UserID, Username: e.Username, Rank: rank, Score: e.Score}
+		if p := seasonPrizeFor(s.Prizes, rank); p != nil {
+			st.RewardBundle = *p
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// bảng tạm tính của mùa đang mở, cache như bảng xếp hạng thường
+var seasonLiveCache = struct {
+	mu   sync.Mutex
+	rows map[uint][]SeasonStanding
+	at   map[uint]time.Time
+}{rows: map[uint][]SeasonStanding{}, at: map[uint]time.Time{}}
+
+func seasonLiveStandings(s Season) ([]SeasonStanding, error) {
+	seasonLiveCache.mu.Lock()
+	rows, at := seasonLiveCache.rows[s.ID], seasonLiveCache.at[s.ID]
+	seasonLiveCache.mu.Unlock()
+	if rows != nil && time.Since(at) < leaderboardRefreshEvery {
+		return rows, nil
+	}
+
+	until := time.Now()
+	if s.EndsAt.Before(until) {
+		until = s.EndsAt
+	}
+	entries, err := leaderboardScores(s.Metric, &s.StartsAt, &until)
+	if err != nil {
+		return nil, err
+	}
+	rows = seasonStandings(s, entries)
+	seasonLiveCache.mu.Lock()
+	seasonLiveCache.rows[s.ID], seasonLiveCache.at[s.ID] = rows, time.Now()
+	seasonLiveCache.mu.Unlock()
+	return rows, nil
+}
+
+// Chốt bảng & trả thưởng trong 1 transaction: lỗi giữa chừng thì không ai nhận, mùa vẫn OPEN để thử lại.
+// Khoá hàng season nên nhiều instance cùng chạy cũng chỉ trả 1 lần.
+func closeSeason(id uint) error {
+	return withEvents(func(tx *gorm.DB) error {
+		var s Season
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, id).Error; err != nil {
+			return err
+		}
+		if s.Status != SEASON_OPEN || s.EndsAt.After(time.Now()) {
+			return nil
+		}
+		if err := tx.Where("season_id = ?", s.ID).Order("rank_from ASC").Find(&s.Prizes).Error; err != nil {
+			return err
+		}
+		entries, err := leaderboardScores(s.Metric, &s.StartsAt, &s.EndsAt)
+		if err != nil {
+			return err
+		}
+		rows := seasonStandings(s, entries)
+		if len(rows) > 0 {
+			if err := tx.CreateInBatches(&rows, 500).Error; err != nil {
+				return err
+			}
+		}
+		for _, st := range rows {
+			if st.RewardBundle.empty() {
+				continue
+			}
+			if err := grantReward(tx, st.UserID, st.RewardBundle, LEDGER_SEASON_PRIZE, s.ID); err != nil {
+				return err
+			}
+			_ = notify(tx, st.UserID, NT_SEASON_PRIZE, map[string]any{
+				"season": s.Name, "rank": st.Rank, "score": st.Score,
+			})
+		}
+		return tx.Model(&Season{}).Where("id = ?", s.ID).
+			Updates(map[string]any{"status": SEASON_CLOSED, "closed_at": time.Now()}).Error
+	})
+}
+
+// đóng các mùa đến hạn (job seasons.close)
+func runDueSeasons() error {
+	var ids []uint
+	if err := DB.Model(&Season{}).Where("status = ? AND ends_at <= ?", SEASON_OPEN, time.Now()).
+		Order("ends_at ASC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	var errs []error
+	for _, id := range ids {
+		if err := closeSeason(id); err != nil {
+			errs = append(errs, fmt.Errorf("season %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/* ----- admin ----- */
+
+type SeasonRequest struct {
+	Name     string        `json:"name" binding:"required,max=120"`
+	Metric   string        `json:"metric" binding:"required"` // f1 | system | chest | market | vip_referrals
+	StartsAt time.Time     `json:"startsAt" binding:"required"`
+	EndsAt   time.Time     `json:"endsAt" binding:"required"`
+	Prizes   []SeasonPrize `json:"prizes"`
+}
+
+func (r *SeasonRequest) normalize() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Metric = strings.ToLower(strings.TrimSpace(r.Metric))
+	if r.Name == "" {
+		return apiError(ERR_SEASON_INVALID, "field", "name")
+	}
+	if _, ok := leaderboardBoards[r.Metric]; !ok {
+		return apiError(ERR_SEASON_INVALID, "field", "metric")
+	}
+	if !r.EndsAt.After(r.StartsAt) || !r.EndsAt.After(time.Now()) {
+		return apiError(ERR_SEASON_INVALID, "field", "endsAt")
+	}
+	slices.SortFunc(r.Prizes, func(a, b SeasonPrize) int { return a.RankFrom - b.RankFrom })
+	for i := range r.Prizes {
+		p := &r.Prizes[i]
+		p.ID, p.SeasonID = 0, 0
+		if p.RankFrom < 1 || p.RankTo < p.RankFrom || (i > 0 && p.RankFrom <= r.Prizes[i-1].RankTo) {
+			return apiError(ERR_SEASON_INVALID, "field", "prizes.rank")
+		}
+		if f := p.invalidField(); f != "" {
+			return apiError(ERR_SEASON_INVALID, "field", "prizes."+f)
+		}
+	}
+	return nil
+}
+
+func parseSeasonID(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+func loadSeason(id uint) (Season, error) {
+	var s Season
+	if err := DB.Preload("Prizes", func(db *gorm.DB) *gorm.DB { return db.Order("rank_from ASC") }).
+		First(&s, id).Error; err != nil {
+		return s, apiError(ERR_SEASON_NOT_FOUND)
+	}
+	return s, nil
+}
+
+// GET /admin/seasons?status=
+func adminListSeasonsHandler(c *gin.Context) {
+	q := DB.Preload("Prizes", func(db *gorm.DB) *gorm.DB { return db.Order("rank_from ASC") })
+	if st := strings.ToUpper(strings.TrimSpace(c.Query("status"))); st != "" {
+		q = q.Where("status = ?", st)
+	}
+	var rows []Season
+	q.Order("id DESC").Limit(200).Find(&rows)
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// POST /admin/seasons { name, metric, startsAt, endsAt, prizes: [{ rankFrom, rankTo, coins, bonusCoins, freeSpins, itemCode, itemQty }] }
+func adminCreateSeasonHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req SeasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	s := Season{
+		Name: req.Name, Metric: req.Metric, StartsAt: req.StartsAt, EndsAt: req.EndsAt,
+		Status: SEASON_OPEN, Prizes: req.Prizes, CreatedBy: adminID,
+	}
+	if err := DB.Create(&s).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã tạo mùa giải", "season": s})
+}
+
+// PUT /admin/seasons/:id (chỉ khi mùa chưa bắt đầu; thay toàn bộ bảng giải thưởng)
+func adminUpdateSeasonHandler(c *gin.Context) {
+	id, ok := parseSeasonID(c)
+	if !ok {
+		return
+	}
+	var req SeasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		var s Season
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, id).Error; err != nil {
+			return apiError(ERR_SEASON_NOT_FOUND)
+		}
+		if s.Status != SEASON_OPEN {
+			return apiError(ERR_SEASON_CLOSED)
+		}
+		if !s.StartsAt.After(time.Now()) {
+			return apiError(ERR_SEASON_STARTED)
+		}
+		if err := tx.Where("season_id = ?", id).Delete(&SeasonPrize{}).Error; err != nil {
+			return err
+		}
+		for i := range req.Prizes {
+			req.Prizes[i].SeasonID = id
+		}
+		if len(req.Prizes) > 0 {
+			if err := tx.Create(&req.Prizes).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&s).Updates(map[string]any{
+			"name": req.Name, "metric": req.Metric, "starts_at": req.StartsAt, "ends_at": req.EndsAt,
+		}).Error
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	s, _ := loadSeason(id)
+	c.JSON(200, gin.H{"message": "Đã cập nhật mùa giải", "season": s})
+}
+
+// POST /admin/seasons/:id/cancel
+func adminCancelSeasonHandler(c *gin.Context) {
+	id, ok := parseSeasonID(c)
+	if !ok {
+		return
+	}
+	res := DB.Model(&Season{}).Where("id = ? AND status = ?", id, SEASON_OPEN).
+		Updates(map[string]any{"status": SEASON_CANCELLED, "closed_at": time.Now()})
+	if res.Error != nil {
+		respondError(c, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		if _, err := loadSeason(id); err != nil {
+			respondError(c, err)
+			return
+		}
+		respondError(c, apiError(ERR_SEASON_CLOSED))
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã huỷ mùa giải"})
+}
+
+/* ----- người dùng ----- */
+
+// GET /public/seasons?status=OPEN|CLOSED
+func publicListSeasonsHandler(c *gin.Context) {
+	q := DB.Preload("Prizes", func(db *gorm.DB) *gorm.DB { return db.Order("rank_from ASC") }).
+		Where("status <> ?", SEASON_CANCELLED)
+	if st := strings.ToUpper(strings.TrimSpace(c.Query("status"))); st != "" {
+		q = q.Where("status = ?", st)
+	}
+	var rows []Season
+	q.Order("ends_at DESC").Limit(100).Find(&rows)
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// GET /public/seasons/:id?limit=100
+// Mùa đã đóng: bảng chốt cuối mùa (final = true); mùa đang mở: bảng tạm tính theo thời điểm hiện tại.
+func publicSeasonStandingsHandler(c *gin.Context) {
+	id, ok := parseSeasonID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(seasonStandingsLimit)))
+	if limit <= 0 || limit > 1000 {
+		limit = seasonStandingsLimit
+	}
+	s, err := loadSeason(id)
+	if err != nil || s.Status == SEASON_CANCELLED {
+		respondError(c, apiError(ERR_SEASON_NOT_FOUND))
+		return
+	}
+
+	rows := []SeasonStanding{}
+	switch {
+	case s.Status == SEASON_CLOSED:
+		if err := DB.Where("season_id = ?", s.ID).Order("rank_no ASC, id ASC").Limit(limit).Find(&rows).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+	case s.StartsAt.Before(time.Now()):
+		all, err := seasonLiveStandings(s)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		rows = all[:min(limit, len(all))]
+	}
+	c.JSON(200, gin.H{"season": s, "final": s.Status == SEASON_CLOSED, "rows": rows})
+}
+
+// GET /private/seasons/:id/me — hạng, điểm & phần thưởng (đã nhận hoặc dự kiến) của tôi
+func mySeasonStandingHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	id, ok := parseSeasonID(c)
+	if !ok {
+		return
+	}
+	s, err := loadSeason(id)
+	if err != nil || s.Status == SEASON_CANCELLED {
+		respondError(c, apiError(ERR_SEASON_NOT_FOUND))
+		return
+	}
+
+	var me *SeasonStanding
+	switch {
+	case s.Status == SEASON_CLOSED:
+		var st SeasonStanding
+		if DB.Where("season_id = ? AND user_id = ?", s.ID, uid).Limit(1).Find(&st).Error == nil && st.ID != 0 {
+			me = &st
+		}
+	case s.StartsAt.Before(time.Now()):
+		all, err := seasonLiveStandings(s)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if i := slices.IndexFunc(all, func(st SeasonStanding) bool { return st.UserID == uid }); i >= 0 {
+			me = &all[i]
+		}
+	}
+	c.JSON(200, gin.H{"final": s.Status == SEASON_CLOSED, "standing": me})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSeasonStandings(t *testing.T) {
+	season := Season{ID: 7, Prizes: []SeasonPrize{
+		{RankFrom: 1, RankTo: 1, RewardBundle: RewardBundle{Coins: 1000}},
+		{RankFrom: 2, RankTo: 3, RewardBundle: RewardBundle{Coins: 500}},
+		{RankFrom: 4, RankTo: 5, RewardBundle: RewardBundle{ItemCode: "DB1", ItemQty: 1}},
+	}}
+	entries := func(scores ...int64) []leaderboardEntry {
+		out := make([]leaderboardEntry, len(scores))
+		for i, s := range scores {
+			out[i] = leaderboardEntry{UserID: uint(i + 1), Score: s}
+		}
+		return out
+	}
+	tests := []struct {
+		name      string
+		entries   []leaderboardEntry
+		wantRanks []int
+		wantCoins []int64
+	}{
+		{"rỗng", nil, []int{}, []int64{}},
+		{"không đồng điểm", entries(90, 80, 70), []int{1, 2, 3}, []int64{1000, 500, 500}},
+		{"đồng điểm đồng hạng, hạng sau bị đẩy xuống", entries(100, 100, 80, 50, 50, 10),
+			[]int{1, 1, 3, 4, 4, 6}, []int64{1000, 1000, 500, 0, 0, 0}},
+		{"đồng điểm cả bảng", entries(5, 5, 5), []int{1, 1, 1}, []int64{1000, 1000, 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := seasonStandings(season, tt.entries)
+			ranks, coins := []int{}, []int64{}
+			for i, r := range rows {
+				if r.SeasonID != season.ID || r.UserID != tt.entries[i].UserID || r.Score != tt.entries[i].Score {
+					t.Errorf("row %d = %+v, không khớp entry %+v", i, r, tt.entries[i])
+				}
+				ranks, coins = append(ranks, r.Rank), append(coins, r.Coins)
+			}
+			if !slices.Equal(ranks, tt.wantRanks) {
+				t.Errorf("ranks = %v, want %v", ranks, tt.wantRanks)
+			}
+			if !slices.Equal(coins, tt.wantCoins) {
+				t.Errorf("coins = %v, want %v", coins, tt.wantCoins)
+			}
+		})
+	}
+
+	// hạng 4-5 nhận vật phẩm
+	rows := seasonStandings(season, entries(9, 8, 7, 6, 5, 4))
+	for i, r := range rows {
+		wantItem := i == 3 || i == 4
+		if got := r.ItemCode == "DB1" && r.ItemQty == 1; got != wantItem {
+			t.Errorf("rank %d item = %q x%d, want item %v", r.Rank, r.ItemCode, r.ItemQty, wantItem)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== CẤU HÌNH ĐỘNG (admin chỉnh được) & TÀI KHOẢN HỆ THỐNG ===== */
+
+// username của tài khoản hệ thống nhận phí (không đăng nhập được vì không có mật khẩu)
+const SYSTEM_USERNAME = "system"
+
+// role riêng của tài khoản hệ thống: không phải admin (không nhận hoa hồng dự phòng, thông báo admin, quyền superadmin)
+// cũng không phải user (không nằm trong bảng xếp hạng, broadcast)
+const ROLE_SYSTEM = "system"
+
+var systemUserID uint
+
+type AppSetting struct {
+	Key       string    `gorm:"primaryKey;size:64" json:"key"`
+	Value     string    `gorm:"size:500;not null" json:"value"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// các key hợp lệ + giá trị mặc định
+var settingDefaults = map[string]string{
+	"market.seller_fee_bps":        "200",  // phí người bán 2% (basis points), làm tròn lên
+	"market.listing_ttl_hours":     "168",  // hạn tối đa của listing (giờ), 0 => không hết hạn
+	"checkin.grace_days":           "1",    // số ngày được bỏ lỡ liên tiếp mà không đứt chuỗi điểm danh
+	"checkin.vip_bonus_pct":        "50",   // thưởng điểm danh +% cho mỗi cấp VIP
+	"promo.redeem_url":             "",     // link nhập code in trên QR, {code} được thay bằng code; rỗng => QR chỉ chứa code
+	"promo.archive_after_days":     "30",   // tự lưu trữ chiến dịch hết hạn quá N ngày, 0 => tắt
+	"jobs.run_retention_days":      "30",   // giữ lịch sử chạy job N ngày, 0 => giữ mãi
+	"recon.alert_threshold":        "1",    // báo admin khi số dư lệch sổ cái từ N coin, 0 => không báo
+	"closure.downline_policy":      "keep", // tuyến dưới khi đóng tài khoản: keep (giữ upline cũ) | reparent (chuyển lên upline kế)
+	"closure.purge_retention_days": "1825", // chỉ xoá cứng tài khoản đã đóng quá N ngày (mặc định 5 năm)
+	"export.link_ttl_hours":        "48",   // link tải file xuất dữ liệu cá nhân hết hạn sau N giờ
+	"recon.freeze_threshold":       "0",    // khoá tài khoản khi lệch từ N coin, 0 => không khoá
+}
+
+const settingsTTL = 30 * time.Second
+
+var settingsCache = struct {
+	sync.Mutex
+	m  map[string]string // nil = chưa tải / đã xoá
+	at time.Time
+}{}
+
+// Đọc cả bảng app_settings 1 lần rồi cache 30 giây/instance. Admin sửa cấu hình thì phát event
+// settings.changed sau commit, mọi instance nhận qua Hub và xoá cache; TTL là lưới an toàn khi event bị lỡ.
+// DB lỗi: dùng lại bản cache cũ nếu có, không thì giá trị mặc định.
+func settingString(key string) string {
+	settingsCache.Lock()
+	defer settingsCache.Unlock()
+	if settingsCache.m == nil || time.Since(settingsCache.at) >= settingsTTL {
+		var saved []AppSetting
+		if err := DB.Find(&saved).Error; err == nil {
+			m := make(map[string]string, len(saved))
+			for _, s := range saved {
+				m[s.Key] = s.Value
+			}
+			settingsCache.m, settingsCache.at = m, time.Now()
+		} else if settingsCache.m == nil {
+			return settingDefaults[key]
+		}
+	}
+	if v, ok := settingsCache.m[key]; ok {
+		return v
+	}
+	return settingDefaults[key]
+}
+
+func forgetSettings() {
+	settingsCache.Lock()
+	settingsCache.m = nil
+	settingsCache.Unlock()
+}
+
+func settingInt(key string) int64 {
+	v, err := strconv.ParseInt(strings.TrimSpace(settingString(key)), 10, 64)
+	if err != nil {
+		v, _ = strconv.ParseInt(settingDefaults[key], 10, 64)
+	}
+	return v
+}
+
+// tạo tài khoản hệ thống nếu chưa có
+func ensureSystemAccount() {
+	var u User
+	if err := ensureSystemRoleEnum(); err != nil {
+		fmt.Println("⚠️  Không thêm được role system:", err)
+		return
+	}
+	err := DB.Where("username = ?", SYSTEM_USERNAME).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		u = User{Username: SYSTEM_USERNAME, Name: "System", Role: ROLE_SYSTEM}
+		err = DB.Create(&u).Error
+	}
+	if err != nil {
+		fmt.Println("⚠️  Không tạo được tài khoản hệ thống:", err)
+		return
+	}
+	if u.PasswordHash != "" {
+		// username đã bị người dùng thật đăng ký trước -> không dùng làm ví phí
+		fmt.Println("⚠️  Username 'system' đang thuộc người dùng thường, bỏ qua ví phí hệ thống")
+		return
+	}
+	// DB cũ: tài khoản hệ thống từng mang role admin
+	if u.Role != ROLE_SYSTEM {
+		if err := DB.Model(&u).Update("role", ROLE_SYSTEM).Error; err != nil {
+			fmt.Println("⚠️  Không đổi được role tài khoản hệ thống:", err)
+			return
+		}
+	}
+	systemUserID = u.ID
+}
+
+// AutoMigrate không thêm giá trị mới vào cột enum đã có ⇒ tự ALTER khi users.role chưa có 'system'
+func ensureSystemRoleEnum() error {
+	cols, err := DB.Migrator().ColumnTypes(&User{})
+	if err != nil {
+		return err
+	}
+	for _, c := range cols {
+		if c.Name() != "role" {
+			continue
+		}
+		if t, ok := c.ColumnType(); ok && !strings.Contains(strings.ToLower(t), "'"+ROLE_SYSTEM+"'") {
+			return DB.Migrator().AlterColumn(&User{}, "Role")
+		}
+	}
+	return nil
+}
+
+// cộng phí vào tài khoản hệ thống (typ: MARKET_FEE ref market_trades.id | TRANSFER_FEE_IN ref transfer_txns.id)
+func creditSystemFee(tx *gorm.DB, typ string, amount int64, ref uint) error {
+	if amount <= 0 || systemUserID == 0 {
+		return nil
+	}
+	if err := tx.Model(&User{}).Where("id = ?", systemUserID).
+		Update("coins", gorm.Expr("coins + ?", amount)).Error; err != nil {
+		return err
+	}
+	return addLedger(tx, systemUserID, CUR_COIN, typ, ref, amount)
+}
+
+// GET /admin/settings
+func adminListSettingsHandler(c *gin.Context) {
+	var saved []AppSetting
+	DB.Find(&saved)
+	cur := map[string]string{}
+	for k, v := range settingDefaults {
+		cur[k] = v
+	}
+	for _, s := range saved {
+		cur[s.Key] = s.Value
+	}
+	c.JSON(200, gin.H{"settings": cur, "defaults": settingDefaults})
+}
+
+type SettingUpdateRequest struct {
+	Key   string `json:"key" binding:"required"`
+	Value string `json:"value"`
+}
+
+// PUT /admin/settings { key, value }
+func adminUpdateSettingHandler(c *gin.Context) {
+	var req SettingUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	req.Key = strings.TrimSpace(req.Key)
+	req.Value = strings.TrimSpace(req.Value)
+	def, ok := settingDefaults[req.Key]
+	if !ok {
+		respondError(c, apiError(ERR_SETTING_KEY_UNKNOWN))
+		return
+	}
+	// giá trị mặc định là số thì giá trị mới cũng phải là số >= 0
+	if _, err := strconv.ParseInt(def, 10, 64); err == nil {
+		if n, err := strconv.ParseInt(req.Value, 10, 64); err != nil || n < 0 {
+			respondError(c, apiError(ERR_SETTING_VALUE_INVALID))
+			return
+		}
+	}
+
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	if err := withEvents(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&AppSetting{Key: req.Key, Value: req.Value}).Error; err != nil {
+			return err
+		}
+		emitEvent(tx, adminID, EV_SETTINGS_CHANGED, gin.H{"key": req.Key})
+		return nil
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+	forgetSettings()
+	c.JSON(200, gin.H{"message": "Đã lưu cấu hình", "key": req.Key, "value": req.Value})
+}
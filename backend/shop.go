@@ -0,0 +1,449 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== SHOP ĐỔI THẺ SỰ KIỆN (EV) ===== */
+
+// Loại phần thưởng của shop
+const (
+	SHOP_REWARD_FREE_SPIN    = "FREE_SPIN"    // + lượt quay miễn phí
+	SHOP_REWARD_BONUS_COIN   = "BONUS_COIN"   // + bonus coin
+	SHOP_REWARD_DRAGON_BALL  = "DRAGON_BALL"  // + viên DB1..DB7
+	SHOP_REWARD_VIP_DISCOUNT = "VIP_DISCOUNT" // phiếu giảm % giá VIP
+)
+
+// món hàng trong shop (admin quản lý)
+type ShopItem struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"size:120;not null" json:"name"`
+	Description  string     `gorm:"size:500" json:"description"`
+	CostCode     string     `gorm:"size:10;not null;default:'EV'" json:"costCode"` // vật phẩm dùng để đổi (mặc định EV)
+	CostQty      int64      `gorm:"not null" json:"costQty"`                       // số lượng / 1 lần đổi
+	RewardKind   string     `gorm:"size:16;not null" json:"rewardKind"`
+	RewardCode   string     `gorm:"size:10" json:"rewardCode"`    // DB1..DB7 (nếu là DRAGON_BALL)
+	RewardAmount int64      `gorm:"not null" json:"rewardAmount"` // số lượt / coin / viên / % giảm
+	Stock        *int64     `json:"stock"`                        // NULL => không giới hạn
+	SoldCount    int64      `gorm:"not null;default:0" json:"soldCount"`
+	PerUserLimit *int64     `json:"perUserLimit"` // NULL => không giới hạn
+	StartsAt     *time.Time `gorm:"index" json:"startsAt"`
+	EndsAt       *time.Time `gorm:"index" json:"endsAt"`
+	IsActive     bool       `gorm:"not null;default:true" json:"isActive"`
+	CreatedBy    *uint      `json:"-"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// nhật ký đổi quà
+type ShopPurchase struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"userId"`
+	ShopItemID   uint      `gorm:"not null;index" json:"itemId"`
+	Qty          int64     `gorm:"not null" json:"qty"`
+	CostCode     string    `gorm:"size:10;not null" json:"costCode"`
+	CostQty      int64     `gorm:"not null" json:"costQty"` // tổng đã trừ
+	RewardKind   string    `gorm:"size:16;not null" json:"rewardKind"`
+	RewardCode   string    `gorm:"size:10" json:"rewardCode"`
+	RewardAmount int64     `gorm:"not null" json:"rewardAmount"` // tổng đã nhận
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// phiếu giảm giá VIP (nhận từ shop), dùng 1 lần khi mua VIP
+type VipVoucher struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Percent    int        `gorm:"not null" json:"percent"`
+	PurchaseID *uint      `json:"-"`
+	UsedAt     *time.Time `gorm:"index" json:"usedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type ShopItemRequest struct {
+	Name         string     `json:"name" binding:"required,max=120"`
+	Description  string     `json:"description" binding:"max=500"`
+	CostCode     string     `json:"costCode"` // bỏ trống => EV
+	CostQty      int64      `json:"costQty" binding:"required,gt=0,lte=1000000000"`
+	RewardKind   string     `json:"rewardKind" binding:"required"`
+	RewardCode   string     `json:"rewardCode"`
+	RewardAmount int64      `json:"rewardAmount" binding:"required,gt=0,lte=1000000000"`
+	Stock        *int64     `json:"stock"`
+	PerUserLimit *int64     `json:"perUserLimit"`
+	StartsAt     *time.Time `json:"startsAt"`
+	EndsAt       *time.Time `json:"endsAt"`
+	IsActive     *bool      `json:"isActive"`
+}
+
+// chỉ cho phép DB1..DB7 hoặc EV (giống chợ)
+func isTradableCode(code string) bool {
+	isDB := strings.HasPrefix(code, "DB") && len(code) == 3 && code[2] >= '1' && code[2] <= '7'
+	return isDB || code == "EV"
+}
+
+func (r *ShopItemRequest) normalize() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.CostCode = strings.ToUpper(strings.TrimSpace(r.CostCode))
+	if r.CostCode == "" {
+		r.CostCode = "EV"
+	}
+	if !isTradableCode(r.CostCode) {
+		return apiError(ERR_SHOP_COST_CODE_INVALID)
+	}
+	r.RewardKind = strings.ToUpper(strings.TrimSpace(r.RewardKind))
+	r.RewardCode = strings.ToUpper(strings.TrimSpace(r.RewardCode))
+	switch r.RewardKind {
+	case SHOP_REWARD_FREE_SPIN, SHOP_REWARD_BONUS_COIN:
+		r.RewardCode = ""
+	case SHOP_REWARD_DRAGON_BALL:
+		if !strings.HasPrefix(r.RewardCode, "DB") || !isTradableCode(r.RewardCode) {
+			return apiError(ERR_SHOP_REWARD_INVALID)
+		}
+	case SHOP_REWARD_VIP_DISCOUNT:
+		r.RewardCode = ""
+		if r.RewardAmount > 100 {
+			return apiError(ERR_SHOP_DISCOUNT_TOO_HIGH)
+		}
+	default:
+		return apiError(ERR_SHOP_REWARD_INVALID)
+	}
+	if r.Stock != nil && *r.Stock < 0 {
+		return apiError(ERR_SHOP_STOCK_INVALID)
+	}
+	if r.PerUserLimit != nil && *r.PerUserLimit <= 0 {
+		return apiError(ERR_SHOP_LIMIT_INVALID)
+	}
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return apiError(ERR_SHOP_TIME_RANGE_INVALID)
+	}
+	return nil
+}
+
+// phát thưởng shop cho user (trong transaction)
+func grantShopReward(tx *gorm.DB, userID uint, item ShopItem, qty int64, purchaseID uint) error {
+	total := item.RewardAmount * qty
+	switch item.RewardKind {
+	case SHOP_REWARD_FREE_SPIN:
+		return tx.Model(&User{}).Where("id = ?", userID).
+			Update("free_spins", gorm.Expr("free_spins + ?", total)).Error
+	case SHOP_REWARD_BONUS_COIN:
+		if err := tx.Model(&User{}).Where("id = ?", userID).
+			Update("bonus_coins", gorm.Expr("bonus_coins + ?", total)).Error; err != nil {
+			return err
+		}
+		return addLedger(tx, userID, CUR_BONUS, LEDGER_SHOP_REWARD, purchaseID, total)
+	case SHOP_REWARD_DRAGON_BALL:
+		return addToInventory(tx, userID, item.RewardCode, total)
+	case SHOP_REWARD_VIP_DISCOUNT:
+		// mỗi lần đổi = 1 phiếu
+		for i := int64(0); i < qty; i++ {
+			if err := tx.Create(&VipVoucher{
+				UserID: userID, Percent: int(item.RewardAmount), PurchaseID: &purchaseID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return apiError(ERR_SHOP_REWARD_INVALID)
+}
+
+// món hàng kèm tồn kho còn lại & số lượt user đã đổi
+type ShopItemView struct {
+	ShopItem
+	Remaining *int64 `json:"remaining"` // tồn kho còn lại (NULL => vô hạn)
+	MyBought  int64  `json:"myBought"`
+}
+
+// GET /private/shop
+func shopListHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	now := time.Now()
+
+	var items []ShopItem
+	if err := DB.Where("is_active = ?", true).
+		Where("(starts_at IS NULL OR starts_at <= ?)", now).
+		Where("(ends_at IS NULL OR ends_at > ?)", now).
+		Order("id ASC").Find(&items).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// số lượng user đã đổi theo từng món
+	type boughtRow struct {
+		ShopItemID uint
+		Qty        int64
+	}
+	var bought []boughtRow
+	DB.Model(&ShopPurchase{}).
+		Select("shop_item_id, COALESCE(SUM(qty),0) AS qty").
+		Where("user_id = ?", uid).
+		Group("shop_item_id").Scan(&bought)
+	mine := map[uint]int64{}
+	for _, b := range bought {
+		mine[b.ShopItemID] = b.Qty
+	}
+
+	rows := make([]ShopItemView, 0, len(items))
+	for _, it := range items {
+		r := ShopItemView{ShopItem: it, MyBought: mine[it.ID]}
+		if it.Stock != nil {
+			left := *it.Stock - it.SoldCount
+			if left < 0 {
+				left = 0
+			}
+			r.Remaining = &left
+		}
+		rows = append(rows, r)
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// maxShopBuyQty × giá/thưởng tối đa (1e9) vẫn nằm trong int64
+const maxShopBuyQty = 1000
+
+type ShopBuyRequest struct {
+	ItemID uint  `json:"itemId" binding:"required"`
+	Qty    int64 `json:"qty" binding:"gte=0,lte=1000"` // 0 => 1, tối đa maxShopBuyQty
+}
+
+// POST /private/shop/buy { itemId, qty }
+func shopBuyHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req ShopBuyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if req.Qty <= 0 {
+		req.Qty = 1
+	}
+	if req.Qty > maxShopBuyQty {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "qty", "detail", fmt.Sprintf("tối đa %d", maxShopBuyQty)))
+		return
+	}
+
+	var out ShopPurchase
+	if err := withEvents(func(tx *gorm.DB) error {
+		// 🔒 khoá món hàng để giữ đúng tồn kho
+		var it ShopItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, req.ItemID).Error; err != nil {
+			return apiError(ERR_SHOP_ITEM_NOT_FOUND)
+		}
+		now := time.Now()
+		if !it.IsActive {
+			return apiError(ERR_SHOP_ITEM_INACTIVE)
+		}
+		if it.StartsAt != nil && now.Before(*it.StartsAt) {
+			return apiError(ERR_SHOP_NOT_STARTED)
+		}
+		if it.EndsAt != nil && !now.Before(*it.EndsAt) {
+			return apiError(ERR_SHOP_ENDED)
+		}
+		if it.Stock != nil && it.SoldCount+req.Qty > *it.Stock {
+			return apiError(ERR_SHOP_OUT_OF_STOCK, "left", max(*it.Stock-it.SoldCount, 0))
+		}
+		if it.PerUserLimit != nil {
+			var mine int64
+			if err := tx.Model(&ShopPurchase{}).
+				Where("user_id = ? AND shop_item_id = ?", uid, it.ID).
+				Select("COALESCE(SUM(qty),0)").Scan(&mine).Error; err != nil {
+				return err
+			}
+			if mine+req.Qty > *it.PerUserLimit {
+				return apiError(ERR_SHOP_LIMIT_REACHED, "limit", *it.PerUserLimit, "used", mine)
+			}
+		}
+
+		// giá / thưởng của món cũ có thể chưa bị giới hạn: chặn tràn số
+		if it.CostQty > math.MaxInt64/req.Qty || it.RewardAmount > math.MaxInt64/req.Qty {
+			return apiError(ERR_INVALID_INPUT, "field", "qty", "detail", "qty quá lớn")
+		}
+		// trừ vật phẩm
+		cost := it.CostQty * req.Qty
+		if err := invSub(tx, uid, it.CostCode, cost); err != nil {
+			return err
+		}
+
+		// tăng số đã bán
+		if err := tx.Model(&ShopItem{}).Where("id = ?", it.ID).
+			Update("sold_count", gorm.Expr("sold_count + ?", req.Qty)).Error; err != nil {
+			return err
+		}
+
+		// log
+		out = ShopPurchase{
+			UserID: uid, ShopItemID: it.ID, Qty: req.Qty,
+			CostCode: it.CostCode, CostQty: cost,
+			RewardKind: it.RewardKind, RewardCode: it.RewardCode, RewardAmount: it.RewardAmount * req.Qty,
+		}
+		if err := tx.Create(&out).Error; err != nil {
+			return err
+		}
+
+		// phát thưởng
+		emitBalanceChanged(tx, uid, "shop")
+		return grantShopReward(tx, uid, it, req.Qty, out.ID)
+	}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Đổi quà thành công", "purchase": out})
+}
+
+type ShopPurchaseRow struct {
+	ShopPurchase
+	ItemName string `json:"itemName"`
+}
+
+// GET /private/shop/purchases
+func shopMyPurchasesHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var rows []ShopPurchaseRow
+	DB.Table("shop_purchases p").
+		Select("p.*, i.name AS item_name").
+		Joins("LEFT JOIN shop_items i ON i.id = p.shop_item_id").
+		Where("p.user_id = ?", uid).
+		Order("p.id DESC").Limit(200).Scan(&rows)
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// GET /private/vip-vouchers (phiếu giảm giá VIP chưa dùng)
+func myVipVouchersHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var rows []VipVoucher
+	DB.Where("user_id = ? AND used_at IS NULL", uid).Order("percent DESC, id ASC").Find(&rows)
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// GET /admin/shop/items
+func adminListShopItemsHandler(c *gin.Context) {
+	var rows []ShopItem
+	if err := DB.Order("id DESC").Find(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+// POST /admin/shop/items
+func adminCreateShopItemHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req ShopItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	it := ShopItem{
+		Name: req.Name, Description: strings.TrimSpace(req.Description),
+		CostCode: req.CostCode, CostQty: req.CostQty,
+		RewardKind: req.RewardKind, RewardCode: req.RewardCode, RewardAmount: req.RewardAmount,
+		Stock: req.Stock, PerUserLimit: req.PerUserLimit,
+		StartsAt: req.StartsAt, EndsAt: req.EndsAt,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+		CreatedBy: &adminID,
+	}
+	if err := DB.Create(&it).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã tạo quà", "item": it})
+}
+
+// PUT /admin/shop/items/:id
+func adminUpdateShopItemHandler(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id64 == 0 {
+		respondError(c, apiError(ERR_INVALID_ID))
+		return
+	}
+	var req ShopItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	if err := req.normalize(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var it ShopItem
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&it, uint(id64)).Error; err != nil {
+			return err
+		}
+		if req.Stock != nil && *req.Stock < it.SoldCount {
+			return apiError(ERR_SHOP_STOCK_BELOW_SOLD, "sold", it.SoldCount)
+		}
+		up := map[string]any{
+			"name":           req.Name,
+			"description":    strings.TrimSpace(req.Description),
+			"cost_code":      req.CostCode,
+			"cost_qty":       req.CostQty,
+			"reward_kind":    req.RewardKind,
+			"reward_code":    req.RewardCode,
+			"reward_amount":  req.RewardAmount,
+			"stock":          req.Stock,
+			"per_user_limit": req.PerUserLimit,
+			"starts_at":      req.StartsAt,
+			"ends_at":        req.EndsAt,
+		}
+		if req.IsActive != nil {
+			up["is_active"] = *req.IsActive
+		}
+		if err := tx.Model(&it).Updates(up).Error; err != nil {
+			return err
+		}
+		return tx.First(&it, it.ID).Error
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, apiError(ERR_SHOP_ITEM_NOT_FOUND))
+			return
+		}
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã cập nhật quà", "item": it})
+}
+
+type AdminShopPurchaseRow struct {
+	ShopPurchase
+	ItemName string `json:"itemName"`
+	Username string `json:"username"`
+}
+
+// GET /admin/shop/purchases?itemId=&userId=
+func adminShopPurchasesHandler(c *gin.Context) {
+	q := DB.Table("shop_purchases p").
+		Select("p.*, i.name AS item_name, u.username").
+		Joins("LEFT JOIN shop_items i ON i.id = p.shop_item_id").
+		Joins("LEFT JOIN users u ON u.id = p.user_id")
+	if v, err := strconv.ParseUint(c.Query("itemId"), 10, 64); err == nil && v > 0 {
+		q = q.Where("p.shop_item_id = ?", v)
+	}
+	if v, err := strconv.ParseUint(c.Query("userId"), 10, 64); err == nil && v > 0 {
+		q = q.Where("p.user_id = ?", v)
+	}
+
+	var rows []AdminShopPurchaseRow
+	if err := q.Order("p.id DESC").Limit(500).Scan(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
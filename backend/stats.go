@@ -0,0 +1,441 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== THỐNG KÊ KINH TẾ (ADMIN): ROLLUP THEO NGÀY, NGUỒN VÀO / NGUỒN RA ===== */
+
+// chỉ số lưu trong stats_dailies
+const (
+	STAT_LEDGER         = "ledger"       // key TYPE:CURRENCY, tổng biến động sổ cái của user (không tính tài khoản hệ thống)
+	STAT_CIRCULATION    = "circulation"  // key CURRENCY, tổng số dư cuối ngày
+	STAT_ACTIVE_USERS   = "active_users" // user có request đăng nhập trong ngày
+	STAT_NEW_USERS      = "new_users"
+	STAT_CHEST_OPENS    = "chest_opens"
+	STAT_CHEST_PAID     = "chest_paid_opens" // lượt mở mất phí (không dùng lượt quay miễn phí)
+	STAT_CHEST_DROP     = "chest_drop"       // key CODE:AMOUNT
+	STAT_VIP_PURCHASES  = "vip_purchases"
+	STAT_VIP_FIRST      = "vip_first_purchases" // lượt mua VIP đầu tiên của user (từ cấp 0)
+	STAT_MARKET_TRADES  = "market_trades"
+	STAT_MARKET_QTY     = "market_qty"
+	STAT_MARKET_VOLUME  = "market_volume"
+	STAT_MARKET_FEE     = "market_fee"
+	statsDayLayout      = "2006-01-02"
+	statsMaxRangeDays   = 366
+	statsBackfillMaxDay = 365 // lần rollup đầu tiên: tính lùi tối đa N ngày
+)
+
+// phân loại dòng sổ cái: nguồn vào (tạo thêm coin) / nguồn ra (coin biến mất khỏi tay user).
+// Các loại còn lại (chuyển khoản, mua bán trên chợ, ký quỹ) chỉ luân chuyển giữa user.
+var statsFaucetTypes = []string{
+	LEDGER_TOPUP, LEDGER_COMMISSION, LEDGER_REFERRAL_BONUS, LEDGER_CHEST_MILESTONE, LEDGER_MERGE_REWARD,
+	LEDGER_PROMO, LEDGER_BONUS_CODE, LEDGER_SHOP_REWARD, LEDGER_SEASON_PRIZE, LEDGER_CHECKIN, LEDGER_MISSION,
+}
+
+var statsSinkTypes = []string{LEDGER_CHEST_OPEN, LEDGER_VIP_PURCHASE, LEDGER_TRANSFER_FEE, LEDGER_WITHDRAW}
+
+type StatsDaily struct {
+	Day       string    `gorm:"primaryKey;size:10"` // YYYY-MM-DD giờ server
+	Metric    string    `gorm:"primaryKey;size:32"`
+	Key       string    `gorm:"primaryKey;size:48"`
+	Value     int64     `gorm:"not null;default:0"`
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+// user hoạt động theo ngày (ghi từ authRequired)
+type UserActiveDay struct {
+	Day    string `gorm:"primaryKey;size:10"`
+	UserID uint   `gorm:"primaryKey;autoIncrement:false"`
+}
+
+// nhớ trong RAM để mỗi user chỉ ghi DB 1 lần/ngày/instance
+var activeSeen struct {
+	sync.Mutex
+	day string
+	ids map[uint]bool
+}
+
+func markUserActive(uid uint) {
+	day := time.Now().Format(statsDayLayout)
+	activeSeen.Lock()
+	if activeSeen.day != day {
+		activeSeen.day, activeSeen.ids = day, map[uint]bool{}
+	}
+	seen := activeSeen.ids[uid]
+	activeSeen.ids[uid] = true
+	activeSeen.Unlock()
+	if seen {
+		return
+	}
+	if err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserActiveDay{Day: day, UserID: uid}).Error; err != nil {
+		log.Println("mark active error:", err)
+	}
+}
+
+/* ----- rollup (job stats.rollup) ----- */
+
+func statsDayRange(day string) (time.Time, time.Time) {
+	start, _ := time.ParseInLocation(statsDayLayout, day, time.Local)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// tính lại từ ngày cuối đã có - 1 (chốt số hôm qua) tới hôm nay; lần đầu tính lùi theo dữ liệu sổ cái
+func rollupStats() error {
+	today := time.Now().Format(statsDayLayout)
+	var last string
+	if err := DB.Model(&StatsDaily{}).Select("COALESCE(MAX(day), '')").Scan(&last).Error; err != nil {
+		return err
+	}
+	var start time.Time
+	if last != "" {
+		s, _ := statsDayRange(last)
+		start = s.AddDate(0, 0, -1)
+	} else {
+		var first *time.Time
+		if err := DB.Model(&LedgerEntry{}).Select("MIN(created_at)").Scan(&first).Error; err != nil {
+			return err
+		}
+		start, _ = statsDayRange(today)
+		if first != nil {
+			start, _ = statsDayRange(first.Local().Format(statsDayLayout))
+		}
+		if floor := time.Now().AddDate(0, 0, -statsBackfillMaxDay); start.Before(floor) {
+			start, _ = statsDayRange(floor.Format(statsDayLayout))
+		}
+	}
+	for d := start; d.Format(statsDayLayout) <= today; d = d.AddDate(0, 0, 1) {
+		if err := rollupStatsDay(d.Format(statsDayLayout)); err != nil {
+			return fmt.Errorf("rollup %s: %w", d.Format(statsDayLayout), err)
+		}
+	}
+	return nil
+}
+
+func rollupStatsDay(day string) error {
+	from, to := statsDayRange(day)
+	var rows []StatsDaily
+	add := func(metric, key string, v int64) {
+		if v != 0 {
+			rows = append(rows, StatsDaily{Day: day, Metric: metric, Key: key, Value: v})
+		}
+	}
+	type kv struct {
+		K1, K2 string
+		V      int64
+	}
+
+	var ledger []kv
+	if err := DB.Model(&LedgerEntry{}).
+		Select("type AS k1, currency AS k2, SUM(amount) AS v").
+		Where("created_at >= ? AND created_at < ? AND user_id <> ?", from, to, systemUserID).
+		Group("type, currency").Scan(&ledger).Error; err != nil {
+		return err
+	}
+	for _, r := range ledger {
+		add(STAT_LEDGER, r.K1+":"+r.K2, r.V)
+	}
+
+	// số dư cuối ngày = số dư hiện tại - biến động sau cuối ngày (sổ cái luôn khớp số dư)
+	var bal struct{ Coins, BonusCoins int64 }
+	if err := DB.Unscoped().Model(&User{}).Select("COALESCE(SUM(coins),0) AS coins, COALESCE(SUM(bonus_coins),0) AS bonus_coins").
+		Where("id <> ?", systemUserID).Scan(&bal).Error; err != nil {
+		return err
+	}
+	after, err := ledgerSums(DB.Where("created_at >= ? AND user_id <> ?", to, systemUserID))
+	if err != nil {
+		return err
+	}
+	// luôn ghi (kể cả 0) để ngày không có giao dịch vẫn được đánh dấu đã rollup
+	rows = append(rows,
+		StatsDaily{Day: day, Metric: STAT_CIRCULATION, Key: CUR_COIN, Value: bal.Coins - after[CUR_COIN]},
+		StatsDaily{Day: day, Metric: STAT_CIRCULATION, Key: CUR_BONUS, Value: bal.BonusCoins - after[CUR_BONUS]})
+
+	counts := []struct {
+		metric string
+		q      *gorm.DB
+	}{
+		{STAT_ACTIVE_USERS, DB.Model(&UserActiveDay{}).Where("day = ?", day)},
+		{STAT_NEW_USERS, DB.Unscoped().Model(&User{}).Where("created_at >= ? AND created_at < ? AND role = 'user'", from, to)},
+		{STAT_CHEST_OPENS, DB.Model(&ChestTxn{}).Where("created_at >= ? AND created_at < ?", from, to)},
+		{STAT_CHEST_PAID, DB.Model(&ChestTxn{}).Where("created_at >= ? AND created_at < ? AND cost > 0", from, to)},
+		{STAT_VIP_PURCHASES, DB.Model(&VipPurchaseTxn{}).Where("created_at >= ? AND created_at < ?", from, to)},
+		{STAT_VIP_FIRST, DB.Model(&VipPurchaseTxn{}).Where("created_at >= ? AND created_at < ? AND old_level = 0", from, to)},
+	}
+	for _, cnt := range counts {
+		var n int64
+		if err := cnt.q.Count(&n).Error; err != nil {
+			return err
+		}
+		add(cnt.metric, "", n)
+	}
+
+	var drops []kv
+	if err := DB.Model(&ChestTxn{}).
+		Select("reward_code AS k1, CAST(reward_amount AS CHAR) AS k2, COUNT(*) AS v").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("reward_code, reward_amount").Scan(&drops).Error; err != nil {
+		return err
+	}
+	for _, r := range drops {
+		add(STAT_CHEST_DROP, r.K1+":"+r.K2, r.V)
+	}
+
+	var mk struct{ Trades, Qty, Volume, Fee int64 }
+	if err := DB.Model(&MarketTrade{}).
+		Select("COUNT(*) AS trades, COALESCE(SUM(qty),0) AS qty, COALESCE(SUM(total),0) AS volume, COALESCE(SUM(fee),0) AS fee").
+		Where("created_at >= ? AND created_at < ?", from, to).Scan(&mk).Error; err != nil {
+		return err
+	}
+	add(STAT_MARKET_TRADES, "", mk.Trades)
+	add(STAT_MARKET_QTY, "", mk.Qty)
+	add(STAT_MARKET_VOLUME, "", mk.Volume)
+	add(STAT_MARKET_FEE, "", mk.Fee)
+
+	// ghi đè cả ngày trong 1 transaction: đọc giữa chừng không thấy số dở dang
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("day = ?", day).Delete(&StatsDaily{}).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(rows, 200).Error
+	})
+}
+
+/* ----- GET /admin/stats ----- */
+
+type StatsFlow struct {
+	Coin  int64 `json:"coin"`
+	Bonus int64 `json:"bonus"`
+}
+
+func (f *StatsFlow) add(currency string, v int64) {
+	if currency == CUR_BONUS {
+		f.Bonus += v
+	} else {
+		f.Coin += v
+	}
+}
+
+type StatsDay struct {
+	Day               string               `json:"day"`
+	Faucets           map[string]StatsFlow `json:"faucets"` // theo loại sổ cái
+	Sinks             map[string]StatsFlow `json:"sinks"`   // số dương; MARKET_FEE lấy từ phí chợ
+	FaucetTotal       StatsFlow            `json:"faucetTotal"`
+	SinkTotal         StatsFlow            `json:"sinkTotal"`
+	Net               StatsFlow            `json:"net"`         // faucetTotal - sinkTotal
+	Circulation       StatsFlow            `json:"circulation"` // số dư cuối ngày (tổng: của ngày cuối kỳ)
+	ActiveUsers       int64                `json:"activeUsers"` // tổng: số user khác nhau trong kỳ
+	NewUsers          int64                `json:"newUsers"`
+	ChestOpens        int64                `json:"chestOpens"`
+	ChestPaidOpens    int64                `json:"chestPaidOpens"`
+	VipPurchases      int64                `json:"vipPurchases"`
+	VipFirstPurchases int64                `json:"vipFirstPurchases"`
+	VipConversionPct  float64              `json:"vipConversionPct"` // vipFirstPurchases / activeUsers
+	MarketTrades      int64                `json:"marketTrades"`
+	MarketQty         int64                `json:"marketQty"`
+	MarketVolume      int64                `json:"marketVolume"`
+	MarketFee         int64                `json:"marketFee"`
+}
+
+func newStatsDay(day string) *StatsDay {
+	return &StatsDay{Day: day, Faucets: map[string]StatsFlow{}, Sinks: map[string]StatsFlow{}}
+}
+
+func (d *StatsDay) apply(r StatsDaily) {
+	switch r.Metric {
+	case STAT_LEDGER:
+		typ, cur, _ := strings.Cut(r.Key, ":")
+		switch {
+		case slices.Contains(statsFaucetTypes, typ):
+			f := d.Faucets[typ]
+			f.add(cur, r.Value)
+			d.Faucets[typ] = f
+			d.FaucetTotal.add(cur, r.Value)
+		case slices.Contains(statsSinkTypes, typ):
+			f := d.Sinks[typ]
+			f.add(cur, -r.Value)
+			d.Sinks[typ] = f
+			d.SinkTotal.add(cur, -r.Value)
+		}
+	case STAT_CIRCULATION:
+		d.Circulation.add(r.Key, r.Value)
+	case STAT_ACTIVE_USERS:
+		d.ActiveUsers += r.Value
+	case STAT_NEW_USERS:
+		d.NewUsers += r.Value
+	case STAT_CHEST_OPENS:
+		d.ChestOpens += r.Value
+	case STAT_CHEST_PAID:
+		d.ChestPaidOpens += r.Value
+	case STAT_VIP_PURCHASES:
+		d.VipPurchases += r.Value
+	case STAT_VIP_FIRST:
+		d.VipFirstPurchases += r.Value
+	case STAT_MARKET_TRADES:
+		d.MarketTrades += r.Value
+	case STAT_MARKET_QTY:
+		d.MarketQty += r.Value
+	case STAT_MARKET_VOLUME:
+		d.MarketVolume += r.Value
+	case STAT_MARKET_FEE:
+		d.MarketFee += r.Value
+		f := d.Sinks["MARKET_FEE"]
+		f.Coin += r.Value
+		d.Sinks["MARKET_FEE"] = f
+		d.SinkTotal.Coin += r.Value
+	}
+}
+
+func (d *StatsDay) finish() {
+	d.Net = StatsFlow{Coin: d.FaucetTotal.Coin - d.SinkTotal.Coin, Bonus: d.FaucetTotal.Bonus - d.SinkTotal.Bonus}
+	d.VipConversionPct = pct(d.VipFirstPurchases, d.ActiveUsers)
+}
+
+func pct(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(int64(float64(a)*10000/float64(b))) / 100 // 2 chữ số thập phân
+}
+
+type ChestDropStat struct {
+	Code        string  `json:"code"`
+	Amount      int64   `json:"amount"`
+	Count       int64   `json:"count"`
+	Pct         float64 `json:"pct"`
+	ExpectedPct float64 `json:"expectedPct"` // theo bảng rơi hiện tại
+}
+
+type AdminStats struct {
+	From          string          `json:"from"`
+	To            string          `json:"to"`
+	Circulation   StatsFlow       `json:"circulation"` // hiện tại, không tính tài khoản hệ thống
+	FreeSpins     int64           `json:"freeSpins"`   // tổng lượt quay miễn phí đang giữ
+	SystemBalance StatsFlow       `json:"systemBalance"`
+	Users         int64           `json:"users"`
+	VipUsers      int64           `json:"vipUsers"`
+	VipPct        float64         `json:"vipPct"`
+	Days          []*StatsDay     `json:"days"`
+	Totals        *StatsDay       `json:"totals"`
+	ChestDrops    []ChestDropStat `json:"chestDrops"`
+	RolledUpAt    *time.Time      `json:"rolledUpAt"` // lần rollup gần nhất (job stats.rollup)
+}
+
+// GET /admin/stats?from=YYYY-MM-DD&to=YYYY-MM-DD (mặc định 30 ngày gần nhất, gồm hôm nay)
+func adminStatsHandler(c *gin.Context) {
+	now := time.Now()
+	out := AdminStats{From: now.AddDate(0, 0, -29).Format(statsDayLayout), To: now.Format(statsDayLayout)}
+	for _, p := range []struct {
+		name string
+		dst  *string
+	}{{"from", &out.From}, {"to", &out.To}} {
+		if raw := strings.TrimSpace(c.Query(p.name)); raw != "" {
+			if _, err := time.ParseInLocation(statsDayLayout, raw, time.Local); err != nil {
+				respondError(c, apiError(ERR_INVALID_INPUT, "field", p.name, "detail", "YYYY-MM-DD"))
+				return
+			}
+			*p.dst = raw
+		}
+	}
+	fromT, _ := statsDayRange(out.From)
+	toT, endT := statsDayRange(out.To)
+	if toT.Before(fromT) || endT.Sub(fromT) > statsMaxRangeDays*24*time.Hour {
+		respondError(c, apiError(ERR_INVALID_INPUT, "field", "to", "detail", fmt.Sprintf("from <= to, tối đa %d ngày", statsMaxRangeDays)))
+		return
+	}
+
+	// số liệu hiện tại
+	var live struct{ Coins, BonusCoins, FreeSpins, Users, VipUsers int64 }
+	if err := DB.Model(&User{}).
+		Select("COALESCE(SUM(coins),0) AS coins, COALESCE(SUM(bonus_coins),0) AS bonus_coins, COALESCE(SUM(free_spins),0) AS free_spins, "+
+			"COALESCE(SUM(role = 'user'),0) AS users, COALESCE(SUM(role = 'user' AND v_ip_level > 0),0) AS vip_users").
+		Where("id <> ?", systemUserID).Scan(&live).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	out.Circulation = StatsFlow{Coin: live.Coins, Bonus: live.BonusCoins}
+	out.FreeSpins, out.Users, out.VipUsers = live.FreeSpins, live.Users, live.VipUsers
+	out.VipPct = pct(live.VipUsers, live.Users)
+	if systemUserID != 0 {
+		var sys User
+		if err := DB.Select("coins, bonus_coins").First(&sys, systemUserID).Error; err == nil {
+			out.SystemBalance = StatsFlow{Coin: sys.Coins, Bonus: sys.BonusCoins}
+		}
+	}
+
+	var rows []StatsDaily
+	if err := DB.Where("day >= ? AND day <= ?", out.From, out.To).Order("day ASC").Find(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	byDay := map[string]*StatsDay{}
+	out.Totals = newStatsDay("")
+	drops := map[string]int64{}
+	for _, r := range rows {
+		d := byDay[r.Day]
+		if d == nil {
+			d = newStatsDay(r.Day)
+			byDay[r.Day] = d
+		}
+		d.apply(r)
+		// tổng kỳ: user hoạt động đếm user khác nhau, số dư lấy ngày cuối (bên dưới)
+		if r.Metric != STAT_ACTIVE_USERS && r.Metric != STAT_CIRCULATION {
+			out.Totals.apply(r)
+		}
+		if r.Metric == STAT_CHEST_DROP {
+			drops[r.Key] += r.Value
+		}
+		if out.RolledUpAt == nil || r.UpdatedAt.After(*out.RolledUpAt) {
+			t := r.UpdatedAt
+			out.RolledUpAt = &t
+		}
+	}
+	out.Days = []*StatsDay{}
+	for d := fromT; d.Before(endT); d = d.AddDate(0, 0, 1) {
+		day := d.Format(statsDayLayout)
+		sd := byDay[day]
+		if sd == nil {
+			sd = newStatsDay(day)
+		}
+		sd.finish()
+		out.Days = append(out.Days, sd)
+		out.Totals.Circulation = sd.Circulation
+	}
+	if err := DB.Model(&UserActiveDay{}).Where("day >= ? AND day <= ?", out.From, out.To).
+		Distinct("user_id").Count(&out.Totals.ActiveUsers).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	out.Totals.finish()
+
+	// tỉ lệ rơi thực tế so với bảng rơi
+	total, weights := out.Totals.ChestOpens, chestDropWeightTotal()
+	out.ChestDrops = []ChestDropStat{}
+	for _, d := range chestDropTable {
+		key := fmt.Sprintf("%s:%d", d.Code, d.Amount)
+		out.ChestDrops = append(out.ChestDrops, ChestDropStat{
+			Code: d.Code, Amount: d.Amount, Count: drops[key], Pct: pct(drops[key], total),
+			ExpectedPct: pct(int64(d.Weight), int64(weights)),
+		})
+		delete(drops, key)
+	}
+	// phần thưởng không còn trong bảng (đổi tỉ lệ sau này)
+	for _, key := range slices.Sorted(maps.Keys(drops)) {
+		code, amt, _ := strings.Cut(key, ":")
+		a, _ := strconv.ParseInt(amt, 10, 64)
+		out.ChestDrops = append(out.ChestDrops, ChestDropStat{Code: code, Amount: a, Count: drops[key], Pct: pct(drops[key], total)})
+	}
+
+	c.JSON(200, out)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/* ===== HẠN MỨC & PHÍ CHUYỂN COIN THEO CẤP VIP / KYC ===== */
+
+// Mỗi tier áp dụng cho user có VIP >= MinVIP; tier KYC chỉ áp dụng khi đã KYC (VERIFIED).
+// Chọn tier có MinVIP cao nhất, cùng MinVIP thì ưu tiên tier KYC. Hạn mức 0 = không giới hạn.
+type TransferTier struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	MinVIP              int       `gorm:"column:min_vip;not null;uniqueIndex:idx_transfer_tier" json:"minVip"`
+	KYC                 bool      `gorm:"column:kyc;not null;uniqueIndex:idx_transfer_tier" json:"kyc"`
+	PerTxMax            int64     `gorm:"not null;default:0" json:"perTxMax"`            // coin / lần
+	DailyMax            int64     `gorm:"not null;default:0" json:"dailyMax"`            // coin / ngày (theo giờ server)
+	MonthlyMax          int64     `gorm:"not null;default:0" json:"monthlyMax"`          // coin / tháng dương lịch
+	MinAccountAgeDays   int       `gorm:"not null;default:0" json:"minAccountAgeDays"`   // tài khoản tạo đủ N ngày mới được chuyển
+	NewRecipientsPerDay int       `gorm:"not null;default:0" json:"newRecipientsPerDay"` // người nhận chưa từng chuyển tới / ngày
+	FeeBps              int64     `gorm:"not null;default:100" json:"feeBps"`            // phí người gửi (basis points), làm tròn lên
+	FeeMin              int64     `gorm:"not null;default:0" json:"feeMin"`              // phí tối thiểu mỗi lần
+	UpdatedBy           *uint     `json:"updatedBy,omitempty"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+func (t TransferTier) label() string {
+	s := fmt.Sprintf("VIP %d+", t.MinVIP)
+	if t.KYC {
+		s += " KYC"
+	}
+	return s
+}
+
+func (t TransferTier) fee(amount int64) int64 {
+	fee := (amount*t.FeeBps + 9999) / 10000
+	return max(fee, t.FeeMin)
+}
+
+func seedTransferTiers() {
+	var cnt int64
+	DB.Model(&TransferTier{}).Count(&cnt)
+	if cnt > 0 {
+		return
+	}
+	tiers := []TransferTier{
+		{MinVIP: 0, KYC: false, PerTxMax: 1_000, DailyMax: 3_000, MonthlyMax: 30_000, MinAccountAgeDays: 1, NewRecipientsPerDay: 3, FeeBps: 100, FeeMin: 1},
+		{MinVIP: 0, KYC: true, PerTxMax: 5_000, DailyMax: 20_000, MonthlyMax: 200_000, NewRecipientsPerDay: 10, FeeBps: 100, FeeMin: 1},
+		{MinVIP: 1, KYC: false, PerTxMax: 5_000, DailyMax: 20_000, MonthlyMax: 200_000, NewRecipientsPerDay: 10, FeeBps: 100, FeeMin: 1},
+		{MinVIP: 1, KYC: true, PerTxMax: 50_000, DailyMax: 200_000, MonthlyMax: 2_000_000, NewRecipientsPerDay: 30, FeeBps: 50, FeeMin: 1},
+	}
+	DB.Create(&tiers)
+	fmt.Println("🌱 Seeded transfer_tiers")
+}
+
+func transferTierFor(db *gorm.DB, u User) (TransferTier, error) {
+	var t TransferTier
+	q := db.Where("min_vip <= ?", u.VIPLevel)
+	if u.KYCStatus != "VERIFIED" {
+		q = q.Where("kyc = ?", false)
+	}
+	if err := q.Order("min_vip DESC, kyc DESC").First(&t).Error; err != nil {
+		return t, apiError(ERR_TRANSFER_TIER_MISSING)
+	}
+	return t, nil
+}
+
+/* ----- mức đã dùng ----- */
+
+type transferAllowance struct {
+	Limit     int64     `json:"limit"`               // 0 = không giới hạn
+	Used      int64     `json:"used"`                //
+	Remaining *int64    `json:"remaining,omitempty"` // nil khi không giới hạn
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+func newAllowance(limit, used int64, reset time.Time) transferAllowance {
+	a := transferAllowance{Limit: limit, Used: used, ResetAt: reset}
+	if limit > 0 {
+		r := max(limit-used, 0)
+		a.Remaining = &r
+	}
+	return a
+}
+
+type TransferLimits struct {
+	Tier          TransferTier      `json:"tier"`
+	TierName      string            `json:"tierName"`
+	AllowedFrom   *time.Time        `json:"allowedFrom,omitempty"` // còn trong thời gian tài khoản mới
+	Daily         transferAllowance `json:"daily"`
+	Monthly       transferAllowance `json:"monthly"`
+	NewRecipients transferAllowance `json:"newRecipients"`
+	// số coin tối đa chuyển được ngay lần tới (min của hạn mức lần / ngày / tháng), nil = không giới hạn
+	MaxNextAmount *int64 `json:"maxNextAmount,omitempty"`
+}
+
+// hạn mức & mức đã dùng của u (tx đã khoá hàng người gửi thì số liệu không đổi tới khi commit)
+func loadTransferLimits(tx *gorm.DB, u User, now time.Time) (TransferLimits, error) {
+	t, err := transferTierFor(tx, u)
+	if err != nil {
+		return TransferLimits{}, err
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	var used struct{ Day, Month int64 }
+	if err := tx.Model(&TransferTxn{}).
+		Select("COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS day, COALESCE(SUM(amount), 0) AS month", day).
+		Where("from_id = ? AND created_at >= ?", u.ID, month).Scan(&used).Error; err != nil {
+		return TransferLimits{}, err
+	}
+	var newToday int64
+	if err := tx.Table("transfer_txns t").
+		Where("t.from_id = ? AND t.created_at >= ?", u.ID, day).
+		Where("NOT EXISTS (SELECT 1 FROM transfer_txns p WHERE p.from_id = t.from_id AND p.to_id = t.to_id AND p.created_at < ?)", day).
+		Distinct("t.to_id").Count(&newToday).Error; err != nil {
+		return TransferLimits{}, err
+	}
+
+	l := TransferLimits{
+		Tier: t, TierName: t.label(),
+		Daily:         newAllowance(t.DailyMax, used.Day, day.AddDate(0, 0, 1)),
+		Monthly:       newAllowance(t.MonthlyMax, used.Month, month.AddDate(0, 1, 0)),
+		NewRecipients: newAllowance(int64(t.NewRecipientsPerDay), newToday, day.AddDate(0, 0, 1)),
+	}
+	if from := u.CreatedAt.AddDate(0, 0, t.MinAccountAgeDays); now.Before(from) {
+		l.AllowedFrom = &from
+	}
+	for _, r := range []*int64{l.Daily.Remaining, l.Monthly.Remaining} {
+		if r != nil && (l.MaxNextAmount == nil || *r < *l.MaxNextAmount) {
+			l.MaxNextAmount = r
+		}
+	}
+	if t.PerTxMax > 0 && (l.MaxNextAmount == nil || t.PerTxMax < *l.MaxNextAmount) {
+		l.MaxNextAmount = &t.PerTxMax
+	}
+	return l, nil
+}
+
+// kiểm tra hạn mức cho lần chuyển amount tới toID; lỗi kèm hạn mức & phần còn lại
+func checkTransferLimits(tx *gorm.DB, from User, toID uint, amount int64) (TransferLimits, error) {
+	now := time.Now()
+	l, err := loadTransferLimits(tx, from, now)
+	if err != nil {
+		return l, err
+	}
+	t := l.Tier
+	if l.AllowedFrom != nil {
+		return l, apiError(ERR_TRANSFER_ACCOUNT_TOO_NEW, "tier", l.TierName, "days", t.MinAccountAgeDays, "until", l.AllowedFrom.Format(exportTimeLayout))
+	}
+	if t.PerTxMax > 0 && amount > t.PerTxMax {
+		return l, apiError(ERR_TRANSFER_OVER_TX_LIMIT, "tier", l.TierName, "limit", t.PerTxMax)
+	}
+	for _, c := range []struct {
+		code string
+		a    transferAllowance
+	}{{ERR_TRANSFER_DAILY_LIMIT, l.Daily}, {ERR_TRANSFER_MONTHLY_LIMIT, l.Monthly}} {
+		if c.a.Remaining != nil && amount > *c.a.Remaining {
+			return l, apiError(c.code, "tier", l.TierName, "limit", c.a.Limit, "used", c.a.Used,
+				"remaining", *c.a.Remaining, "resetAt", c.a.ResetAt.Format(exportTimeLayout))
+		}
+	}
+	if r := l.NewRecipients.Remaining; r != nil && *r == 0 {
+		var known int64
+		if err := tx.Model(&TransferTxn{}).Where("from_id = ? AND to_id = ?", from.ID, toID).Limit(1).Count(&known).Error; err != nil {
+			return l, err
+		}
+		if known == 0 {
+			return l, apiError(ERR_TRANSFER_NEW_RECIPIENTS, "tier", l.TierName, "limit", l.NewRecipients.Limit,
+				"resetAt", l.NewRecipients.ResetAt.Format(exportTimeLayout))
+		}
+	}
+	return l, nil
+}
+
+// GET /private/transfer/limits — tier hiện tại, phí, hạn mức & phần còn lại
+func myTransferLimitsHandler(c *gin.Context) {
+	uid := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var u User
+	if err := DB.Select("id, v_ip_level, kyc_status, created_at").First(&u, uid).Error; err != nil {
+		respondError(c, apiError(ERR_USER_NOT_FOUND))
+		return
+	}
+	l, err := loadTransferLimits(DB, u, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, l)
+}
+
+/* ----- admin ----- */
+
+// GET /admin/transfer-tiers
+func adminListTransferTiersHandler(c *gin.Context) {
+	var rows []TransferTier
+	if err := DB.Order("min_vip ASC, kyc ASC").Find(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"rows": rows})
+}
+
+type TransferTierRequest struct {
+	MinVIP              int   `json:"minVip" binding:"min=0"`
+	KYC                 bool  `json:"kyc"`
+	PerTxMax            int64 `json:"perTxMax" binding:"min=0"`
+	DailyMax            int64 `json:"dailyMax" binding:"min=0"`
+	MonthlyMax          int64 `json:"monthlyMax" binding:"min=0"`
+	MinAccountAgeDays   int   `json:"minAccountAgeDays" binding:"min=0,max=3650"`
+	NewRecipientsPerDay int   `json:"newRecipientsPerDay" binding:"min=0"`
+	FeeBps              int64 `json:"feeBps" binding:"min=0,max=10000"`
+	FeeMin              int64 `json:"feeMin" binding:"min=0"`
+}
+
+// PUT /admin/transfer-tiers — tạo / sửa tier theo (minVip, kyc)
+func adminUpsertTransferTierHandler(c *gin.Context) {
+	adminID := uint(c.MustGet("claims").(jwt.MapClaims)["sub"].(float64))
+	var req TransferTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidInput(err))
+		return
+	}
+	t := TransferTier{
+		MinVIP: req.MinVIP, KYC: req.KYC,
+		PerTxMax: req.PerTxMax, DailyMax: req.DailyMax, MonthlyMax: req.MonthlyMax,
+		MinAccountAgeDays: req.MinAccountAgeDays, NewRecipientsPerDay: req.NewRecipientsPerDay,
+		FeeBps: req.FeeBps, FeeMin: req.FeeMin, UpdatedBy: &adminID,
+	}
+	if err := DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "min_vip"}, {Name: "kyc"}},
+		DoUpdates: clause.AssignmentColumns([]string{"per_tx_max", "daily_max", "monthly_max", "min_account_age_days",
+			"new_recipients_per_day", "fee_bps", "fee_min", "updated_by", "updated_at"}),
+	}).Create(&t).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	DB.Where("min_vip = ? AND kyc = ?", t.MinVIP, t.KYC).First(&t)
+	c.JSON(200, gin.H{"message": "Đã lưu hạn mức " + t.label(), "tier": t})
+}
+
+// DELETE /admin/transfer-tiers/:id — không xoá được tier gốc (VIP 0, chưa KYC)
+func adminDeleteTransferTierHandler(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var t TransferTier
+	if err := DB.First(&t, id).Error; err != nil {
+		respondError(c, apiError(ERR_TRANSFER_TIER_NOT_FOUND))
+		return
+	}
+	if t.MinVIP == 0 && !t.KYC {
+		respondError(c, apiError(ERR_TRANSFER_TIER_BASE))
+		return
+	}
+	if err := DB.Delete(&t).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "Đã xoá hạn mức " + t.label()})
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestTransferTierFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		tier   TransferTier
+		amount int64
+		want   int64
+	}{
+		{"phí tối thiểu", TransferTier{FeeBps: 100, FeeMin: 1}, 1, 1},
+		{"đúng 1%", TransferTier{FeeBps: 100, FeeMin: 1}, 1_000, 10},
+		{"làm tròn lên", TransferTier{FeeBps: 100, FeeMin: 1}, 101, 2},
+		{"làm tròn lên nửa phần trăm", TransferTier{FeeBps: 50}, 199, 1},
+		{"nửa phần trăm", TransferTier{FeeBps: 50}, 200, 1},
+		{"nửa phần trăm lẻ", TransferTier{FeeBps: 50}, 201, 2},
+		{"chỉ phí tối thiểu", TransferTier{FeeMin: 5}, 1_000, 5},
+		{"miễn phí", TransferTier{}, 1_000, 0},
+		{"phí theo % vượt tối thiểu", TransferTier{FeeBps: 100, FeeMin: 5}, 10_000, 100},
+	}
+	for _, tt := range tests {
+		if got := tt.tier.fee(tt.amount); got != tt.want {
+			t.Errorf("%s: fee(%d) = %d, want %d", tt.name, tt.amount, got, tt.want)
+		}
+	}
+}
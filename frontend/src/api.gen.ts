@@ -0,0 +1,1655 @@
+// Code generated by `go run . gen-client` (backend/openapi_ts.go). DO NOT EDIT.
+// Nguồn: apiRoutes trong backend/openapi_routes.go và các DTO của handler.
+
+export type AccountCloseRequest = {
+  id: number;
+  userId: number;
+  reason: string;
+  payoutInfo: string;
+  coins: number;
+  bonusCoins: number;
+  status: string;
+  adminId?: number;
+  adminNote?: string;
+  withdrawId?: number;
+  createdAt: string;
+  decidedAt?: string;
+};
+
+export type AccountClosure = {
+  id: number;
+  userId: number;
+  initiator: string;
+  closedBy: number;
+  reason: string;
+  downlinePolicy: string;
+  reparentedTo?: number;
+  downlines: number;
+  coins: number;
+  bonusCoins: number;
+  closedAt: string;
+  purgedAt?: string;
+  purgedBy?: number;
+};
+
+export type AccountRestrictions = {
+  status: string;
+  statusReason?: string;
+  statusUntil?: string;
+  restrictions: UserRestriction[];
+};
+
+export type AccountStatusRequest = {
+  status: string;
+  reason?: string;
+  until?: string | null;
+};
+
+export type AdminListingRow = {
+  id: number;
+  sellerId: number;
+  sellerUsername: string;
+  code: string;
+  qty: number;
+  pricePerUnit: number;
+  isActive: boolean;
+  expiresAt: string | null;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type AdminShopPurchaseRow = {
+  id: number;
+  userId: number;
+  itemId: number;
+  qty: number;
+  costCode: string;
+  costQty: number;
+  rewardKind: string;
+  rewardCode: string;
+  rewardAmount: number;
+  createdAt: string;
+  itemName: string;
+  username: string;
+};
+
+export type AdminStats = {
+  from: string;
+  to: string;
+  circulation: StatsFlow;
+  freeSpins: number;
+  systemBalance: StatsFlow;
+  users: number;
+  vipUsers: number;
+  vipPct: number;
+  days: (StatsDay | null)[];
+  totals: StatsDay | null;
+  chestDrops: ChestDropStat[];
+  rolledUpAt: string | null;
+};
+
+export type AdminUserDetail = {
+  id: number;
+  username: string;
+  name: string;
+  phone: string;
+  avatarUrl: string;
+  role: string;
+  coins: number;
+  totalTopup: number;
+  vipLevel: number;
+  kycStatus: string;
+  kycFullName: string;
+  kycNumber: string;
+  kycDob: string;
+  hasKycFront: boolean;
+  hasKycBack: boolean;
+  status: string;
+  statusReason?: string;
+  closedAt?: string;
+};
+
+export type AdminUserRow = {
+  id: number;
+  username: string;
+  nickname: string;
+  vipLevel: number;
+  totalTopup: number;
+  coins: number;
+};
+
+export type AuthResponse = {
+  token: string;
+  user: User;
+};
+
+export type BalanceCheckpoint = {
+  userId: number;
+  ledgerId: number;
+  coins: number;
+  bonusCoins: number;
+  openingCoins: number;
+  openingBonus: number;
+  checkedAt: string;
+};
+
+export type BalanceDrift = {
+  id: number;
+  userId: number;
+  currency: string;
+  expected: number;
+  actual: number;
+  drift: number;
+  fromLedgerId: number;
+  toLedgerId: number;
+  status: string;
+  resolution?: string;
+  note?: string;
+  frozen: boolean;
+  resolvedBy?: number;
+  detectedAt: string;
+  lastSeenAt: string;
+  resolvedAt?: string;
+};
+
+export type BalanceDriftRow = {
+  id: number;
+  userId: number;
+  currency: string;
+  expected: number;
+  actual: number;
+  drift: number;
+  fromLedgerId: number;
+  toLedgerId: number;
+  status: string;
+  resolution?: string;
+  note?: string;
+  frozen: boolean;
+  resolvedBy?: number;
+  detectedAt: string;
+  lastSeenAt: string;
+  resolvedAt?: string;
+  username: string;
+};
+
+export type Broadcast = {
+  id: number;
+  title: string;
+  body: string;
+  status: string;
+  scheduledAt: string;
+  total: number;
+  sent: number;
+  error?: string;
+  createdBy: number;
+  startedAt: string | null;
+  finishedAt: string | null;
+  createdAt: string;
+};
+
+export type BroadcastRequest = {
+  title?: string;
+  body?: string;
+  segment?: BroadcastSegment;
+  scheduledAt?: string | null;
+  dryRun?: boolean;
+};
+
+export type BroadcastSegment = {
+  vipLevels?: number[];
+  kycStatus?: string;
+  registeredFrom?: string;
+  registeredTo?: string;
+  downlineOf?: number;
+  downlineDepth?: number;
+};
+
+export type BroadcastView = {
+  id: number;
+  title: string;
+  body: string;
+  status: string;
+  scheduledAt: string;
+  total: number;
+  sent: number;
+  error?: string;
+  createdBy: number;
+  startedAt: string | null;
+  finishedAt: string | null;
+  createdAt: string;
+  segment: BroadcastSegment;
+  progress: number;
+};
+
+export type CancelListingRequest = {
+  reason?: string;
+};
+
+export type Candle = {
+  time: string;
+  open: number;
+  high: number;
+  low: number;
+  close: number;
+  volume: number;
+  value: number;
+};
+
+export type ChangePasswordRequest = {
+  oldPassword: string;
+  newPassword: string;
+};
+
+export type CheckinCalendarRequest = {
+  days: RewardBundle[];
+};
+
+export type CheckinDay = {
+  day: number;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+};
+
+export type CheckinLog = {
+  id: number;
+  day: string;
+  streak: number;
+  calendarDay: number;
+  multiplierPct: number;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+  createdAt: string;
+};
+
+export type CheckinStatus = {
+  today: string;
+  timezone: string;
+  checkedInToday: boolean;
+  streak: number;
+  bestStreak: number;
+  nextDay: number;
+  graceDays: number;
+  multiplierPct: number;
+  calendar: CheckinDay[];
+};
+
+export type CheckinTimezoneRequest = {
+  timezone: string;
+};
+
+export type ChestDropStat = {
+  code: string;
+  amount: number;
+  count: number;
+  pct: number;
+  expectedPct: number;
+};
+
+export type ChestOpenResult = {
+  result: string;
+  code?: string;
+  amount: number;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  inv: Record<string, number>;
+  used_free_spin?: boolean;
+  remaining_free_spins?: number;
+  milestoneRewarded?: boolean;
+  milestoneRewardCoins?: number;
+  chest_opens: number;
+  remaining_until_bonus: number;
+};
+
+export type CloseAccountRequest = {
+  reason: string;
+  downlinePolicy?: 'keep' | 'reparent';
+};
+
+export type CloseRequestDecision = {
+  note?: string;
+};
+
+export type CommissionRow = {
+  id: number;
+  buyerUsername: string;
+  depth: number;
+  percent: number;
+  amount: number;
+  kind: string;
+  vipLevel: number;
+  createdAt: string;
+};
+
+export type DailyCommission = {
+  day: number;
+  amount: number;
+};
+
+export type DashboardOverview = {
+  totalAssets: number;
+  f1Count: number;
+  f1CommissionTotal: number;
+  systemCount: number;
+  systemCommissionTotal: number;
+  vipLevel: number;
+};
+
+export type DataExportView = {
+  id: number;
+  status: string;
+  size: number;
+  error?: string;
+  createdAt: string;
+  startedAt?: string;
+  finishedAt?: string;
+  expiresAt?: string;
+  url?: string;
+};
+
+export type DayEarning = {
+  day: number;
+  amount: number;
+};
+
+export type DomainEventDef = {
+  type: string;
+  label: string;
+};
+
+export type DownlineDashboardResp = {
+  userId: number;
+  username: string;
+  overview: DashboardOverview;
+  month: string;
+  earnings: DayEarning[];
+};
+
+export type DownlineRow = {
+  id: number;
+  username: string;
+  depth: number;
+  vipLevel: number;
+  createdAt: string;
+};
+
+export type EditListingRequest = {
+  pricePerUnit?: number | null;
+  qty?: number | null;
+};
+
+export type Error = {
+  error: string;
+  code: 'ACCOUNT_ALREADY_CLOSED' | 'ACCOUNT_BANNED' | 'ACCOUNT_CLOSED' | 'ACCOUNT_FROZEN' | 'ACCOUNT_NOT_CLOSED' | 'ACCOUNT_RESTRICTED' | 'ADMIN_ONLY' | 'BID_CLOSED' | 'BID_NOT_FOUND' | 'BID_NOT_OWNER' | 'BROADCAST_FINISHED' | 'BROADCAST_NOT_FOUND' | 'CATEGORY_INVALID' | 'CATEGORY_NOT_MUTABLE' | 'CHECKIN_CALENDAR_EMPTY' | 'CHECKIN_CALENDAR_INVALID' | 'CHECKIN_TIMEZONE_INVALID' | 'CHECKIN_TIMEZONE_LOCKED' | 'CLOSE_BALANCE_NOT_ZERO' | 'CLOSE_REQUEST_DECIDED' | 'CLOSE_REQUEST_NOT_FOUND' | 'CLOSE_REQUEST_PENDING' | 'CONFLICT' | 'CONTENT_REQUIRED' | 'CONTENT_TOO_LONG' | 'DATA_EXPORT_NOT_FOUND' | 'DATA_EXPORT_PENDING' | 'DATA_EXPORT_TOO_SOON' | 'DRAGON_BALL_MISSING' | 'DRIFT_NOT_FOUND' | 'DRIFT_RESOLVED' | 'FILE_REQUIRED' | 'FORBIDDEN' | 'INSUFFICIENT_BALANCE' | 'INSUFFICIENT_ITEMS' | 'INTERNAL' | 'INTERVAL_INVALID' | 'INVALID_ID' | 'INVALID_INPUT' | 'ITEM_CODE_INVALID' | 'JOB_NOT_FOUND' | 'JOB_RUNNING' | 'JOB_SCHEDULE_INVALID' | 'KYC_FIELDS_REQUIRED' | 'KYC_FILES_REQUIRED' | 'KYC_IMAGE_NOT_FOUND' | 'LISTING_EXPIRED' | 'LISTING_INACTIVE' | 'LISTING_NOT_FOUND' | 'LISTING_NOT_OWNER' | 'LISTING_QTY_INSUFFICIENT' | 'LOCALE_UNSUPPORTED' | 'LOGIN_FAILED' | 'MISSION_CLAIMED' | 'MISSION_INVALID' | 'MISSION_NOT_COMPLETED' | 'MISSION_NOT_FOUND' | 'NOTIFICATION_NOT_FOUND' | 'NOT_FOUND' | 'NO_RECIPIENTS' | 'ORDER_MODE_INVALID' | 'ORDER_NOT_FILLED' | 'ORDER_NO_LIQUIDITY' | 'PASSWORD_INCORRECT' | 'PASSWORD_REQUIRED' | 'PIN_FORMAT' | 'PIN_INVALID' | 'PIN_NOT_SET' | 'PROMO_ACCOUNT_TOO_NEW' | 'PROMO_ALREADY_USED' | 'PROMO_CAMPAIGN_NOT_FOUND' | 'PROMO_CODE_REQUIRED' | 'PROMO_CODE_TAKEN' | 'PROMO_EXHAUSTED' | 'PROMO_EXPIRED' | 'PROMO_GENERATION_FAILED' | 'PROMO_INVALID' | 'PROMO_KYC_REQUIRED' | 'PROMO_MAX_USES_INVALID' | 'PROMO_NEW_USERS_ONLY' | 'PROMO_NOT_FOUND' | 'PROMO_NOT_STARTED' | 'PROMO_USER_LIMIT' | 'PROMO_VIP_REQUIRED' | 'PURGE_TOO_EARLY' | 'RECIPIENT_NOT_FOUND' | 'RECIPIENT_UNAVAILABLE' | 'RESTRICTION_NOT_FOUND' | 'SEASON_CLOSED' | 'SEASON_INVALID' | 'SEASON_NOT_FOUND' | 'SEASON_STARTED' | 'SECOND_PASSWORD_INVALID' | 'SECOND_PASSWORD_NOT_SET' | 'SECOND_PASSWORD_TOO_SHORT' | 'SEGMENT_INVALID' | 'SELF_TRADE' | 'SETTING_KEY_UNKNOWN' | 'SETTING_VALUE_INVALID' | 'SHOP_COST_CODE_INVALID' | 'SHOP_DISCOUNT_TOO_HIGH' | 'SHOP_ENDED' | 'SHOP_ITEM_INACTIVE' | 'SHOP_ITEM_NOT_FOUND' | 'SHOP_LIMIT_INVALID' | 'SHOP_LIMIT_REACHED' | 'SHOP_NOT_STARTED' | 'SHOP_OUT_OF_STOCK' | 'SHOP_REWARD_INVALID' | 'SHOP_STOCK_BELOW_SOLD' | 'SHOP_STOCK_INVALID' | 'SHOP_TIME_RANGE_INVALID' | 'SUPERADMIN_ONLY' | 'TEMPLATE_NOT_OVERRIDDEN' | 'TEMPLATE_TYPE_UNKNOWN' | 'TOKEN_INVALID' | 'TRANSFER_ACCOUNT_TOO_NEW' | 'TRANSFER_DAILY_LIMIT' | 'TRANSFER_MONTHLY_LIMIT' | 'TRANSFER_NEW_RECIPIENTS' | 'TRANSFER_OVER_TX_LIMIT' | 'TRANSFER_SELF' | 'TRANSFER_TIER_BASE' | 'TRANSFER_TIER_MISSING' | 'TRANSFER_TIER_NOT_FOUND' | 'UNAUTHORIZED' | 'USERNAME_REQUIRED' | 'USERNAME_TAKEN' | 'USER_NOT_FOUND' | 'VIP_ALREADY';
+  params?: Record<string, unknown>;
+};
+
+export type ForgotPasswordReq = {
+  username: string;
+  secPassword: string;
+  newPassword: string;
+};
+
+export type InventoryItem = {
+  code: string;
+  qty: number;
+};
+
+export type Job = {
+  name: string;
+  schedule: string;
+  isPaused: boolean;
+  nextRunAt: string | null;
+  runRequested: boolean;
+  lockedBy: string;
+  lockedUntil: string | null;
+  lastRunAt: string | null;
+  lastStatus: string;
+  updatedAt: string;
+};
+
+export type JobRun = {
+  id: number;
+  job: string;
+  trigger: string;
+  triggeredBy: number | null;
+  instance: string;
+  status: string;
+  error: string;
+  startedAt: string;
+  finishedAt: string | null;
+  durationMs: number;
+};
+
+export type JobScheduleRequest = {
+  schedule: string;
+};
+
+export type JobView = {
+  name: string;
+  schedule: string;
+  isPaused: boolean;
+  nextRunAt: string | null;
+  runRequested: boolean;
+  lockedBy: string;
+  lockedUntil: string | null;
+  lastRunAt: string | null;
+  lastStatus: string;
+  updatedAt: string;
+  description: string;
+  running: boolean;
+};
+
+export type KycUpdateRequest = {
+  nickname?: string;
+  idNumber?: string;
+};
+
+export type LeaderboardRow = {
+  rank: number;
+  username: string;
+  score: number;
+};
+
+export type LedgerEntry = {
+  id: number;
+  userId: number;
+  currency: string;
+  type: string;
+  refId: number;
+  amount: number;
+  createdAt: string;
+};
+
+export type LoginRequest = {
+  username: string;
+  password: string;
+};
+
+export type MarkReadRequest = {
+  ids?: number[];
+};
+
+export type MarketBid = {
+  id: number;
+  buyerId: number;
+  code: string;
+  qty: number;
+  filledQty: number;
+  maxPrice: number;
+  escrow: number;
+  status: string;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type MarketBuyRequest = {
+  listingId?: number;
+  qty?: number;
+};
+
+export type MarketFill = {
+  listingId: number;
+  bidId?: number;
+  sellerId: number;
+  buyerId: number;
+  code: string;
+  qty: number;
+  pricePerUnit: number;
+  fee: number;
+  kind: string;
+  tradeId: number;
+};
+
+export type MarketListRequest = {
+  code?: string;
+  qty: number;
+  pricePerUnit: number;
+  ttlHours?: number;
+};
+
+export type MarketListing = {
+  ID: number;
+  SellerID: number;
+  Code: string;
+  Qty: number;
+  PricePerUnit: number;
+  IsActive: boolean;
+  ExpiresAt: string | null;
+  CreatedAt: string;
+  UpdatedAt: string;
+  Seller: User;
+};
+
+export type MarketOrderRequest = {
+  code?: string;
+  qty: number;
+  maxTotal?: number;
+  mode?: string;
+};
+
+export type MarketRow = {
+  id: number;
+  code: string;
+  qty: number;
+  pricePerUnit: number;
+  sellerId: number;
+  sellerUsername: string;
+  expiresAt: string | null;
+};
+
+export type MarketWithdrawRequest = {
+  listingId?: number;
+  qty?: number | null;
+};
+
+export type Mission = {
+  id: number;
+  title: string;
+  description: string;
+  event: string;
+  target: number;
+  period: string;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+  sortOrder: number;
+  isActive: boolean;
+  startsAt: string | null;
+  endsAt: string | null;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type MissionRequest = {
+  title: string;
+  description?: string;
+  event: string;
+  target: number;
+  period: string;
+  coins?: number;
+  bonusCoins?: number;
+  freeSpins?: number;
+  itemCode?: string;
+  itemQty?: number;
+  sortOrder?: number;
+  isActive?: boolean | null;
+  startsAt?: string | null;
+  endsAt?: string | null;
+};
+
+export type MissionView = {
+  id: number;
+  title: string;
+  description: string;
+  event: string;
+  target: number;
+  period: string;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+  sortOrder: number;
+  isActive: boolean;
+  startsAt: string | null;
+  endsAt: string | null;
+  createdAt: string;
+  updatedAt: string;
+  periodKey: string;
+  progress: number;
+  completed: boolean;
+  claimed: boolean;
+  resetsAt: string | null;
+};
+
+export type MonthlyCommissionResp = {
+  year: number;
+  month: number;
+  days: DailyCommission[];
+  monthTotal: number;
+};
+
+export type MyRankResp = {
+  rank: number;
+  username: string;
+  score: number;
+};
+
+export type MyTradeRow = {
+  id: number;
+  side: string;
+  code: string;
+  qty: number;
+  pricePerUnit: number;
+  total: number;
+  fee: number;
+  net: number;
+  kind: string;
+  counterpart: string;
+  createdAt: string;
+};
+
+export type Notification = {
+  id: number;
+  userId: number;
+  type: string;
+  category: string;
+  title: string;
+  body: string;
+  isRead: boolean;
+  archivedAt?: string;
+  createdAt: string;
+};
+
+export type NotificationPrefRequest = {
+  locale?: string | null;
+  muted?: string[] | null;
+};
+
+export type NotificationTemplate = {
+  id: number;
+  type: string;
+  locale: string;
+  title: string;
+  body: string;
+  updatedBy: number;
+  updatedAt: string;
+};
+
+export type NotificationTemplateRequest = {
+  type?: string;
+  locale?: string;
+  title?: string;
+  body?: string;
+};
+
+export type NotificationTemplateRow = {
+  type: string;
+  category: string;
+  defaults: Record<string, NotificationText>;
+  overrides: NotificationTemplate[];
+};
+
+export type NotificationText = {
+  title: string;
+  body: string;
+};
+
+export type OrderBookLevel = {
+  price: number;
+  qty: number;
+  orders: number;
+};
+
+export type PlaceBidRequest = {
+  code?: string;
+  qty: number;
+  maxPrice: number;
+};
+
+export type ProfileUpdateRequest = {
+  name: string;
+  phone: string;
+  avatarUrl?: string;
+};
+
+export type PromoCampaign = {
+  id: number;
+  name: string;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+  minVipLevel: number;
+  requireKyc: boolean;
+  minAccountAgeHours: number;
+  newUsersWithinHours: number;
+  perUserLimit: number;
+  maxRedemptions: number | null;
+  usedCount: number;
+  startsAt: string | null;
+  expiresAt: string | null;
+  isActive: boolean;
+  archivedAt: string | null;
+  legacySource?: string;
+  createdBy: number | null;
+  createdAt: string;
+};
+
+export type PromoCampaignCode = {
+  id: number;
+  campaignId: number;
+  code: string;
+  maxUses: number | null;
+  usedCount: number;
+  isActive: boolean;
+  createdAt: string;
+};
+
+export type PromoCampaignRequest = {
+  name: string;
+  coins?: number;
+  bonusCoins?: number;
+  freeSpins?: number;
+  itemCode?: string;
+  itemQty?: number;
+  minVipLevel?: number;
+  requireKyc?: boolean;
+  minAccountAgeHours?: number;
+  newUsersWithinHours?: number;
+  perUserLimit?: number | null;
+  maxRedemptions?: number | null;
+  startsAt?: string | null;
+  expiresAt?: string | null;
+  codes?: PromoCodesRequest;
+};
+
+export type PromoCampaignRow = {
+  id: number;
+  name: string;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+  minVipLevel: number;
+  requireKyc: boolean;
+  minAccountAgeHours: number;
+  newUsersWithinHours: number;
+  perUserLimit: number;
+  maxRedemptions: number | null;
+  usedCount: number;
+  startsAt: string | null;
+  expiresAt: string | null;
+  isActive: boolean;
+  archivedAt: string | null;
+  legacySource?: string;
+  createdBy: number | null;
+  createdAt: string;
+  codeCount: number;
+  status: string;
+};
+
+export type PromoCodeRow = {
+  id: number;
+  campaignId: number;
+  campaign: string;
+  code: string;
+  maxUses: number | null;
+  usedCount: number;
+  isActive: boolean;
+  status: string;
+  expiresAt: string | null;
+  createdAt: string;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+};
+
+export type PromoCodesRequest = {
+  count?: number;
+  code?: string;
+  maxUses?: number | null;
+};
+
+export type PromoRedemptionRow = {
+  id: number;
+  campaignId: number;
+  codeId: number;
+  code: string;
+  userId: number;
+  username: string;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+  createdAt: string;
+};
+
+export type ReconSourceRow = {
+  source: string;
+  refId: number;
+  expected: number;
+  recorded: number;
+  createdAt: string;
+};
+
+export type RedeemReq = {
+  code: string;
+};
+
+export type RegisterRequest = {
+  username?: string;
+  password?: string;
+  ref?: string;
+};
+
+export type ResolveDriftRequest = {
+  action: string;
+  note?: string;
+  unfreeze?: boolean;
+};
+
+export type RestrictionRequest = {
+  kind: string;
+  reason: string;
+  expiresAt?: string | null;
+};
+
+export type RewardBundle = {
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+};
+
+export type Season = {
+  id: number;
+  name: string;
+  metric: string;
+  startsAt: string;
+  endsAt: string;
+  status: string;
+  prizes: SeasonPrize[];
+  createdBy: number;
+  closedAt: string | null;
+  createdAt: string;
+};
+
+export type SeasonPrize = {
+  rankFrom: number;
+  rankTo: number;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+};
+
+export type SeasonRequest = {
+  name: string;
+  metric: string;
+  startsAt: string;
+  endsAt: string;
+  prizes?: SeasonPrize[];
+};
+
+export type SeasonStanding = {
+  username: string;
+  rank: number;
+  score: number;
+  coins: number;
+  bonusCoins: number;
+  freeSpins: number;
+  itemCode?: string;
+  itemQty: number;
+};
+
+export type SelfCloseAccountRequest = {
+  txnPin: string;
+  secondPassword: string;
+  reason?: string;
+  payout?: boolean;
+  payoutInfo?: string;
+};
+
+export type SettingUpdateRequest = {
+  key: string;
+  value?: string;
+};
+
+export type ShopBuyRequest = {
+  itemId: number;
+  qty?: number;
+};
+
+export type ShopItem = {
+  id: number;
+  name: string;
+  description: string;
+  costCode: string;
+  costQty: number;
+  rewardKind: string;
+  rewardCode: string;
+  rewardAmount: number;
+  stock: number | null;
+  soldCount: number;
+  perUserLimit: number | null;
+  startsAt: string | null;
+  endsAt: string | null;
+  isActive: boolean;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type ShopItemRequest = {
+  name: string;
+  description?: string;
+  costCode?: string;
+  costQty: number;
+  rewardKind: string;
+  rewardCode?: string;
+  rewardAmount: number;
+  stock?: number | null;
+  perUserLimit?: number | null;
+  startsAt?: string | null;
+  endsAt?: string | null;
+  isActive?: boolean | null;
+};
+
+export type ShopItemView = {
+  id: number;
+  name: string;
+  description: string;
+  costCode: string;
+  costQty: number;
+  rewardKind: string;
+  rewardCode: string;
+  rewardAmount: number;
+  stock: number | null;
+  soldCount: number;
+  perUserLimit: number | null;
+  startsAt: string | null;
+  endsAt: string | null;
+  isActive: boolean;
+  createdAt: string;
+  updatedAt: string;
+  remaining: number | null;
+  myBought: number;
+};
+
+export type ShopPurchase = {
+  id: number;
+  userId: number;
+  itemId: number;
+  qty: number;
+  costCode: string;
+  costQty: number;
+  rewardKind: string;
+  rewardCode: string;
+  rewardAmount: number;
+  createdAt: string;
+};
+
+export type ShopPurchaseRow = {
+  id: number;
+  userId: number;
+  itemId: number;
+  qty: number;
+  costCode: string;
+  costQty: number;
+  rewardKind: string;
+  rewardCode: string;
+  rewardAmount: number;
+  createdAt: string;
+  itemName: string;
+};
+
+export type StatementBalances = {
+  coins: number;
+  bonusCoins: number;
+};
+
+export type StatementRow = {
+  id: number;
+  currency: string;
+  type: string;
+  refId: number;
+  amount: number;
+  balance: number;
+  createdAt: string;
+};
+
+export type StatsDay = {
+  day: string;
+  faucets: Record<string, StatsFlow>;
+  sinks: Record<string, StatsFlow>;
+  faucetTotal: StatsFlow;
+  sinkTotal: StatsFlow;
+  net: StatsFlow;
+  circulation: StatsFlow;
+  activeUsers: number;
+  newUsers: number;
+  chestOpens: number;
+  chestPaidOpens: number;
+  vipPurchases: number;
+  vipFirstPurchases: number;
+  vipConversionPct: number;
+  marketTrades: number;
+  marketQty: number;
+  marketVolume: number;
+  marketFee: number;
+};
+
+export type StatsFlow = {
+  coin: number;
+  bonus: number;
+};
+
+export type TopupRequest = {
+  userId: number;
+  amount: number;
+  note?: string;
+};
+
+export type TopupRow = {
+  id: number;
+  amount: number;
+  note: string;
+  adminUsername: string;
+  createdAt: string;
+};
+
+export type TransferAllowance = {
+  limit: number;
+  used: number;
+  remaining?: number;
+  resetAt: string;
+};
+
+export type TransferLimits = {
+  tier: TransferTier;
+  tierName: string;
+  allowedFrom?: string;
+  daily: TransferAllowance;
+  monthly: TransferAllowance;
+  newRecipients: TransferAllowance;
+  maxNextAmount?: number;
+};
+
+export type TransferRequest = {
+  toUsername: string;
+  amount: number;
+  note?: string;
+  txnPin: string;
+  secondPassword?: string;
+};
+
+export type TransferRow = {
+  id: number;
+  direction: string;
+  amount: number;
+  fee: number;
+  counterpart: string;
+  note: string;
+  createdAt: string;
+};
+
+export type TransferTier = {
+  id: number;
+  minVip: number;
+  kyc: boolean;
+  perTxMax: number;
+  dailyMax: number;
+  monthlyMax: number;
+  minAccountAgeDays: number;
+  newRecipientsPerDay: number;
+  feeBps: number;
+  feeMin: number;
+  updatedBy?: number;
+  updatedAt: string;
+};
+
+export type TransferTierRequest = {
+  minVip?: number;
+  kyc?: boolean;
+  perTxMax?: number;
+  dailyMax?: number;
+  monthlyMax?: number;
+  minAccountAgeDays?: number;
+  newRecipientsPerDay?: number;
+  feeBps?: number;
+  feeMin?: number;
+};
+
+export type UpdateSecurityRequest = {
+  oldSecondPassword?: string;
+  newSecondPassword?: string;
+  newTxnPin?: string;
+};
+
+export type User = {
+  id: number;
+  username: string;
+  name: string;
+  phone: string;
+  avatarUrl: string;
+  role: string;
+  coins: number;
+  totalTopup: number;
+  vipLevel: number;
+  bonusCoins: number;
+  kycStatus: string;
+  kycFullName: string;
+  kycNumber: string;
+  kycDob: string;
+  referralCode?: string;
+  referredBy?: number;
+  createdAt: string;
+  updatedAt: string;
+  freeSpins: number;
+  ChestOpenCount: number;
+  status: string;
+  statusReason?: string;
+  statusUntil?: string;
+  closedAt?: string;
+};
+
+export type UserRestriction = {
+  id: number;
+  userId: number;
+  kind: string;
+  reason: string;
+  expiresAt?: string;
+  createdBy: number;
+  createdAt: string;
+  liftedAt?: string;
+  liftedBy?: number;
+};
+
+export type VipBuyRow = {
+  id: number;
+  level: number;
+  price: number;
+  oldLevel: number;
+  createdAt: string;
+};
+
+export type VipTier = {
+  id: number;
+  level: number;
+  name: string;
+  minTopup: number;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type VipVoucher = {
+  id: number;
+  percent: number;
+  usedAt: string | null;
+  createdAt: string;
+};
+
+export type WithdrawRequest = {
+  userId: number;
+  amount: number;
+  note?: string;
+};
+
+export type WithdrawRow = {
+  id: number;
+  amount: number;
+  note: string;
+  adminUsername: string;
+  createdAt: string;
+};
+
+export type ApiRequestInit = {
+  query?: Record<string, string | number | boolean | undefined | null>;
+  body?: unknown;
+  form?: FormData;
+  blob?: boolean;
+};
+
+// hàm gửi request thật (fetch + token + xử lý lỗi) do app cung cấp
+export type ApiRequest = <T>(method: string, path: string, init?: ApiRequestInit) => Promise<T>;
+
+export function createApiClient(request: ApiRequest) {
+  return {
+    /** GET /openapi.json — Tài liệu OpenAPI 3 (file này) */
+    openapi: () =>
+      request<Record<string, unknown>>('GET', '/openapi.json'),
+    /** POST /register — Đăng ký tài khoản */
+    register: (body: RegisterRequest) =>
+      request<{ message: string }>('POST', '/register', { body }),
+    /** POST /login — Đăng nhập, trả JWT */
+    login: (body: LoginRequest) =>
+      request<AuthResponse>('POST', '/login', { body }),
+    /** GET /vip-tiers — Bảng cấp VIP */
+    vipTiers: () =>
+      request<{ tiers: VipTier[] }>('GET', '/vip-tiers'),
+    /** GET /market — Danh sách bài đăng bán đang mở */
+    marketQuery: (query?: { code?: string }) =>
+      request<{ rows: MarketRow[] }>('GET', '/market', { query }),
+    /** GET /market/orderbook — Sổ lệnh gộp theo giá */
+    marketOrderBook: (query?: { code?: string; depth?: number }) =>
+      request<{ asks: OrderBookLevel[]; bids: OrderBookLevel[]; code: string }>('GET', '/market/orderbook', { query }),
+    /** GET /market/stats — Thống kê 24h và nến giá */
+    marketStats: (query?: { code?: string; interval?: string; limit?: number }) =>
+      request<{ candles: Candle[]; code: string; high24h: number; interval: string; lastPrice: number | null; low24h: number; trades24h: number; value24h: number; volume24h: number }>('GET', '/market/stats', { query }),
+    /** POST /forgot-password — Đặt lại mật khẩu bằng mật khẩu cấp 2 */
+    forgotPassword: (body: ForgotPasswordReq) =>
+      request<{ message: string }>('POST', '/forgot-password', { body }),
+    /** GET /public/leaderboard — Bảng xếp hạng (tính sẵn mỗi phút) */
+    publicLeaderboard: (query?: { kind?: string; window?: string; limit?: number }) =>
+      request<{ kind: string; rows: LeaderboardRow[]; updatedAt: string; window: string }>('GET', '/public/leaderboard', { query }),
+    /** GET /public/seasons — Danh sách mùa giải (kèm bảng giải thưởng) */
+    seasons: (query?: { status?: string }) =>
+      request<{ rows: Season[] }>('GET', '/public/seasons', { query }),
+    /** GET /public/data-export/:token — Tải file xuất dữ liệu cá nhân (link trong thông báo, có hạn) */
+    downloadDataExport: (token: string) =>
+      request<Blob>('GET', `/public/data-export/${token}`, { blob: true }),
+    /** GET /public/seasons/:id — Bảng xếp hạng mùa giải (đã chốt hoặc tạm tính) */
+    seasonStandings: (id: number, query?: { limit?: number }) =>
+      request<{ final: boolean; rows: SeasonStanding[]; season: Season }>('GET', `/public/seasons/${id}`, { query }),
+    /** GET /private/me — Thông tin tài khoản */
+    me: () =>
+      request<{ user: User }>('GET', '/private/me'),
+    /** PUT /private/profile — Cập nhật hồ sơ */
+    updateProfile: (body: ProfileUpdateRequest) =>
+      request<{ message: string; user: User }>('PUT', '/private/profile', { body }),
+    /** POST /private/upload — Tải ảnh đại diện */
+    uploadAvatar: (form: FormData /* file */) =>
+      request<{ url: string }>('POST', '/private/upload', { form }),
+    /** PUT /private/change-password — Đổi mật khẩu */
+    changePassword: (body: ChangePasswordRequest) =>
+      request<{ message: string }>('PUT', '/private/change-password', { body }),
+    /** POST /private/change-password — Đổi mật khẩu (alias cũ) @deprecated */
+    changePasswordLegacy: (body: ChangePasswordRequest) =>
+      request<{ message: string }>('POST', '/private/change-password', { body }),
+    /** PUT /private/security — Đặt mật khẩu cấp 2 / PIN giao dịch */
+    updateSecurity: (body: UpdateSecurityRequest) =>
+      request<{ message: string }>('PUT', '/private/security', { body }),
+    /** POST /private/update-security — Đặt mật khẩu cấp 2 / PIN (alias cũ) @deprecated */
+    updateSecurityLegacy: (body: UpdateSecurityRequest) =>
+      request<{ message: string }>('POST', '/private/update-security', { body }),
+    /** POST /private/kyc-submit — Gửi KYC kèm ảnh CCCD */
+    kycSubmit: (form: FormData /* fullName, dob, number, front, back */) =>
+      request<{ message: string; status: string }>('POST', '/private/kyc-submit', { form }),
+    /** POST /private/kyc — Gửi KYC (alias cũ) @deprecated */
+    kycSubmitLegacy: (form: FormData /* fullName, dob, number, front, back */) =>
+      request<{ message: string; status: string }>('POST', '/private/kyc', { form }),
+    /** PUT /private/kyc — Xác minh KYC nhanh (nickname + số CCCD) */
+    updateKyc: (body: KycUpdateRequest) =>
+      request<{ message: string }>('PUT', '/private/kyc', { body }),
+    /** GET /private/data-export — Các lần xuất dữ liệu cá nhân gần đây (kèm link tải) */
+    myDataExports: () =>
+      request<{ rows: DataExportView[] }>('GET', '/private/data-export'),
+    /** POST /private/data-export — Yêu cầu xuất dữ liệu cá nhân (tạo nền, 1 lần/24 giờ) */
+    requestDataExport: () =>
+      request<{ export: DataExportView; message: string }>('POST', '/private/data-export'),
+    /** POST /private/account/close — Đóng tài khoản (PIN + mật khẩu cấp 2; còn coin thì yêu cầu chi trả) */
+    closeMyAccount: (body: SelfCloseAccountRequest) =>
+      request<{ closed: boolean; message: string; request: AccountCloseRequest | null }>('POST', '/private/account/close', { body }),
+    /** GET /private/account/restrictions — Trạng thái tài khoản & các hạn chế đang áp dụng (lý do, hạn) */
+    myRestrictions: () =>
+      request<AccountRestrictions>('GET', '/private/account/restrictions'),
+    /** GET /private/wallet — Số dư và tiến độ rương */
+    wallet: () =>
+      request<{ bonusCoins: number; chestOpens: number; coins: number; freeSpins: number; remainingUntilBonus: number; totalCoins: number; totalTopup: number; vipLevel: number }>('GET', '/private/wallet'),
+    /** POST /private/transfer — Chuyển coin cho user khác */
+    transfer: (body: TransferRequest) =>
+      request<{ debit: number; fee: number; message: string; tier: string }>('POST', '/private/transfer', { body }),
+    /** GET /private/transfer/limits — Hạn mức chuyển coin theo VIP/KYC: phí, đã dùng, còn lại */
+    transferLimits: () =>
+      request<TransferLimits>('GET', '/private/transfer/limits'),
+    /** POST /private/buy-vip — Mua VIP 1 */
+    buyVip: () =>
+      request<{ coins: number; level: number; message: string }>('POST', '/private/buy-vip'),
+    /** GET /private/vip-vouchers — Phiếu giảm giá VIP của tôi */
+    myVipVouchers: () =>
+      request<{ rows: VipVoucher[] }>('GET', '/private/vip-vouchers'),
+    /** GET /private/history/withdraws — Lịch sử rút coin */
+    withdrawHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: WithdrawRow[] }>('GET', '/private/history/withdraws', { query }),
+    /** GET /private/history/withdraws/export — Xuất lịch sử rút coin (CSV/XLSX) */
+    exportWithdrawHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; format?: 'csv' | 'xlsx' }) =>
+      request<Blob>('GET', '/private/history/withdraws/export', { query, blob: true }),
+    /** GET /private/history/topups — Lịch sử nạp coin */
+    topupHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: TopupRow[] }>('GET', '/private/history/topups', { query }),
+    /** GET /private/history/topups/export — Xuất lịch sử nạp coin (CSV/XLSX) */
+    exportTopupHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; format?: 'csv' | 'xlsx' }) =>
+      request<Blob>('GET', '/private/history/topups/export', { query, blob: true }),
+    /** GET /private/history/transfers — Lịch sử chuyển coin */
+    transferHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; direction?: 'in' | 'out'; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: TransferRow[] }>('GET', '/private/history/transfers', { query }),
+    /** GET /private/history/transfers/export — Xuất lịch sử chuyển coin (CSV/XLSX) */
+    exportTransferHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; direction?: 'in' | 'out'; format?: 'csv' | 'xlsx' }) =>
+      request<Blob>('GET', '/private/history/transfers/export', { query, blob: true }),
+    /** GET /private/history/vip — Lịch sử mua VIP (amount = giá) */
+    vipHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: VipBuyRow[] }>('GET', '/private/history/vip', { query }),
+    /** GET /private/history/vip/export — Xuất lịch sử mua VIP (CSV/XLSX) */
+    exportVipHistory: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; format?: 'csv' | 'xlsx' }) =>
+      request<Blob>('GET', '/private/history/vip/export', { query, blob: true }),
+    /** GET /private/history/commissions — Hoa hồng đã nhận */
+    myCommissions: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; kind?: string; depth?: number; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: CommissionRow[] }>('GET', '/private/history/commissions', { query }),
+    /** GET /private/history/commissions/export — Xuất hoa hồng đã nhận (CSV/XLSX) */
+    exportMyCommissions: (query?: { from?: string; to?: string; minAmount?: number; maxAmount?: number; kind?: string; depth?: number; format?: 'csv' | 'xlsx' }) =>
+      request<Blob>('GET', '/private/history/commissions/export', { query, blob: true }),
+    /** GET /private/statement — Sao kê coin/bonus kèm số dư chạy, số dư đầu/cuối kỳ */
+    statement: (query?: { from?: string; to?: string; currency?: 'COIN' | 'BONUS'; cursor?: number; limit?: number }) =>
+      request<{ closing: StatementBalances; nextCursor: number | null; opening: StatementBalances; rows: StatementRow[] }>('GET', '/private/statement', { query }),
+    /** POST /private/redeem-code — Nhập gift code (mọi chiến dịch) */
+    redeemCode: (body: RedeemReq) =>
+      request<{ campaign: string; message: string; reward: RewardBundle }>('POST', '/private/redeem-code', { body }),
+    /** POST /private/chest-open — Mở rương */
+    chestOpen: () =>
+      request<ChestOpenResult>('POST', '/private/chest-open'),
+    /** GET /private/inventory — Túi đồ */
+    inventory: () =>
+      request<{ items: InventoryItem[] }>('GET', '/private/inventory'),
+    /** POST /private/merge-dragon — Hợp nhất 7 viên ngọc rồng */
+    mergeDragon: () =>
+      request<{ coins: number; message: string }>('POST', '/private/merge-dragon'),
+    /** POST /private/market/list — Đăng bán vật phẩm */
+    marketCreate: (body: MarketListRequest) =>
+      request<{ fills: MarketFill[]; message: string }>('POST', '/private/market/list', { body }),
+    /** POST /private/market/buy — Mua từ 1 bài đăng */
+    marketBuy: (body: MarketBuyRequest) =>
+      request<{ message: string; trade: MarketFill }>('POST', '/private/market/buy', { body }),
+    /** POST /private/market/withdraw — Rút vật phẩm về túi */
+    marketWithdraw: (body: MarketWithdrawRequest) =>
+      request<{ message: string }>('POST', '/private/market/withdraw', { body }),
+    /** POST /private/market/bids — Đặt lệnh mua giới hạn */
+    marketPlaceBid: (body: PlaceBidRequest) =>
+      request<{ bid: MarketBid; fills: MarketFill[]; message: string }>('POST', '/private/market/bids', { body }),
+    /** GET /private/market/bids — Lệnh mua của tôi */
+    marketMyBids: (query?: { status?: string }) =>
+      request<{ rows: MarketBid[] }>('GET', '/private/market/bids', { query }),
+    /** POST /private/market/bids/:id/cancel — Huỷ lệnh mua */
+    marketCancelBid: (id: number) =>
+      request<{ message: string; refund: number }>('POST', `/private/market/bids/${id}/cancel`),
+    /** POST /private/market/market-order — Lệnh mua thị trường */
+    marketOrder: (body: MarketOrderRequest) =>
+      request<{ avgPrice: number; filledQty: number; fills: MarketFill[]; message: string; mode: string; totalCost: number }>('POST', '/private/market/market-order', { body }),
+    /** GET /private/market/trades — Giao dịch khớp của tôi */
+    myMarketTrades: (query?: { side?: string; code?: string; limit?: number }) =>
+      request<{ rows: MyTradeRow[] }>('GET', '/private/market/trades', { query }),
+    /** GET /private/market/listings — Bài đăng của tôi */
+    myMarketListings: (query?: { activeOnly?: '0' | '1' }) =>
+      request<{ rows: MarketListing[] }>('GET', '/private/market/listings', { query }),
+    /** PUT /private/market/listings/:id — Sửa giá / số lượng bài đăng */
+    marketEditListing: (id: number, body: EditListingRequest) =>
+      request<{ fills: MarketFill[]; listing: MarketListing; message: string }>('PUT', `/private/market/listings/${id}`, { body }),
+    /** GET /private/notifications — Danh sách thông báo (phân trang cursor) */
+    notifications: (query?: { unreadOnly?: '0' | '1'; archived?: '0' | '1'; category?: string; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: Notification[]; unread: number }>('GET', '/private/notifications', { query }),
+    /** POST /private/events/ticket — Vé dùng 1 lần (1 phút) để mở luồng SSE */
+    eventsTicket: () =>
+      request<{ expiresAt: string; ticket: string }>('POST', '/private/events/ticket'),
+    /** PUT /private/notifications/mark-read — Đánh dấu đã đọc (không gửi ids = tất cả) */
+    markNotificationsRead: (body: MarkReadRequest) =>
+      request<void>('PUT', '/private/notifications/mark-read', { body }),
+    /** DELETE /private/notifications/:id — Xoá thông báo */
+    deleteNotification: (id: number) =>
+      request<void>('DELETE', `/private/notifications/${id}`),
+    /** POST /private/notifications/:id/archive — Lưu trữ thông báo */
+    archiveNotification: (id: number) =>
+      request<void>('POST', `/private/notifications/${id}/archive`),
+    /** POST /private/notifications/:id/unarchive — Bỏ lưu trữ thông báo */
+    unarchiveNotification: (id: number) =>
+      request<void>('POST', `/private/notifications/${id}/unarchive`),
+    /** GET /private/notification-preferences — Tuỳ chọn thông báo */
+    notificationPrefs: () =>
+      request<{ categories: string[]; locale: string; locales: string[]; muted: string[] }>('GET', '/private/notification-preferences'),
+    /** PUT /private/notification-preferences — Lưu tuỳ chọn thông báo */
+    updateNotificationPrefs: (body: NotificationPrefRequest) =>
+      request<{ locale: string; message: string; muted: string[] }>('PUT', '/private/notification-preferences', { body }),
+    /** GET /private/referral-info — Mã mời và số người đã mời */
+    referralInfo: () =>
+      request<{ code: string; count: number; link: string; total: number }>('GET', '/private/referral-info'),
+    /** GET /private/dashboard/overview — Tổng quan hệ thống F1..F9 */
+    dashboardOverview: () =>
+      request<DashboardOverview>('GET', '/private/dashboard/overview'),
+    /** GET /private/dashboard/commissions — Hoa hồng theo ngày trong tháng */
+    dashboardCommissions: (query?: { year?: number; month?: number }) =>
+      request<MonthlyCommissionResp>('GET', '/private/dashboard/commissions', { query }),
+    /** GET /private/downlines — Danh sách tuyến dưới */
+    myDownlines: (query?: { depth?: number }) =>
+      request<{ rows: DownlineRow[] }>('GET', '/private/downlines', { query }),
+    /** GET /private/downlines/:id/dashboard — Tổng quan của 1 tuyến dưới */
+    downlineDashboard: (id: number, query?: { month?: string }) =>
+      request<DownlineDashboardResp>('GET', `/private/downlines/${id}/dashboard`, { query }),
+    /** GET /private/leaderboard/me — Thứ hạng của tôi */
+    myLeaderboardRank: (query?: { kind?: string; window?: string }) =>
+      request<MyRankResp>('GET', '/private/leaderboard/me', { query }),
+    /** GET /private/seasons/:id/me — Hạng & phần thưởng của tôi trong mùa giải */
+    mySeasonStanding: (id: number) =>
+      request<{ final: boolean; standing: SeasonStanding | null }>('GET', `/private/seasons/${id}/me`),
+    /** GET /private/checkin — Trạng thái điểm danh, chuỗi ngày & lịch thưởng */
+    checkinStatus: () =>
+      request<CheckinStatus>('GET', '/private/checkin'),
+    /** POST /private/checkin — Điểm danh hôm nay (gọi lại trong ngày không nhận thêm) */
+    checkin: () =>
+      request<{ alreadyCheckedIn: boolean; checkin: CheckinLog; message: string }>('POST', '/private/checkin'),
+    /** PUT /private/checkin/timezone — Đổi múi giờ điểm danh (7 ngày/lần) */
+    checkinTimezone: (body: CheckinTimezoneRequest) =>
+      request<{ message: string; timezone: string }>('PUT', '/private/checkin/timezone', { body }),
+    /** GET /private/missions — Nhiệm vụ đang mở & tiến độ chu kỳ hiện tại */
+    missions: () =>
+      request<{ rows: MissionView[] }>('GET', '/private/missions'),
+    /** POST /private/missions/:id/claim — Nhận thưởng nhiệm vụ đã hoàn thành */
+    claimMission: (id: number) =>
+      request<{ message: string; reward: RewardBundle }>('POST', `/private/missions/${id}/claim`),
+    /** GET /private/shop — Quà đang mở đổi */
+    shopList: () =>
+      request<{ rows: ShopItemView[] }>('GET', '/private/shop'),
+    /** POST /private/shop/buy — Đổi quà */
+    shopBuy: (body: ShopBuyRequest) =>
+      request<{ message: string; purchase: ShopPurchase }>('POST', '/private/shop/buy', { body }),
+    /** GET /private/shop/purchases — Lịch sử đổi quà */
+    shopMyPurchases: () =>
+      request<{ rows: ShopPurchaseRow[] }>('GET', '/private/shop/purchases'),
+    /** POST /admin/topup — Nạp coin cho user */
+    adminTopup: (body: TopupRequest) =>
+      request<{ message: string; userId: number }>('POST', '/admin/topup', { body }),
+    /** POST /admin/withdraw — Rút coin của user */
+    adminWithdraw: (body: WithdrawRequest) =>
+      request<{ message: string; userId: number }>('POST', '/admin/withdraw', { body }),
+    /** GET /admin/users — Tìm user */
+    adminUsers: (query?: { vipLevel?: string; nickname?: string; username?: string; email?: string }) =>
+      request<{ rows: AdminUserRow[] }>('GET', '/admin/users', { query }),
+    /** GET /admin/users/:id — Chi tiết user */
+    adminUserDetail: (id: number) =>
+      request<{ user: AdminUserDetail }>('GET', `/admin/users/${id}`),
+    /** POST /admin/users/:id/close — Đóng tài khoản: ẩn danh hoá, giữ lịch sử tài chính */
+    adminCloseAccount: (id: number, body: CloseAccountRequest) =>
+      request<{ closure: AccountClosure; message: string }>('POST', `/admin/users/${id}/close`, { body }),
+    /** PUT /admin/users/:id/status — Khoá (FROZEN) / cấm (BANNED) / mở lại tài khoản, kèm lý do & hạn */
+    adminSetAccountStatus: (id: number, body: AccountStatusRequest) =>
+      request<{ account: AccountRestrictions; message: string }>('PUT', `/admin/users/${id}/status`, { body }),
+    /** GET /admin/users/:id/restrictions — Trạng thái & lịch sử hạn chế của user */
+    adminUserRestrictions: (id: number) =>
+      request<AccountRestrictions>('GET', `/admin/users/${id}/restrictions`),
+    /** POST /admin/users/:id/restrictions — Hạn chế chức năng TRANSFER | MARKET | CHEST | WITHDRAW */
+    adminAddRestriction: (id: number, body: RestrictionRequest) =>
+      request<{ message: string; restriction: UserRestriction }>('POST', `/admin/users/${id}/restrictions`, { body }),
+    /** DELETE /admin/users/:id/restrictions/:rid — Gỡ hạn chế */
+    adminLiftRestriction: (id: number, rid: string) =>
+      request<{ message: string; restriction: UserRestriction }>('DELETE', `/admin/users/${id}/restrictions/${rid}`),
+    /** DELETE /admin/users/:id — Xoá hẳn tài khoản đã đóng quá hạn lưu trữ (superadmin) */
+    adminDeleteUser: (id: number) =>
+      request<void>('DELETE', `/admin/users/${id}`),
+    /** GET /admin/account-closures — Hồ sơ đóng tài khoản */
+    adminAccountClosures: (query?: { purgeable?: string; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; retentionDays: number; rows: AccountClosure[] }>('GET', '/admin/account-closures', { query }),
+    /** GET /admin/account-close-requests — Yêu cầu đóng tài khoản chờ chi trả */
+    adminCloseRequests: (query?: { status?: 'PENDING' | 'APPROVED' | 'REJECTED' | 'all'; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: AccountCloseRequest[] }>('GET', '/admin/account-close-requests', { query }),
+    /** POST /admin/account-close-requests/:id/approve — Đã chi trả: rút toàn bộ coin & đóng tài khoản */
+    adminApproveCloseRequest: (id: number, body: CloseRequestDecision) =>
+      request<{ closure: AccountClosure; message: string; request: AccountCloseRequest }>('POST', `/admin/account-close-requests/${id}/approve`, { body }),
+    /** POST /admin/account-close-requests/:id/reject — Từ chối yêu cầu đóng tài khoản, mở khoá */
+    adminRejectCloseRequest: (id: number, body: CloseRequestDecision) =>
+      request<{ message: string; request: AccountCloseRequest }>('POST', `/admin/account-close-requests/${id}/reject`, { body }),
+    /** GET /admin/kyc/:userId/front — Ảnh CCCD mặt trước */
+    adminKycFront: (userId: number) =>
+      request<Blob>('GET', `/admin/kyc/${userId}/front`, { blob: true }),
+    /** GET /admin/kyc/:userId/back — Ảnh CCCD mặt sau */
+    adminKycBack: (userId: number) =>
+      request<Blob>('GET', `/admin/kyc/${userId}/back`, { blob: true }),
+    /** GET /admin/kyc-file/:userId/:side — Ảnh CCCD theo mặt */
+    adminKycImage: (userId: number, side: 'front' | 'back') =>
+      request<Blob>('GET', `/admin/kyc-file/${userId}/${side}`, { blob: true }),
+    /** GET /admin/promo-campaigns — Chiến dịch gift code */
+    adminPromoCampaigns: (query?: { status?: string; q?: string; from?: string; to?: string; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: PromoCampaignRow[] }>('GET', '/admin/promo-campaigns', { query }),
+    /** POST /admin/promo-campaigns — Tạo chiến dịch & sinh code */
+    adminCreatePromoCampaign: (body: PromoCampaignRequest) =>
+      request<{ campaign: PromoCampaign; codes: string[]; message: string }>('POST', '/admin/promo-campaigns', { body }),
+    /** POST /admin/promo-campaigns/:id/codes — Sinh thêm code cho chiến dịch */
+    adminCreatePromoCodes: (id: number, body: PromoCodesRequest) =>
+      request<{ codes: string[]; count: number; message: string }>('POST', `/admin/promo-campaigns/${id}/codes`, { body }),
+    /** GET /admin/promo-campaigns/:id/redemptions — Lượt nhập code của chiến dịch */
+    adminPromoCampaignRedemptions: (id: number, query?: { from?: string; to?: string; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: PromoRedemptionRow[] }>('GET', `/admin/promo-campaigns/${id}/redemptions`, { query }),
+    /** GET /admin/promo-campaigns/:id/export — Xuất lô code (CSV hoặc trang in kèm QR) */
+    adminExportPromoCodes: (id: number, query?: { format?: 'csv' | 'html'; status?: string }) =>
+      request<Blob>('GET', `/admin/promo-campaigns/${id}/export`, { query, blob: true }),
+    /** POST /admin/promo-campaigns/:id/revoke — Thu hồi chiến dịch (mọi code ngừng nhận) */
+    adminRevokePromoCampaign: (id: number) =>
+      request<{ campaign: PromoCampaign; message: string }>('POST', `/admin/promo-campaigns/${id}/revoke`),
+    /** POST /admin/promo-campaigns/:id/reactivate — Kích hoạt lại chiến dịch */
+    adminReactivatePromoCampaign: (id: number) =>
+      request<{ campaign: PromoCampaign; message: string }>('POST', `/admin/promo-campaigns/${id}/reactivate`),
+    /** POST /admin/promo-campaigns/:id/archive — Lưu trữ chiến dịch (thay cho xoá) */
+    adminArchivePromoCampaign: (id: number) =>
+      request<{ campaign: PromoCampaign; message: string }>('POST', `/admin/promo-campaigns/${id}/archive`),
+    /** POST /admin/promo-campaigns/:id/unarchive — Bỏ lưu trữ chiến dịch */
+    adminUnarchivePromoCampaign: (id: number) =>
+      request<{ campaign: PromoCampaign; message: string }>('POST', `/admin/promo-campaigns/${id}/unarchive`),
+    /** GET /admin/promo-codes — Danh sách code */
+    adminPromoCodes: (query?: { campaignId?: number; status?: string; q?: string; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: PromoCodeRow[] }>('GET', '/admin/promo-codes', { query }),
+    /** GET /admin/promo-codes/:id/redemptions — Ai đã nhập code, lúc nào */
+    adminPromoCodeRedemptions: (id: number, query?: { from?: string; to?: string; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: PromoRedemptionRow[] }>('GET', `/admin/promo-codes/${id}/redemptions`, { query }),
+    /** POST /admin/promo-codes/:id/revoke — Thu hồi 1 code */
+    adminRevokePromoCode: (id: number) =>
+      request<{ code: PromoCampaignCode; message: string }>('POST', `/admin/promo-codes/${id}/revoke`),
+    /** POST /admin/promo-codes/:id/reactivate — Kích hoạt lại 1 code */
+    adminReactivatePromoCode: (id: number) =>
+      request<{ code: PromoCampaignCode; message: string }>('POST', `/admin/promo-codes/${id}/reactivate`),
+    /** GET /admin/shop/items — Tất cả quà */
+    adminShopItems: () =>
+      request<{ rows: ShopItem[] }>('GET', '/admin/shop/items'),
+    /** POST /admin/shop/items — Tạo quà */
+    adminCreateShopItem: (body: ShopItemRequest) =>
+      request<{ item: ShopItem; message: string }>('POST', '/admin/shop/items', { body }),
+    /** PUT /admin/shop/items/:id — Sửa quà */
+    adminUpdateShopItem: (id: number, body: ShopItemRequest) =>
+      request<{ item: ShopItem; message: string }>('PUT', `/admin/shop/items/${id}`, { body }),
+    /** GET /admin/shop/purchases — Lịch sử đổi quà toàn hệ thống */
+    adminShopPurchases: (query?: { itemId?: number; userId?: number }) =>
+      request<{ rows: AdminShopPurchaseRow[] }>('GET', '/admin/shop/purchases', { query }),
+    /** GET /admin/market/listings — Bài đăng trên chợ */
+    adminMarketListings: (query?: { status?: string; code?: string; sellerId?: number }) =>
+      request<{ rows: AdminListingRow[] }>('GET', '/admin/market/listings', { query }),
+    /** POST /admin/market/listings/:id/cancel — Gỡ bài đăng, trả hàng cho người bán */
+    adminCancelMarketListing: (id: number, body: CancelListingRequest) =>
+      request<{ message: string; returnedQty: number }>('POST', `/admin/market/listings/${id}/cancel`, { body }),
+    /** GET /admin/transfer-tiers — Hạn mức & phí chuyển coin theo VIP/KYC */
+    adminTransferTiers: () =>
+      request<{ rows: TransferTier[] }>('GET', '/admin/transfer-tiers'),
+    /** PUT /admin/transfer-tiers — Tạo / sửa hạn mức theo (minVip, kyc) */
+    adminUpsertTransferTier: (body: TransferTierRequest) =>
+      request<{ message: string; tier: TransferTier }>('PUT', '/admin/transfer-tiers', { body }),
+    /** DELETE /admin/transfer-tiers/:id — Xoá hạn mức (trừ tier gốc VIP 0, chưa KYC) */
+    adminDeleteTransferTier: (id: number) =>
+      request<{ message: string }>('DELETE', `/admin/transfer-tiers/${id}`),
+    /** GET /admin/settings — Cấu hình runtime */
+    adminSettings: () =>
+      request<{ defaults: Record<string, string>; settings: Record<string, string> }>('GET', '/admin/settings'),
+    /** PUT /admin/settings — Lưu 1 cấu hình */
+    adminUpdateSetting: (body: SettingUpdateRequest) =>
+      request<{ key: string; message: string; value: string }>('PUT', '/admin/settings', { body }),
+    /** POST /admin/notifications/broadcast — Gửi thông báo hàng loạt (dryRun: chỉ đếm) */
+    adminSendBroadcast: (body: BroadcastRequest) =>
+      request<{ broadcast?: Broadcast; message?: string; recipients?: number; segment?: BroadcastSegment }>('POST', '/admin/notifications/broadcast', { body }),
+    /** GET /admin/notifications/broadcasts — Danh sách broadcast */
+    adminBroadcasts: (query?: { status?: string }) =>
+      request<{ rows: BroadcastView[] }>('GET', '/admin/notifications/broadcasts', { query }),
+    /** GET /admin/notifications/broadcasts/:id — Chi tiết broadcast */
+    adminBroadcastDetail: (id: number) =>
+      request<BroadcastView>('GET', `/admin/notifications/broadcasts/${id}`),
+    /** POST /admin/notifications/broadcasts/:id/cancel — Huỷ broadcast chưa gửi xong */
+    adminCancelBroadcast: (id: number) =>
+      request<{ message: string }>('POST', `/admin/notifications/broadcasts/${id}/cancel`),
+    /** GET /admin/notification-templates — Mẫu thông báo (mặc định + ghi đè) */
+    adminNotificationTemplates: () =>
+      request<{ locales: string[]; rows: NotificationTemplateRow[] }>('GET', '/admin/notification-templates'),
+    /** PUT /admin/notification-templates — Ghi đè mẫu thông báo */
+    adminUpsertNotificationTemplate: (body: NotificationTemplateRequest) =>
+      request<{ message: string }>('PUT', '/admin/notification-templates', { body }),
+    /** DELETE /admin/notification-templates — Khôi phục mẫu mặc định */
+    adminResetNotificationTemplate: (query?: { type?: string; locale?: string }) =>
+      request<{ message: string }>('DELETE', '/admin/notification-templates', { query }),
+    /** GET /admin/seasons — Danh sách mùa giải */
+    adminSeasons: (query?: { status?: string }) =>
+      request<{ rows: Season[] }>('GET', '/admin/seasons', { query }),
+    /** POST /admin/seasons — Tạo mùa giải */
+    adminCreateSeason: (body: SeasonRequest) =>
+      request<{ message: string; season: Season }>('POST', '/admin/seasons', { body }),
+    /** PUT /admin/seasons/:id — Sửa mùa giải chưa bắt đầu */
+    adminUpdateSeason: (id: number, body: SeasonRequest) =>
+      request<{ message: string; season: Season }>('PUT', `/admin/seasons/${id}`, { body }),
+    /** POST /admin/seasons/:id/cancel — Huỷ mùa giải chưa kết thúc */
+    adminCancelSeason: (id: number) =>
+      request<{ message: string }>('POST', `/admin/seasons/${id}/cancel`),
+    /** GET /admin/checkin/calendar — Lịch thưởng điểm danh */
+    adminCheckinCalendar: () =>
+      request<{ days: CheckinDay[] }>('GET', '/admin/checkin/calendar'),
+    /** PUT /admin/checkin/calendar — Thay lịch thưởng điểm danh */
+    adminUpdateCheckinCalendar: (body: CheckinCalendarRequest) =>
+      request<{ days: CheckinDay[]; message: string }>('PUT', '/admin/checkin/calendar', { body }),
+    /** GET /admin/missions — Mọi nhiệm vụ & danh sách sự kiện */
+    adminMissions: () =>
+      request<{ events: DomainEventDef[]; rows: Mission[] }>('GET', '/admin/missions'),
+    /** POST /admin/missions — Tạo nhiệm vụ */
+    adminCreateMission: (body: MissionRequest) =>
+      request<{ message: string; mission: Mission }>('POST', '/admin/missions', { body }),
+    /** PUT /admin/missions/:id — Sửa / bật tắt nhiệm vụ */
+    adminUpdateMission: (id: number, body: MissionRequest) =>
+      request<{ message: string; mission: Mission }>('PUT', `/admin/missions/${id}`, { body }),
+    /** GET /admin/stats — Kinh tế: nguồn vào/ra, coin lưu hành, hoạt động theo ngày */
+    adminStats: (query?: { from?: string; to?: string }) =>
+      request<AdminStats>('GET', '/admin/stats', { query }),
+    /** GET /admin/jobs — Job nền & trạng thái */
+    adminJobs: () =>
+      request<{ rows: JobView[] }>('GET', '/admin/jobs'),
+    /** PUT /admin/jobs/:name — Đổi lịch cron của job */
+    adminUpdateJob: (name: string, body: JobScheduleRequest) =>
+      request<{ job: Job; message: string }>('PUT', `/admin/jobs/${name}`, { body }),
+    /** GET /admin/jobs/:name/runs — Lịch sử chạy job */
+    adminJobRuns: (name: string, query?: { status?: 'RUNNING' | 'OK' | 'FAILED'; from?: string; to?: string; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: JobRun[] }>('GET', `/admin/jobs/${name}/runs`, { query }),
+    /** POST /admin/jobs/:name/run — Chạy job ngay */
+    adminRunJob: (name: string) =>
+      request<{ message: string }>('POST', `/admin/jobs/${name}/run`),
+    /** POST /admin/jobs/:name/pause — Tạm dừng job */
+    adminPauseJob: (name: string) =>
+      request<{ job: Job; message: string }>('POST', `/admin/jobs/${name}/pause`),
+    /** POST /admin/jobs/:name/resume — Chạy lại job theo lịch */
+    adminResumeJob: (name: string) =>
+      request<{ job: Job; message: string }>('POST', `/admin/jobs/${name}/resume`),
+    /** GET /admin/reconciliation/drifts — Số dư lệch sổ cái (đối soát) */
+    adminBalanceDrifts: (query?: { status?: 'OPEN' | 'RESOLVED' | 'all'; userId?: number; cursor?: number; limit?: number }) =>
+      request<{ nextCursor: number | null; rows: BalanceDriftRow[] }>('GET', '/admin/reconciliation/drifts', { query }),
+    /** GET /admin/reconciliation/drifts/:id — Chi tiết lệch: sổ cái sau mốc khớp cuối & bản ghi nguồn không khớp */
+    adminBalanceDrift: (id: number) =>
+      request<{ checkpoint: BalanceCheckpoint; drift: BalanceDrift; ledger: LedgerEntry[]; sources: ReconSourceRow[] }>('GET', `/admin/reconciliation/drifts/${id}`),
+    /** POST /admin/reconciliation/drifts/:id/resolve — Xử lý lệch: ACCEPT (ghi bù sổ cái) | FIX (trả số dư về sổ cái) */
+    adminResolveBalanceDrift: (id: number, body: ResolveDriftRequest) =>
+      request<{ applied: number; drift: BalanceDrift; message: string; unfrozen: boolean }>('POST', `/admin/reconciliation/drifts/${id}/resolve`, { body }),
+  };
+}
+
+export type ApiClient = ReturnType<typeof createApiClient>;
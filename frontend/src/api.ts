@@ -1,5 +1,7 @@
 // src/api.ts
 import { getToken, clearAuth } from './auth';
+import { createApiClient, type ApiRequest, type ApiRequestInit } from './api.gen';
+import type * as gen from './api.gen';
 
 export class AuthError extends Error {}
 
@@ -46,190 +48,117 @@ async function http<T>(path: string, opts: RequestInit = {}): Promise<T> {
   return (text ? JSON.parse(text) : (undefined as unknown)) as T;
 }
 
-/* -------------------------------- types ---------------------------- */
-export type User = {
-  id: number;
-  username: string;   // tên đăng nhập
-  name: string;       // biệt danh
-  phone: string;
-  avatarUrl?: string;
-  role: 'admin' | 'user';
-  coins: number;
-  totalTopup: number;
-  vipLevel: number;   // giữ tương thích
-};
-
-export type VipTier = { level: number; name: string; minTopup: number };
-export type Wallet = { coins: number; totalTopup: number; vipLevel: number };
-
-export type AdminUserRow = {
-  id: number;
-  username: string;
-  nickname: string;
-  vipLevel: number;
-  totalTopup: number;
-  coins: number;
-};
-
-export type InventoryItem = { code: string; qty: number };
-export type AdminUserDetail = {
-  id: number;
-  username: string;
-  name: string;
-  phone: string;
-  avatarUrl?: string;
-  role: 'admin'|'user';
-  coins: number;
-  totalTopup: number;
-  vipLevel: number;
-
-  kycStatus?: 'NONE'|'VERIFIED'|'APPROVED'|'PENDING'|'REJECTED'; // tuỳ BE
-  kycFullName?: string;
-  kycNumber?: string;
-  kycDob?: string;
-  hasKycFront: boolean;
-  hasKycBack: boolean;
-};
-export type MarketRow = {
-  id: number;
-  code: string;
-  qty: number;
-  pricePerUnit: number;
-  sellerId: number;
-  // hỗ trợ cả 2 để không vỡ UI cũ:
-  sellerUsername?: string;
-  sellerEmail?: string;
-  buyQty?: number;
-};
-
-export type TopupHistoryRow = {
-  id: number; amount: number; note: string; adminUsername: string; createdAt: string;
-};
-
-export type WithdrawHistoryRow = {
-  id: number; amount: number; note: string; adminUsername: string; createdAt: string;
-};
-
-export type TransferHistoryRow = {
-  id: number; direction: 'in' | 'out'; amount: number; fee: number; counterpart: string; createdAt: string;
+// client sinh từ /openapi.json (backend: go run . gen-client ../frontend/src/api.gen.ts)
+const request: ApiRequest = async <T>(method: string, path: string, init: ApiRequestInit = {}) => {
+  const url = `${path}${qs(init.query)}`;
+  if (!init.blob) {
+    const body = init.form ?? (init.body === undefined ? undefined : JSON.stringify(init.body));
+    return http<T>(url, { method, body });
+  }
+  const token = getToken();
+  const res = await fetch(`${BASE}${url}`, { method, headers: token ? { Authorization: `Bearer ${token}` } : {} });
+  if (res.status === 401) { try { clearAuth(); } catch {} throw new AuthError('UNAUTHORIZED'); }
+  if (!res.ok) throw new Error(`HTTP ${res.status}`);
+  return (await res.blob()) as T;
 };
 
-export type VipHistoryRow = {
-  id: number; level: number; price: number; oldLevel: number; createdAt: string;
-};
+export const client = createApiClient(request);
 
-export type CommissionHistoryRow = {
-  id: number;
-  buyerUsername: string; // nếu BE còn trả email, đổi mapper ở BE hoặc sửa tên ở đây
-  depth: number; percent: number; amount: number; kind: 'UPLINE' | 'ADMIN'; vipLevel: number; createdAt: string;
-};
+/* -------------------------------- types ---------------------------- */
+// Kiểu dữ liệu lấy từ api.gen.ts, chỉ giữ tên cũ cho các view
+export type User = gen.User;
+export type VipTier = gen.VipTier;
+export type Wallet = Awaited<ReturnType<typeof client.wallet>>;
+export type AdminUserRow = gen.AdminUserRow;
+export type InventoryItem = gen.InventoryItem;
+export type AdminUserDetail = gen.AdminUserDetail;
+export type MarketRow = gen.MarketRow & { buyQty?: number };
+
+export type TopupHistoryRow = gen.TopupRow;
+export type WithdrawHistoryRow = gen.WithdrawRow;
+export type TransferHistoryRow = gen.TransferRow;
+export type VipHistoryRow = gen.VipBuyRow;
+export type CommissionHistoryRow = gen.CommissionRow;
+export type StatementRow = gen.StatementRow;
 
 /* -------------------------------- api ------------------------------ */
 export const api = {
   /* ===== Public ===== */
-  register: (body: { username: string; password: string; nickname: string; phone: string; ref?: string }) =>
-    http<{ message: string }>('/register', { method: 'POST', body: JSON.stringify(body) }),
+  register: (body: gen.RegisterRequest) => client.register(body),
 
-  login: (body: { username: string; password: string }) =>
-    http<{ token: string; user: User }>('/login', { method: 'POST', body: JSON.stringify(body) }),
+  login: (body: gen.LoginRequest) => client.login(body),
 
-  vipTiers: () => http<{ tiers: VipTier[] }>('/vip-tiers'),
+  vipTiers: () => client.vipTiers(),
 
   /* ===== Private ===== */
-  me: () => http<{ user: User }>('/private/me'),
+  me: () => client.me(),
 
-  updateProfile: (body: { name: string; phone: string; avatarUrl?: string }) =>
-    http<{ user: User; message: string }>('/private/profile', { method: 'PUT', body: JSON.stringify(body) }),
+  updateProfile: (body: gen.ProfileUpdateRequest) => client.updateProfile(body),
 
   // Thêm: đổi mật khẩu đăng nhập
-  changePassword: (body: { oldPassword: string; newPassword: string }) =>
-    http<{ message: string }>('/private/change-password', { method: 'PUT', body: JSON.stringify(body) }),
+  changePassword: (body: gen.ChangePasswordRequest) => client.changePassword(body),
 
-  wallet: () => http<Wallet>('/private/wallet'),
+  wallet: () => client.wallet(),
 
-  uploadAvatar: async (file: File): Promise<{ url: string }> => {
+  uploadAvatar: (file: File) => {
     const fd = new FormData();
     fd.append('file', file);
-    const token = getToken();
-    const res = await fetch(`${BASE}/private/upload`, {
-      method: 'POST',
-      headers: token ? { Authorization: `Bearer ${token}` } : {},
-      body: fd,
-    });
-    if (res.status === 401) {
-      try { clearAuth(); } catch {}
-      throw new AuthError('UNAUTHORIZED');
-    }
-    if (!res.ok) {
-      let msg = `HTTP ${res.status}`;
-      try { const j = await res.json(); msg = j?.error || j?.message || msg; } catch {}
-      throw new Error(msg);
-    }
-    return res.json();
+    return client.uploadAvatar(fd);
   },
 
   /* ===== Referral ===== */
-  referralInfo: () =>
-    http<{ code: string; link: string; count: number; total: number }>('/private/referral-info'),
+  referralInfo: () => client.referralInfo(),
 
   /* ===== Ví & giao dịch ===== */
-transfer: (body: { toUsername: string; amount: number; note?: string; txnPin: string }) =>
-  http<{ message: string; fee: number; debit: number }>('/private/transfer', {
-    method: 'POST',
-    body: JSON.stringify(body),
-  }),
+  transfer: (body: gen.TransferRequest) => client.transfer(body),
 
-  buyVip: () => http<{ message: string; level: number; coins: number }>('/private/buy-vip', { method: 'POST' }),
+  buyVip: () => client.buyVip(),
 
   /* ===== Lịch sử ===== */
-  topupHistory: () => http<{ rows: TopupHistoryRow[] }>('/private/history/topups'),
-  withdrawHistory: () => http<{ rows: WithdrawHistoryRow[] }>('/private/history/withdraws'),
-  transferHistory: () => http<{ rows: TransferHistoryRow[] }>('/private/history/transfers'),
-  vipHistory: () => http<{ rows: VipHistoryRow[] }>('/private/history/vip'),
-  myCommissions: () => http<{ rows: CommissionHistoryRow[] }>('/private/history/commissions'),
+  // phân trang cursor: truyền nextCursor của trang trước vào query.cursor; xuất file dùng client.export*History
+  topupHistory: (query?: Parameters<typeof client.topupHistory>[0]) => client.topupHistory(query),
+  withdrawHistory: (query?: Parameters<typeof client.withdrawHistory>[0]) => client.withdrawHistory(query),
+  transferHistory: (query?: Parameters<typeof client.transferHistory>[0]) => client.transferHistory(query),
+  vipHistory: (query?: Parameters<typeof client.vipHistory>[0]) => client.vipHistory(query),
+  myCommissions: (query?: Parameters<typeof client.myCommissions>[0]) => client.myCommissions(query),
   commissionsHistory() { return this.myCommissions(); },
+  // sao kê gộp coin + bonus; rows[i].balance = số dư sau dòng đó, opening/closing theo from/to
+  statement: (query?: Parameters<typeof client.statement>[0]) => client.statement(query),
 
   /* ===== Treasure ===== */
-  chestOpen: () =>
-    http<{ result: 'COIN' | 'DRAGON_BALL'; code?: string; amount: number; coins: number; inv: Record<string, number> }>(
-      '/private/chest-open', { method: 'POST' }
-    ),
+  chestOpen: () => client.chestOpen(),
 
-  inventory: () => http<{ items: InventoryItem[] }>('/private/inventory'),
-  mergeDragon: () => http<{ message: string; coins: number }>('/private/merge-dragon', { method: 'POST' }),
+  inventory: () => client.inventory(),
+  mergeDragon: () => client.mergeDragon(),
 
   /* ===== Market ===== */
-  marketList: (code?: string) =>
-    http<{ rows: MarketRow[] }>(`/market${qs({ code })}`),
+  marketList: (code?: string) => client.marketQuery({ code }),
 
-  marketCreate: (body: { code: string; qty: number; pricePerUnit: number }) =>
-    http<{ message: string }>('/private/market/list', { method: 'POST', body: JSON.stringify(body) }),
+  marketCreate: (body: gen.MarketListRequest) => client.marketCreate(body),
 
-  marketBuy: (body: { listingId: number; qty: number }) =>
-    http<{ message: string }>('/private/market/buy', { method: 'POST', body: JSON.stringify(body) }),
+  marketBuy: (body: gen.MarketBuyRequest) => client.marketBuy(body),
 
-  marketWithdraw: (body: { listingId: number; qty?: number }) =>
-    http<{ message: string }>('/private/market/withdraw', { method: 'POST', body: JSON.stringify(body) }),
+  marketWithdraw: (body: gen.MarketWithdrawRequest) => client.marketWithdraw(body),
 
   /* ===== Admin ===== */
   adminUsers: (filters?: { vipLevel?: string | number; username?: string; nickname?: string }) =>
-    http<{ rows: AdminUserRow[] }>(
-      `/admin/users${qs({ vipLevel: filters?.vipLevel, username: filters?.username, nickname: filters?.nickname })}`
-    ),
+    client.adminUsers({
+      vipLevel: filters?.vipLevel === undefined ? undefined : String(filters.vipLevel),
+      username: filters?.username,
+      nickname: filters?.nickname,
+    }),
+
+  adminTopup: (body: gen.TopupRequest) => client.adminTopup(body),
 
-  adminTopup: (body: { userId: number; amount: number; note?: string }) =>
-    http<{ message: string; userId: number }>('/admin/topup', { method: 'POST', body: JSON.stringify(body) }),
+  adminWithdraw: (body: gen.WithdrawRequest) => client.adminWithdraw(body),
 
-  adminWithdraw: (body: { userId: number; amount: number; note?: string }) =>
-    http<{ message: string; userId: number }>('/admin/withdraw', { method: 'POST', body: JSON.stringify(body) }),
+  adminCloseAccount: (id: number, body: gen.CloseAccountRequest) => client.adminCloseAccount(id, body),
 
-  adminDeleteUser: (id: number) =>
-    http<{ message: string }>(`/admin/users/${id}`, { method: 'DELETE' }),
+  adminDeleteUser: (id: number) => client.adminDeleteUser(id),
 
   /* ===== Quên mật khẩu (bằng mật khẩu cấp 2) ===== */
 
-forgotPassword: (body:{username:string; secPassword:string; newPassword:string}) =>
-  http<{message:string}>('/forgot-password', { method:'POST', body: JSON.stringify(body) }),
+  forgotPassword: (body: gen.ForgotPasswordReq) => client.forgotPassword(body),
 
 
   /* ===== Bảo mật nâng cao ===== */
@@ -264,10 +193,7 @@ updateSecurity: (rawBody: any) => {
     throw new Error('Chưa có thay đổi nào để lưu.');
   }
 
-  return http<{ message: string }>('/private/security', {
-    method: 'PUT',
-    body: JSON.stringify(payload),
-  });
+  return client.updateSecurity(payload);
 },
 
 
@@ -280,20 +206,11 @@ updateSecurity: (rawBody: any) => {
       method: 'PUT',
       body: JSON.stringify({ frontPath: body.frontUrl, backPath: body.backUrl }),
     }),
-adminUserDetail: (id: number) =>
-  http<{ user: AdminUserDetail }>(`/admin/users/${id}`),
+adminUserDetail: (id: number) => client.adminUserDetail(id),
 
 // Lấy ảnh KYC (trả về ObjectURL để gán vào <img>)
-adminKycImage: async (userId: number, side: 'front'|'back'): Promise<string> => {
-  const token = getToken();
-  const res = await fetch(`${BASE}/admin/kyc-file/${userId}/${side}`, {
-    headers: token ? { Authorization: `Bearer ${token}` } : {},
-  });
-  if (res.status === 401) { try{ clearAuth(); }catch{}; throw new AuthError('UNAUTHORIZED'); }
-  if (!res.ok) throw new Error(`HTTP ${res.status}`);
-  const blob = await res.blob();
-  return URL.createObjectURL(blob);
-},
+adminKycImage: async (userId: number, side: 'front'|'back'): Promise<string> =>
+  URL.createObjectURL(await client.adminKycImage(userId, side)),
 };
 
 export default api;